@@ -0,0 +1,243 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// a command line is worth an alias when run this often and at least this long
+const (
+	aliasSuggestionRuns   = 3
+	aliasSuggestionLength = 12
+)
+
+// aliasSuggestion proposes an alias for a command line run often
+type aliasSuggestion struct {
+	Name  string
+	ID    string
+	Line  string
+	Count int
+}
+
+// aliasName proposes a name made of the initials of the words of the command line starting with a
+// letter, so not the flags nor the paths, e.g. dcu for docker compose up -d; a name already taken or
+// naming an executable of the PATH gets a number
+func aliasName(c models.Command, taken map[string]bool) string {
+	var initials = ""
+	for _, word := range append([]string{c.Name}, c.Arguments...) {
+		if word != "" && aliasRegexp.MatchString(word[:1]) {
+			initials += strings.ToLower(word[:1])
+		}
+	}
+	if initials == "" {
+		initials = "a"
+	}
+
+	var name = initials
+	for n := 2; ; n++ {
+		if _, err := exec.LookPath(name); !taken[name] && err != nil {
+			return name
+		}
+		name = initials + strconv.Itoa(n)
+	}
+}
+
+// suggestAliases proposes aliases for the long command lines run often and not named by an alias yet,
+// the ones saving the most typing first; the history comes the latest first
+func suggestAliases(history []models.Command, aliases map[string]string, aliased map[string]bool) []aliasSuggestion {
+	var counts = map[string]int{}
+	for _, c := range history {
+		counts[commandLine(c)]++
+	}
+
+	var taken = map[string]bool{}
+	for alias := range aliases {
+		taken[alias] = true
+	}
+
+	var suggestions = []aliasSuggestion{}
+	for _, c := range uniqueCommandLines(history) {
+		var line = commandLine(c)
+		if counts[line] < aliasSuggestionRuns || len(line) < aliasSuggestionLength || aliased[line] {
+			continue
+		}
+		suggestions = append(suggestions, aliasSuggestion{ID: c.ID, Line: line, Count: counts[line]})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Count*len(suggestions[i].Line) > suggestions[j].Count*len(suggestions[j].Line)
+	})
+
+	// named in order, so the most useful suggestions get the shortest names
+	for i := range suggestions {
+		var parts = utils.SplitCommandLine(suggestions[i].Line)
+		suggestions[i].Name = aliasName(models.Command{Name: parts[0], Arguments: parts[1:]}, taken)
+		taken[suggestions[i].Name] = true
+	}
+
+	return suggestions
+}
+
+// shellAlias defines an alias in the shell; a command with placeholders becomes a function running it
+// through ambros, with the arguments filling the placeholders
+func shellAlias(shell string, alias string, c models.Command, executable string) (string, error) {
+	var quote = func(s string) string { return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'" }
+
+	if c.Placeholders() > 0 {
+		switch shell {
+		case "bash", "zsh":
+			return alias + "() { " + quote(executable) + " store --run " + c.ID + ` "$@"; }`, nil
+		case "fish":
+			return "function " + alias + "; " + quote(executable) + " store --run " + c.ID + " $argv; end", nil
+		}
+	}
+
+	var line = utils.JoinCommandLine(append([]string{c.Name}, c.Arguments...))
+	switch shell {
+	case "bash", "zsh":
+		return "alias " + alias + "=" + quote(line), nil
+	case "fish":
+		return "alias " + alias + " " + quote(line), nil
+	}
+
+	return "", errors.New("unknown shell " + shell)
+}
+
+// aliasedLines returns the command lines named by the aliases
+func aliasedLines(aliases map[string]string) map[string]bool {
+	var lines = map[string]bool{}
+	for _, id := range aliases {
+		if c, err := findCommand(id); err == nil {
+			lines[commandLine(c)] = true
+		}
+	}
+	return lines
+}
+
+// aliasSuggestCmd represents the alias suggest command
+var aliasSuggestCmd = &cobra.Command{
+	Use:   "suggest",
+	Short: "Suggest",
+	Long: `Suggest command, proposes aliases for the long command lines run often, the ones saving the most
+typing first; --accept <name> creates the suggested alias with that name`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Alias suggest command invoked")
+
+			aliases, err := Repository.GetAllAliases()
+			if err != nil {
+				Parrot.Println("Error retrieving the aliases", err)
+				return
+			}
+
+			history, err := Repository.GetAllCommands()
+			if err != nil {
+				Parrot.Println("Error retrieving commands", err)
+				return
+			}
+			sort.Slice(history, func(i, j int) bool { return history[i].CreatedAt.After(history[j].CreatedAt) })
+
+			var suggestions = suggestAliases(history, aliases, aliasedLines(aliases))
+
+			if accept := cmd.Flag("accept").Value.String(); accept != "" {
+				for _, s := range suggestions {
+					if s.Name != accept {
+						continue
+					}
+					if err := Repository.PutAlias(s.Name, s.ID); err != nil {
+						Parrot.Println("Error storing the alias", err)
+						return
+					}
+					Parrot.Println("Done!")
+					return
+				}
+				Parrot.Println("No suggestion named " + accept)
+				return
+			}
+
+			if len(suggestions) == 0 {
+				Parrot.Println("No aliases to suggest!")
+				return
+			}
+
+			limit, _ := cmd.Flags().GetInt("limit")
+			if limit > 0 && len(suggestions) > limit {
+				suggestions = suggestions[:limit]
+			}
+
+			var rows = [][]string{}
+			for _, s := range suggestions {
+				// rows are printed as format strings by Tablify
+				rows = append(rows, []string{s.Name, strconv.Itoa(s.Count), strings.ReplaceAll(s.Line, "%", "%%")})
+			}
+			Parrot.Tablify([]string{"ALIAS", "RUNS", "COMMAND"}, rows)
+		})
+	},
+}
+
+// aliasExportCmd represents the alias export command
+var aliasExportCmd = &cobra.Command{
+	Use:   "export --shell bash|zsh|fish",
+	Short: "Export",
+	Long: `Export command, prints the aliases as aliases of the shell, e.g. eval "$(ambros alias export --shell zsh)"
+in .zshrc; a stored command with placeholders becomes a function running it with its arguments`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Alias export command invoked")
+
+			var shell = cmd.Flag("shell").Value.String()
+			if shell != "bash" && shell != "zsh" && shell != "fish" {
+				Parrot.Println("Please provide a shell: bash, zsh or fish")
+				return
+			}
+
+			aliases, err := Repository.GetAllAliases()
+			if err != nil {
+				Parrot.Println("Error retrieving the aliases", err)
+				return
+			}
+
+			var names = []string{}
+			for alias := range aliases {
+				names = append(names, alias)
+			}
+			sort.Strings(names)
+
+			executable, err := os.Executable()
+			if err != nil {
+				executable = "ambros"
+			}
+
+			// printed as is, the output is read by the shell
+			for _, alias := range names {
+				command, err := findCommand(aliases[alias])
+				if err != nil {
+					fmt.Println("# " + alias + ": command not available anymore (" + aliases[alias] + ")")
+					continue
+				}
+
+				definition, _ := shellAlias(shell, alias, command, executable)
+				fmt.Println(definition)
+			}
+		})
+	},
+}
+
+func init() {
+	aliasCmd.AddCommand(aliasSuggestCmd)
+	aliasCmd.AddCommand(aliasExportCmd)
+
+	aliasSuggestCmd.Flags().String("accept", "", "creates the suggested alias with the given name")
+	aliasSuggestCmd.Flags().Int("limit", 10, "how many suggestions are listed")
+	aliasExportCmd.Flags().String("shell", "bash", "the shell the aliases are written for: bash, zsh or fish")
+}
@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var aliasRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._-]*$`)
+
+// resolveID returns the id of the command with the given alias, or the given value when it is not an alias
+func resolveID(id string) string {
+	if target, err := Repository.GetAlias(id); err == nil {
+		return target
+	}
+	return id
+}
+
+// completeAliases offers the aliases wherever a command id is expected
+func completeAliases(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cache, err := cachedCompletion()
+	if err != nil || len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var candidates = []string{}
+	for alias, id := range cache.Aliases {
+		if strings.HasPrefix(alias, toComplete) {
+			candidates = append(candidates, alias+"\t"+id)
+		}
+	}
+	sort.Strings(candidates)
+
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// aliasCmd represents the alias command
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Alias",
+	Long:  `Alias command, names commands of the history or of the store, the names are accepted wherever an id is`,
+}
+
+// aliasSetCmd represents the alias set command
+var aliasSetCmd = &cobra.Command{
+	Use:   "set <id> <alias>",
+	Short: "Set",
+	Long:  `Set command, gives an alias to a command`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Alias set command invoked")
+
+			if len(args) != 2 {
+				Parrot.Println("Please provide a command id and an alias")
+				return
+			}
+
+			var id, alias = resolveID(args[0]), args[1]
+
+			if !aliasRegexp.MatchString(alias) {
+				Parrot.Println("Please provide a valid alias, letters, digits, '.', '_' and '-' starting with a letter (" + alias + ")")
+				return
+			}
+
+			if _, err := findCommand(id); err != nil {
+				Parrot.Println("Id not available in the store (" + id + ")")
+				return
+			}
+
+			if _, err := findCommand(alias); err == nil {
+				Parrot.Println("Alias " + alias + " is the id of another command")
+				return
+			}
+
+			if current, err := Repository.GetAlias(alias); err == nil && current != id && !cmd.Flag("force").Changed {
+				Parrot.Println("Alias " + alias + " already names " + current + ", use --force to move it")
+				return
+			}
+
+			if err := Repository.PutAlias(alias, id); err != nil {
+				Parrot.Println("Error storing the alias", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// aliasListCmd represents the alias list command
+var aliasListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List",
+	Long:  `List command, shows the aliases and the commands they name`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Alias list command invoked")
+
+			aliases, err := Repository.GetAllAliases()
+			if err != nil {
+				Parrot.Println("Error retrieving the aliases", err)
+				return
+			}
+
+			if len(aliases) == 0 {
+				Parrot.Println("No aliases available!")
+				return
+			}
+
+			var names = []string{}
+			for alias := range aliases {
+				names = append(names, alias)
+			}
+			sort.Strings(names)
+
+			var rows = [][]string{}
+			for _, alias := range names {
+				var line = "(not available anymore)"
+				if command, err := findCommand(aliases[alias]); err == nil {
+					line = strings.ReplaceAll(command.Name+" "+strings.Join(command.Arguments, " "), "%", "%%")
+				}
+				rows = append(rows, []string{alias, aliases[alias], line})
+			}
+
+			Parrot.Tablify([]string{"ALIAS", "ID", "COMMAND"}, rows)
+		})
+	},
+}
+
+// aliasDeleteCmd represents the alias delete command
+var aliasDeleteCmd = &cobra.Command{
+	Use:   "delete <alias>",
+	Short: "Delete",
+	Long:  `Delete command, removes an alias, the command stays`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Alias delete command invoked")
+
+			alias, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid alias")
+				return
+			}
+
+			if err := Repository.DeleteAlias(alias); err != nil {
+				Parrot.Println("Alias not available ("+alias+")", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(aliasCmd)
+	aliasCmd.AddCommand(aliasSetCmd)
+	aliasCmd.AddCommand(aliasListCmd)
+	aliasCmd.AddCommand(aliasDeleteCmd)
+
+	aliasSetCmd.Flags().Bool("force", false, "moves an alias already naming another command")
+	aliasSetCmd.ValidArgsFunction = completeAliases
+	aliasDeleteCmd.ValidArgsFunction = completeAliases
+
+	showCmd.ValidArgsFunction = completeExecutions(1)
+	recallCmd.ValidArgsFunction = completeExecutions(1)
+	outputCmd.ValidArgsFunction = completeExecutions(1)
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+func TestSuggestAliases(t *testing.T) {
+	var history = []models.Command{}
+	var run = func(id string, line string, times int) {
+		var parts = strings.Fields(line)
+		for i := 0; i < times; i++ {
+			history = append(history, models.Command{Entity: models.Entity{ID: id}, Name: parts[0], Arguments: parts[1:]})
+		}
+	}
+	run("k1", "kubectl get pods -n production", 3)
+	run("d1", "docker compose up -d", 5)
+	run("l1", "ls -la", 10)
+	run("g1", "git log --oneline --graph", 2)
+	run("m1", "make integration-tests", 4)
+
+	var suggestions = suggestAliases(history, map[string]string{"dcu": "x1"}, map[string]bool{"make integration-tests": true})
+
+	var expected = []aliasSuggestion{
+		{Name: "dcu2", ID: "d1", Line: "docker compose up -d", Count: 5},
+		{Name: "kgpp", ID: "k1", Line: "kubectl get pods -n production", Count: 3},
+	}
+	if !reflect.DeepEqual(suggestions, expected) {
+		t.Errorf("suggestAliases() = %+v, want %+v", suggestions, expected)
+	}
+}
+
+func TestShellAlias(t *testing.T) {
+	var command = models.Command{Entity: models.Entity{ID: "c1"}, Name: "grep", Arguments: []string{"it's", "my file"}}
+	var template = models.Command{Entity: models.Entity{ID: "c2"}, Name: "kubectl", Arguments: []string{"logs", "{1}"}}
+
+	tests := []struct {
+		shell      string
+		command    models.Command
+		definition string
+	}{
+		{"bash", command, `alias g='grep '\''it'\''\'\'''\''s'\'' '\''my file'\'''`},
+		{"fish", command, `alias g 'grep '\''it'\''\'\'''\''s'\'' '\''my file'\'''`},
+		{"zsh", template, `g() { '/bin/ambros' store --run c2 "$@"; }`},
+		{"fish", template, `function g; '/bin/ambros' store --run c2 $argv; end`},
+	}
+
+	for _, test := range tests {
+		definition, err := shellAlias(test.shell, "g", test.command, "/bin/ambros")
+		if err != nil || definition != test.definition {
+			t.Errorf("shellAlias(%s, %s) = %s %v, want %s", test.shell, test.command.Name, definition, err, test.definition)
+		}
+	}
+
+	if _, err := shellAlias("powershell", "g", command, "/bin/ambros"); err == nil {
+		t.Errorf("shellAlias(powershell) returned no error, want one")
+	}
+}
@@ -0,0 +1,342 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// sinceFromFlag parses a --since value, either a duration back from now (e.g. 30d), today, yesterday
+// or a RFC3339 date
+func sinceFromFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	var now = time.Now()
+	var today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch value {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if d, err := Utilities.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
+
+// inlineOutput loads a streamed output into the command so the archive is self contained
+func inlineOutput(command models.Command) models.Command {
+	if !command.Streamed {
+		return command
+	}
+
+	var builder strings.Builder
+	io.Copy(&builder, Repository.GetOutputReader(command.ID))
+
+	command.Output = builder.String() + command.Output
+	command.Streamed = false
+	command.Cold = false
+	return command
+}
+
+// buildArchive collects the whole repository, keeping only the executions after since
+func buildArchive(since time.Time) (models.Archive, error) {
+	var archive = models.Archive{Version: models.ArchiveVersion, ExportedAt: time.Now()}
+
+	commands, err := Repository.GetAllCommands()
+	if err != nil {
+		return archive, err
+	}
+
+	for _, c := range commands {
+		if c.CreatedAt.Before(since) {
+			continue
+		}
+		archive.Commands = append(archive.Commands, inlineOutput(c))
+	}
+
+	if archive.Stored, err = Repository.GetAllStoredCommands(); err != nil {
+		return archive, err
+	}
+
+	if archive.Chains, err = Repository.GetAllChains(); err != nil {
+		return archive, err
+	}
+
+	if archive.Environments, err = Repository.ListEnvironments(); err != nil {
+		return archive, err
+	}
+
+	return archive, nil
+}
+
+// writeArchive writes the archive as a single json document or as ndjson records
+func writeArchive(w io.Writer, archive models.Archive, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(archive)
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		if err := encoder.Encode(models.ArchiveRecord{Kind: "header", Version: archive.Version}); err != nil {
+			return err
+		}
+		for i := range archive.Commands {
+			if err := encoder.Encode(models.ArchiveRecord{Kind: "command", Command: &archive.Commands[i]}); err != nil {
+				return err
+			}
+		}
+		for i := range archive.Stored {
+			if err := encoder.Encode(models.ArchiveRecord{Kind: "stored", Command: &archive.Stored[i]}); err != nil {
+				return err
+			}
+		}
+		for i := range archive.Chains {
+			if err := encoder.Encode(models.ArchiveRecord{Kind: "chain", Chain: &archive.Chains[i]}); err != nil {
+				return err
+			}
+		}
+		for i := range archive.Environments {
+			if err := encoder.Encode(models.ArchiveRecord{Kind: "environment", Environment: &archive.Environments[i]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return errors.New("unknown format " + format + ", use json or ndjson")
+}
+
+// readArchive reads an archive written either as json or as ndjson
+func readArchive(r io.Reader) (models.Archive, error) {
+	var archive = models.Archive{}
+
+	reader := bufio.NewReader(r)
+	first, err := reader.Peek(1)
+	if err != nil {
+		return archive, err
+	}
+
+	decoder := json.NewDecoder(reader)
+
+	// a json archive is a single indented document, ndjson starts with the header record
+	if first[0] == '{' {
+		var probe json.RawMessage
+		if err := decoder.Decode(&probe); err != nil {
+			return archive, err
+		}
+
+		var record models.ArchiveRecord
+		if json.Unmarshal(probe, &record) == nil && record.Kind != "" {
+			archive, err := readArchiveRecords(decoder)
+			archive.Version = record.Version
+			return archive, err
+		}
+
+		err := json.Unmarshal(probe, &archive)
+		return archive, err
+	}
+
+	return archive, errors.New("unrecognized archive format")
+}
+
+func readArchiveRecords(decoder *json.Decoder) (models.Archive, error) {
+	var archive = models.Archive{}
+
+	for decoder.More() {
+		var record models.ArchiveRecord
+		if err := decoder.Decode(&record); err != nil {
+			return archive, err
+		}
+
+		switch {
+		case record.Kind == "command" && record.Command != nil:
+			archive.Commands = append(archive.Commands, *record.Command)
+		case record.Kind == "stored" && record.Command != nil:
+			archive.Stored = append(archive.Stored, *record.Command)
+		case record.Kind == "chain" && record.Chain != nil:
+			archive.Chains = append(archive.Chains, *record.Chain)
+		case record.Kind == "environment" && record.Environment != nil:
+			archive.Environments = append(archive.Environments, *record.Environment)
+		}
+	}
+
+	return archive, nil
+}
+
+// exportArchive dumps the repository to a file
+func exportArchive(cmd *cobra.Command, file string) {
+	since, err := sinceFromFlag(cmd.Flag("since").Value.String())
+	if err != nil {
+		Parrot.Println("Please provide a valid --since value (e.g. 30d or 2024-05-01)")
+		return
+	}
+
+	archive, err := buildArchive(since)
+	if err != nil {
+		Parrot.Println("Error reading the repository", err)
+		return
+	}
+
+	fileHandle, err := os.Create(file)
+	if err != nil {
+		Parrot.Println("Impossible to create the required file (" + file + ")")
+		return
+	}
+	defer fileHandle.Close()
+
+	writer := bufio.NewWriter(fileHandle)
+	if err := writeArchive(writer, archive, cmd.Flag("format").Value.String()); err != nil {
+		Parrot.Println("Error writing the archive", err)
+		return
+	}
+	writer.Flush()
+
+	Parrot.Println(strconv.Itoa(len(archive.Commands)) + " commands, " + strconv.Itoa(len(archive.Stored)) +
+		" stored commands, " + strconv.Itoa(len(archive.Chains)) + " chains and " + strconv.Itoa(len(archive.Environments)) +
+		" environments exported")
+}
+
+// clockSkewWarnings reports the clocks of the archive that cannot be trusted to order the history
+func clockSkewWarnings(archive models.Archive) {
+	if ahead := archive.ExportedAt.Sub(time.Now()); ahead > time.Minute {
+		Parrot.Warn("The archive was exported by a clock " + ahead.Round(time.Second).String() + " ahead of this machine")
+	}
+
+	var drifts = map[string]time.Duration{}
+	var counts = map[string]int{}
+	for _, skew := range models.ClockSkews(archive.Commands) {
+		counts[skew.Origin]++
+		if skew.Drift > drifts[skew.Origin] {
+			drifts[skew.Origin] = skew.Drift
+		}
+	}
+
+	for origin, count := range counts {
+		Parrot.Warn("The clock of " + origin + " went backwards " + strconv.Itoa(count) + " times (up to " +
+			drifts[origin].Round(time.Second).String() + "), its commands are ordered by sequence in 'ambros logs'")
+	}
+}
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import",
+	Long:  `Import command, restores an archive created by 'ambros export --all'`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Import command invoked")
+
+			file, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid archive file")
+				return
+			}
+
+			var mode = cmd.Flag("mode").Value.String()
+			if mode != "merge" && mode != "overwrite" {
+				Parrot.Println("Please provide a valid mode: merge or overwrite")
+				return
+			}
+
+			fileHandle, err := os.Open(file)
+			if err != nil {
+				Parrot.Println("Impossible to open the required file (" + file + ")")
+				return
+			}
+			defer fileHandle.Close()
+
+			archive, err := readArchive(fileHandle)
+			if err != nil {
+				Parrot.Println("Error reading the archive", err)
+				return
+			}
+
+			if archive.Version > models.ArchiveVersion {
+				Parrot.Println("The archive was created by a newer ambros (version " + strconv.Itoa(archive.Version) + ")")
+				return
+			}
+
+			clockSkewWarnings(archive)
+
+			// merge keeps the existing records, overwrite replaces the ones with the same id
+			var imported, skipped = 0, 0
+
+			for _, c := range archive.Commands {
+				if _, err := Repository.FindById(c.ID); err == nil && mode == "merge" {
+					skipped++
+					continue
+				}
+				if err := Repository.Put(c); err != nil {
+					Parrot.Println("Error storing the command ("+c.ID+")", err)
+					return
+				}
+				imported++
+			}
+
+			for _, c := range archive.Stored {
+				if _, err := Repository.FindInStoreById(c.ID); err == nil && mode == "merge" {
+					skipped++
+					continue
+				}
+				if err := Repository.Push(c); err != nil {
+					Parrot.Println("Error storing the command ("+c.ID+")", err)
+					return
+				}
+				imported++
+			}
+
+			for _, c := range archive.Chains {
+				if _, err := Repository.FindChainByName(c.Name); err == nil && mode == "merge" {
+					skipped++
+					continue
+				}
+				if err := Repository.PutChain(c); err != nil {
+					Parrot.Println("Error storing the chain ("+c.Name+")", err)
+					return
+				}
+				imported++
+			}
+
+			for _, e := range archive.Environments {
+				if _, err := Repository.GetEnvironment(e.Name); err == nil && mode == "merge" {
+					skipped++
+					continue
+				}
+				if err := Repository.PutEnvironment(e); err != nil {
+					Parrot.Println("Error storing the environment ("+e.Name+")", err)
+					return
+				}
+				imported++
+			}
+
+			Parrot.Println(strconv.Itoa(imported) + " records imported, " + strconv.Itoa(skipped) + " already present")
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().String("mode", "merge", "merge keeps existing records, overwrite replaces them")
+}
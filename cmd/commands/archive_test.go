@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// importArchive runs ambros import on the file, with the repository opened by the command
+func importArchive(t *testing.T, file string) {
+	t.Helper()
+
+	var previous = invokedCommand
+	invokedCommand = "ambros import"
+	defer func() { invokedCommand = previous }()
+
+	Repository.CloseDB()
+	importCmd.Run(importCmd, []string{file})
+	if err := Repository.InitDB(); err != nil {
+		t.Fatalf("InitDB returned unexpected error: %v", err)
+	}
+}
+
+func sameJSON(t *testing.T, got interface{}, want interface{}) bool {
+	t.Helper()
+
+	g, err1 := json.Marshal(got)
+	w, err2 := json.Marshal(want)
+	return err1 == nil && err2 == nil && string(g) == string(w)
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	useScriptedExecutor(t, scriptedRule{Pattern: "^make", Stdout: "built"})
+
+	var executed = initializeCommand("make", []string{"build"})
+	executeCommand(&executed)
+	finalizeCommand(&executed)
+
+	// a streamed output lives in its own bucket, the archive carries it inline
+	var streamed = initializeCommand("make", []string{"watch"})
+	streamed.Streamed = true
+	streamed.Status = true
+	streamed.TerminatedAt = time.Now()
+	if err := Repository.Put(streamed); err != nil {
+		t.Fatalf("Put returned unexpected error: %v", err)
+	}
+	if err := Repository.AppendOutput(streamed.ID, []byte("line 1\nline 2\n")); err != nil {
+		t.Fatalf("AppendOutput returned unexpected error: %v", err)
+	}
+
+	var stored = storeCommand(t, "make test")
+	var chain = models.Chain{Name: "deploy", Steps: []models.ChainStep{{CommandID: stored}}}
+	if err := Repository.PutChain(chain); err != nil {
+		t.Fatalf("PutChain returned unexpected error: %v", err)
+	}
+	if err := Repository.PutEnvironment(models.Environment{Name: "prod", Variables: map[string]string{"STAGE": "prod"}}); err != nil {
+		t.Fatalf("PutEnvironment returned unexpected error: %v", err)
+	}
+
+	archive, err := buildArchive(time.Time{})
+	if err != nil {
+		t.Fatalf("buildArchive returned unexpected error: %v", err)
+	}
+
+	var want = map[string]models.Command{}
+	for _, c := range archive.Commands {
+		want[c.ID] = c
+	}
+	if len(want) != 2 || want[streamed.ID].Streamed || want[streamed.ID].Output != "line 1\nline 2\n" {
+		t.Fatalf("buildArchive() = %+v, want the two commands with the streamed output inline", archive.Commands)
+	}
+
+	for _, format := range []string{"json", "ndjson"} {
+		var file = filepath.Join(t.TempDir(), "archive."+format)
+
+		handle, err := os.Create(file)
+		if err != nil {
+			t.Fatalf("Create returned unexpected error: %v", err)
+		}
+		if err := writeArchive(handle, archive, format); err != nil {
+			t.Fatalf("writeArchive(%s) returned unexpected error: %v", format, err)
+		}
+		handle.Close()
+
+		t.Run(format, func(t *testing.T) {
+			// the archive is imported in an empty repository
+			useScriptedExecutor(t)
+			importArchive(t, file)
+
+			for id, command := range want {
+				restored, err := Repository.FindById(id)
+				if err != nil || !sameJSON(t, restored, command) {
+					t.Errorf("FindById(%s) = %+v, %v, want %+v", id, restored, err, command)
+				}
+			}
+
+			if restored, err := Repository.FindInStoreById(stored); err != nil || restored.Name != "make" || len(restored.Arguments) != 1 || restored.Arguments[0] != "test" {
+				t.Errorf("FindInStoreById(%s) = %+v, %v, want make test", stored, restored, err)
+			}
+			if restored, err := Repository.FindChainByName("deploy"); err != nil || len(restored.Steps) != 1 || restored.Steps[0].CommandID != stored {
+				t.Errorf("FindChainByName(deploy) = %+v, %v, want its step", restored, err)
+			}
+			if restored, err := Repository.GetEnvironment("prod"); err != nil || restored.Variables["STAGE"] != "prod" {
+				t.Errorf("GetEnvironment(prod) = %+v, %v, want STAGE=prod", restored, err)
+			}
+
+			// merging the archive again adds nothing
+			importArchive(t, file)
+			if commands, err := Repository.GetAllCommands(); err != nil || len(commands) != 2 {
+				t.Errorf("GetAllCommands() after a second import = %d commands, %v, want 2", len(commands), err)
+			}
+		})
+	}
+}
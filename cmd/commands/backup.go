@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// backupInChain tells if the backup chain has a link with the given name
+func backupInChain(backups []models.Backup, name string) bool {
+	for _, b := range backups {
+		if b.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduledBackup adds a link to the backup chain when the last one is older than backupEvery,
+// a full backup when the chain is empty
+func scheduledBackup() {
+	if Configuration.BackupEvery <= 0 || strings.HasPrefix(invokedCommand, "ambros backup") {
+		return
+	}
+
+	backups, err := Repository.ListBackups()
+	if err != nil {
+		Parrot.Error("Error reading the backups", err)
+		return
+	}
+
+	if len(backups) > 0 && time.Since(backups[len(backups)-1].CreatedAt) < Configuration.BackupEvery {
+		return
+	}
+
+	backup, err := Repository.CreateBackup(len(backups) > 0, false)
+	if err != nil {
+		Parrot.Error("Error creating the scheduled backup", err)
+		return
+	}
+
+	Parrot.Debug("--> Scheduled backup " + backup.Name + " (" + backup.Kind + ", " + strconv.Itoa(backup.Changes) + " entries)")
+}
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Backup",
+	Long: `Backup command, manages the chain of backups of the repository kept in its backups directory;
+the cold tier and the secrets key are not part of the backups. With backupEvery in the configuration,
+the commands add a link to the chain once the last one is older than that`,
+}
+
+// backupCreateCmd represents the backup create command
+var backupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create",
+	Long: `Create command, backs up the whole repository or, with --incremental, the changes since the previous backup;
+with --to the whole repository is copied to the given file instead, outside the backup chain`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Backup create command invoked")
+
+			var incremental = cmd.Flag("incremental").Changed
+			var compress = cmd.Flag("compress").Changed
+
+			if to := cmd.Flag("to").Value.String(); to != "" {
+				if incremental {
+					Parrot.Println("Please choose between --to and --incremental, the incremental backups are links of the backup chain")
+					return
+				}
+
+				size, err := Repository.ExportBackup(to, compress)
+				if err != nil {
+					Parrot.Println("Error creating the backup", err)
+					return
+				}
+
+				Parrot.Println("Backup " + to + " (" + Utilities.FormatSize(size) + "), restore it with 'ambros backup restore " + to + " <file>'")
+				return
+			}
+
+			backup, err := Repository.CreateBackup(incremental, compress)
+			if err != nil {
+				Parrot.Println("Error creating the backup", err)
+				return
+			}
+
+			Parrot.Println("Backup " + backup.Name + " (" + backup.Kind + ", " + strconv.Itoa(backup.Changes) + " entries, " + Utilities.FormatSize(backup.Size) + ")")
+		})
+	},
+}
+
+// backupListCmd represents the backup list command
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List",
+	Long:  `List command, shows the chain of backups, oldest first`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Backup list command invoked")
+
+			backups, err := Repository.ListBackups()
+			if err != nil {
+				Parrot.Println("Error reading the backups", err)
+				return
+			}
+
+			if len(backups) == 0 {
+				Parrot.Println("No backups available!")
+				return
+			}
+
+			var rows = [][]string{}
+			for _, b := range backups {
+				rows = append(rows, []string{b.Name, b.Kind, b.Base, strconv.Itoa(b.Changes), Utilities.FormatSize(b.Size)})
+			}
+
+			Parrot.Tablify([]string{"NAME", "KIND", "BASE", "ENTRIES", "SIZE"}, rows)
+		})
+	},
+}
+
+// backupVerifyCmd represents the backup verify command
+var backupVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify",
+	Long:  `Verify command, checks the files and the links of the backup chain`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Backup verify command invoked")
+
+			problems, err := Repository.VerifyBackups()
+			if err != nil {
+				Parrot.Println("Error reading the backups", err)
+				return
+			}
+
+			for _, problem := range problems {
+				Parrot.Println(problem)
+			}
+
+			if len(problems) == 0 {
+				Parrot.Println("The backup chain is intact")
+			}
+		})
+	},
+}
+
+// backupRestoreCmd represents the backup restore command
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <name|backup file> <file>",
+	Short: "Restore",
+	Long: `Restore command, rebuilds in a new file the repository as it was at the given backup,
+applying the incremental backups on top of their full backup; a backup created with --to is given by its file`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Backup restore command invoked")
+
+			if len(args) != 2 {
+				Parrot.Println("Please provide a backup name and the file to create")
+				return
+			}
+
+			backups, err := Repository.ListBackups()
+			if err != nil {
+				Parrot.Println("Error reading the backups", err)
+				return
+			}
+
+			var restore = Repository.RestoreBackup
+			if _, err := os.Stat(args[0]); err == nil && !backupInChain(backups, args[0]) {
+				restore = Repository.RestoreFile
+			}
+
+			if err := restore(args[0], args[1]); err != nil {
+				Parrot.Println("Error restoring the backup", err)
+				return
+			}
+
+			Parrot.Println("Restored to " + args[1] + ", replace " + Configuration.RepositoryFullName() + " with it to use it")
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupCreateCmd)
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupVerifyCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+
+	backupCreateCmd.Flags().Bool("incremental", false, "backs up only the changes since the previous backup")
+	backupCreateCmd.Flags().String("to", "", "copies the whole repository to the given file, outside the backup chain")
+	backupCreateCmd.Flags().Bool("compress", false, "gzips the copy of the whole repository (the incremental backups are always gzipped)")
+}
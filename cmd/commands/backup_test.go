@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduledBackup(t *testing.T) {
+	useScriptedExecutor(t)
+
+	var previous = invokedCommand
+	invokedCommand = "ambros last"
+	defer func() { invokedCommand = previous }()
+
+	// off by default
+	Configuration.BackupEvery = 0
+	scheduledBackup()
+	if backups, _ := Repository.ListBackups(); len(backups) != 0 {
+		t.Fatalf("scheduledBackup() with backupEvery 0 created %d backups, want none", len(backups))
+	}
+
+	// the first backup is a full one, the next ones wait for the last to be old enough
+	Configuration.BackupEvery = time.Hour
+	scheduledBackup()
+	scheduledBackup()
+
+	backups, err := Repository.ListBackups()
+	if err != nil || len(backups) != 1 || backups[0].Kind != "full" {
+		t.Fatalf("ListBackups() = %+v, %v, want one full backup", backups, err)
+	}
+
+	// then the chain grows with incremental links
+	Configuration.BackupEvery = time.Millisecond
+	time.Sleep(2 * time.Millisecond)
+	storeCommand(t, "make test")
+	scheduledBackup()
+
+	backups, err = Repository.ListBackups()
+	if err != nil || len(backups) != 2 || backups[1].Kind != "incremental" || backups[1].Base != backups[0].Name {
+		t.Fatalf("ListBackups() = %+v, %v, want an incremental backup on top of the full one", backups, err)
+	}
+
+	// the backup commands never add a link by themselves
+	invokedCommand = "ambros backup verify"
+	time.Sleep(2 * time.Millisecond)
+	scheduledBackup()
+
+	if backups, _ = Repository.ListBackups(); len(backups) != 2 {
+		t.Errorf("scheduledBackup() during ambros backup verify created a backup")
+	}
+}
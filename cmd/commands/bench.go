@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	repos "github.com/gi4nks/ambros/internal/repos"
+)
+
+// benchResult collects the latencies of one kind of operation
+type benchResult struct {
+	lock      sync.Mutex
+	latencies []time.Duration
+	failures  int
+}
+
+func (b *benchResult) add(latency time.Duration, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err != nil {
+		b.failures++
+		return
+	}
+	b.latencies = append(b.latencies, latency)
+}
+
+// percentile returns the latency under which the given share of the operations completed
+func percentile(sorted []time.Duration, share float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	var i = int(float64(len(sorted))*share+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func (b *benchResult) row(name string, elapsed time.Duration) []string {
+	var sorted = append([]time.Duration{}, b.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var throughput = float64(len(sorted)) / elapsed.Seconds()
+	var format = func(d time.Duration) string {
+		return d.Round(time.Microsecond).String()
+	}
+
+	return []string{name, strconv.Itoa(len(sorted)), strconv.Itoa(b.failures), strconv.FormatFloat(throughput, 'f', 0, 64),
+		format(percentile(sorted, 0.5)), format(percentile(sorted, 0.9)), format(percentile(sorted, 0.99)), format(percentile(sorted, 1))}
+}
+
+// benchCommand builds a history entry with an output of the given size
+func benchCommand(payload int) models.Command {
+	var command = initializeCommand("bench", []string{"--run", strconv.Itoa(rand.Intn(1000))})
+	command.Output = strings.Repeat("x", payload)
+	command.Status = true
+	command.TerminatedAt = time.Now()
+	return command
+}
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:    "bench",
+	Short:  "Bench",
+	Hidden: true,
+	Long: `Bench command, measures the repository under concurrent writers and readers;
+it works on a throw-away repository, the real one is never touched`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Parrot.Debug("Bench command invoked")
+
+		writers, _ := cmd.Flags().GetInt("writers")
+		readers, _ := cmd.Flags().GetInt("readers")
+		duration, _ := cmd.Flags().GetDuration("duration")
+
+		payload, err := Utilities.ParseSize(cmd.Flag("payload").Value.String())
+		if err != nil || writers < 0 || readers < 0 || writers+readers == 0 || duration <= 0 {
+			Parrot.Println("Please provide valid --writers, --readers, --duration and --payload values")
+			return
+		}
+
+		directory, err := os.MkdirTemp("", "ambros-bench-")
+		if err != nil {
+			Parrot.Println("Impossible to create the bench repository", err)
+			return
+		}
+		defer os.RemoveAll(directory)
+
+		var configuration = *Configuration
+		configuration.RepositoryDirectory = directory
+
+		var repository = repos.NewRepository(*Parrot, configuration)
+		if err := repository.InitDB(); err != nil {
+			Parrot.Println("Impossible to open the bench repository", err)
+			return
+		}
+		defer repository.CloseDB()
+
+		if err := repository.InitSchema(); err != nil {
+			Parrot.Println("Impossible to initialize the bench repository", err)
+			return
+		}
+
+		// readers need something to read from the start
+		var ids = []string{}
+		for i := 0; i < 100; i++ {
+			var command = benchCommand(int(payload))
+			repository.Put(command)
+			ids = append(ids, command.ID)
+		}
+
+		Parrot.Println("Running " + strconv.Itoa(writers) + " writers and " + strconv.Itoa(readers) + " readers for " +
+			duration.String() + " with " + Utilities.FormatSize(payload) + " outputs")
+
+		var writes, reads, lists = &benchResult{}, &benchResult{}, &benchResult{}
+		var deadline = time.Now().Add(duration)
+		var group sync.WaitGroup
+
+		for i := 0; i < writers; i++ {
+			group.Add(1)
+			go func() {
+				defer group.Done()
+				for time.Now().Before(deadline) {
+					var command = benchCommand(int(payload))
+					var start = time.Now()
+					err := repository.Put(command)
+					writes.add(time.Since(start), err)
+				}
+			}()
+		}
+
+		for i := 0; i < readers; i++ {
+			group.Add(1)
+			go func() {
+				defer group.Done()
+				for n := 0; time.Now().Before(deadline); n++ {
+					var start = time.Now()
+
+					// one listing every ten lookups, as last and logs do
+					if n%10 == 9 {
+						_, err := repository.GetLimitCommands(Configuration.LastCountDefault)
+						lists.add(time.Since(start), err)
+						continue
+					}
+
+					_, err := repository.FindById(ids[rand.Intn(len(ids))])
+					reads.add(time.Since(start), err)
+				}
+			}()
+		}
+
+		var started = time.Now()
+		group.Wait()
+		var elapsed = time.Since(started)
+
+		var size, _ = repository.Size()
+
+		Parrot.Tablify([]string{"OPERATION", "COUNT", "FAILED", "OPS/S", "P50", "P90", "P99", "MAX"}, [][]string{
+			writes.row("write", elapsed),
+			reads.row("read", elapsed),
+			lists.row("list", elapsed),
+		})
+		Parrot.Println("Repository size after the run: " + Utilities.FormatSize(size))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().Int("writers", 2, "number of concurrent writers")
+	benchCmd.Flags().Int("readers", 4, "number of concurrent readers")
+	benchCmd.Flags().Duration("duration", 10*time.Second, "how long the workload runs")
+	benchCmd.Flags().String("payload", "1KB", "size of the output of each written command")
+}
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// stepComparison is how a step went in two runs of a chain; the step is the command it runs, as in
+// the analytics, a step in one run only was added or removed in between
+type stepComparison struct {
+	Step      int           `json:"Step"`
+	CommandID string        `json:"CommandID"`
+	From      string        `json:"From"`
+	To        string        `json:"To"`
+	FromTime  time.Duration `json:"FromDuration"`
+	ToTime    time.Duration `json:"ToDuration"`
+}
+
+// stepOutcome is how a step ended in a run: ok, failed, skipped, or none when it was not there
+func stepOutcome(step *models.ChainStepExecution) string {
+	switch {
+	case step == nil:
+		return "none"
+	case step.Skipped:
+		return "skipped"
+	case step.Status:
+		return "ok"
+	default:
+		return "failed"
+	}
+}
+
+func (s stepComparison) Delta() time.Duration {
+	return s.ToTime - s.FromTime
+}
+
+// Change names what happened to the step from a run to the other, empty when nothing worth a look
+func (s stepComparison) Change() string {
+	switch {
+	case s.From == "none":
+		return "added"
+	case s.To == "none":
+		return "removed"
+	case s.To == "failed" && s.From != "failed":
+		return "newly failed"
+	case s.From == "failed" && s.To != "failed":
+		return "fixed"
+	case s.FromTime > 0 && s.ToTime >= 2*s.FromTime:
+		return "slower x" + strconv.FormatFloat(float64(s.ToTime)/float64(s.FromTime), 'f', 1, 64)
+	}
+	return ""
+}
+
+// chainComparison is how two runs of a chain differ, step by step in the order of the later run
+type chainComparison struct {
+	From  string           `json:"From"`
+	To    string           `json:"To"`
+	Delta time.Duration    `json:"Delta"`
+	Steps []stepComparison `json:"Steps"`
+}
+
+// compareChainRuns compares the steps of two runs, the steps removed come last
+func compareChainRuns(from models.ChainExecution, to models.ChainExecution) chainComparison {
+	var comparison = chainComparison{
+		From:  from.ID,
+		To:    to.ID,
+		Delta: to.TerminatedAt.Sub(to.CreatedAt) - from.TerminatedAt.Sub(from.CreatedAt),
+		Steps: []stepComparison{},
+	}
+
+	var before = map[string]*models.ChainStepExecution{}
+	for i := range from.Steps {
+		before[from.Steps[i].CommandID] = &from.Steps[i]
+	}
+
+	var compared = map[string]bool{}
+	for i := range to.Steps {
+		var step = &to.Steps[i]
+		var previous = before[step.CommandID]
+
+		var s = stepComparison{Step: step.Step, CommandID: step.CommandID, From: stepOutcome(previous), To: stepOutcome(step), ToTime: step.Duration}
+		if previous != nil {
+			s.FromTime = previous.Duration
+		}
+
+		comparison.Steps = append(comparison.Steps, s)
+		compared[step.CommandID] = true
+	}
+
+	for i := range from.Steps {
+		var step = &from.Steps[i]
+		if compared[step.CommandID] {
+			continue
+		}
+
+		comparison.Steps = append(comparison.Steps, stepComparison{Step: step.Step, CommandID: step.CommandID, From: stepOutcome(step), To: "none", FromTime: step.Duration})
+	}
+
+	return comparison
+}
+
+// signedDuration prints a duration delta with its sign
+func signedDuration(d time.Duration) string {
+	if d >= 0 {
+		return "+" + d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// findChainRuns returns the two runs of a chain to compare
+func findChainRuns(fromID string, toID string) (models.ChainExecution, models.ChainExecution, error) {
+	from, err := Repository.FindChainExecution(fromID)
+	if err != nil {
+		return from, models.ChainExecution{}, err
+	}
+
+	to, err := Repository.FindChainExecution(toID)
+	return from, to, err
+}
+
+// chainCompareCmd represents the chain compare command
+var chainCompareCmd = &cobra.Command{
+	Use:   "compare <run-id> <run-id>",
+	Short: "Compare",
+	Long: `Compare command, shows side by side how each step went in two runs of a chain, as listed by chain history:
+the status and the duration in both, which steps got slower and which newly failed`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain compare command invoked")
+
+			if len(args) != 2 {
+				Parrot.Println("Please provide the ids of the two runs to compare")
+				return
+			}
+
+			from, to, err := findChainRuns(args[0], args[1])
+			if err != nil {
+				Parrot.Println("Chain run not available", err)
+				return
+			}
+
+			if from.Chain != to.Chain {
+				Parrot.Warn("The runs are of different chains: " + from.Chain + " and " + to.Chain)
+			}
+
+			var comparison = compareChainRuns(from, to)
+
+			var rows = [][]string{}
+			for _, s := range comparison.Steps {
+				rows = append(rows, []string{
+					strconv.Itoa(s.Step),
+					stepCommand(s.CommandID),
+					s.From + " -> " + s.To,
+					s.FromTime.Round(time.Millisecond).String(),
+					s.ToTime.Round(time.Millisecond).String(),
+					signedDuration(s.Delta()),
+					s.Change(),
+				})
+			}
+
+			Parrot.Tablify([]string{"STEP", "COMMAND", "STATUS", "BEFORE", "AFTER", "DELTA", "CHANGE"}, rows)
+			Parrot.Println("Total: " + from.TerminatedAt.Sub(from.CreatedAt).Round(time.Millisecond).String() + " -> " +
+				to.TerminatedAt.Sub(to.CreatedAt).Round(time.Millisecond).String() + " (" + signedDuration(comparison.Delta) + ")")
+		})
+	},
+}
+
+func init() {
+	chainCmd.AddCommand(chainCompareCmd)
+}
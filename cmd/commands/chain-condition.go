@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// the statuses of the steps as the conditions read them
+const (
+	conditionPending = "pending"
+	conditionSuccess = "success"
+	conditionFailure = "failure"
+	conditionSkipped = "skipped"
+)
+
+// stepResults are how the steps of a run went so far, as the conditions of the steps read them
+type stepResults struct {
+	statuses []string
+	outputs  []string
+}
+
+func newStepResults(steps int) stepResults {
+	var results = stepResults{statuses: make([]string, steps), outputs: make([]string, steps)}
+	for i := range results.statuses {
+		results.statuses[i] = conditionPending
+	}
+	return results
+}
+
+// record keeps the result of a step which ran
+func (r stepResults) record(step int, status bool, output string) {
+	r.statuses[step], r.outputs[step] = conditionFailure, output
+	if status {
+		r.statuses[step] = conditionSuccess
+	}
+}
+
+// resolve returns the value of a reference of a condition: steps.<n>.status, steps.<n>.output or
+// env.<name>, a variable of the environment of the step or of ambros
+func (r stepResults) resolve(step models.ChainStep, reference string) (string, error) {
+	var parts = strings.SplitN(reference, ".", 3)
+
+	switch {
+	case len(parts) == 2 && parts[0] == "env":
+		if step.Environment != "" {
+			environment, _, err := resolveEnvironment(step.Environment)
+			if err != nil {
+				return "", err
+			}
+			if value, ok := environment.Variables[parts[1]]; ok {
+				return value, nil
+			}
+		}
+		return os.Getenv(parts[1]), nil
+
+	case len(parts) == 3 && parts[0] == "steps":
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 1 || n > len(r.statuses) {
+			return "", errors.New("unknown step in " + reference)
+		}
+
+		switch parts[2] {
+		case "status":
+			return r.statuses[n-1], nil
+		case "output":
+			// without the last new line, so $ anchors the last line
+			return strings.TrimRight(r.outputs[n-1], "\n"), nil
+		}
+	}
+
+	return "", errors.New("unknown reference " + reference)
+}
+
+// holds tells if the step runs, as its condition says; a step without condition always does
+func (r stepResults) holds(step models.ChainStep) (bool, error) {
+	if step.When == "" {
+		return true, nil
+	}
+
+	return utils.EvaluateCondition(step.When, func(reference string) (string, error) {
+		return r.resolve(step, reference)
+	})
+}
+
+// chainWhenCmd represents the chain when command
+var chainWhenCmd = &cobra.Command{
+	Use:   "when <name> <step> [condition]",
+	Short: "When",
+	Long: `When command, sets the condition a step runs on, no condition clears it. A condition compares
+{{steps.<n>.status}} (success, failure, skipped or pending), {{steps.<n>.output}} or {{env.<name>}} to a
+value with ==, != or a regular expression with =~ and !~, e.g. "{{steps.1.status}} == success", and
+combines comparisons with && and ||. A step not holding its condition is skipped; a step with a
+condition runs even after a failure, once the steps it needs terminated, e.g. to clean up`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain when command invoked")
+
+			if len(args) < 2 {
+				Parrot.Println("Please provide a chain name and a step number")
+				return
+			}
+
+			chain, err := Repository.FindChainByName(args[0])
+			if err != nil {
+				Parrot.Println("Chain not available (" + args[0] + ")")
+				return
+			}
+
+			number, err := strconv.Atoi(args[1])
+			if err != nil || number < 1 || number > len(chain.Steps) {
+				Parrot.Println("Please provide a step number between 1 and " + strconv.Itoa(len(chain.Steps)))
+				return
+			}
+
+			var step = &chain.Steps[number-1]
+			step.When = strings.Join(args[2:], " ")
+
+			if _, err := newStepResults(len(chain.Steps)).holds(*step); err != nil {
+				Parrot.Println("Invalid condition:", err)
+				return
+			}
+
+			if err := Repository.PutChain(chain); err != nil {
+				Parrot.Println("Error storing the chain", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+func init() {
+	chainCmd.AddCommand(chainWhenCmd)
+}
@@ -0,0 +1,315 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// states of a step while a chain graph runs
+const (
+	stepWaiting = iota
+	stepRunning
+	stepSucceeded
+	stepFailed
+	stepSkipped
+)
+
+// stepNumbers formats step numbers as the chain commands take them
+func stepNumbers(numbers []int) string {
+	var formatted = make([]string, len(numbers))
+	for i, n := range numbers {
+		formatted[i] = strconv.Itoa(n)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// graphDuration is how long a chain graph runs when its steps take the given durations: the longest
+// path through the steps needing each other
+func graphDuration(chain models.Chain, durations []time.Duration) time.Duration {
+	var ends = make([]time.Duration, len(chain.Steps))
+	var done = make([]bool, len(chain.Steps))
+
+	var end func(step int) time.Duration
+	end = func(step int) time.Duration {
+		if done[step] {
+			return ends[step]
+		}
+
+		var start time.Duration
+		for _, n := range chain.Steps[step].Needs {
+			if e := end(n - 1); e > start {
+				start = e
+			}
+		}
+
+		ends[step], done[step] = start+durations[step], true
+		return ends[step]
+	}
+
+	var total time.Duration
+	for i := range chain.Steps {
+		if e := end(i); e > total {
+			total = e
+		}
+	}
+	return total
+}
+
+// graphResult is a step of a chain graph which terminated
+type graphResult struct {
+	step    int
+	command models.Command
+	output  string
+}
+
+// executeChainGraph runs each step of a chain as soon as the steps it needs succeeded, the independent
+// ones in parallel; the steps needing a failed or skipped step are skipped, the others still run.
+// The done steps are not run again
+func executeChainGraph(chain models.Chain, execution *models.ChainExecution, done map[int]models.ChainStepExecution) bool {
+	var span = tracer.Start("chain "+chain.Name, invocation)
+	span.SetAttribute("ambros.chain.steps", len(chain.Steps))
+	defer tracer.End(span)
+
+	if err := chain.CheckNeeds(); err != nil {
+		Parrot.Println("Chain "+chain.Name+" cannot run:", err)
+		span.SetError(err.Error())
+		return false
+	}
+
+	var stored = make([]models.Command, len(chain.Steps))
+	var backends = make([]executor, len(chain.Steps))
+
+	for i, step := range chain.Steps {
+		command, err := findCommand(step.CommandID)
+		if err != nil {
+			Parrot.Println("Id not available in the store (" + step.CommandID + ")")
+			span.SetError("step " + step.CommandID + " not available")
+			return false
+		}
+
+		if command.Placeholders() > 0 {
+			Parrot.Println("Step " + command.AsStoredCommand() + " has placeholders and cannot run in a chain")
+			span.SetError("step " + step.CommandID + " has placeholders")
+			return false
+		}
+
+		if _, ok := done[i+1]; ok {
+			stored[i] = command
+			continue
+		}
+
+		// resolved before the steps start, they run in parallel
+		backend, err := stepBackend(step)
+		if err != nil {
+			Parrot.Println("Error applying the environment "+step.Environment+" of step "+command.AsStoredCommand(), err)
+			span.SetError("step " + step.CommandID + " environment not applied")
+			return false
+		}
+
+		stored[i], backends[i] = command, backend
+	}
+
+	// the parallel steps keep the repository open until the last one terminates
+	sharedRepository = true
+	defer func() { sharedRepository = false }()
+
+	var states = make([]int, len(chain.Steps))
+	var results = newStepResults(len(chain.Steps))
+
+	for i := range chain.Steps {
+		if d, ok := done[i+1]; ok {
+			states[i] = stepSucceeded
+			results.record(i, true, resumedOutput(d))
+			execution.Steps = append(execution.Steps, d)
+			Parrot.Println("--> step " + strconv.Itoa(i+1) + " " + stored[i].AsStoredCommand() + " succeeded in the resumed run")
+		}
+	}
+
+	var terminated = make(chan graphResult)
+	var running = 0
+	var failed = []string{}
+
+	for {
+		// skipping a step may skip the ones needing it, until nothing changes; a step with a condition
+		// waits for the steps it needs to terminate, whatever their result
+		for changed := true; changed; {
+			changed = false
+
+			for i, step := range chain.Steps {
+				if states[i] != stepWaiting {
+					continue
+				}
+
+				var ready, blocked = true, false
+				for _, n := range step.Needs {
+					switch states[n-1] {
+					case stepSucceeded:
+					case stepFailed, stepSkipped:
+						blocked = step.When == ""
+					default:
+						ready = false
+					}
+				}
+
+				switch {
+				case blocked:
+					states[i] = stepSkipped
+					changed = true
+					execution.Steps = append(execution.Steps, skippedStep(i+1, step))
+					results.statuses[i] = conditionSkipped
+					Parrot.Println("--> step " + strconv.Itoa(i+1) + " " + stored[i].AsStoredCommand() + " skipped, a step it needs did not succeed")
+				case ready:
+					holds, err := results.holds(step)
+					if err != nil {
+						states[i], changed = stepFailed, true
+						results.statuses[i] = conditionFailure
+						execution.Steps = append(execution.Steps, models.ChainStepExecution{Step: i + 1, CommandID: step.CommandID, Environment: step.Environment})
+						failed = append(failed, strconv.Itoa(i+1)+" "+stored[i].AsStoredCommand()+" (condition: "+err.Error()+")")
+						Parrot.Println("Error evaluating the condition of step "+strconv.Itoa(i+1)+" "+stored[i].AsStoredCommand(), err)
+						continue
+					}
+
+					if !holds {
+						states[i], changed = stepSkipped, true
+						results.statuses[i] = conditionSkipped
+						execution.Steps = append(execution.Steps, skippedStep(i+1, step))
+						Parrot.Println("--> step " + strconv.Itoa(i+1) + " " + stored[i].AsStoredCommand() + " skipped, its condition does not hold")
+						continue
+					}
+
+					states[i] = stepRunning
+					running++
+					Parrot.Println("--> step " + strconv.Itoa(i+1) + " " + stored[i].AsStoredCommand())
+
+					// a piped step reads the outputs of the steps it needs, in the order they are listed
+					var input strings.Builder
+					for _, n := range step.Needs {
+						input.WriteString(results.outputs[n-1])
+					}
+
+					go func(i int, input string) {
+						var stepSpan = tracer.Start("chain step "+strconv.Itoa(i+1), span)
+						stepSpan.SetAttribute("ambros.step.command.id", chain.Steps[i].CommandID)
+
+						var command, output = runStep(stepSpan, i+1, chain.Steps[i], stored[i], backends[i], input)
+
+						if !command.Status {
+							stepSpan.SetError("step failed")
+						}
+						tracer.End(stepSpan)
+
+						terminated <- graphResult{step: i, command: command, output: output}
+					}(i, input.String())
+				}
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+
+		var result = <-terminated
+		running--
+		results.record(result.step, result.command.Status, result.output)
+		execution.Steps = append(execution.Steps, stepExecution(result.step+1, chain.Steps[result.step], result.command))
+
+		if result.command.Status {
+			states[result.step] = stepSucceeded
+			continue
+		}
+
+		if chain.Steps[result.step].ContinueOnError {
+			states[result.step] = stepSucceeded
+			Parrot.Println("--> step " + strconv.Itoa(result.step+1) + " " + stored[result.step].AsStoredCommand() + " failed, the chain goes on (continue on error)")
+			continue
+		}
+
+		states[result.step] = stepFailed
+		failed = append(failed, strconv.Itoa(result.step+1)+" "+stored[result.step].AsStoredCommand()+" (see 'ambros show "+result.command.ID+"')")
+		Parrot.Println("--> step " + strconv.Itoa(result.step+1) + " " + stored[result.step].AsStoredCommand() + " failed")
+	}
+
+	var skipped = []int{}
+	for i, state := range states {
+		if state == stepSkipped {
+			skipped = append(skipped, i+1)
+		}
+	}
+
+	if len(failed) == 0 {
+		Parrot.Println("Chain " + chain.Name + " completed")
+		return true
+	}
+
+	var message = "Failed steps: " + strings.Join(failed, "; ")
+	if len(skipped) > 0 {
+		message += "\nSkipped steps: " + stepNumbers(skipped)
+	}
+
+	span.SetError(strconv.Itoa(len(failed)) + " steps failed")
+	Parrot.Println("Chain " + chain.Name + " failed")
+	Parrot.Println(message)
+	notifyFailure("ambros: chain "+chain.Name+" failed", message)
+	return false
+}
+
+// chainNeedsCmd represents the chain needs command
+var chainNeedsCmd = &cobra.Command{
+	Use:   "needs <name> <step> [step]...",
+	Short: "Needs",
+	Long: `Needs command, sets the steps which must succeed before a step starts, no step clears them.
+Once a step needs others, each step of the chain starts as soon as the steps it needs succeeded,
+the independent ones in parallel, and a failure skips only the steps depending on it`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain needs command invoked")
+
+			if len(args) < 2 {
+				Parrot.Println("Please provide a chain name and a step number")
+				return
+			}
+
+			chain, err := Repository.FindChainByName(args[0])
+			if err != nil {
+				Parrot.Println("Chain not available (" + args[0] + ")")
+				return
+			}
+
+			var numbers = []int{}
+			for _, arg := range args[1:] {
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 1 || n > len(chain.Steps) {
+					Parrot.Println("Please provide step numbers between 1 and " + strconv.Itoa(len(chain.Steps)))
+					return
+				}
+				numbers = append(numbers, n)
+			}
+
+			chain.Steps[numbers[0]-1].Needs = numbers[1:]
+			if len(numbers) == 1 {
+				chain.Steps[numbers[0]-1].Needs = nil
+			}
+
+			if err := chain.CheckNeeds(); err != nil {
+				Parrot.Println("Invalid needs:", err)
+				return
+			}
+
+			if err := Repository.PutChain(chain); err != nil {
+				Parrot.Println("Error storing the chain", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+func init() {
+	chainCmd.AddCommand(chainNeedsCmd)
+}
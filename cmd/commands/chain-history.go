@@ -0,0 +1,291 @@
+package commands
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// chainPeriod counts the runs of a chain started in a day or a week
+type chainPeriod struct {
+	Start     time.Time
+	Runs      int
+	Succeeded int
+}
+
+func (p chainPeriod) SuccessRate() float64 {
+	if p.Runs == 0 {
+		return 0
+	}
+	return float64(p.Succeeded) / float64(p.Runs)
+}
+
+// chainStepStats is how a step went across the runs of a chain; the step is the command it runs,
+// its number may change when the chain is edited
+type chainStepStats struct {
+	Step      int
+	CommandID string
+	Runs      int
+	Failures  int
+	// Flips counts the runs ending differently from the previous one, a flaky step flips often
+	Flips int
+	Total time.Duration
+	Max   time.Duration
+
+	last bool
+}
+
+func (s chainStepStats) Average() time.Duration {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Runs)
+}
+
+// chainReport is what the runs of a chain tell
+type chainReport struct {
+	Periods  []chainPeriod
+	Slowest  []chainStepStats
+	Flakiest []chainStepStats
+}
+
+// periodStart returns the day, or the week starting on monday, the instant belongs to
+func periodStart(when time.Time, weekly bool) time.Time {
+	var start = time.Date(when.Year(), when.Month(), when.Day(), 0, 0, 0, 0, when.Location())
+	if weekly {
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+	}
+	return start
+}
+
+// chainAnalytics summarizes the runs of a chain, the oldest first: the success rate by day or week,
+// the steps taking longest on average and the ones which failed without failing always
+func chainAnalytics(executions []models.ChainExecution, weekly bool) chainReport {
+	var report = chainReport{Periods: []chainPeriod{}, Slowest: []chainStepStats{}, Flakiest: []chainStepStats{}}
+	var steps = map[string]*chainStepStats{}
+	var order = []string{}
+
+	for _, e := range executions {
+		var start = periodStart(e.CreatedAt, weekly)
+		if n := len(report.Periods); n == 0 || !report.Periods[n-1].Start.Equal(start) {
+			report.Periods = append(report.Periods, chainPeriod{Start: start})
+		}
+
+		var period = &report.Periods[len(report.Periods)-1]
+		period.Runs++
+		if e.Status {
+			period.Succeeded++
+		}
+
+		for _, step := range e.Steps {
+			if step.Skipped {
+				continue
+			}
+
+			s, found := steps[step.CommandID]
+			if !found {
+				s = &chainStepStats{CommandID: step.CommandID}
+				steps[step.CommandID] = s
+				order = append(order, step.CommandID)
+			} else if s.last != step.Status {
+				s.Flips++
+			}
+
+			s.Step, s.last = step.Step, step.Status
+			s.Runs++
+			s.Total += step.Duration
+			if step.Duration > s.Max {
+				s.Max = step.Duration
+			}
+			if !step.Status {
+				s.Failures++
+			}
+		}
+	}
+
+	for _, id := range order {
+		var s = *steps[id]
+		report.Slowest = append(report.Slowest, s)
+		if s.Failures > 0 && s.Failures < s.Runs {
+			report.Flakiest = append(report.Flakiest, s)
+		}
+	}
+
+	sort.SliceStable(report.Slowest, func(i, j int) bool {
+		return report.Slowest[i].Average() > report.Slowest[j].Average()
+	})
+	sort.SliceStable(report.Flakiest, func(i, j int) bool {
+		if report.Flakiest[i].Flips != report.Flakiest[j].Flips {
+			return report.Flakiest[i].Flips > report.Flakiest[j].Flips
+		}
+		return report.Flakiest[i].Failures > report.Flakiest[j].Failures
+	})
+
+	return report
+}
+
+// stepCommand describes the command a step runs, as the chain show command does, escaped for
+// Tablify which prints the rows as format strings
+func stepCommand(id string) string {
+	command, err := findCommand(id)
+	if err != nil {
+		return "[" + id + "] <missing>"
+	}
+	return strings.ReplaceAll(command.AsStoredCommand(), "%", "%%")
+}
+
+// chainHistoryCmd represents the chain history command
+var chainHistoryCmd = &cobra.Command{
+	Use:   "history <name>",
+	Short: "History",
+	Long:  `History command, lists the latest runs of a chain and how each of its steps went, a failed run may be resumed by its id`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain history command invoked")
+
+			name, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid chain name")
+				return
+			}
+
+			executions, err := Repository.GetChainExecutions(name)
+			if err != nil {
+				Parrot.Println("Error retrieving the chain executions", err)
+				return
+			}
+
+			if len(executions) == 0 {
+				Parrot.Println("Chain " + name + " never ran!")
+				return
+			}
+
+			limit, _ := cmd.Flags().GetInt("limit")
+			if limit > 0 && len(executions) > limit {
+				executions = executions[len(executions)-limit:]
+			}
+
+			var rows = [][]string{}
+			for i := len(executions) - 1; i >= 0; i-- {
+				var e = executions[i]
+
+				var ran, skipped = 0, 0
+				for _, s := range e.Steps {
+					if s.Skipped {
+						skipped++
+					} else {
+						ran++
+					}
+				}
+
+				var steps = strconv.Itoa(ran-len(e.Failed())) + "/" + strconv.Itoa(len(e.Steps)) + " succeeded"
+				if skipped > 0 {
+					steps += ", " + strconv.Itoa(skipped) + " skipped"
+				}
+
+				var failed = "-"
+				if len(e.Failed()) > 0 {
+					failed = stepNumbers(e.Failed())
+				}
+
+				rows = append(rows, []string{
+					e.ID,
+					e.CreatedAt.Format("02.01.2006 15:04:05"),
+					strconv.FormatBool(e.Status),
+					e.TerminatedAt.Sub(e.CreatedAt).Round(time.Millisecond).String(),
+					steps,
+					failed,
+				})
+			}
+
+			Parrot.Tablify([]string{"ID", "STARTED", "STATUS", "DURATION", "STEPS", "FAILED STEPS"}, rows)
+		})
+	},
+}
+
+// chainAnalyticsCmd represents the chain analytics command
+var chainAnalyticsCmd = &cobra.Command{
+	Use:   "analytics <name>",
+	Short: "Analytics",
+	Long: `Analytics command, reports from the runs of a chain its success rate by day (or by week with --weekly),
+the steps taking longest and the flakiest ones, failing only in some runs`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain analytics command invoked")
+
+			name, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid chain name")
+				return
+			}
+
+			executions, err := Repository.GetChainExecutions(name)
+			if err != nil {
+				Parrot.Println("Error retrieving the chain executions", err)
+				return
+			}
+
+			if len(executions) == 0 {
+				Parrot.Println("Chain " + name + " never ran!")
+				return
+			}
+
+			var report = chainAnalytics(executions, cmd.Flag("weekly").Changed)
+
+			var rows = [][]string{}
+			for _, p := range report.Periods {
+				rows = append(rows, []string{
+					p.Start.Format("02.01.2006"),
+					strconv.Itoa(p.Runs),
+					strconv.Itoa(p.Succeeded),
+					strconv.Itoa(int(p.SuccessRate()*100)) + "%%",
+				})
+			}
+			Parrot.Println("Success rate")
+			Parrot.Tablify([]string{"SINCE", "RUNS", "SUCCEEDED", "RATE"}, rows)
+
+			rows = [][]string{}
+			for _, s := range report.Slowest {
+				rows = append(rows, []string{
+					strconv.Itoa(s.Step),
+					stepCommand(s.CommandID),
+					s.Average().Round(time.Millisecond).String(),
+					s.Max.Round(time.Millisecond).String(),
+					strconv.Itoa(s.Runs),
+				})
+			}
+			Parrot.Println("Slowest steps")
+			Parrot.Tablify([]string{"STEP", "COMMAND", "AVG DURATION", "MAX DURATION", "RUNS"}, rows)
+
+			if len(report.Flakiest) == 0 {
+				Parrot.Println("No flaky steps")
+				return
+			}
+
+			rows = [][]string{}
+			for _, s := range report.Flakiest {
+				rows = append(rows, []string{
+					strconv.Itoa(s.Step),
+					stepCommand(s.CommandID),
+					strconv.Itoa(s.Failures) + "/" + strconv.Itoa(s.Runs),
+					strconv.Itoa(s.Flips),
+				})
+			}
+			Parrot.Println("Flakiest steps")
+			Parrot.Tablify([]string{"STEP", "COMMAND", "FAILED RUNS", "FLIPS"}, rows)
+		})
+	},
+}
+
+func init() {
+	chainCmd.AddCommand(chainHistoryCmd)
+	chainCmd.AddCommand(chainAnalyticsCmd)
+
+	chainHistoryCmd.Flags().Int("limit", 20, "how many runs are listed, the latest first")
+	chainAnalyticsCmd.Flags().Bool("weekly", false, "the success rate is reported by week")
+}
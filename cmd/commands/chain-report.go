@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"html/template"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// reportStep is a step of a chain run as the report shows it
+type reportStep struct {
+	Step     int
+	Command  string
+	Needs    string
+	Outcome  string
+	Duration string
+	Output   string
+	Error    string
+}
+
+// chainRunReport is everything the report of a chain run shows, gathered beforehand so the file
+// needs nothing else
+type chainRunReport struct {
+	ID          string
+	Chain       string
+	Description string
+	Started     string
+	Duration    string
+	Status      string
+	Resumed     string
+	Steps       []reportStep
+	Generated   string
+}
+
+// reportTemplate is a single self contained page, the outputs fold in details elements
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Chain}} run {{.ID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+.ok { color: #1a7f37; } .failed { color: #cf222e; } .skipped { color: #888; }
+pre { background: #f6f8fa; padding: 0.8em; overflow-x: auto; max-height: 40em; }
+summary { cursor: pointer; }
+footer { margin-top: 2em; color: #888; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Chain}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+<table>
+<tr><th>Run</th><td>{{.ID}}</td></tr>
+<tr><th>Started</th><td>{{.Started}}</td></tr>
+<tr><th>Duration</th><td>{{.Duration}}</td></tr>
+<tr><th>Status</th><td class="{{.Status}}">{{.Status}}</td></tr>
+{{if .Resumed}}<tr><th>Resumed</th><td>{{.Resumed}}</td></tr>{{end}}
+</table>
+<h2>Steps</h2>
+<table>
+<tr><th>Step</th><th>Command</th><th>Needs</th><th>Status</th><th>Duration</th></tr>
+{{range .Steps}}<tr><td>{{.Step}}</td><td><code>{{.Command}}</code></td><td>{{.Needs}}</td><td class="{{.Outcome}}">{{.Outcome}}</td><td>{{.Duration}}</td></tr>
+{{end}}</table>
+<h2>Outputs</h2>
+{{range .Steps}}<details{{if eq .Outcome "failed"}} open{{end}}>
+<summary>{{.Step}}. <code>{{.Command}}</code> <span class="{{.Outcome}}">{{.Outcome}}</span></summary>
+{{if .Output}}<pre>{{.Output}}</pre>{{end}}{{if .Error}}<pre class="failed">{{.Error}}</pre>{{end}}
+{{if not (or .Output .Error)}}<p>No output</p>{{end}}
+</details>
+{{end}}
+<footer>Generated by ambros on {{.Generated}}</footer>
+</body>
+</html>
+`))
+
+// buildChainReport gathers the report of a run; the topology, the steps each step needs in a graph,
+// comes from the chain as it is now
+func buildChainReport(execution models.ChainExecution) chainRunReport {
+	var report = chainRunReport{
+		ID:        execution.ID,
+		Chain:     execution.Chain,
+		Started:   execution.CreatedAt.Format("02.01.2006 15:04:05"),
+		Duration:  execution.TerminatedAt.Sub(execution.CreatedAt).Round(time.Millisecond).String(),
+		Status:    "failed",
+		Resumed:   execution.Resumed,
+		Steps:     []reportStep{},
+		Generated: time.Now().Format("02.01.2006 15:04:05"),
+	}
+	if execution.Status {
+		report.Status = "ok"
+	}
+
+	chain, err := Repository.FindChainByName(execution.Chain)
+	var defined = err == nil
+	if defined {
+		report.Description = chain.Description
+	}
+
+	for _, s := range execution.Steps {
+		var step = reportStep{
+			Step:     s.Step,
+			Command:  "[" + s.CommandID + "] <missing>",
+			Needs:    "-",
+			Outcome:  stepOutcome(&s),
+			Duration: s.Duration.Round(time.Millisecond).String(),
+		}
+
+		if command, err := findCommand(s.CommandID); err == nil {
+			step.Command = command.AsStoredCommand()
+		}
+
+		if defined && s.Step > 0 && s.Step <= len(chain.Steps) && len(chain.Steps[s.Step-1].Needs) > 0 {
+			step.Needs = stepNumbers(chain.Steps[s.Step-1].Needs)
+		}
+
+		if !s.Skipped {
+			step.Output = resumedOutput(s)
+		}
+		if executed, err := Repository.FindById(s.Command); err == nil {
+			step.Error = executed.Error
+		}
+
+		report.Steps = append(report.Steps, step)
+	}
+
+	return report
+}
+
+// writeChainReport writes the report of a run as a single html page
+func writeChainReport(w io.Writer, execution models.ChainExecution) error {
+	return reportTemplate.Execute(w, buildChainReport(execution))
+}
+
+// chainReportCmd represents the chain report command
+var chainReportCmd = &cobra.Command{
+	Use:   "report <run-id>",
+	Short: "Report",
+	Long: `Report command, writes a run of a chain, as listed by chain history, to a single html file with nothing
+else needed to read it: the steps, how long each took, how it ended and its output`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain report command invoked")
+
+			id, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid chain run id")
+				return
+			}
+
+			execution, err := Repository.FindChainExecution(id)
+			if err != nil {
+				Parrot.Println("Chain run not available", err)
+				return
+			}
+
+			var out = cmd.Flag("out").Value.String()
+			if out == "" {
+				out = execution.Chain + "-" + execution.ID + ".html"
+			}
+
+			file, err := os.Create(out)
+			if err != nil {
+				Parrot.Println("Error creating the report", err)
+				return
+			}
+			defer file.Close()
+
+			if err := writeChainReport(file, execution); err != nil {
+				Parrot.Println("Error writing the report", err)
+				return
+			}
+
+			Parrot.Println("Report of " + strconv.Itoa(len(execution.Steps)) + " steps written to " + out)
+		})
+	},
+}
+
+func init() {
+	chainCmd.AddCommand(chainReportCmd)
+
+	chainReportCmd.Flags().String("out", "", "the html file written, <chain>-<run-id>.html by default")
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// resumedSteps returns the steps of the chain which succeeded in the resumed run, by number, and gives
+// the others the environment they ran with; a step edited since then runs as it is now
+func resumedSteps(chain *models.Chain, resumed models.ChainExecution) map[int]models.ChainStepExecution {
+	var done = map[int]models.ChainStepExecution{}
+
+	for _, s := range resumed.Steps {
+		if s.Step < 1 || s.Step > len(chain.Steps) || chain.Steps[s.Step-1].CommandID != s.CommandID {
+			continue
+		}
+
+		if s.Status && !s.Skipped {
+			done[s.Step] = s
+			continue
+		}
+
+		if s.Environment != "" {
+			chain.Steps[s.Step-1].Environment = s.Environment
+		}
+	}
+
+	return done
+}
+
+// resumedOutput is the output of a step of the resumed run, as recorded, for the steps piping it
+func resumedOutput(s models.ChainStepExecution) string {
+	command, err := Repository.FindById(s.Command)
+	if err != nil {
+		return ""
+	}
+
+	if command.Streamed || command.Cold {
+		streamed, _ := io.ReadAll(Repository.GetOutputReader(command.ID))
+		return string(streamed)
+	}
+	return command.Output
+}
+
+// chainResumeCmd represents the chain resume command
+var chainResumeCmd = &cobra.Command{
+	Use:   "resume <execution-id>",
+	Short: "Resume",
+	Long: `Resume command, runs again a failed run of a chain (see chain history) without the steps which
+succeeded then: the others run with the environment they had and a piped step reads the recorded output
+of the step before it. The steps changed since then run as they are now`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain resume command invoked")
+
+			id, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid execution id")
+				return
+			}
+
+			execution, err := Repository.FindChainExecution(id)
+			if err != nil {
+				Parrot.Println("Chain execution not available (" + id + ")")
+				return
+			}
+
+			if execution.Status {
+				Parrot.Println("Chain " + execution.Chain + " succeeded in that run, nothing to resume")
+				return
+			}
+
+			chain, err := Repository.FindChainByName(execution.Chain)
+			if err != nil {
+				Parrot.Println("Chain not available (" + execution.Chain + ")")
+				return
+			}
+
+			notifyFlag(cmd)
+			runChain(chain, &execution)
+		})
+	},
+}
+
+func init() {
+	chainCmd.AddCommand(chainResumeCmd)
+
+	chainResumeCmd.Flags().Bool("notify-on-failure", false, "notifies the configured channels when a step fails")
+}
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	tracing "github.com/gi4nks/ambros/internal/tracing"
+)
+
+// stepBackend returns the backend running a step, with its environment and in its directory
+func stepBackend(step models.ChainStep) (executor, error) {
+	var backend = commandExecutor
+
+	if step.Environment != "" {
+		environment, secrets, err := resolveEnvironment(step.Environment)
+		if err != nil {
+			return nil, err
+		}
+
+		backend = backend.with(environment.Environ())
+		environmentVariables = append(environmentVariables, environment.Environ()...)
+		environmentSecrets = append(environmentSecrets, secrets...)
+	}
+
+	if step.Dir != "" {
+		backend = backend.in(step.Dir)
+	}
+
+	return backend, nil
+}
+
+// runStep runs a step of a chain, again up to its retries while it fails, returning the last execution
+// and its output as written, before being redacted; a piped step reads the input on each attempt.
+// The executions are traced as children of span
+func runStep(span *tracing.Span, number int, step models.ChainStep, stored models.Command, backend executor, input string) (models.Command, string) {
+	for attempt := 0; ; attempt++ {
+		var command = initializeCommand(stored.Name, stored.Arguments)
+		command.Environment = step.Environment
+		command.AllowedExitCodes = stored.AllowedExitCodes
+		if step.Dir != "" {
+			inDirectory(&command, step.Dir)
+		}
+
+		var stdin io.Reader
+		if step.Pipe {
+			stdin = strings.NewReader(input)
+		}
+
+		executeCommandOn(span, backend, &command, step.Timeout, stdin)
+		var output = command.Output
+		finalizeCommand(&command)
+
+		if command.Status || attempt == step.Retries {
+			return command, output
+		}
+
+		Parrot.Println("--> step " + strconv.Itoa(number) + " failed, running it again (" + strconv.Itoa(attempt+1) + " of " +
+			strconv.Itoa(step.Retries) + " retries)")
+	}
+}
+
+// chainStepCmd represents the chain step command
+var chainStepCmd = &cobra.Command{
+	Use:   "step <name> <step>",
+	Short: "Step",
+	Long: `Step command, sets how a step of a chain runs: the environment applied, the working directory,
+how long it may run, how many times it runs again when it fails, if the chain goes on when it still fails
+and if it reads the output of the previous step (of the steps it needs, in a graph) on its input;
+an empty value (or 0, or false) restores the default`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain step command invoked")
+
+			if len(args) != 2 {
+				Parrot.Println("Please provide a chain name and a step number")
+				return
+			}
+
+			chain, err := Repository.FindChainByName(args[0])
+			if err != nil {
+				Parrot.Println("Chain not available (" + args[0] + ")")
+				return
+			}
+
+			number, err := strconv.Atoi(args[1])
+			if err != nil || number < 1 || number > len(chain.Steps) {
+				Parrot.Println("Please provide a step number between 1 and " + strconv.Itoa(len(chain.Steps)))
+				return
+			}
+
+			var step = &chain.Steps[number-1]
+
+			if cmd.Flag("env").Changed {
+				step.Environment = cmd.Flag("env").Value.String()
+				if _, err := Repository.GetEnvironment(step.Environment); step.Environment != "" && err != nil {
+					Parrot.Println("Environment not available (" + step.Environment + ")")
+					return
+				}
+			}
+			if cmd.Flag("dir").Changed {
+				step.Dir = cmd.Flag("dir").Value.String()
+			}
+			if cmd.Flag("timeout").Changed {
+				step.Timeout, _ = cmd.Flags().GetDuration("timeout")
+			}
+			if cmd.Flag("retries").Changed {
+				step.Retries, _ = cmd.Flags().GetInt("retries")
+			}
+			if cmd.Flag("continue-on-error").Changed {
+				step.ContinueOnError, _ = cmd.Flags().GetBool("continue-on-error")
+			}
+			if cmd.Flag("pipe").Changed {
+				step.Pipe, _ = cmd.Flags().GetBool("pipe")
+			}
+
+			if step.Timeout < 0 || step.Retries < 0 {
+				Parrot.Println("Please provide a positive --timeout and --retries")
+				return
+			}
+
+			if err := Repository.PutChain(chain); err != nil {
+				Parrot.Println("Error storing the chain", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+func init() {
+	chainCmd.AddCommand(chainStepCmd)
+
+	chainStepCmd.Flags().String("env", "", "the environment applied to the step")
+	chainStepCmd.Flags().String("dir", "", "the working directory of the step")
+	chainStepCmd.Flags().Duration("timeout", 0, "how long the step may run before being killed")
+	chainStepCmd.Flags().Int("retries", 0, "how many times the step runs again when it fails")
+	chainStepCmd.Flags().Bool("continue-on-error", false, "the chain goes on as if the step succeeded when it still fails")
+	chainStepCmd.Flags().Bool("pipe", false, "the step reads the output of the previous step, or of the steps it needs, on its input")
+}
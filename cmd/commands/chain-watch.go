@@ -0,0 +1,283 @@
+package commands
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// watchedFiles expands the patterns (globs or directories) into the files they match with their modification time
+func watchedFiles(patterns []string) map[string]time.Time {
+	var files = map[string]time.Time{}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+
+		for _, match := range matches {
+			filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					files[path] = info.ModTime()
+				}
+				return nil
+			})
+		}
+	}
+
+	return files
+}
+
+// changedFiles returns the files added, modified or removed between two snapshots
+func changedFiles(before map[string]time.Time, after map[string]time.Time) []string {
+	var changed = []string{}
+
+	for path, t := range after {
+		if old, ok := before[path]; !ok || !old.Equal(t) {
+			changed = append(changed, path)
+		}
+	}
+
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+
+	return changed
+}
+
+// matchesPatterns tells if any of the files is covered by the patterns
+func matchesPatterns(files []string, patterns []string) bool {
+	var covered = watchedFiles(patterns)
+
+	for _, f := range files {
+		if _, ok := covered[f]; ok {
+			return true
+		}
+
+		// removed files are not on disk anymore, match them against the patterns
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, f); ok {
+				return true
+			}
+			if strings.HasPrefix(f, filepath.Clean(pattern)+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// chainPatterns collects all the patterns watched for a chain
+func chainPatterns(chain models.Chain) []string {
+	var patterns = append([]string{}, chain.Triggers...)
+
+	for _, step := range chain.Steps {
+		patterns = append(patterns, step.Inputs...)
+	}
+
+	return patterns
+}
+
+// affectedChain returns the chain reduced to the steps to run for the changed files:
+// a change of a trigger runs every step, otherwise only the steps whose inputs changed
+func affectedChain(chain models.Chain, changed []string) models.Chain {
+	if matchesPatterns(changed, chain.Triggers) {
+		return chain
+	}
+
+	var affected = chain
+	affected.Steps = []models.ChainStep{}
+
+	// the steps keep needing the affected steps by their new numbers, the others do not run again
+	var numbers = map[int]int{}
+
+	for i, step := range chain.Steps {
+		if len(step.Inputs) > 0 && matchesPatterns(changed, step.Inputs) {
+			affected.Steps = append(affected.Steps, step)
+			numbers[i+1] = len(affected.Steps)
+		}
+	}
+
+	for i, step := range affected.Steps {
+		var needs []int
+		for _, n := range step.Needs {
+			if number, ok := numbers[n]; ok {
+				needs = append(needs, number)
+			}
+		}
+		affected.Steps[i].Needs = needs
+	}
+
+	return affected
+}
+
+// chainInputsCmd represents the chain inputs command
+var chainInputsCmd = &cobra.Command{
+	Use:   "inputs <name> <step> [pattern]...",
+	Short: "Inputs",
+	Long:  `Inputs command, sets the files (globs or directories) a chain step depends on, no pattern clears them`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain inputs command invoked")
+
+			if len(args) < 2 {
+				Parrot.Println("Please provide a chain name and a step number")
+				return
+			}
+
+			chain, err := Repository.FindChainByName(args[0])
+			if err != nil {
+				Parrot.Println("Chain not available (" + args[0] + ")")
+				return
+			}
+
+			step, err := strconv.Atoi(args[1])
+			if err != nil || step < 1 || step > len(chain.Steps) {
+				Parrot.Println("Please provide a step number between 1 and " + strconv.Itoa(len(chain.Steps)))
+				return
+			}
+
+			chain.Steps[step-1].Inputs = args[2:]
+
+			if err := Repository.PutChain(chain); err != nil {
+				Parrot.Println("Error storing the chain", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// chainTriggersCmd represents the chain triggers command
+var chainTriggersCmd = &cobra.Command{
+	Use:   "triggers <name> [pattern]...",
+	Short: "Triggers",
+	Long:  `Triggers command, sets the files (globs or directories) whose change re-runs the whole chain, no pattern clears them`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain triggers command invoked")
+
+			name, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid chain name")
+				return
+			}
+
+			chain, err := Repository.FindChainByName(name)
+			if err != nil {
+				Parrot.Println("Chain not available (" + name + ")")
+				return
+			}
+
+			chain.Triggers = Utilities.Tail(args)
+
+			if err := Repository.PutChain(chain); err != nil {
+				Parrot.Println("Error storing the chain", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// chainWatchCmd represents the chain watch command
+var chainWatchCmd = &cobra.Command{
+	Use:   "watch <name>",
+	Short: "Watch",
+	Long: `Watch command, runs the chain steps affected when their watched files change;
+SIGHUP reads the configuration again`,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, err := stringFromArguments(args)
+		if err != nil {
+			Parrot.Println("Please provide a valid chain name")
+			return
+		}
+
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil || interval <= 0 {
+			Parrot.Println("Please provide a valid interval")
+			return
+		}
+
+		var chain models.Chain
+		var found = false
+
+		commandWrapper(args, func() {
+			chain, err = Repository.FindChainByName(name)
+			found = err == nil
+		})
+
+		if !found {
+			Parrot.Println("Chain not available (" + name + ")")
+			return
+		}
+
+		var patterns = chainPatterns(chain)
+		if len(patterns) == 0 {
+			Parrot.Println("Chain " + name + " has no triggers or step inputs, see 'ambros chain triggers' and 'ambros chain inputs'")
+			return
+		}
+
+		notifyFlag(cmd)
+
+		Parrot.Println("Watching " + strconv.Itoa(len(patterns)) + " patterns for chain " + name + ", press Ctrl+C to stop")
+
+		// the repository is opened only while steps run, so other ambros commands are not blocked
+		var reloads = make(chan os.Signal, 1)
+		signal.Notify(reloads, syscall.SIGHUP)
+
+		var snapshot = watchedFiles(patterns)
+		for {
+			select {
+			case <-reloads:
+				applied, restart, err := reloadConfiguration()
+				reportReload(applied, restart, err)
+				notifyFlag(cmd)
+				continue
+			case <-time.After(interval):
+			}
+
+			var current = watchedFiles(patterns)
+			var changed = changedFiles(snapshot, current)
+			snapshot = current
+
+			if len(changed) == 0 {
+				continue
+			}
+
+			var affected = affectedChain(chain, changed)
+			if len(affected.Steps) == 0 {
+				continue
+			}
+
+			Parrot.Println(strconv.Itoa(len(changed)) + " files changed, running " + strconv.Itoa(len(affected.Steps)) + " steps")
+
+			commandWrapper(args, func() {
+				executeChain(affected)
+			})
+		}
+	},
+}
+
+func init() {
+	chainCmd.AddCommand(chainInputsCmd)
+	chainCmd.AddCommand(chainTriggersCmd)
+	chainCmd.AddCommand(chainWatchCmd)
+
+	chainWatchCmd.Flags().Duration("interval", time.Second, "how often the watched files are checked")
+	chainWatchCmd.Flags().Bool("notify-on-failure", false, "notifies the configured channels when a step fails")
+}
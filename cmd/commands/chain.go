@@ -0,0 +1,534 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// steps failing at least this often in the history are highlighted by the dry run
+const chainRiskyFailureRate = 0.3
+
+// chainCmd represents the chain command
+var chainCmd = &cobra.Command{
+	Use:   "chain",
+	Short: "Chain",
+	Long:  `Chain command, manages named sequences of commands`,
+}
+
+// chainCreateCmd represents the chain create command
+var chainCreateCmd = &cobra.Command{
+	Use:   "create <name> <id>...",
+	Short: "Create",
+	Long:  `Create command, stores a chain of command ids to be executed in order`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain create command invoked")
+
+			if len(args) < 2 {
+				Parrot.Println("Please provide a chain name and at least one command id")
+				return
+			}
+
+			var chain = models.Chain{Name: args[0]}
+			chain.ID = Utilities.Random()
+			chain.CreatedAt = time.Now()
+			chain.Description = cmd.Flag("description").Value.String()
+
+			for _, step := range Utilities.Tail(args) {
+				var id = resolveID(step)
+				if _, err := findCommand(id); err != nil {
+					Parrot.Println("Id not available in the store (" + id + ")")
+					return
+				}
+
+				chain.Steps = append(chain.Steps, models.ChainStep{CommandID: id})
+			}
+
+			if err := Repository.PutChain(chain); err != nil {
+				Parrot.Println("Error storing the chain", err)
+				return
+			}
+
+			Parrot.Println(chain.AsStoredChain())
+		})
+	},
+}
+
+// chainListCmd represents the chain list command
+var chainListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List",
+	Long:  `List command, shows all the stored chains`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain list command invoked")
+
+			chains, err := Repository.GetAllChains()
+			if err != nil {
+				Parrot.Println("Error retrieving chains", err)
+				return
+			}
+
+			if len(chains) == 0 {
+				Parrot.Println("No chains available!")
+				return
+			}
+
+			for _, c := range chains {
+				Parrot.Println(c.AsStoredChain())
+			}
+		})
+	},
+}
+
+// chainShowCmd represents the chain show command
+var chainShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show",
+	Long: `Show command, shows a chain and the commands it runs; --history lists the variants it replaced
+and --rollback restores one of them`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain show command invoked")
+
+			name, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid chain name")
+				return
+			}
+
+			chain, err := Repository.FindChainByName(name)
+			if err != nil {
+				Parrot.Println("Chain not available (" + name + ")")
+				return
+			}
+
+			if cmd.Flag("history").Changed {
+				showChainVersions(chain)
+				return
+			}
+
+			if cmd.Flag("rollback").Changed {
+				number, _ := cmd.Flags().GetInt("rollback")
+				rollbackChain(chain, number)
+				return
+			}
+
+			Parrot.Println(chain.Name)
+			if chain.Description != "" {
+				Parrot.Println(chain.Description)
+			}
+
+			for i, step := range chain.Steps {
+				var settings = ""
+				if s := step.Settings(); s != "" {
+					settings = " (" + s + ")"
+				}
+
+				command, err := findCommand(step.CommandID)
+				if err != nil {
+					Parrot.Println("  " + strconv.Itoa(i+1) + ". [" + step.CommandID + "] <missing>" + settings)
+					continue
+				}
+				Parrot.Println("  " + strconv.Itoa(i+1) + ". " + command.AsStoredCommand() + settings)
+			}
+		})
+	},
+}
+
+// chainDeleteCmd represents the chain delete command
+var chainDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete",
+	Long:  `Delete command, removes a chain`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain delete command invoked")
+
+			name, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid chain name")
+				return
+			}
+
+			if err := Repository.DeleteChain(name); err != nil {
+				Parrot.Println("Chain not available ("+name+")", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// chainExecCmd represents the chain exec command
+var chainExecCmd = &cobra.Command{
+	Use:   "exec <name>",
+	Short: "Exec",
+	Long: `Exec command, runs the commands of a chain in order stopping at the first failure; when the steps
+declare the steps they need (see chain needs) each one starts as soon as those succeeded. The steps with
+a condition (see chain when) run only when it holds, even after a failure`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain exec command invoked")
+
+			name, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid chain name")
+				return
+			}
+
+			chain, err := Repository.FindChainByName(name)
+			if err != nil {
+				Parrot.Println("Chain not available (" + name + ")")
+				return
+			}
+
+			if cmd.Flag("dry-run").Changed {
+				estimateChain(chain)
+				return
+			}
+
+			notifyFlag(cmd)
+			executeChain(chain)
+		})
+	},
+}
+
+// chainExportCmd represents the chain export command
+var chainExportCmd = &cobra.Command{
+	Use:     "export <name> <file>",
+	Aliases: []string{"snapshot"},
+	Short:   "Export",
+	Long:    `Export command, writes a chain and its commands to a json file, restored by chain restore`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain export command invoked")
+
+			if len(args) != 2 {
+				Parrot.Println("Please provide a valid chain name and output file")
+				return
+			}
+
+			chain, err := Repository.FindChainByName(args[0])
+			if err != nil {
+				Parrot.Println("Chain not available (" + args[0] + ")")
+				return
+			}
+
+			var export = models.Snapshot{Kind: "chain", CreatedAt: time.Now(), Chain: &chain, Commands: []models.Command{}}
+
+			for _, step := range chain.Steps {
+				command, err := findCommand(step.CommandID)
+				if err != nil {
+					Parrot.Println("Id not available in the store (" + step.CommandID + ")")
+					return
+				}
+				export.Commands = append(export.Commands, command)
+			}
+
+			buf := new(bytes.Buffer)
+			json.Indent(buf, []byte(Utilities.AsJson(export)), "", "  ")
+
+			if err := os.WriteFile(args[1], buf.Bytes(), 0644); err != nil {
+				Parrot.Println("Impossible to create the required file (" + args[1] + ")")
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// chainRestoreCmd represents the chain restore command
+var chainRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore",
+	Long:  `Restore command, stores the chain written by chain export, its commands missing here go to the store`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Chain restore command invoked")
+
+			if len(args) != 1 {
+				Parrot.Println("Please provide a snapshot file")
+				return
+			}
+
+			snapshot, err := readSnapshot(args[0])
+			if err != nil || snapshot.Chain == nil {
+				Parrot.Println("Please provide a valid chain snapshot ("+args[0]+")", err)
+				return
+			}
+
+			if _, err := Repository.FindChainByName(snapshot.Chain.Name); err == nil && !cmd.Flag("force").Changed {
+				Parrot.Println("Chain " + snapshot.Chain.Name + " already exists, use --force to replace it")
+				return
+			}
+
+			for _, command := range snapshot.Commands {
+				if _, err := findCommand(command.ID); err == nil {
+					continue
+				}
+
+				if err := Repository.Push(command); err != nil {
+					Parrot.Println("Error storing the command "+command.ID, err)
+					return
+				}
+			}
+
+			if err := Repository.PutChain(*snapshot.Chain); err != nil {
+				Parrot.Println("Error storing the chain", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// executeChain runs the steps of a chain in order, stopping at the first failure; the steps of a chain
+// graph run as the steps they need succeed, and the steps with a condition only when it holds. The run
+// is recorded in the chain history
+func executeChain(chain models.Chain) bool {
+	return runChain(chain, nil)
+}
+
+// runChain runs a chain as executeChain does; when it resumes a failed run, the steps which succeeded
+// then are not run again and the others run with the environment they had
+func runChain(chain models.Chain, resumed *models.ChainExecution) bool {
+	var execution = models.ChainExecution{Chain: chain.Name, Steps: []models.ChainStepExecution{}}
+	execution.ID = Utilities.Random()
+	execution.CreatedAt = time.Now()
+
+	var done = map[int]models.ChainStepExecution{}
+	if resumed != nil {
+		execution.Resumed = resumed.ID
+		done = resumedSteps(&chain, *resumed)
+	}
+
+	if chain.Graph() {
+		execution.Status = executeChainGraph(chain, &execution, done)
+	} else {
+		execution.Status = executeChainSteps(chain, &execution, done)
+	}
+
+	execution.TerminatedAt = time.Now()
+	recordChainExecution(execution)
+
+	return execution.Status
+}
+
+// recordChainExecution stores a run of a chain, unless incognito is on
+func recordChainExecution(execution models.ChainExecution) {
+	if incognito() {
+		return
+	}
+
+	sort.Slice(execution.Steps, func(i, j int) bool {
+		return execution.Steps[i].Step < execution.Steps[j].Step
+	})
+
+	if err := Repository.PutChainExecution(execution); err != nil {
+		Parrot.Error("Error storing the chain execution", err)
+	}
+}
+
+// stepExecution is how a step which ran went
+func stepExecution(number int, step models.ChainStep, command models.Command) models.ChainStepExecution {
+	return models.ChainStepExecution{
+		Step:        number,
+		CommandID:   step.CommandID,
+		Command:     command.ID,
+		Environment: step.Environment,
+		Status:      command.Status,
+		Duration:    command.TerminatedAt.Sub(command.CreatedAt),
+	}
+}
+
+// skippedStep is a step which did not run
+func skippedStep(number int, step models.ChainStep) models.ChainStepExecution {
+	return models.ChainStepExecution{Step: number, CommandID: step.CommandID, Environment: step.Environment, Skipped: true}
+}
+
+// executeChainSteps runs the steps of a chain in order, after a failure only the ones with a condition;
+// the done steps are not run again
+func executeChainSteps(chain models.Chain, execution *models.ChainExecution, done map[int]models.ChainStepExecution) bool {
+	var span = tracer.Start("chain "+chain.Name, invocation)
+	span.SetAttribute("ambros.chain.steps", len(chain.Steps))
+	defer tracer.End(span)
+
+	// the output of the previous step, read by a piped step
+	var previous string
+	var results = newStepResults(len(chain.Steps))
+
+	// after a failure only the steps with a condition still run, when it holds
+	var failure = ""
+
+	for i, step := range chain.Steps {
+		stored, err := findCommand(step.CommandID)
+		if err != nil {
+			Parrot.Println("Id not available in the store (" + step.CommandID + ")")
+			span.SetError("step " + step.CommandID + " not available")
+			return false
+		}
+
+		if d, ok := done[i+1]; ok {
+			Parrot.Println("--> step " + stored.AsStoredCommand() + " succeeded in the resumed run")
+			execution.Steps = append(execution.Steps, d)
+			previous = resumedOutput(d)
+			results.record(i, true, previous)
+			continue
+		}
+
+		if failure != "" && step.When == "" {
+			execution.Steps = append(execution.Steps, skippedStep(i+1, step))
+			results.statuses[i] = conditionSkipped
+			continue
+		}
+
+		holds, err := results.holds(step)
+		if err != nil {
+			Parrot.Println("Error evaluating the condition of step "+stored.AsStoredCommand(), err)
+			span.SetError("step " + step.CommandID + " condition not evaluated")
+			return false
+		}
+
+		if !holds {
+			Parrot.Println("--> step " + stored.AsStoredCommand() + " skipped, its condition does not hold")
+			execution.Steps = append(execution.Steps, skippedStep(i+1, step))
+			results.statuses[i] = conditionSkipped
+			previous = ""
+			continue
+		}
+
+		if stored.Placeholders() > 0 {
+			Parrot.Println("Step " + stored.AsStoredCommand() + " has placeholders and cannot run in a chain")
+			span.SetError("step " + step.CommandID + " has placeholders")
+			return false
+		}
+
+		backend, err := stepBackend(step)
+		if err != nil {
+			Parrot.Println("Error applying the environment "+step.Environment+" of step "+stored.AsStoredCommand(), err)
+			span.SetError("step " + step.CommandID + " environment not applied")
+			return false
+		}
+
+		Parrot.Println("--> step " + stored.AsStoredCommand())
+
+		var stepSpan = tracer.Start("chain step "+strconv.Itoa(i+1), span)
+		stepSpan.SetAttribute("ambros.step.command.id", step.CommandID)
+
+		var command, output = runStep(stepSpan, i+1, step, stored, backend, previous)
+		previous = output
+		execution.Steps = append(execution.Steps, stepExecution(i+1, step, command))
+		results.record(i, command.Status, output)
+
+		if !command.Status {
+			stepSpan.SetError("step failed")
+		}
+		tracer.End(stepSpan)
+
+		if !command.Status && step.ContinueOnError {
+			Parrot.Println("--> step " + stored.AsStoredCommand() + " failed, the chain goes on (continue on error)")
+			continue
+		}
+
+		if !command.Status && failure == "" {
+			span.SetError("failed at step " + strconv.Itoa(i+1))
+			Parrot.Println("--> step " + stored.AsStoredCommand() + " failed")
+			failure = "Step " + stored.AsStoredCommand() + " failed, see 'ambros show " + command.ID + "'"
+		}
+	}
+
+	if failure != "" {
+		Parrot.Println("Chain " + chain.Name + " failed")
+		Parrot.Println(failure)
+		notifyFailure("ambros: chain "+chain.Name+" failed", failure)
+		return false
+	}
+
+	Parrot.Println("Chain " + chain.Name + " completed")
+	return true
+}
+
+// estimateChain reports the expected duration and failure risk of each step from the history
+func estimateChain(chain models.Chain) {
+	commands, err := Repository.GetAllCommands()
+	if err != nil {
+		Parrot.Println("Error retrieving commands in the store", err)
+		return
+	}
+
+	var stats = statsByFingerprint(commands)
+	var total time.Duration
+	var body = [][]string{}
+
+	// when the steps of a graph end, the ones running in parallel overlap
+	var averages = make([]time.Duration, len(chain.Steps))
+
+	for i, step := range chain.Steps {
+		stored, err := findCommand(step.CommandID)
+		if err != nil {
+			body = append(body, []string{strconv.Itoa(i + 1), "[" + step.CommandID + "] <missing>", "-", "-", "will fail"})
+			continue
+		}
+
+		var s = stats[stored.Fingerprint()]
+		if s.Count == 0 {
+			body = append(body, []string{strconv.Itoa(i + 1), stored.AsStoredCommand(), "unknown", "0/0", "never run"})
+			continue
+		}
+
+		var note = ""
+		if s.FailureRate() >= chainRiskyFailureRate {
+			note = "risky"
+		}
+
+		total += s.Average()
+		averages[i] = s.Average()
+		body = append(body, []string{
+			strconv.Itoa(i + 1),
+			stored.AsStoredCommand(),
+			s.Average().Round(time.Millisecond).String(),
+			strconv.Itoa(s.Failures) + "/" + strconv.Itoa(s.Count),
+			note,
+		})
+	}
+
+	var mode = "steps run sequentially"
+	if chain.Graph() && chain.CheckNeeds() == nil {
+		mode = "independent steps run in parallel"
+		total = graphDuration(chain, averages)
+	}
+
+	Parrot.Println("Dry run of chain " + chain.Name + " (" + mode + ")")
+	Parrot.Tablify([]string{"STEP", "COMMAND", "AVG DURATION", "FAILED RUNS", "NOTE"}, body)
+	Parrot.Println("Estimated total duration: " + total.Round(time.Millisecond).String())
+}
+
+func init() {
+	RootCmd.AddCommand(chainCmd)
+	chainCmd.AddCommand(chainCreateCmd)
+	chainCmd.AddCommand(chainListCmd)
+	chainCmd.AddCommand(chainShowCmd)
+	chainCmd.AddCommand(chainDeleteCmd)
+	chainCmd.AddCommand(chainExecCmd)
+	chainCmd.AddCommand(chainExportCmd)
+	chainCmd.AddCommand(chainRestoreCmd)
+
+	chainCreateCmd.Flags().StringP("description", "d", "", "description of the chain")
+	chainExecCmd.Flags().Bool("dry-run", false, "estimates duration and failure risk of the steps without running them")
+	chainExecCmd.Flags().Bool("notify-on-failure", false, "notifies the configured channels when a step fails")
+	chainRestoreCmd.Flags().Bool("force", false, "replaces a chain with the same name")
+	chainShowCmd.Flags().Bool("history", false, "lists the previous versions of the chain, the last undoDepth (0 keeps them all)")
+	chainShowCmd.Flags().Int("rollback", 0, "restores a previous version of the chain")
+}
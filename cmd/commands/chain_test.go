@@ -0,0 +1,646 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+func TestExecuteCommandScripted(t *testing.T) {
+	useScriptedExecutor(t,
+		scriptedRule{Pattern: `^make build$`, Stdout: "built"},
+		scriptedRule{Pattern: `^make test`, Stdout: "running", Stderr: "2 tests failed", ExitCode: 2},
+		scriptedRule{Pattern: `^sleep`, Delay: 200 * time.Millisecond},
+	)
+
+	var tests = []struct {
+		line   string
+		status bool
+		output string
+		error  string
+	}{
+		{"make build", true, "built\n", ""},
+		{"make test ./...", false, "running\n", "2 tests failed\nexit status 2"},
+		{"sleep 1", true, "", ""},
+		{"unknown --flag", false, "", "unknown: command not found\nexit status 127"},
+	}
+
+	for _, test := range tests {
+		var parts = utils.SplitCommandLine(test.line)
+		var command = initializeCommand(parts[0], parts[1:])
+
+		executeCommand(&command)
+
+		if command.Status != test.status || command.Output != test.output || command.Error != test.error {
+			t.Errorf("executeCommand(%s) = %v %q %q, want %v %q %q", test.line,
+				command.Status, command.Output, command.Error, test.status, test.output, test.error)
+		}
+	}
+}
+
+func TestExecuteCommandScriptedDelay(t *testing.T) {
+	useScriptedExecutor(t, scriptedRule{Pattern: `^sleep`, Delay: 300 * time.Millisecond})
+
+	var command = initializeCommand("sleep", []string{"1"})
+	var start = time.Now()
+
+	executeCommand(&command)
+
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("executeCommand(sleep 1) took %v, want at least the scripted 300ms", elapsed)
+	}
+}
+
+func TestExecuteCommandTimeout(t *testing.T) {
+	useScriptedExecutor(t)
+
+	// the shell leaves a child behind holding the output open, killed only with the process group
+	var line = []string{"-c", "sleep 5 & sleep 5"}
+
+	var command = initializeCommand("sh", line)
+	var start = time.Now()
+	executeCommandOn(nil, localExecutor{}, &command, 200*time.Millisecond, nil)
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("executeCommandOn(sh) took %v, want it killed after 200ms", elapsed)
+	}
+	if command.Status || !command.TimedOut || !strings.Contains(command.Error, "timed out after 200ms") {
+		t.Errorf("executeCommandOn(sh) = status %v, timed out %v, error %q, want a timeout", command.Status, command.TimedOut, command.Error)
+	}
+
+	// restored by the cleanup of useScriptedExecutor
+	commandExecutor = localExecutor{}
+
+	var piped = initializeCommand("sh", line)
+	start = time.Now()
+	executeCommands([]*models.Command{&piped}, false, 200*time.Millisecond)
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("executeCommands(sh) took %v, want it killed after 200ms", elapsed)
+	}
+	if piped.Status || !piped.TimedOut {
+		t.Errorf("executeCommands(sh) = status %v, timed out %v, want a timeout", piped.Status, piped.TimedOut)
+	}
+}
+
+func TestExecuteCommandDockerTimeout(t *testing.T) {
+	useScriptedExecutor(t)
+
+	// a docker client running forever, recording the containers it is asked to kill
+	var bin = t.TempDir()
+	var killed = filepath.Join(bin, "killed")
+	var docker = "#!/bin/sh\nif [ \"$1\" = kill ]; then echo \"$2\" >> " + killed + "; exit 0; fi\nexec sleep 5\n"
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(docker), 0755); err != nil {
+		t.Fatalf("WriteFile returned unexpected error: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	backend, err := newDockerExecutor("alpine")
+	if err != nil {
+		t.Fatalf("newDockerExecutor returned unexpected error: %v", err)
+	}
+
+	var command = initializeCommand("make", []string{"build"})
+	executeCommandOn(nil, backend, &command, 200*time.Millisecond, nil)
+
+	if !command.TimedOut {
+		t.Errorf("executeCommandOn(docker) timed out %v, want a timeout", command.TimedOut)
+	}
+	if stopped, _ := os.ReadFile(killed); strings.TrimSpace(string(stopped)) != "ambros-"+command.ID {
+		t.Errorf("executeCommandOn(docker) killed the containers %q, want ambros-%s", stopped, command.ID)
+	}
+}
+
+func TestExecuteCommandScriptedEnvironment(t *testing.T) {
+	useScriptedExecutor(t, scriptedRule{Pattern: `^deploy`, Stdout: "to $TARGET"})
+
+	var previous = commandExecutor
+	commandExecutor = commandExecutor.with([]string{"TARGET=staging"})
+	defer func() { commandExecutor = previous }()
+
+	var command = initializeCommand("deploy", nil)
+	executeCommand(&command)
+
+	if command.Output != "to staging\n" {
+		t.Errorf("executeCommand(deploy) output = %q, want %q", command.Output, "to staging\n")
+	}
+}
+
+func TestExecuteChainStopsAtFailure(t *testing.T) {
+	var scripted = useScriptedExecutor(t,
+		scriptedRule{Pattern: `^git pull$`, Stdout: "Already up to date."},
+		scriptedRule{Pattern: `^make test$`, Stderr: "1 test failed", ExitCode: 1},
+		scriptedRule{Pattern: `^make deploy$`, Stdout: "deployed"},
+	)
+
+	var chain = models.Chain{Name: "release"}
+	for _, line := range []string{"git pull", "make test", "make deploy"} {
+		chain.Steps = append(chain.Steps, models.ChainStep{CommandID: storeCommand(t, line)})
+	}
+
+	if executeChain(chain) {
+		t.Fatalf("executeChain(release) succeeded, want a failure at step 2")
+	}
+
+	if lines, want := scripted.lines(), []string{"git pull", "make test"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("executeChain(release) executed %v, want %v", lines, want)
+	}
+
+	commands, err := Repository.GetAllCommands()
+	if err != nil {
+		t.Fatalf("GetAllCommands returned unexpected error: %v", err)
+	}
+
+	var statuses = map[string]bool{}
+	for _, c := range commands {
+		statuses[c.Name+" "+strings.Join(c.Arguments, " ")] = c.Status
+	}
+
+	if want := map[string]bool{"git pull": true, "make test": false}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("executeChain(release) recorded %v, want %v", statuses, want)
+	}
+}
+
+func TestExecuteChainNotifiesFailure(t *testing.T) {
+	var mutex sync.Mutex
+	var received = []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mutex.Lock()
+		received = append(received, string(body))
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	useScriptedExecutor(t,
+		scriptedRule{Pattern: `^make build$`},
+		scriptedRule{Pattern: `^make test$`, ExitCode: 1},
+	)
+
+	Configuration.Notifications = []utils.NotificationChannel{{Type: "webhook", URL: server.URL}}
+
+	var chain = models.Chain{Name: "ci", Steps: []models.ChainStep{{CommandID: storeCommand(t, "make build")}}}
+
+	notifyFailures = true
+	if !executeChain(chain) {
+		t.Fatalf("executeChain(ci) failed, want a success")
+	}
+	if len(received) != 0 {
+		t.Fatalf("executeChain(ci) sent %d notifications on success, want none", len(received))
+	}
+
+	chain.Steps = append(chain.Steps, models.ChainStep{CommandID: storeCommand(t, "make test")})
+
+	notifyFailures = false
+	executeChain(chain)
+	if len(received) != 0 {
+		t.Fatalf("executeChain(ci) sent %d notifications with notifications off, want none", len(received))
+	}
+
+	notifyFailures = true
+	executeChain(chain)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(received) != 1 || !strings.Contains(received[0], "chain ci failed") {
+		t.Errorf("executeChain(ci) sent %q, want one notification of the failure of chain ci", received)
+	}
+}
+
+func TestExecuteChainGraph(t *testing.T) {
+	var scripted = useScriptedExecutor(t,
+		scriptedRule{Pattern: `^make build$`, Delay: 300 * time.Millisecond},
+		scriptedRule{Pattern: `^make lint$`, Delay: 300 * time.Millisecond},
+		scriptedRule{Pattern: `^make test$`, Stderr: "1 test failed", ExitCode: 1},
+		scriptedRule{Pattern: `^make docs$`},
+		scriptedRule{Pattern: `^make deploy$`},
+	)
+
+	// build and lint are independent, deploy needs test which fails, docs needs lint only
+	var chain = models.Chain{Name: "release"}
+	for _, step := range []struct {
+		line  string
+		needs []int
+	}{{"make build", nil}, {"make lint", nil}, {"make test", []int{1}}, {"make docs", []int{2}}, {"make deploy", []int{3, 4}}} {
+		chain.Steps = append(chain.Steps, models.ChainStep{CommandID: storeCommand(t, step.line), Needs: step.needs})
+	}
+
+	if executeChain(chain) {
+		t.Fatalf("executeChain(release) succeeded, want a failure of step 3")
+	}
+
+	commands, err := Repository.GetAllCommands()
+	if err != nil {
+		t.Fatalf("GetAllCommands returned unexpected error: %v", err)
+	}
+
+	var runs = map[string]models.Command{}
+	for _, c := range commands {
+		runs[c.Name+" "+strings.Join(c.Arguments, " ")] = c
+	}
+
+	var build, lint = runs["make build"], runs["make lint"]
+	if !lint.CreatedAt.Before(build.TerminatedAt) || !build.CreatedAt.Before(lint.TerminatedAt) {
+		t.Errorf("executeChain(release) ran build (%v-%v) and lint (%v-%v) one after the other, want them in parallel",
+			build.CreatedAt, build.TerminatedAt, lint.CreatedAt, lint.TerminatedAt)
+	}
+
+	var executed = map[string]bool{}
+	for _, line := range scripted.lines() {
+		executed[line] = true
+	}
+
+	if want := map[string]bool{"make build": true, "make lint": true, "make test": true, "make docs": true}; !reflect.DeepEqual(executed, want) {
+		t.Errorf("executeChain(release) executed %v, want %v", executed, want)
+	}
+
+	chain.Steps[0].Needs = []int{5}
+	if executeChain(chain) {
+		t.Errorf("executeChain(release) with a cycle succeeded, want a failure")
+	}
+}
+
+func TestExecuteChainStepSettings(t *testing.T) {
+	for _, graph := range []bool{false, true} {
+		var scripted = useScriptedExecutor(t,
+			scriptedRule{Pattern: `^make flaky$`, ExitCode: 1},
+			scriptedRule{Pattern: `^make slow$`, Delay: 5 * time.Second},
+			scriptedRule{Pattern: `^deploy$`, Stdout: "to $TARGET"},
+		)
+
+		if err := Repository.PutEnvironment(models.Environment{Name: "staging", Variables: map[string]string{"TARGET": "staging"}}); err != nil {
+			t.Fatalf("PutEnvironment returned unexpected error: %v", err)
+		}
+
+		var chain = models.Chain{Name: "release", Steps: []models.ChainStep{
+			{CommandID: storeCommand(t, "make flaky"), Retries: 2, ContinueOnError: true},
+			{CommandID: storeCommand(t, "make slow"), Timeout: 200 * time.Millisecond, ContinueOnError: true},
+			{CommandID: storeCommand(t, "deploy"), Environment: "staging"},
+		}}
+		if graph {
+			chain.Steps[2].Needs = []int{1, 2}
+		}
+
+		if !executeChain(chain) {
+			t.Errorf("executeChain(release) graph %v failed, want the failures tolerated", graph)
+		}
+
+		var runs = map[string]int{}
+		for _, line := range scripted.lines() {
+			runs[line]++
+		}
+		if want := map[string]int{"make flaky": 3, "make slow": 1, "deploy": 1}; !reflect.DeepEqual(runs, want) {
+			t.Errorf("executeChain(release) graph %v executed %v, want %v", graph, runs, want)
+		}
+
+		commands, err := Repository.GetAllCommands()
+		if err != nil {
+			t.Fatalf("GetAllCommands returned unexpected error: %v", err)
+		}
+
+		for _, c := range commands {
+			switch c.Name {
+			case "make":
+				if c.Arguments[0] == "slow" && !strings.Contains(c.Error, "timed out after 200ms") {
+					t.Errorf("make slow recorded error %q, want a timeout", c.Error)
+				}
+			case "deploy":
+				if c.Output != "to staging\n" || c.Environment != "staging" {
+					t.Errorf("deploy recorded %q in %q, want the output of the staging environment", c.Output, c.Environment)
+				}
+			}
+		}
+	}
+}
+
+func TestExecuteChainPipe(t *testing.T) {
+	for _, graph := range []bool{false, true} {
+		useScriptedExecutor(t,
+			scriptedRule{Pattern: `^ls$`, Stdout: "a.go\nb.go"},
+			scriptedRule{Pattern: `^date$`, Stdout: "today"},
+			scriptedRule{Pattern: `^sort$`, Echo: true},
+			scriptedRule{Pattern: `^wc$`, Stdout: "counted", Echo: true},
+		)
+
+		var chain = models.Chain{Name: "pipeline", Steps: []models.ChainStep{
+			{CommandID: storeCommand(t, "ls")},
+			{CommandID: storeCommand(t, "sort"), Pipe: true},
+			{CommandID: storeCommand(t, "date")},
+			{CommandID: storeCommand(t, "wc")},
+		}}
+
+		// sequentially wc does not pipe, it reads nothing; in a graph it reads what sort and date wrote
+		var want = map[string]string{"sort": "a.go\nb.go\n", "wc": "counted\n"}
+		if graph {
+			chain.Steps[1].Needs = []int{1}
+			chain.Steps[3].Needs = []int{2, 3}
+			chain.Steps[3].Pipe = true
+			want["wc"] = "counted\na.go\nb.go\ntoday\n"
+		}
+
+		if !executeChain(chain) {
+			t.Errorf("executeChain(pipeline) graph %v failed, want it completed", graph)
+		}
+
+		commands, err := Repository.GetAllCommands()
+		if err != nil {
+			t.Fatalf("GetAllCommands returned unexpected error: %v", err)
+		}
+
+		for _, c := range commands {
+			if output, ok := want[c.Name]; ok && c.Output != output {
+				t.Errorf("executeChain(pipeline) graph %v recorded %q for %s, want %q", graph, c.Output, c.Name, output)
+			}
+		}
+	}
+}
+
+func TestExecuteChainHistory(t *testing.T) {
+	useScriptedExecutor(t, scriptedRule{Pattern: `^make build$`}, scriptedRule{Pattern: `^make test$`, ExitCode: 1})
+
+	var chain = models.Chain{Name: "release", Steps: []models.ChainStep{
+		{CommandID: storeCommand(t, "make build")},
+		{CommandID: storeCommand(t, "make test")},
+		{CommandID: storeCommand(t, "make deploy")},
+	}}
+
+	executeChain(chain)
+	chain.Steps[1].ContinueOnError = true
+	executeChain(chain)
+
+	executions, err := Repository.GetChainExecutions("release")
+	if err != nil {
+		t.Fatalf("GetChainExecutions returned unexpected error: %v", err)
+	}
+
+	if len(executions) != 2 {
+		t.Fatalf("GetChainExecutions(release) returned %d executions, want 2", len(executions))
+	}
+
+	var statuses = func(e models.ChainExecution) string {
+		var s = []string{}
+		for _, step := range e.Steps {
+			switch {
+			case step.Skipped:
+				s = append(s, "skipped")
+			case step.Status:
+				s = append(s, "ok")
+			default:
+				s = append(s, "failed")
+			}
+		}
+		return strings.Join(s, " ")
+	}
+
+	for i, want := range []string{"ok failed skipped", "ok failed failed"} {
+		if got := statuses(executions[i]); got != want || executions[i].Status {
+			t.Errorf("execution %d recorded %q (status %v), want %q (status false)", i+1, got, executions[i].Status, want)
+		}
+	}
+}
+
+func TestChainAnalytics(t *testing.T) {
+	var monday = time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+
+	var execution = func(when time.Time, build, test time.Duration, testStatus bool) models.ChainExecution {
+		var e = models.ChainExecution{Chain: "release", Status: testStatus, Steps: []models.ChainStepExecution{
+			{Step: 1, CommandID: "build", Status: true, Duration: build},
+			{Step: 2, CommandID: "test", Status: testStatus, Duration: test},
+			{Step: 3, CommandID: "deploy", Skipped: !testStatus, Status: testStatus},
+		}}
+		e.CreatedAt = when
+		return e
+	}
+
+	var executions = []models.ChainExecution{
+		execution(monday, time.Second, 3*time.Second, true),
+		execution(monday.Add(time.Hour), 3*time.Second, 3*time.Second, false),
+		execution(monday.AddDate(0, 0, 1), 2*time.Second, 3*time.Second, true),
+		execution(monday.AddDate(0, 0, 7), 2*time.Second, 3*time.Second, true),
+	}
+
+	var report = chainAnalytics(executions, false)
+
+	var periods = []string{}
+	for _, p := range report.Periods {
+		periods = append(periods, p.Start.Format("2006-01-02")+" "+strconv.Itoa(p.Succeeded)+"/"+strconv.Itoa(p.Runs))
+	}
+	if want := []string{"2024-05-06 1/2", "2024-05-07 1/1", "2024-05-13 1/1"}; !reflect.DeepEqual(periods, want) {
+		t.Errorf("chainAnalytics daily periods = %v, want %v", periods, want)
+	}
+
+	if weekly := chainAnalytics(executions, true); len(weekly.Periods) != 2 || weekly.Periods[0].Runs != 3 {
+		t.Errorf("chainAnalytics weekly periods = %+v, want 3 runs in the first of 2 weeks", weekly.Periods)
+	}
+
+	var slowest = []string{}
+	for _, s := range report.Slowest {
+		slowest = append(slowest, s.CommandID+" "+s.Average().String())
+	}
+	if want := []string{"test 3s", "build 2s", "deploy 0s"}; !reflect.DeepEqual(slowest, want) {
+		t.Errorf("chainAnalytics slowest = %v, want %v", slowest, want)
+	}
+
+	if len(report.Flakiest) != 1 || report.Flakiest[0].CommandID != "test" || report.Flakiest[0].Flips != 2 || report.Flakiest[0].Failures != 1 {
+		t.Errorf("chainAnalytics flakiest = %+v, want test failing once with 2 flips", report.Flakiest)
+	}
+}
+
+func TestCompareChainRuns(t *testing.T) {
+	var run = func(id string, took time.Duration, steps ...models.ChainStepExecution) models.ChainExecution {
+		var e = models.ChainExecution{Chain: "nightly", Steps: steps}
+		e.ID, e.CreatedAt = id, time.Date(2024, 5, 6, 2, 0, 0, 0, time.UTC)
+		e.TerminatedAt = e.CreatedAt.Add(took)
+		return e
+	}
+
+	var from = run("r1", 10*time.Second,
+		models.ChainStepExecution{Step: 1, CommandID: "build", Status: true, Duration: 4 * time.Second},
+		models.ChainStepExecution{Step: 2, CommandID: "test", Status: true, Duration: 5 * time.Second},
+		models.ChainStepExecution{Step: 3, CommandID: "lint", Status: false, Duration: time.Second},
+	)
+	var to = run("r2", 25*time.Second,
+		models.ChainStepExecution{Step: 1, CommandID: "build", Status: true, Duration: 9 * time.Second},
+		models.ChainStepExecution{Step: 2, CommandID: "test", Status: false, Duration: 5 * time.Second},
+		models.ChainStepExecution{Step: 3, CommandID: "deploy", Skipped: true},
+	)
+
+	var comparison = compareChainRuns(from, to)
+	if comparison.Delta != 15*time.Second {
+		t.Errorf("compareChainRuns delta = %v, want 15s", comparison.Delta)
+	}
+
+	var changes = []string{}
+	for _, s := range comparison.Steps {
+		changes = append(changes, s.CommandID+" "+s.From+"->"+s.To+" "+signedDuration(s.Delta())+" "+s.Change())
+	}
+	var want = []string{
+		"build ok->ok +5s slower x2.2",
+		"test ok->failed +0s newly failed",
+		"deploy none->skipped +0s added",
+		"lint failed->none -1s removed",
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("compareChainRuns steps = %q, want %q", changes, want)
+	}
+}
+
+func TestWriteChainReport(t *testing.T) {
+	useScriptedExecutor(t, scriptedRule{Pattern: `^make build$`, Stdout: "<built>"}, scriptedRule{Pattern: `^make test$`, Stderr: "1 test failed", ExitCode: 1})
+
+	var chain = models.Chain{Name: "release", Steps: []models.ChainStep{
+		{CommandID: storeCommand(t, "make build")},
+		{CommandID: storeCommand(t, "make test"), Needs: []int{1}},
+	}}
+	if err := Repository.PutChain(chain); err != nil {
+		t.Fatalf("PutChain returned unexpected error: %v", err)
+	}
+	executeChain(chain)
+
+	executions, err := Repository.GetChainExecutions("release")
+	if err != nil || len(executions) != 1 {
+		t.Fatalf("GetChainExecutions(release) = %d executions, %v, want 1", len(executions), err)
+	}
+
+	var report bytes.Buffer
+	if err := writeChainReport(&report, executions[0]); err != nil {
+		t.Fatalf("writeChainReport returned unexpected error: %v", err)
+	}
+
+	// the outputs are escaped, the failed step is unfolded
+	for _, want := range []string{executions[0].ID, "make build", "&lt;built&gt;", "1 test failed", `<td class="failed">failed</td>`, "<details open>", "<td>1</td><td class=\"failed\">"} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("writeChainReport() does not contain %q", want)
+		}
+	}
+}
+
+func TestResumeChain(t *testing.T) {
+	for _, graph := range []bool{false, true} {
+		var scripted = useScriptedExecutor(t,
+			scriptedRule{Pattern: `^make build$`, Stdout: "built"},
+			scriptedRule{Pattern: `^make lint$`},
+			scriptedRule{Pattern: `^make package$`, Echo: true},
+			scriptedRule{Pattern: `^make deploy$`, Stdout: "to $TARGET"},
+		)
+
+		if err := Repository.PutEnvironment(models.Environment{Name: "staging", Variables: map[string]string{"TARGET": "staging"}}); err != nil {
+			t.Fatalf("PutEnvironment returned unexpected error: %v", err)
+		}
+
+		// package fails the first time, it is unknown to the scripted executor
+		var chain = models.Chain{Name: "release", Steps: []models.ChainStep{
+			{CommandID: storeCommand(t, "make lint")},
+			{CommandID: storeCommand(t, "make build")},
+			{CommandID: storeCommand(t, "make pkg"), Pipe: true},
+			{CommandID: storeCommand(t, "make deploy"), Environment: "staging"},
+		}}
+		if graph {
+			chain.Steps[2].Needs = []int{2}
+			chain.Steps[3].Needs = []int{1, 3}
+		}
+
+		if executeChain(chain) {
+			t.Fatalf("executeChain(release) graph %v succeeded, want a failure of step 3", graph)
+		}
+
+		executions, err := Repository.GetChainExecutions("release")
+		if err != nil || len(executions) != 1 {
+			t.Fatalf("GetChainExecutions(release) = %d executions, %v, want 1", len(executions), err)
+		}
+
+		// fixed in the store, the step keeps its id; the environment of deploy changed since
+		var fixed = initializeCommand("make", []string{"package"})
+		fixed.ID = chain.Steps[2].CommandID
+		if err := Repository.Push(fixed); err != nil {
+			t.Fatalf("Push returned unexpected error: %v", err)
+		}
+		chain.Steps[3].Environment = ""
+
+		var before = len(scripted.lines())
+		if !runChain(chain, &executions[0]) {
+			t.Fatalf("runChain(release) graph %v failed, want it resumed and completed", graph)
+		}
+
+		if got, want := scripted.lines()[before:], []string{"make package", "make deploy"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("runChain(release) graph %v executed %v, want %v", graph, got, want)
+		}
+
+		commands, err := Repository.GetAllCommands()
+		if err != nil {
+			t.Fatalf("GetAllCommands returned unexpected error: %v", err)
+		}
+
+		for _, c := range commands {
+			var line = c.Name + " " + strings.Join(c.Arguments, " ")
+			if line == "make package" && c.Output != "built\n" {
+				t.Errorf("resumed make package read %q, want the recorded output of make build", c.Output)
+			}
+			if line == "make deploy" && c.Output != "to staging\n" {
+				t.Errorf("resumed make deploy wrote %q, want the environment of the resumed run", c.Output)
+			}
+		}
+
+		executions, _ = Repository.GetChainExecutions("release")
+		if last := executions[len(executions)-1]; !last.Status || last.Resumed != executions[0].ID || len(last.Steps) != 4 {
+			t.Errorf("resumed execution = %+v, want a successful run of 4 steps resuming %s", last, executions[0].ID)
+		}
+	}
+}
+
+func TestExecuteChainConditions(t *testing.T) {
+	for _, graph := range []bool{false, true} {
+		var scripted = useScriptedExecutor(t,
+			scriptedRule{Pattern: `^make build$`, Stdout: "version 1.2.0"},
+			scriptedRule{Pattern: `^make test$`, ExitCode: 1},
+			scriptedRule{Pattern: `.`},
+		)
+
+		if err := Repository.PutEnvironment(models.Environment{Name: "staging", Variables: map[string]string{"TARGET": "staging"}}); err != nil {
+			t.Fatalf("PutEnvironment returned unexpected error: %v", err)
+		}
+
+		var chain = models.Chain{Name: "release", Steps: []models.ChainStep{
+			{CommandID: storeCommand(t, "make build")},
+			{CommandID: storeCommand(t, "make changelog"), When: `{{steps.1.output}} =~ "\.0$"`},
+			{CommandID: storeCommand(t, "make test")},
+			{CommandID: storeCommand(t, "make deploy")},
+			{CommandID: storeCommand(t, "make rollback"), When: "{{steps.3.status}} == failure && {{env.TARGET}} == staging", Environment: "staging"},
+			{CommandID: storeCommand(t, "make release-notes"), When: "{{steps.4.status}} == success"},
+		}}
+		if graph {
+			chain.Steps[1].Needs = []int{1}
+			chain.Steps[2].Needs = []int{1}
+			chain.Steps[3].Needs = []int{3}
+			chain.Steps[4].Needs = []int{3}
+			chain.Steps[5].Needs = []int{4}
+		}
+
+		if executeChain(chain) {
+			t.Errorf("executeChain(release) graph %v succeeded, want the failure of make test", graph)
+		}
+
+		var executed = scripted.lines()
+		sort.Strings(executed)
+
+		if want := []string{"make build", "make changelog", "make rollback", "make test"}; !reflect.DeepEqual(executed, want) {
+			t.Errorf("executeChain(release) graph %v executed %v, want %v", graph, executed, want)
+		}
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	models "github.com/gi4nks/ambros/internal/models"
+	tracing "github.com/gi4nks/ambros/internal/tracing"
+	utils "github.com/gi4nks/ambros/internal/utils"
 	"github.com/gi4nks/quant"
 )
 
@@ -19,26 +24,86 @@ import (
 func CmdWrapper(args []string) {
 }
 
+// readOnlyCommands only read the repository, they open it alongside the other readers and
+// leave out the housekeeping done by the other commands
+var readOnlyCommands = map[string]bool{
+	"ambros last":             true,
+	"ambros logs":             true,
+	"ambros find":             true,
+	"ambros stats":            true,
+	"ambros diff":             true,
+	"ambros alias export":     true,
+	"ambros whatchanged":      true,
+	"ambros project stats":    true,
+	"ambros project branches": true,
+}
+
 func commandWrapper(args []string, cmd quant.Action0) {
-	err := Repository.InitDB()
+	var started = time.Now()
+
+	startTracing()
+	defer stopTracing()
+
+	var opened = trackPhase("open")
 
+	// incognito writes nothing, whatever the command
+	var readOnly = readOnlyCommands[invokedCommand] || incognito()
+
+	var err = checkProfile()
 	if err != nil {
 		Parrot.Println(err)
 		return
 	}
 
-	err = Repository.InitSchema()
+	if readOnly {
+		err = Repository.InitReadOnlyDB()
+	} else {
+		err = Repository.InitDB()
+	}
 
 	if err != nil {
 		Parrot.Println(err)
 		return
 	}
 
+	if !readOnly {
+		err = Repository.InitSchema()
+
+		if err != nil {
+			Parrot.Println(err)
+			return
+		}
+	}
+
+	opened()
+
+	if !readOnly {
+		recoverOrphans()
+	}
+
 	CmdWrapper(args)
 
+	var ran = trackPhase("command")
 	cmd()
+	ran()
+
+	if !readOnly {
+		staleHint()
+		evictColdOutputs()
+		scheduledBackup()
+	}
+	repositorySizeWarning()
+
+	if readOnly {
+		// the read only handle cannot write the timings, they are stored once it is released
+		Repository.CloseDB()
+		storeQueryPerf(started)
+		return
+	}
 
 	defer Repository.CloseDB()
+
+	storePerf(started)
 }
 
 // ----------------
@@ -52,9 +117,27 @@ func initializeCommand(name string, arguments []string) models.Command {
 	command.Arguments = arguments
 
 	command.CreatedAt = time.Now()
+	inDirectory(&command, "")
 	return command
 }
 
+// inDirectory records the directory the command runs in, the current one when empty, the project
+// holding it and, unless gitContext is off, the state of its git repository
+func inDirectory(command *models.Command, dir string) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+
+	command.Dir = dir
+	command.Project = Utilities.ProjectRoot(dir)
+
+	command.Git = nil
+	if command.Project != "" && Configuration.GitContext {
+		command.Git = Utilities.GitContext(dir)
+	}
+}
+
 func initializeCommands(cmds [][]string) []models.Command {
 	var commands = []models.Command{}
 
@@ -65,6 +148,7 @@ func initializeCommands(cmds [][]string) []models.Command {
 		command.Name = cmdParts[0]
 		command.Arguments = cmdParts[1:]
 		command.CreatedAt = time.Now()
+		inDirectory(&command, "")
 
 		// Append the command to the commands slice
 		commands = append(commands, command)
@@ -72,23 +156,289 @@ func initializeCommands(cmds [][]string) []models.Command {
 	return commands
 }
 
+// redacting tells if the secrets must be removed before anything is persisted
+func redacting() bool {
+	return Configuration.Redact && !noRedact
+}
+
+// environmentVariables are the variables of the environments applied to the current executions
+var environmentVariables = []string{}
+
+// environmentSecrets are the values of the secret variables injected in the current executions
+var environmentSecrets = []string{}
+
+// redactEnvironmentSecrets replaces the values of the injected secrets, whatever the redaction settings
+func redactEnvironmentSecrets(text string) string {
+	for _, secret := range environmentSecrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, utils.RedactedMarker)
+		}
+	}
+	return text
+}
+
+// redactCommand removes the secrets from the arguments and the outputs of a command
+func redactCommand(command *models.Command) {
+	// a new slice, the arguments may be shared with a command still to be executed
+	var arguments = make([]string, len(command.Arguments))
+	for i, argument := range command.Arguments {
+		arguments[i] = redactEnvironmentSecrets(argument)
+	}
+	command.Arguments = arguments
+	command.Output = redactEnvironmentSecrets(command.Output)
+	command.Error = redactEnvironmentSecrets(command.Error)
+
+	if !redacting() {
+		return
+	}
+
+	var found, names []string
+	command.Arguments, found = Utilities.RedactArguments(command.Arguments, Configuration.RedactPatterns)
+	command.Output, names = Utilities.Redact(command.Output, Configuration.RedactPatterns)
+	found = append(found, names...)
+	command.Error, names = Utilities.Redact(command.Error, Configuration.RedactPatterns)
+	found = append(found, names...)
+
+	if len(found) > 0 {
+		Parrot.Debug("--> Secrets redacted " + strings.Join(found, ", "))
+	}
+}
+
+// sequenceCommand gives the command its place in the history of this machine, once
+func sequenceCommand(command *models.Command) error {
+	if command.Sequence > 0 {
+		return nil
+	}
+
+	sequence, err := Repository.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	command.Origin = Configuration.Origin
+	command.Sequence = sequence
+	return nil
+}
+
+// reproFingerprint fingerprints the environment of an executable, caching the probed versions
+// per binary so --version runs only once for each installed release
+func reproFingerprint(name string, probe bool) models.Repro {
+	var repro = Utilities.Repro(name, false)
+	if !probe || repro.Path == "" {
+		return repro
+	}
+
+	info, err := os.Stat(repro.Path)
+	if err != nil {
+		return repro
+	}
+
+	var key = "version " + repro.Path + " " + strconv.FormatInt(info.ModTime().UnixNano(), 10)
+	if version, err := Repository.GetMeta(key); err == nil && version != "" {
+		repro.Version = version
+		return repro
+	}
+
+	repro.Version = Utilities.ProbeVersion(repro.Path)
+	if repro.Version != "" {
+		Repository.PutMeta(key, repro.Version)
+	}
+
+	return repro
+}
+
+// startCommand persists the command as running so a crash leaves a trace
+func startCommand(command *models.Command) {
+	command.PID = os.Getpid()
+
+	if Configuration.HostSnapshot {
+		var snapshot = Utilities.HostSnapshot(".")
+		command.HostStart = &snapshot
+	}
+
+	assessRisk(command)
+
+	if incognito() {
+		return
+	}
+
+	if err := sequenceCommand(command); err != nil {
+		Parrot.Error("Error numbering the command", err)
+	}
+
+	// the executable of a container is not the one installed here
+	if commandExecutor.local() {
+		var repro = reproFingerprint(command.Name, Configuration.ReproProbe)
+		command.Repro = &repro
+	}
+
+	// the command is still running with its real arguments, only the record is redacted
+	var running = *command
+	redactCommand(&running)
+
+	if err := Repository.PutRunning(running); err != nil {
+		Parrot.Error("Error storing the running command", err)
+	}
+}
+
+// recoverOrphans marks as interrupted the running commands whose process is gone
+func recoverOrphans() {
+	running, err := Repository.GetRunningCommands()
+	if err != nil {
+		Parrot.Error("Error retrieving running commands", err)
+		return
+	}
+
+	for _, command := range running {
+		if Utilities.ProcessAlive(command.PID) {
+			continue
+		}
+
+		command.Status = false
+		command.Interrupted = true
+		command.TerminatedAt = time.Now()
+
+		if err := Repository.Put(command); err != nil {
+			Parrot.Error("Error storing the interrupted command", err)
+			continue
+		}
+
+		Repository.DeleteRunning(command.ID)
+		Parrot.Println("Command [" + command.ID + "] was interrupted, see 'ambros jobs orphans'")
+	}
+}
+
+// checkDiskSpace refuses writes when the filesystem of the repository is almost full
+func checkDiskSpace() error {
+	if Configuration.MinFreeDisk <= 0 {
+		return nil
+	}
+
+	free, err := Utilities.FreeDiskSpace(Configuration.RepositoryDirectory)
+	if err != nil {
+		Parrot.Debug("--> Unable to check the free disk space", err)
+		return nil
+	}
+
+	if free < Configuration.MinFreeDisk {
+		return errors.New("Not enough free disk space to record the command (" + Utilities.FormatSize(free) + " free, " +
+			Utilities.FormatSize(Configuration.MinFreeDisk) + " required) in " + Configuration.RepositoryDirectory +
+			": free some space, run 'ambros revive' to reset the history or lower minFreeDisk in the configuration")
+	}
+
+	return nil
+}
+
+// repositorySizeWarning warns when the repository grows beyond the configured threshold
+func repositorySizeWarning() {
+	if Configuration.MaxRepositorySize <= 0 {
+		return
+	}
+
+	size, err := Repository.Size()
+	if err != nil || size <= Configuration.MaxRepositorySize {
+		return
+	}
+
+	Parrot.Warn("The ambros repository is " + Utilities.FormatSize(size) + ", above the configured maxRepositorySize of " +
+		Utilities.FormatSize(Configuration.MaxRepositorySize))
+}
+
+// recordCommand stores an executed command unless the sampling policy discards it, telling if it was stored
+func recordCommand(command *models.Command) (bool, error) {
+	defer trackPhase("store")()
+
+	if incognito() {
+		return false, nil
+	}
+
+	Repository.DeleteRunning(command.ID)
+
+	if err := checkDiskSpace(); err != nil {
+		return false, err
+	}
+
+	if Configuration.HostSnapshot {
+		var snapshot = Utilities.HostSnapshot(".")
+		command.HostEnd = &snapshot
+	}
+
+	command.Warnings, command.Errors = Utilities.Severities(command.Output + "\n" + command.Error)
+	redactCommand(command)
+
+	record, err := Repository.ShouldRecord(*command, Configuration.SamplingIntervalFor(command.Name))
+	if err != nil {
+		return false, err
+	}
+
+	if !record {
+		Parrot.Debug("--> Command sampled out " + command.Fingerprint())
+
+		// the chunks streamed while it ran belong to no command then
+		if command.Streamed {
+			return false, Repository.DeleteOutput(command.ID)
+		}
+		return false, nil
+	}
+
+	if err := sequenceCommand(command); err != nil {
+		return false, err
+	}
+
+	if err := Repository.Put(*command); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// printRecorded prints the id to look the command up with, only a stored command has one
+func printRecorded(command *models.Command, recorded bool) {
+	switch {
+	case recorded:
+		Parrot.Println("[" + command.ID + "]")
+	case incognito():
+		Parrot.Println("not recorded (incognito)")
+	default:
+		Parrot.Println("not recorded (sampled out)")
+	}
+}
+
 func finalizeCommand(command *models.Command) {
 	command.TerminatedAt = time.Now()
-	Repository.Put(*command)
+	recorded, err := recordCommand(command)
+	if err != nil {
+		Parrot.Error("Error storing the command", err)
+		return
+	}
 
-	Parrot.Println("[" + command.ID + "]")
+	printRecorded(command, recorded)
 }
 
 func finalizeCommands(commands []*models.Command) {
 	for _, command := range commands {
-		command.TerminatedAt = time.Now()
-		Repository.Put(*command)
-		Parrot.Println("[" + command.ID + "]")
+		finalizeCommand(command)
 	}
 }
 
 func pushCommand(command *models.Command, showid bool) {
 	command.TerminatedAt = time.Now()
+
+	if incognito() {
+		Parrot.Println("Incognito is on: the command is not stored")
+		return
+	}
+
+	if err := checkDiskSpace(); err != nil {
+		Parrot.Println(err)
+		return
+	}
+
+	if command.Risk == nil {
+		assessRisk(command)
+	}
+
+	redactCommand(command)
 	Repository.Push(*command)
 
 	if showid {
@@ -102,6 +452,22 @@ func pushCommands(commands []*models.Command, showid bool) {
 		Parrot.Println(command.AsStoredCommand())
 
 		command.TerminatedAt = time.Now()
+
+		if incognito() {
+			Parrot.Println("Incognito is on: the command is not stored")
+			continue
+		}
+
+		if err := checkDiskSpace(); err != nil {
+			Parrot.Println(err)
+			return
+		}
+
+		if command.Risk == nil {
+			assessRisk(command)
+		}
+
+		redactCommand(command)
 		Repository.Push(*command)
 
 		if showid {
@@ -111,10 +477,56 @@ func pushCommands(commands []*models.Command, showid bool) {
 }
 
 func executeCommand(command *models.Command) {
+	executeCommandOn(invocation, commandExecutor, command, 0, nil)
+}
+
+// allowedExitCodes returns the non zero exit codes meaning success for the command, its own or else
+// the ones configured for its name
+func allowedExitCodes(command *models.Command) utils.ExitCodes {
+	var spec = command.AllowedExitCodes
+	if spec == "" {
+		spec = Configuration.AllowedExitCodesFor(command.Name)
+	}
+	if spec == "" {
+		return nil
+	}
+
+	codes, err := utils.ParseExitCodes(spec)
+	if err != nil {
+		Parrot.Error("Invalid allowed exit codes "+spec, err)
+		return nil
+	}
+	return codes
+}
+
+// recordExitCode keeps the exit code of the process of the command, returning the error of its execution
+// unless the command exited with an allowed code
+func recordExitCode(command *models.Command, state *os.ProcessState, err error) error {
+	command.ExitCode = -1
+	if state != nil {
+		command.ExitCode = state.ExitCode()
+	}
+
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) && allowedExitCodes(command).Contains(command.ExitCode) {
+		return nil
+	}
+	return err
+}
+
+// executeCommandOn runs the command with the given backend, killing it when it runs longer than
+// the timeout (0 lets it run as long as it takes); the input, when not nil, is written to its stdin.
+// Its span is a child of parent
+func executeCommandOn(parent *tracing.Span, backend executor, command *models.Command, timeout time.Duration, input io.Reader) {
 	var bufferOutput bytes.Buffer
 	var bufferError bytes.Buffer
 
-	cmd := exec.Command(command.Name, command.Arguments...)
+	cmd := backend.command(command)
+	defer traceExecution(parent, command, cmd)()
+
+	if input != nil {
+		cmd.Stdin = input
+	}
 
 	Parrot.Debug("--> CommandName " + command.Name)
 	Parrot.Debug("--> Command Arguments " + Utilities.AsJson(command.Arguments))
@@ -135,7 +547,14 @@ func executeCommand(command *models.Command) {
 		return
 	}
 
-	err = cmd.Start()
+	if err := checkOverlap(command); err != nil {
+		Parrot.Error("Overlapping command", err)
+		command.Error = err.Error()
+		command.Status = false
+		return
+	}
+
+	timedOut, err := startWithTimeout(cmd, timeout, func() { backend.stop(command) })
 	if err != nil {
 		Parrot.Error("Error starting Cmd", err)
 		command.Error = err.Error()
@@ -143,6 +562,9 @@ func executeCommand(command *models.Command) {
 		return
 	}
 
+	startCommand(command)
+	defer trackPhase("execute")()
+
 	stopOut := make(chan bool)
 	stopErr := make(chan bool)
 
@@ -166,45 +588,120 @@ func executeCommand(command *models.Command) {
 		stop <- true
 	}(stopErr)
 
+	detachRepository()
+
 	<-stopOut
 	<-stopErr
 
 	err = cmd.Wait()
+
+	reattachRepository(command)
+	backend.record(command, err)
+
+	// the usage of a container is not the one of the client process
+	if backend.local() {
+		command.Usage = Utilities.ProcessUsage(cmd.ProcessState)
+	}
+
+	command.Output = bufferOutput.String()
+	command.Error = bufferError.String()
+
+	err = recordExitCode(command, cmd.ProcessState, err)
+	if timedOut() {
+		err = errors.New("timed out after " + timeout.String())
+		command.TimedOut = true
+	}
+
 	if err != nil {
 		Parrot.Error("Error waiting for Cmd", err)
-		command.Error = err.Error()
+		command.Error += err.Error()
 		command.Status = false
 		return
 	}
 
-	command.Output = bufferOutput.String()
-	command.Error = bufferError.String()
-
 	command.Status = true
 }
 
-func executeCommands(commands []*models.Command) {
+// executeCommands runs the commands as a pipeline, one after the other, killing each one running longer
+// than the timeout (0 lets them run as long as they take)
+func executeCommands(commands []*models.Command, stream bool, timeout time.Duration) {
 	var output []byte
+	var previous *models.Command
 
 	// Execute commands sequentially, capturing intermediate output
 	for _, cmdParts := range commands {
+		// checked before the command is marked as running, so queued instances never wait for each other
+		if err := checkOverlap(cmdParts); err != nil {
+			Parrot.Error("Overlapping command", err)
+			cmdParts.Error = err.Error()
+			cmdParts.Status = false
+			cmdParts.TerminatedAt = time.Now()
+			recordCommand(cmdParts)
+			return
+		}
+
 		cmdParts.CreatedAt = time.Now()
-		cmd := exec.Command(cmdParts.Name, cmdParts.Arguments...)
+		startCommand(cmdParts)
+
+		cmd := commandExecutor.command(cmdParts)
+		var traced = traceExecution(invocation, cmdParts, cmd)
 		var intermediate bytes.Buffer
-		cmd.Stdout = &intermediate
-		cmd.Stderr = &intermediate // use stderr to capture combined output
+		var streamed *outputWriter
+
+		if stream {
+			// output goes to the terminal and to the repository in chunks, never fully in memory
+			streamed = newOutputWriter(cmdParts.ID)
+			cmd.Stdout = io.MultiWriter(os.Stdout, streamed)
+			cmd.Stderr = cmd.Stdout
+			cmdParts.Streamed = true
+		} else {
+			cmd.Stdout = &intermediate
+			cmd.Stderr = &intermediate // use stderr to capture combined output
+		}
 
 		// Write previous command output to stdin of current command if needed
-		if len(output) > 0 {
+		if stream && previous != nil {
+			cmd.Stdin = Repository.GetOutputReader(previous.ID)
+		} else if len(output) > 0 {
 			cmd.Stdin = bytes.NewReader(output)
 		}
 
-		// Executing the command and managing the error and sthe status at the end
-		err := cmd.Run()
-		output = intermediate.Bytes()
+		// Executing the command and managing the error and sthe status at the end;
+		// streamed outputs are written to the repository, which stays open then
+		var executed = trackPhase("execute")
+		if !stream {
+			detachRepository()
+		}
+		timedOut, err := startWithTimeout(cmd, timeout, func() { commandExecutor.stop(cmdParts) })
+		if err == nil {
+			err = cmd.Wait()
+		}
+		if !stream {
+			reattachRepository(cmdParts)
+		}
+		executed()
+		commandExecutor.record(cmdParts, err)
 
-		Parrot.Println(string(output))
-		cmdParts.Output = string(output)
+		if commandExecutor.local() {
+			cmdParts.Usage = Utilities.ProcessUsage(cmd.ProcessState)
+		}
+
+		err = recordExitCode(cmdParts, cmd.ProcessState, err)
+		if timedOut() {
+			err = errors.New("timed out after " + timeout.String())
+			cmdParts.TimedOut = true
+		}
+
+		if stream {
+			if err1 := streamed.Close(); err1 != nil {
+				Parrot.Error("Error storing the command output", err1)
+			}
+		} else {
+			output = intermediate.Bytes()
+
+			Parrot.Println(string(output))
+			cmdParts.Output = string(output)
+		}
 		cmdParts.Error = ""
 
 		if err != nil {
@@ -212,13 +709,16 @@ func executeCommands(commands []*models.Command) {
 			cmdParts.Error = err.Error()
 			cmdParts.Status = false
 		} else {
-			Parrot.Println(string(output))
+			if !stream {
+				Parrot.Println(string(output))
+			}
 			cmdParts.Status = true
 		}
 
 		cmdParts.TerminatedAt = time.Now()
+		traced()
 
-		if err1 := Repository.Put(*cmdParts); err1 != nil {
+		if _, err1 := recordCommand(cmdParts); err1 != nil {
 			Parrot.Error("Error storing the command", err1)
 		}
 
@@ -227,7 +727,18 @@ func executeCommands(commands []*models.Command) {
 		if !cmdParts.Status {
 			return
 		}
+
+		previous = cmdParts
+	}
+}
+
+// findCommand looks for a command in the store first and then in the history
+func findCommand(id string) (models.Command, error) {
+	if stored, err := Repository.FindInStoreById(id); err == nil {
+		return stored, nil
 	}
+
+	return Repository.FindById(id)
 }
 
 // ----------------
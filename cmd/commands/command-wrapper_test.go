@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRecordCommandSampledOut(t *testing.T) {
+	useScriptedExecutor(t, scriptedRule{Pattern: "^make"})
+	Configuration.SamplingInterval = time.Hour
+
+	var record = func() (string, bool) {
+		var command = initializeCommand("make", []string{"build"})
+		executeCommand(&command)
+		command.TerminatedAt = time.Now()
+
+		// as run --stream does while the command runs
+		command.Streamed = true
+		if err := Repository.AppendOutput(command.ID, []byte("built\n")); err != nil {
+			t.Fatalf("AppendOutput returned unexpected error: %v", err)
+		}
+
+		recorded, err := recordCommand(&command)
+		if err != nil {
+			t.Fatalf("recordCommand returned unexpected error: %v", err)
+		}
+		return command.ID, recorded
+	}
+
+	first, recorded := record()
+	if !recorded {
+		t.Fatalf("recordCommand(make build) = not recorded, want the first run stored")
+	}
+
+	// a second success within the sampling interval is discarded, it has no id to look up
+	second, recorded := record()
+	if recorded {
+		t.Errorf("recordCommand(make build) again = recorded, want sampled out")
+	}
+
+	if _, err := Repository.FindById(first); err != nil {
+		t.Errorf("FindById(%s) returned unexpected error: %v", first, err)
+	}
+	if _, err := Repository.FindById(second); err == nil {
+		t.Errorf("FindById(%s) returned no error, want the sampled out command missing", second)
+	}
+
+	// nor its streamed output
+	if output, _ := io.ReadAll(Repository.GetOutputReader(second)); len(output) != 0 {
+		t.Errorf("GetOutputReader(%s) = %q, want the streamed output deleted", second, output)
+	}
+	if output, _ := io.ReadAll(Repository.GetOutputReader(first)); string(output) != "built\n" {
+		t.Errorf("GetOutputReader(%s) = %q, want the output of the stored command", first, output)
+	}
+}
@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// number of most recent commands kept in the completion cache
+const completionCacheSize = 5000
+
+// number of most recent command lines whose id is kept in the completion cache
+const completionCacheRecent = 200
+
+// completionCache keeps the command lines completion learns from, so <TAB> does not open the repository
+type completionCache struct {
+	Lines   [][]string
+	Aliases map[string]string
+
+	// the latest executions of each command line and the stored commands, offered where an id is expected
+	Recent []completionEntry
+	Stored []completionEntry
+}
+
+// completionEntry is a command offered by its id, described by its command line
+type completionEntry struct {
+	ID   string
+	Line string
+}
+
+func completionCachePath() string {
+	return filepath.Join(Configuration.RepositoryDirectory, "completion.cache")
+}
+
+// loadCompletionCache reads the cache, telling if it is older than the repository
+func loadCompletionCache() (completionCache, bool, error) {
+	var cache = completionCache{}
+
+	info, err := os.Stat(completionCachePath())
+	if err != nil {
+		return cache, false, err
+	}
+
+	b, err := os.ReadFile(completionCachePath())
+	if err != nil {
+		return cache, false, err
+	}
+
+	if err := json.Unmarshal(b, &cache); err != nil {
+		return cache, false, err
+	}
+
+	var stale = false
+	if repository, err := os.Stat(Configuration.RepositoryFullName()); err == nil {
+		stale = repository.ModTime().After(info.ModTime())
+	}
+
+	return cache, stale, nil
+}
+
+// refreshCompletionCache rebuilds the cache from the history, read alongside the other readers
+func refreshCompletionCache() (completionCache, error) {
+	var cache = completionCache{Lines: [][]string{}, Aliases: map[string]string{}, Recent: []completionEntry{}, Stored: []completionEntry{}}
+
+	if err := Repository.InitReadOnlyDB(); err != nil {
+		return cache, err
+	}
+
+	defer Repository.CloseDB()
+
+	history, err := Repository.GetAllCommands()
+	if err != nil {
+		return cache, err
+	}
+
+	sort.Slice(history, func(i, j int) bool { return history[i].CreatedAt.After(history[j].CreatedAt) })
+	if len(history) > completionCacheSize {
+		history = history[:completionCacheSize]
+	}
+
+	for _, c := range history {
+		cache.Lines = append(cache.Lines, append([]string{c.Name}, c.Arguments...))
+	}
+
+	for _, c := range uniqueCommandLines(history) {
+		if len(cache.Recent) == completionCacheRecent {
+			break
+		}
+		cache.Recent = append(cache.Recent, completionEntry{ID: c.ID, Line: commandLine(c)})
+	}
+
+	stored, err := Repository.GetAllStoredCommands()
+	if err != nil {
+		return cache, err
+	}
+	for _, c := range stored {
+		cache.Stored = append(cache.Stored, completionEntry{ID: c.ID, Line: commandLine(c)})
+	}
+
+	if cache.Aliases, err = Repository.GetAllAliases(); err != nil {
+		return cache, err
+	}
+
+	b, err := json.Marshal(cache)
+	if err != nil {
+		return cache, err
+	}
+
+	// written aside and renamed, so a completion never reads a partial file
+	var temporary = completionCachePath() + ".tmp"
+	if err := os.WriteFile(temporary, b, 0600); err != nil {
+		return cache, err
+	}
+
+	return cache, os.Rename(temporary, completionCachePath())
+}
+
+// cachedCompletion returns the cache when available, refreshed in the background when the
+// repository changed since it was written
+func cachedCompletion() (completionCache, error) {
+	// a cache written before the ids were kept is rebuilt
+	cache, stale, err := loadCompletionCache()
+	if err != nil || cache.Stored == nil {
+		return refreshCompletionCache()
+	}
+
+	if stale {
+		if executable, err := os.Executable(); err == nil {
+			exec.Command(executable, completionRefreshCmd.Use).Start()
+		}
+	}
+
+	return cache, nil
+}
+
+// completionHistory returns the history used by completion
+func completionHistory() ([]models.Command, error) {
+	cache, err := cachedCompletion()
+	if err != nil {
+		return nil, err
+	}
+
+	var history = make([]models.Command, 0, len(cache.Lines))
+	for _, line := range cache.Lines {
+		if len(line) > 0 {
+			history = append(history, models.Command{Name: line[0], Arguments: line[1:]})
+		}
+	}
+
+	return history, nil
+}
+
+// completionRefreshCmd rebuilds the completion cache, started in the background by completion
+var completionRefreshCmd = &cobra.Command{
+	Use:    "__refresh-completion-cache",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		refreshCompletionCache()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(completionRefreshCmd)
+}
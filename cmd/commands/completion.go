@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// maximum number of candidates returned to the shell
+const completionLimit = 20
+
+// historySuggestion is a completion candidate learned from the history
+type historySuggestion struct {
+	Token   string
+	Example string
+	Count   int
+}
+
+// historySuggestions ranks the tokens following the typed command line in the history:
+// command names when nothing is typed yet, then arguments, describing flags with their usual value
+func historySuggestions(history []models.Command, typed []string, toComplete string) []historySuggestion {
+	var counts = map[string]*historySuggestion{}
+	var values = map[string]map[string]int{}
+
+	for _, c := range history {
+		var words = append([]string{c.Name}, c.Arguments...)
+		if len(words) <= len(typed) || !sameWords(words[:len(typed)], typed) {
+			continue
+		}
+
+		var token = words[len(typed)]
+		if !strings.HasPrefix(token, toComplete) {
+			continue
+		}
+
+		if counts[token] == nil {
+			counts[token] = &historySuggestion{Token: token}
+			values[token] = map[string]int{}
+		}
+		counts[token].Count++
+
+		// remember the value usually given to a flag
+		if strings.HasPrefix(token, "-") && len(words) > len(typed)+1 && !strings.HasPrefix(words[len(typed)+1], "-") {
+			values[token][words[len(typed)+1]]++
+		}
+	}
+
+	var suggestions = []historySuggestion{}
+	for token, s := range counts {
+		var best = 0
+		for value, n := range values[token] {
+			if n > best {
+				best = n
+				s.Example = token + " " + value
+			}
+		}
+		suggestions = append(suggestions, *s)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Count != suggestions[j].Count {
+			return suggestions[i].Count > suggestions[j].Count
+		}
+		return suggestions[i].Token < suggestions[j].Token
+	})
+
+	if len(suggestions) > completionLimit {
+		suggestions = suggestions[:completionLimit]
+	}
+
+	return suggestions
+}
+
+func sameWords(a []string, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// completeFromHistory is the cobra completion function offering arguments seen in the history
+func completeFromHistory(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// completion must not print anything else, nor wait for the repository
+	history, err := completionHistory()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	var candidates = []string{}
+	for _, s := range historySuggestions(history, args, toComplete) {
+		var description = strconv.Itoa(s.Count) + " times"
+		if s.Example != "" {
+			description = "e.g. " + s.Example + ", " + description
+		}
+		candidates = append(candidates, s.Token+"\t"+description)
+	}
+
+	return candidates, cobra.ShellCompDirectiveDefault
+}
+
+// completeEntries returns the commands whose id starts with what is typed and the aliases naming
+// any command, each described by its command line
+func completeEntries(cache completionCache, entries []completionEntry, toComplete string) []string {
+	var lines = map[string]string{}
+	for _, entry := range append(append([]completionEntry{}, cache.Recent...), cache.Stored...) {
+		lines[entry.ID] = entry.Line
+	}
+
+	var aliases = []string{}
+	for alias, id := range cache.Aliases {
+		if strings.HasPrefix(alias, toComplete) {
+			var description = id
+			if line, found := lines[id]; found {
+				description = line
+			}
+			aliases = append(aliases, alias+"\t"+description)
+		}
+	}
+	sort.Strings(aliases)
+
+	var candidates = aliases
+	for _, entry := range entries {
+		if len(candidates) >= completionLimit {
+			break
+		}
+		if strings.HasPrefix(entry.ID, toComplete) {
+			candidates = append(candidates, entry.ID+"\t"+entry.Line)
+		}
+	}
+
+	return candidates
+}
+
+// completeExecutions is the cobra completion function offering the ids of the latest executions, the
+// latest first, and the aliases for the first arguments, up to the given count
+func completeExecutions(count int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		cache, err := cachedCompletion()
+		if err != nil || len(args) >= count {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeEntries(cache, cache.Recent, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeStored is the cobra completion function offering the ids of the stored commands and the aliases
+func completeStored(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cache, err := cachedCompletion()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeEntries(cache, cache.Stored, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	rerunCmd.ValidArgsFunction = completeExecutions(1)
+	diffCmd.ValidArgsFunction = completeExecutions(2)
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+func TestCompleteEntries(t *testing.T) {
+	var cache = completionCache{
+		Aliases: map[string]string{"deploy": "s1", "build": "h2", "gone": "xx"},
+		Recent:  []completionEntry{{ID: "h1", Line: "ls -la"}, {ID: "h2", Line: "make build"}},
+		Stored:  []completionEntry{{ID: "s1", Line: "kubectl apply -f {1}"}},
+	}
+
+	tests := []struct {
+		entries    []completionEntry
+		toComplete string
+		candidates []string
+	}{
+		{cache.Recent, "", []string{"build\tmake build", "deploy\tkubectl apply -f {1}", "gone\txx", "h1\tls -la", "h2\tmake build"}},
+		{cache.Recent, "h2", []string{"h2\tmake build"}},
+		{cache.Stored, "s", []string{"s1\tkubectl apply -f {1}"}},
+		{cache.Stored, "de", []string{"deploy\tkubectl apply -f {1}"}},
+	}
+
+	for _, test := range tests {
+		if candidates := completeEntries(cache, test.entries, test.toComplete); !reflect.DeepEqual(candidates, test.candidates) {
+			t.Errorf("completeEntries(%q) = %q, want %q", test.toComplete, candidates, test.candidates)
+		}
+	}
+}
+
+func TestRefreshCompletionCacheIncognito(t *testing.T) {
+	useScriptedExecutor(t, scriptedRule{Pattern: "^make", Stdout: "built"})
+
+	var executed = initializeCommand("make", []string{"build"})
+	executeCommand(&executed)
+	finalizeCommand(&executed)
+	Repository.CloseDB()
+
+	// incognito writes nothing, the history is read all the same
+	t.Setenv(utils.ConstIncognitoEnv, "1")
+
+	cache, err := refreshCompletionCache()
+	if err != nil || len(cache.Recent) != 1 || cache.Recent[0].ID != executed.ID {
+		t.Errorf("refreshCompletionCache() = %+v, %v, want make build", cache.Recent, err)
+	}
+
+	if err := Repository.InitDB(); err != nil {
+		t.Fatalf("InitDB returned unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+const demoPrefix = "DEMO-"
+
+// demoSample describes a realistic command used to seed the demo history
+type demoSample struct {
+	line     string
+	failRate float32
+	duration time.Duration
+	output   string
+	error    string
+}
+
+var demoSamples = []demoSample{
+	{"git status", 0.01, 80 * time.Millisecond, "On branch main\nnothing to commit, working tree clean", "fatal: not a git repository"},
+	{"git pull --rebase", 0.08, 2 * time.Second, "Already up to date.", "error: cannot pull with rebase: You have unstaged changes."},
+	{"git push origin main", 0.1, 3 * time.Second, "To github.com:acme/app.git\n   3f2a1c4..9b8e7d6  main -> main", "! [rejected]        main -> main (fetch first)"},
+	{"go build ./...", 0.12, 8 * time.Second, "", "./main.go:12:2: undefined: handler"},
+	{"go test ./...", 0.2, 25 * time.Second, "ok  \tgithub.com/acme/app\t1.204s", "--- FAIL: TestHandler (0.00s)\nFAIL"},
+	{"npm install", 0.05, 40 * time.Second, "added 812 packages in 38s", "npm ERR! code ERESOLVE"},
+	{"npm run build", 0.1, 30 * time.Second, "webpack compiled successfully", "npm ERR! Failed at the build script."},
+	{"docker ps", 0.02, 300 * time.Millisecond, "CONTAINER ID   IMAGE   COMMAND   STATUS", "Cannot connect to the Docker daemon"},
+	{"docker build -t acme/app .", 0.15, 90 * time.Second, "Successfully tagged acme/app:latest", "failed to solve: process \"/bin/sh -c make\" did not complete successfully"},
+	{"kubectl get pods -n prod", 0.05, time.Second, "NAME        READY   STATUS    RESTARTS\napi-7d9f    1/1     Running   0", "error: You must be logged in to the server (Unauthorized)"},
+	{"kubectl apply -f deploy.yaml", 0.1, 4 * time.Second, "deployment.apps/api configured", "error: error validating \"deploy.yaml\""},
+	{"make build", 0.1, 12 * time.Second, "go build -o bin/app ./cmd", "make: *** [build] Error 1"},
+	{"ls -la", 0, 20 * time.Millisecond, "total 48\ndrwxr-xr-x  12 user  staff  384 .", ""},
+	{"ping -c 3 example.com", 0.05, 3 * time.Second, "3 packets transmitted, 3 received, 0% packet loss", "ping: cannot resolve example.com: Unknown host"},
+	{"terraform plan", 0.15, 20 * time.Second, "Plan: 1 to add, 0 to change, 0 to destroy.", "Error: No valid credential sources found"},
+}
+
+// demoCommand generates a realistic executed command within the last given days
+func demoCommand(rng *rand.Rand, days int) models.Command {
+	var sample = demoSamples[rng.Intn(len(demoSamples))]
+	var parts = strings.Fields(sample.line)
+
+	// spread the executions over the period, mostly during working hours
+	var day = time.Now().AddDate(0, 0, -rng.Intn(days+1)).Truncate(24 * time.Hour)
+	var createdAt = day.Add(time.Duration(8+rng.Intn(11))*time.Hour + time.Duration(rng.Intn(3600))*time.Second)
+	if createdAt.After(time.Now()) {
+		createdAt = time.Now().Add(-time.Duration(rng.Intn(3600)) * time.Second)
+	}
+
+	// durations vary around the typical value of the sample
+	var duration = time.Duration(float64(sample.duration) * (0.5 + rng.Float64()))
+
+	var command = models.Command{
+		Entity: models.Entity{
+			ID:           demoPrefix + Utilities.Random(),
+			CreatedAt:    createdAt,
+			TerminatedAt: createdAt.Add(duration),
+		},
+		Name:      parts[0],
+		Arguments: parts[1:],
+		Status:    rng.Float32() >= sample.failRate,
+	}
+
+	if command.Status {
+		command.Output = sample.output + "\n"
+	} else {
+		command.Error = sample.error + "\n"
+	}
+
+	return command
+}
+
+// demoCmd represents the demo command
+var demoCmd = &cobra.Command{
+	Use:   "demo",
+	Short: "Demo",
+	Long:  `Demo command, manages synthetic sample history`,
+}
+
+// demoSeedCmd represents the demo seed command
+var demoSeedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Seed",
+	Long:  `Seed command, populates the repository with realistic synthetic history`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Demo seed command invoked")
+
+			count, err1 := cmd.Flags().GetInt("commands")
+			days, err2 := cmd.Flags().GetInt("days")
+			if err1 != nil || err2 != nil || count <= 0 || days < 0 {
+				Parrot.Println("Please provide a positive number of commands and days")
+				return
+			}
+
+			var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+			for i := 0; i < count; i++ {
+				if err := Repository.Put(demoCommand(rng, days)); err != nil {
+					Parrot.Println("Error storing the demo command", err)
+					return
+				}
+			}
+
+			Parrot.Println(strconv.Itoa(count) + " demo commands created, remove them with 'ambros demo clear'")
+		})
+	},
+}
+
+// demoClearCmd represents the demo clear command
+var demoClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear",
+	Long:  `Clear command, removes the synthetic history created by demo seed`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Demo clear command invoked")
+
+			var commands, err = Repository.GetAllCommands()
+			if err != nil {
+				Parrot.Println("Error retrieving commands in the store", err)
+				return
+			}
+
+			var count = 0
+			for _, c := range commands {
+				if !strings.HasPrefix(c.ID, demoPrefix) {
+					continue
+				}
+
+				if err := Repository.DeleteCommand(c.ID); err != nil {
+					Parrot.Println("Error deleting command ("+c.ID+")", err)
+					return
+				}
+				count++
+			}
+
+			Parrot.Println(strconv.Itoa(count) + " demo commands removed")
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(demoCmd)
+	demoCmd.AddCommand(demoSeedCmd)
+	demoCmd.AddCommand(demoClearCmd)
+
+	demoSeedCmd.Flags().Int("commands", 500, "number of commands to generate")
+	demoSeedCmd.Flags().Int("days", 30, "number of days the history spans")
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ttacon/chalk"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// diffField is a detail of the two executions compared
+type diffField struct {
+	Name    string `json:"Name"`
+	From    string `json:"From"`
+	To      string `json:"To"`
+	Changed bool   `json:"Changed"`
+}
+
+// commandDiff compares two executions: their details and the unified diff of their outputs
+type commandDiff struct {
+	From   string      `json:"From"`
+	To     string      `json:"To"`
+	Fields []diffField `json:"Fields"`
+	Output []string    `json:"Output"`
+}
+
+// outputLines returns the lines of the whole output of a command, streamed or not, and of its errors
+func outputLines(command models.Command) []string {
+	command = inlineOutput(command)
+
+	var output = strings.TrimRight(command.Output+command.Error, "\n")
+	if output == "" {
+		return []string{}
+	}
+	return strings.Split(output, "\n")
+}
+
+// diffCommands compares two executions, with the given lines of context around the changes of the outputs
+func diffCommands(from models.Command, to models.Command, context int) commandDiff {
+	var details = func(c models.Command) []string {
+		var git = ""
+		if c.Git != nil {
+			git = c.Git.String()
+		}
+		return []string{
+			c.Name + " " + strings.Join(c.Arguments, " "),
+			strconv.FormatBool(c.Status),
+			strconv.Itoa(c.Code()),
+			c.CreatedAt.Format("02.01.2006 15:04:05"),
+			c.TerminatedAt.Sub(c.CreatedAt).Round(time.Millisecond).String(),
+			c.Environment,
+			git,
+			c.Origin,
+		}
+	}
+
+	var names = []string{"Command", "Status", "Exit code", "Started", "Duration", "Environment", "Git", "Origin"}
+	var fromDetails, toDetails = details(from), details(to)
+
+	var diff = commandDiff{From: from.ID, To: to.ID, Fields: []diffField{}}
+	for i, name := range names {
+		// the start always differs, it is not a change
+		diff.Fields = append(diff.Fields, diffField{Name: name, From: fromDetails[i], To: toDetails[i],
+			Changed: name != "Started" && fromDetails[i] != toDetails[i]})
+	}
+
+	diff.Output = utils.UnifiedDiff(outputLines(from), outputLines(to), context)
+	return diff
+}
+
+// printDiff prints the details of the executions, the changed ones marked, and the diff of their outputs
+func printDiff(diff commandDiff, color bool) {
+	var rows = [][]string{}
+	for _, field := range diff.Fields {
+		var mark = ""
+		if field.Changed {
+			mark = "*"
+		}
+		// rows are printed as format strings by Tablify
+		rows = append(rows, []string{mark + field.Name, strings.ReplaceAll(field.From, "%", "%%"), strings.ReplaceAll(field.To, "%", "%%")})
+	}
+	Parrot.Tablify([]string{"FIELD", diff.From, diff.To}, rows)
+
+	if len(diff.Output) == 0 {
+		Parrot.Println("The outputs are the same")
+		return
+	}
+
+	var colors = map[byte]chalk.Color{'-': chalk.Red, '+': chalk.Green, '@': chalk.Cyan}
+
+	Parrot.Println("--- " + diff.From)
+	Parrot.Println("+++ " + diff.To)
+	for _, line := range diff.Output {
+		if c, ok := colors[line[0]]; ok && color {
+			line = c.Color(line)
+		}
+		Parrot.Println(line)
+	}
+}
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <id1> <id2>",
+	Short: "Diff",
+	Long: `Diff command, compares two executions: their command line, status, exit code, duration, environment and git state,
+the changed ones marked with *, and their outputs as a unified diff, e.g. to see why a command working
+yesterday fails today`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Diff command invoked")
+
+			if len(args) != 2 {
+				Parrot.Println("Please provide the ids of the two executions to compare")
+				return
+			}
+
+			var commands = []models.Command{}
+			for _, id := range args {
+				command, err := Repository.FindById(resolveID(id))
+				if err != nil {
+					Parrot.Println("Error retrieving command in the store ("+id+")", err)
+					return
+				}
+				commands = append(commands, command)
+			}
+
+			context, _ := cmd.Flags().GetInt("context")
+			if context < 0 {
+				Parrot.Println("Please provide a context of 0 lines or more")
+				return
+			}
+
+			printDiff(diffCommands(commands[0], commands[1], context), !cmd.Flag("no-color").Changed)
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().Int("context", 3, "how many unchanged lines are shown around the changes of the outputs")
+	diffCmd.Flags().Bool("no-color", false, "prints the diff of the outputs without colors")
+}
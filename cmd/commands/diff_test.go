@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffCommands(t *testing.T) {
+	useScriptedExecutor(t,
+		scriptedRule{Pattern: "^deploy v1", Stdout: "build\nupload\ndone"},
+		scriptedRule{Pattern: "^deploy v2", Stdout: "build\nupload failed", ExitCode: 2})
+
+	var from = initializeCommand("deploy", []string{"v1"})
+	executeCommand(&from)
+	finalizeCommand(&from)
+
+	var to = initializeCommand("deploy", []string{"v2"})
+	executeCommand(&to)
+	finalizeCommand(&to)
+
+	var diff = diffCommands(from, to, 1)
+
+	// the durations may differ as well
+	var changed = map[string]bool{}
+	for _, field := range diff.Fields {
+		changed[field.Name] = field.Changed
+	}
+	if !changed["Command"] || !changed["Status"] || !changed["Exit code"] || changed["Started"] || changed["Environment"] {
+		t.Errorf("diffCommands() changed fields = %v, want the command, status and exit code", changed)
+	}
+
+	// the error of the execution follows its output
+	var output = []string{"@@ -1,3 +1,3 @@", " build", "-upload", "-done", "+upload failed", "+exit status 2"}
+	if !reflect.DeepEqual(diff.Output, output) {
+		t.Errorf("diffCommands() output = %q, want %q", diff.Output, output)
+	}
+
+	// queryd answers the same diff
+	Repository.CloseDB()
+	if response := answerQuery(queryRequest{Op: "diff", ID: from.ID, With: to.ID}); response.Diff == nil ||
+		!reflect.DeepEqual(response.Diff.Output, output) {
+		t.Errorf("answerQuery(diff) = %+v, want the diff of the outputs", response)
+	}
+	Repository.InitDB()
+}
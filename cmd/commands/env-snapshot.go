@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// variable holding the passphrase which encrypts the secrets of a snapshot
+const snapshotPassphraseVariable = "AMBROS_PASSPHRASE"
+
+func snapshotPassphrase() (string, error) {
+	passphrase := os.Getenv(snapshotPassphraseVariable)
+	if passphrase == "" {
+		return "", errors.New("please set " + snapshotPassphraseVariable + " to the passphrase of the secrets")
+	}
+	return passphrase, nil
+}
+
+// how long downloading a snapshot may take
+const snapshotDownloadTimeout = 30 * time.Second
+
+// readSnapshot reads a snapshot written by env snapshot, chain export or store --export,
+// from a file or an http(s) URL
+func readSnapshot(location string) (models.Snapshot, error) {
+	var snapshot = models.Snapshot{}
+
+	var b []byte
+	var err error
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		b, err = downloadSnapshot(location)
+	} else {
+		b, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return snapshot, err
+	}
+
+	err = json.Unmarshal(b, &snapshot)
+	return snapshot, err
+}
+
+func downloadSnapshot(url string) ([]byte, error) {
+	client := http.Client{Timeout: snapshotDownloadTimeout}
+
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(url + " answered " + response.Status)
+	}
+
+	return io.ReadAll(response.Body)
+}
+
+// rekeySecrets decrypts the secrets of the environment with a key and encrypts them with another
+func rekeySecrets(environment *models.Environment, from []byte, to []byte) error {
+	for _, secret := range environment.Secrets {
+		value, ok := environment.Variables[secret]
+		if !ok {
+			continue
+		}
+
+		plain, err := Utilities.DecryptSecret(from, value)
+		if err != nil {
+			return errors.New(secret + ": " + err.Error())
+		}
+
+		if environment.Variables[secret], err = Utilities.EncryptSecret(to, plain); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// envSnapshotCmd represents the env snapshot command
+var envSnapshotCmd = &cobra.Command{
+	Use:   "snapshot <name>",
+	Short: "Snapshot",
+	Long: `Snapshot command, writes an environment to a json file to restore it on another machine;
+the secrets are left out unless --with-secrets encrypts them with the passphrase in ` + snapshotPassphraseVariable,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Env snapshot command invoked")
+
+			if len(args) != 1 {
+				Parrot.Println("Please provide an environment name")
+				return
+			}
+
+			environment, err := Repository.GetEnvironment(args[0])
+			if err != nil {
+				Parrot.Println("Environment not available ("+args[0]+")", err)
+				return
+			}
+
+			var snapshot = models.Snapshot{Kind: "environment", CreatedAt: time.Now(), Environment: &environment}
+
+			if len(environment.Secrets) > 0 {
+				if cmd.Flag("with-secrets").Changed {
+					passphrase, err := snapshotPassphrase()
+					if err != nil {
+						Parrot.Println(err.Error())
+						return
+					}
+
+					local, err := secretKey()
+					if err != nil {
+						Parrot.Println("Error reading the secrets key", err)
+						return
+					}
+
+					salt, err := Utilities.NewSecretKey()
+					if err != nil {
+						Parrot.Println("Error encrypting the secrets", err)
+						return
+					}
+
+					if err := rekeySecrets(&environment, local, Utilities.PassphraseKey(passphrase, salt)); err != nil {
+						Parrot.Println("Error encrypting the secrets", err)
+						return
+					}
+					snapshot.Salt = hex.EncodeToString(salt)
+				} else {
+					// the names are kept, so the restore tells which secrets to set again
+					for _, secret := range environment.Secrets {
+						delete(environment.Variables, secret)
+					}
+				}
+			}
+
+			buf := new(bytes.Buffer)
+			json.Indent(buf, []byte(Utilities.AsJson(snapshot)), "", "  ")
+
+			var out = cmd.Flag("out").Value.String()
+			if out == "" {
+				os.Stdout.Write(append(buf.Bytes(), '\n'))
+				return
+			}
+
+			if err := os.WriteFile(out, buf.Bytes(), 0600); err != nil {
+				Parrot.Println("Impossible to create the required file (" + out + ")")
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// envRestoreCmd represents the env restore command
+var envRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore",
+	Long:  `Restore command, stores the environment written by env snapshot`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Env restore command invoked")
+
+			if len(args) != 1 {
+				Parrot.Println("Please provide a snapshot file")
+				return
+			}
+
+			snapshot, err := readSnapshot(args[0])
+			if err != nil || snapshot.Environment == nil {
+				Parrot.Println("Please provide a valid environment snapshot ("+args[0]+")", err)
+				return
+			}
+
+			var environment = *snapshot.Environment
+			if environment.Variables == nil {
+				environment.Variables = map[string]string{}
+			}
+
+			if _, err := Repository.GetEnvironment(environment.Name); err == nil && !cmd.Flag("force").Changed {
+				Parrot.Println("Environment " + environment.Name + " already exists, use --force to replace it")
+				return
+			}
+
+			var missing = []string{}
+			for _, secret := range environment.Secrets {
+				if _, ok := environment.Variables[secret]; !ok {
+					missing = append(missing, secret)
+				}
+			}
+
+			if snapshot.Salt != "" && len(missing) < len(environment.Secrets) {
+				passphrase, err := snapshotPassphrase()
+				if err != nil {
+					Parrot.Println(err.Error())
+					return
+				}
+
+				salt, err := hex.DecodeString(snapshot.Salt)
+				if err != nil {
+					Parrot.Println("Please provide a valid environment snapshot ("+args[0]+")", err)
+					return
+				}
+
+				local, err := secretKey()
+				if err != nil {
+					Parrot.Println("Error reading the secrets key", err)
+					return
+				}
+
+				if err := rekeySecrets(&environment, Utilities.PassphraseKey(passphrase, salt), local); err != nil {
+					Parrot.Println("Error decrypting the secrets, is the passphrase right?", err)
+					return
+				}
+			}
+
+			if err := Repository.PutEnvironment(environment); err != nil {
+				Parrot.Println("Error storing the environment", err)
+				return
+			}
+
+			for _, parent := range environment.Inherits {
+				if _, err := Repository.GetEnvironment(parent); err != nil {
+					Parrot.Println("Environment " + environment.Name + " inherits from " + parent + ", which is not available")
+				}
+			}
+
+			if len(missing) > 0 {
+				Parrot.Println("Secrets not in the snapshot, set them with 'ambros env set --secret " + environment.Name + "': " + strings.Join(missing, ", "))
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+func init() {
+	envCmd.AddCommand(envSnapshotCmd)
+	envCmd.AddCommand(envRestoreCmd)
+
+	envSnapshotCmd.Flags().String("out", "", "the file written (default standard output)")
+	envSnapshotCmd.Flags().Bool("with-secrets", false, "includes the secrets, encrypted with the passphrase in "+snapshotPassphraseVariable)
+	envRestoreCmd.Flags().Bool("force", false, "replaces an environment with the same name")
+}
@@ -0,0 +1,521 @@
+package commands
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+var variableNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// environmentOrNew returns the stored environment or a new empty one with the given name
+func environmentOrNew(name string) models.Environment {
+	if environment, err := Repository.GetEnvironment(name); err == nil {
+		return environment
+	}
+
+	var environment = models.Environment{Name: name, Variables: map[string]string{}}
+	environment.ID = Utilities.Random()
+	environment.CreatedAt = time.Now()
+	return environment
+}
+
+// environmentLayers returns the layers applied by an environment, the variables of the process first
+func environmentLayers(name string) ([]models.EnvironmentLayer, error) {
+	var process = map[string]string{}
+	for _, variable := range os.Environ() {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) == 2 {
+			process[parts[0]] = parts[1]
+		}
+	}
+
+	layers, err := models.Layers(name, Repository.GetEnvironment)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]models.EnvironmentLayer{{Source: "os", Variables: process}}, layers...), nil
+}
+
+// secretKey returns the key encrypting the secret variables, created with the repository on first use
+func secretKey() ([]byte, error) {
+	var file = filepath.Join(Configuration.RepositoryDirectory, "secrets.key")
+
+	if encoded, err := os.ReadFile(file); err == nil {
+		return hex.DecodeString(strings.TrimSpace(string(encoded)))
+	}
+
+	key, err := Utilities.NewSecretKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(file, []byte(hex.EncodeToString(key)), 0600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// resolveEnvironment returns the environment with the variables inherited merged in and the secrets
+// decrypted, along with the secret values to keep out of the repository
+func resolveEnvironment(name string) (models.Environment, []string, error) {
+	layers, err := models.Layers(name, Repository.GetEnvironment)
+	if err != nil {
+		return models.Environment{}, nil, err
+	}
+
+	var secrets = []string{}
+	var key []byte
+
+	for i, layer := range layers {
+		if len(layer.Secrets) == 0 {
+			continue
+		}
+
+		if key == nil {
+			if key, err = secretKey(); err != nil {
+				return models.Environment{}, nil, err
+			}
+		}
+
+		var variables = map[string]string{}
+		for k, v := range layer.Variables {
+			variables[k] = v
+		}
+
+		for _, secret := range layer.Secrets {
+			value, ok := variables[secret]
+			if !ok {
+				continue
+			}
+
+			if variables[secret], err = Utilities.DecryptSecret(key, value); err != nil {
+				return models.Environment{}, nil, errors.New(layer.Source + "." + secret + ": " + err.Error())
+			}
+			secrets = append(secrets, variables[secret])
+		}
+
+		layers[i].Variables = variables
+	}
+
+	return models.Environment{Name: name, Variables: models.Merge(layers)}, secrets, nil
+}
+
+// applyEnvironment makes the following executions run with the variables of the environment
+func applyEnvironment(name string) error {
+	environment, secrets, err := resolveEnvironment(name)
+	if err != nil {
+		return err
+	}
+
+	commandExecutor = commandExecutor.with(environment.Environ())
+	environmentVariables = append(environmentVariables, environment.Environ()...)
+	environmentSecrets = append(environmentSecrets, secrets...)
+	return nil
+}
+
+// projectEnvironment returns the environment declared by the closest .ambros.yaml
+// in the current directory or its parents, empty when there is none
+func projectEnvironment() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	for {
+		var file = filepath.Join(dir, ".ambros.yaml")
+		if _, err := os.Stat(file); err == nil {
+			project := viper.New()
+			project.SetConfigFile(file)
+			if err := project.ReadInConfig(); err == nil && project.GetString("environment") != "" {
+				return project.GetString("environment")
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// chooseEnvironment picks the environment of an execution: the --env flag first,
+// then the one remembered with the command, then the one of the project, unless --no-env is given
+func chooseEnvironment(cmd *cobra.Command, remembered string) string {
+	if value := cmd.Flag("env").Value.String(); value != "" {
+		return value
+	}
+
+	if cmd.Flag("no-env").Changed {
+		return ""
+	}
+
+	if remembered != "" {
+		return remembered
+	}
+
+	return projectEnvironment()
+}
+
+// maskedValue hides the value of the secret variables
+func maskedValue(value string, secret bool) string {
+	if secret {
+		return "********"
+	}
+
+	// rows are printed as format strings by Tablify
+	return strings.ReplaceAll(value, "%", "%%")
+}
+
+func withoutSecret(secrets []string, key string) []string {
+	var result = []string{}
+	for _, s := range secrets {
+		if s != key {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// envCmd represents the env command
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Env",
+	Long:  `Env command, manages named sets of variables injected in the commands run with --env`,
+}
+
+// envSetCmd represents the env set command
+var envSetCmd = &cobra.Command{
+	Use:   "set [--secret] <name> <key> <value>",
+	Short: "Set",
+	Long:  `Set command, sets a variable of an environment, creating the environment when needed`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Env set command invoked")
+
+			if len(args) != 3 {
+				Parrot.Println("Please provide an environment name, a variable name and its value")
+				return
+			}
+
+			if !variableNameRegexp.MatchString(args[1]) {
+				Parrot.Println("Please provide a valid variable name (" + args[1] + ")")
+				return
+			}
+
+			var environment = environmentOrNew(args[0])
+			environment.Variables[args[1]] = args[2]
+			environment.Secrets = withoutSecret(environment.Secrets, args[1])
+
+			if cmd.Flag("secret").Changed {
+				key, err := secretKey()
+				if err != nil {
+					Parrot.Println("Error reading the secrets key", err)
+					return
+				}
+
+				if environment.Variables[args[1]], err = Utilities.EncryptSecret(key, args[2]); err != nil {
+					Parrot.Println("Error encrypting the value", err)
+					return
+				}
+				environment.Secrets = append(environment.Secrets, args[1])
+			}
+
+			if err := Repository.PutEnvironment(environment); err != nil {
+				Parrot.Println("Error storing the environment", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// envUnsetCmd represents the env unset command
+var envUnsetCmd = &cobra.Command{
+	Use:   "unset <name> <key>",
+	Short: "Unset",
+	Long:  `Unset command, removes a variable from an environment`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Env unset command invoked")
+
+			if len(args) != 2 {
+				Parrot.Println("Please provide an environment name and a variable name")
+				return
+			}
+
+			environment, err := Repository.GetEnvironment(args[0])
+			if err != nil {
+				Parrot.Println("Environment not available (" + args[0] + ")")
+				return
+			}
+
+			if _, ok := environment.Variables[args[1]]; !ok {
+				Parrot.Println("Variable not set in the environment (" + args[1] + ")")
+				return
+			}
+
+			delete(environment.Variables, args[1])
+			environment.Secrets = withoutSecret(environment.Secrets, args[1])
+
+			if err := Repository.PutEnvironment(environment); err != nil {
+				Parrot.Println("Error storing the environment", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// envShowCmd represents the env show command
+var envShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show",
+	Long:  `Show command, shows the variables of an environment`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Env show command invoked")
+
+			name, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid environment name")
+				return
+			}
+
+			environment, err := Repository.GetEnvironment(name)
+			if err != nil {
+				Parrot.Println("Environment not available (" + name + ")")
+				return
+			}
+
+			var names = []string{}
+			for key := range environment.Variables {
+				names = append(names, key)
+			}
+			sort.Strings(names)
+
+			var body = [][]string{}
+			for _, key := range names {
+				body = append(body, []string{key, maskedValue(environment.Variables[key], environment.IsSecret(key))})
+			}
+
+			Parrot.Tablify([]string{"VARIABLE", "VALUE"}, body)
+		})
+	},
+}
+
+// envListCmd represents the env list command
+var envListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List",
+	Long:  `List command, shows all the environments`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Env list command invoked")
+
+			environments, err := Repository.ListEnvironments()
+			if err != nil {
+				Parrot.Println("Error retrieving environments", err)
+				return
+			}
+
+			if len(environments) == 0 {
+				Parrot.Println("No environments available!")
+				return
+			}
+
+			var body = [][]string{}
+			for _, e := range environments {
+				body = append(body, []string{e.Name, strconv.Itoa(len(e.Variables)), e.CreatedAt.Format("02.01.2006 15:04:05")})
+			}
+
+			Parrot.Tablify([]string{"NAME", "VARIABLES", "CREATED"}, body)
+		})
+	},
+}
+
+// envDeleteCmd represents the env delete command
+var envDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete",
+	Long:  `Delete command, deletes an environment`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Env delete command invoked")
+
+			name, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid environment name")
+				return
+			}
+
+			if err := Repository.DeleteEnvironment(name); err != nil {
+				Parrot.Println("Environment not available (" + name + ")")
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// envInheritCmd represents the env inherit command
+var envInheritCmd = &cobra.Command{
+	Use:   "inherit <name> [parent]...",
+	Short: "Inherit",
+	Long:  `Inherit command, sets the environments whose variables are applied before the ones of the environment, no parent clears them`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Env inherit command invoked")
+
+			name, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid environment name")
+				return
+			}
+
+			environment, err := Repository.GetEnvironment(name)
+			if err != nil {
+				Parrot.Println("Environment not available (" + name + ")")
+				return
+			}
+
+			environment.Inherits = Utilities.Tail(args)
+
+			// the inheritance is checked before storing it, with the other environments as stored, so
+			// cycles through them are found too
+			var lookup = func(n string) (models.Environment, error) {
+				if n == name {
+					return environment, nil
+				}
+				return Repository.GetEnvironment(n)
+			}
+			if _, err := models.Layers(name, lookup); err != nil {
+				Parrot.Println("Invalid inheritance", err)
+				return
+			}
+
+			if err := Repository.PutEnvironment(environment); err != nil {
+				Parrot.Println("Error storing the environment", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// envUndoCmd represents the env undo command
+var envUndoCmd = &cobra.Command{
+	Use:   "undo <name>",
+	Short: "Undo",
+	Long: `Undo command, reverts the last change of an environment made by set, unset, inherit or delete;
+run again it reverts the previous ones, up to undoDepth changes (10 by default, 0 keeps them all)`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Env undo command invoked")
+
+			name, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid environment name")
+				return
+			}
+
+			version, err := Repository.UndoEnvironment(name)
+			if err != nil {
+				Parrot.Println("Error undoing the last change of the environment ("+name+")", err)
+				return
+			}
+
+			if version.Environment == nil {
+				Parrot.Println("Environment " + name + " deleted, it did not exist before")
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+// envExplainCmd represents the env explain command
+var envExplainCmd = &cobra.Command{
+	Use:   "explain <name> <key>",
+	Short: "Explain",
+	Long:  `Explain command, shows where the effective value of a variable comes from and which layers it overrides`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Env explain command invoked")
+
+			if len(args) != 2 {
+				Parrot.Println("Please provide an environment name and a variable name")
+				return
+			}
+
+			layers, err := environmentLayers(args[0])
+			if err != nil {
+				Parrot.Println("Error resolving the environment", err)
+				return
+			}
+
+			var key = args[1]
+			var body = [][]string{}
+			var effective = -1
+			for i, layer := range layers {
+				if _, ok := layer.Variables[key]; ok {
+					effective = i
+				}
+			}
+
+			if effective < 0 {
+				Parrot.Println(key + " is not set in " + args[0] + ", in the environments it inherits nor in the process")
+				return
+			}
+
+			for i, layer := range layers {
+				value, ok := layer.Variables[key]
+				if !ok {
+					continue
+				}
+
+				var state = "overridden"
+				if i == effective {
+					state = "effective"
+				}
+				var secret = models.Environment{Secrets: layer.Secrets}.IsSecret(key)
+				body = append(body, []string{strconv.Itoa(i), layer.Source, maskedValue(value, secret), state})
+			}
+
+			Parrot.Tablify([]string{"LAYER", "SOURCE", "VALUE", "STATE"}, body)
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(envCmd)
+	envCmd.AddCommand(envSetCmd)
+	envCmd.AddCommand(envUnsetCmd)
+	envCmd.AddCommand(envShowCmd)
+	envCmd.AddCommand(envListCmd)
+	envCmd.AddCommand(envDeleteCmd)
+	envCmd.AddCommand(envInheritCmd)
+	envCmd.AddCommand(envExplainCmd)
+	envCmd.AddCommand(envUndoCmd)
+
+	envSetCmd.Flags().Bool("secret", false, "stores the value encrypted, masks it and keeps it out of the recorded commands")
+}
@@ -0,0 +1,48 @@
+package commands
+
+import "testing"
+
+// runEnv runs an env subcommand, with the repository opened by the command
+func runEnv(t *testing.T, name string, args ...string) {
+	t.Helper()
+
+	var commands = map[string]func(){
+		"set":     func() { envSetCmd.Run(envSetCmd, args) },
+		"inherit": func() { envInheritCmd.Run(envInheritCmd, args) },
+		"undo":    func() { envUndoCmd.Run(envUndoCmd, args) },
+	}
+
+	var previous = invokedCommand
+	invokedCommand = "ambros env " + name
+	defer func() { invokedCommand = previous }()
+
+	Repository.CloseDB()
+	commands[name]()
+	if err := Repository.InitDB(); err != nil {
+		t.Fatalf("InitDB returned unexpected error: %v", err)
+	}
+}
+
+func TestEnvUndo(t *testing.T) {
+	useScriptedExecutor(t)
+
+	runEnv(t, "set", "base", "REGION", "eu")
+	runEnv(t, "set", "prod", "STAGE", "prod")
+	runEnv(t, "inherit", "prod", "base")
+
+	// an inheritance refused leaves nothing to undo behind
+	runEnv(t, "inherit", "base", "prod")
+	if base, err := Repository.GetEnvironment("base"); err != nil || len(base.Inherits) != 0 {
+		t.Fatalf("GetEnvironment(base) = %+v, %v, want the cycle refused", base, err)
+	}
+
+	runEnv(t, "undo", "prod")
+	if prod, err := Repository.GetEnvironment("prod"); err != nil || len(prod.Inherits) != 0 || prod.Variables["STAGE"] != "prod" {
+		t.Errorf("GetEnvironment(prod) after an undo = %+v, %v, want STAGE=prod inheriting nothing", prod, err)
+	}
+
+	runEnv(t, "undo", "base")
+	if _, err := Repository.GetEnvironment("base"); err == nil {
+		t.Errorf("GetEnvironment(base) after an undo returned no error, want it removed")
+	}
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// executor builds the process running a command and records where it ran
+type executor interface {
+	command(c *models.Command) *exec.Cmd
+	record(c *models.Command, err error)
+	// stop ends what the killed process of a command left running
+	stop(c *models.Command)
+	// in returns the same backend running the commands in the given directory
+	in(dir string) executor
+	// with returns the same backend adding the KEY=VALUE variables to the environment of the commands
+	with(environ []string) executor
+	// local tells if the commands run with the executables installed on this machine
+	local() bool
+}
+
+// commandExecutor is the backend used by the executions of the current invocation
+var commandExecutor executor = localExecutor{}
+
+// localExecutor runs the commands as processes of the current machine
+type localExecutor struct {
+	dir     string
+	environ []string
+}
+
+func (l localExecutor) command(c *models.Command) *exec.Cmd {
+	cmd := exec.Command(c.Name, c.Arguments...)
+	cmd.Dir = l.dir
+	if len(l.environ) > 0 {
+		cmd.Env = append(os.Environ(), l.environ...)
+	}
+	return cmd
+}
+
+func (localExecutor) record(c *models.Command, err error) {}
+
+func (localExecutor) stop(c *models.Command) {}
+
+func (l localExecutor) in(dir string) executor {
+	return localExecutor{dir: dir, environ: l.environ}
+}
+
+func (l localExecutor) with(environ []string) executor {
+	return localExecutor{dir: l.dir, environ: append(append([]string{}, l.environ...), environ...)}
+}
+
+func (localExecutor) local() bool {
+	return true
+}
+
+// dockerExecutor runs the commands in a throwaway container with the working directory mounted
+type dockerExecutor struct {
+	image   string
+	dir     string
+	environ []string
+}
+
+func newDockerExecutor(image string) (executor, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, errors.New("docker not found in PATH")
+	}
+
+	return dockerExecutor{image: image}, nil
+}
+
+func (d dockerExecutor) command(c *models.Command) *exec.Cmd {
+	var dir = d.dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	var arguments = []string{"run", "--rm", "-i", "--name", d.containerName(c), "-v", dir + ":" + dir, "-w", dir}
+
+	// values are passed through the environment of the docker client, never on its command line
+	for _, variable := range d.environ {
+		arguments = append(arguments, "-e", strings.SplitN(variable, "=", 2)[0])
+	}
+
+	cmd := exec.Command("docker", append(append(arguments, d.image, c.Name), c.Arguments...)...)
+	if len(d.environ) > 0 {
+		cmd.Env = append(os.Environ(), d.environ...)
+	}
+	return cmd
+}
+
+func (d dockerExecutor) record(c *models.Command, err error) {
+	var container = models.Container{Image: d.image, Name: d.containerName(c)}
+
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		container.ExitCode = exitError.ExitCode()
+	} else if err != nil {
+		container.ExitCode = -1
+	}
+
+	c.Container = &container
+}
+
+// stop kills the container, killing the docker client leaves it running
+func (d dockerExecutor) stop(c *models.Command) {
+	if err := exec.Command("docker", "kill", d.containerName(c)).Run(); err != nil {
+		Parrot.Debug("--> Unable to kill the container "+d.containerName(c), err)
+	}
+}
+
+func (d dockerExecutor) in(dir string) executor {
+	return dockerExecutor{image: d.image, dir: dir, environ: d.environ}
+}
+
+func (d dockerExecutor) with(environ []string) executor {
+	return dockerExecutor{image: d.image, dir: d.dir, environ: append(append([]string{}, d.environ...), environ...)}
+}
+
+func (dockerExecutor) local() bool {
+	return false
+}
+
+func (d dockerExecutor) containerName(c *models.Command) string {
+	return "ambros-" + c.ID
+}
@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	repos "github.com/gi4nks/ambros/internal/repos"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// scriptedRule is how the scripted executor answers the command lines matching the pattern
+type scriptedRule struct {
+	Pattern  string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Delay    time.Duration
+	// Echo writes what the command reads on its stdin after its stdout
+	Echo bool
+}
+
+// scriptedExecutor runs no real command: each command starts the test binary again as a helper
+// process, which writes the outputs of the first matching rule, waits its delay and exits with its code;
+// the lines not matching any rule fail with exit code 127, as an unknown command does
+type scriptedExecutor struct {
+	rules   []scriptedRule
+	dir     string
+	environ []string
+
+	// shared by the copies returned by in and with
+	mutex    *sync.Mutex
+	executed *[]string
+}
+
+func newScriptedExecutor(rules ...scriptedRule) scriptedExecutor {
+	return scriptedExecutor{rules: rules, mutex: &sync.Mutex{}, executed: &[]string{}}
+}
+
+func (s scriptedExecutor) command(c *models.Command) *exec.Cmd {
+	var line = strings.TrimSpace(c.Name + " " + strings.Join(c.Arguments, " "))
+
+	s.mutex.Lock()
+	*s.executed = append(*s.executed, line)
+	s.mutex.Unlock()
+
+	var rule = scriptedRule{Stderr: c.Name + ": command not found", ExitCode: 127}
+	for _, r := range s.rules {
+		if regexp.MustCompile(r.Pattern).MatchString(line) {
+			rule = r
+			break
+		}
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestScriptedProcess$")
+	cmd.Dir = s.dir
+	cmd.Env = append(append(os.Environ(), s.environ...),
+		"AMBROS_SCRIPTED=1",
+		"AMBROS_SCRIPTED_STDOUT="+rule.Stdout,
+		"AMBROS_SCRIPTED_STDERR="+rule.Stderr,
+		"AMBROS_SCRIPTED_EXIT="+strconv.Itoa(rule.ExitCode),
+		"AMBROS_SCRIPTED_DELAY="+rule.Delay.String(),
+		"AMBROS_SCRIPTED_ECHO="+strconv.FormatBool(rule.Echo))
+	return cmd
+}
+
+func (scriptedExecutor) record(c *models.Command, err error) {}
+
+func (scriptedExecutor) stop(c *models.Command) {}
+
+func (s scriptedExecutor) in(dir string) executor {
+	s.dir = dir
+	return s
+}
+
+func (s scriptedExecutor) with(environ []string) executor {
+	s.environ = append(append([]string{}, s.environ...), environ...)
+	return s
+}
+
+// local is false, the scripted commands have no executable to fingerprint
+func (scriptedExecutor) local() bool {
+	return false
+}
+
+// lines returns the command lines executed so far
+func (s scriptedExecutor) lines() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, *s.executed...)
+}
+
+// TestScriptedProcess is the helper process of the scripted executor, it does nothing in a normal run;
+// the outputs may reference the environment of the command, as $NAME
+func TestScriptedProcess(t *testing.T) {
+	if os.Getenv("AMBROS_SCRIPTED") != "1" {
+		return
+	}
+
+	if delay, err := time.ParseDuration(os.Getenv("AMBROS_SCRIPTED_DELAY")); err == nil {
+		time.Sleep(delay)
+	}
+
+	if stdout := os.ExpandEnv(os.Getenv("AMBROS_SCRIPTED_STDOUT")); stdout != "" {
+		fmt.Fprintln(os.Stdout, stdout)
+	}
+	if os.Getenv("AMBROS_SCRIPTED_ECHO") == "true" {
+		io.Copy(os.Stdout, os.Stdin)
+	}
+	if stderr := os.ExpandEnv(os.Getenv("AMBROS_SCRIPTED_STDERR")); stderr != "" {
+		fmt.Fprintln(os.Stderr, stderr)
+	}
+
+	code, _ := strconv.Atoi(os.Getenv("AMBROS_SCRIPTED_EXIT"))
+	os.Exit(code)
+}
+
+// useScriptedExecutor points the commands to a throw-away repository and runs them with the scripted
+// executor, restoring both at the end of the test
+func useScriptedExecutor(t *testing.T, rules ...scriptedRule) scriptedExecutor {
+	t.Helper()
+
+	var previousConfiguration, previousRepository, previousExecutor = Configuration, Repository, commandExecutor
+	var previousNotify = notifyFailures
+
+	Configuration = utils.NewConfiguration(*Parrot)
+	Configuration.RepositoryDirectory = t.TempDir()
+	Configuration.MinFreeDisk = 0
+
+	Repository = repos.NewRepository(*Parrot, *Configuration)
+	if err := Repository.InitDB(); err != nil {
+		t.Fatalf("InitDB returned unexpected error: %v", err)
+	}
+	if err := Repository.InitSchema(); err != nil {
+		t.Fatalf("InitSchema returned unexpected error: %v", err)
+	}
+
+	var scripted = newScriptedExecutor(rules...)
+	commandExecutor = scripted
+
+	t.Cleanup(func() {
+		Repository.CloseDB()
+		Configuration, Repository, commandExecutor = previousConfiguration, previousRepository, previousExecutor
+		notifyFailures = previousNotify
+	})
+
+	return scripted
+}
+
+// storeCommand stores a command line as a stored command, returning its id
+func storeCommand(t *testing.T, line string) string {
+	t.Helper()
+
+	var parts = utils.SplitCommandLine(line)
+	var command = initializeCommand(parts[0], parts[1:])
+	command.TerminatedAt = time.Now()
+
+	if err := Repository.Push(command); err != nil {
+		t.Fatalf("Push(%s) returned unexpected error: %v", line, err)
+	}
+
+	return command.ID
+}
@@ -4,8 +4,11 @@ import (
 	"github.com/spf13/cobra"
 
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	models "github.com/gi4nks/ambros/internal/models"
 )
@@ -19,12 +22,29 @@ var exportCmd = &cobra.Command{
 		commandWrapper(args, func() {
 			Parrot.Debug("Output command invoked")
 
+			if cmd.Flag("schema").Changed {
+				schema, _ := json.MarshalIndent(models.ArchiveSchema(), "", "  ")
+				fmt.Println(string(schema))
+				return
+			}
+
+			if cmd.Flag("all").Changed {
+				file, err := stringFromArguments(args)
+				if err != nil {
+					Parrot.Println("Please provide a valid output file")
+					return
+				}
+
+				exportArchive(cmd, file)
+				return
+			}
+
 			if len(args) != 2 {
 				Parrot.Println("Please provide a valid command id stored and valid output file")
 				return
 			}
 
-			id := args[0]
+			id := resolveID(args[0])
 			fl := args[1]
 
 			var stored models.Command
@@ -33,6 +53,9 @@ var exportCmd = &cobra.Command{
 				stored, err = Repository.FindInStoreById(id)
 			} else {
 				stored, err = Repository.FindById(id)
+				if err == nil {
+					Repository.TouchCommand(id, time.Now())
+				}
 			}
 
 			if err != nil {
@@ -50,6 +73,10 @@ var exportCmd = &cobra.Command{
 			writer := bufio.NewWriter(fileHandle)
 			defer fileHandle.Close()
 
+			if stored.Streamed {
+				io.Copy(writer, Repository.GetOutputReader(stored.ID))
+			}
+
 			if stored.Output != "" {
 				fmt.Fprintln(writer, stored.Output)
 			}
@@ -68,5 +95,9 @@ var exportCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(exportCmd)
 	exportCmd.Flags().BoolP("history", "y", false, "Recalls a command from history")
+	exportCmd.Flags().Bool("all", false, "Exports the whole repository to the given file")
+	exportCmd.Flags().String("format", "json", "Format of the archive exported with --all: json or ndjson")
+	exportCmd.Flags().String("since", "", "Exports with --all only the commands executed since (e.g. 30d or 2024-05-01)")
+	exportCmd.Flags().Bool("schema", false, "Prints the JSON Schema of the archives exported with --all")
 
 }
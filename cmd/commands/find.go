@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// commandMatches tells if the command line contains the term, the term is lower case
+func commandMatches(c models.Command, term string) bool {
+	return strings.Contains(strings.ToLower(c.Name+" "+strings.Join(c.Arguments, " ")), term)
+}
+
+// exitCodesFlag returns the exit codes given with --exit, nil when the flag is not given
+func exitCodesFlag(cmd *cobra.Command) (utils.ExitCodes, error) {
+	if !cmd.Flag("exit").Changed {
+		return nil, nil
+	}
+	return utils.ParseExitCodes(cmd.Flag("exit").Value.String())
+}
+
+// findGroup prints the results of an entity type, followed by how to act on them
+func findGroup(title string, lines []string, hint string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	Parrot.Println(title + " (" + strconv.Itoa(len(lines)) + ")")
+	for _, line := range lines {
+		Parrot.Println("  " + line)
+	}
+	Parrot.Println("  -> " + hint)
+}
+
+// findCmd represents the find command
+var findCmd = &cobra.Command{
+	Use:   "find <term>",
+	Short: "Find",
+	Long: `Find command, searches the history, the stored commands, the chains and the environments at once;
+chains match by name, description or the command line of a step, environments by name or variable name;
+--exit keeps the history commands which exited with the given codes, e.g. --exit 1-127 the failed ones,
+--cwd the ones run in a directory or below it, the current one by default, --branch the ones run on a
+git branch`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Find command invoked")
+
+			if len(args) == 0 {
+				Parrot.Println("Please provide a term to search")
+				return
+			}
+
+			var term = strings.ToLower(strings.Join(args, " "))
+
+			limit, _ := cmd.Flags().GetInt("limit")
+			if limit <= 0 {
+				limit = Configuration.LastCountDefault
+			}
+
+			codes, err := exitCodesFlag(cmd)
+			if err != nil {
+				Parrot.Println("Please provide valid exit codes (e.g. 1 or 1-127)")
+				return
+			}
+
+			dir, err := cwdFlag(cmd)
+			if err != nil {
+				Parrot.Println("Please provide a valid directory", err)
+				return
+			}
+
+			var where = allFilters(inDirectoryFilter(dir), onBranchFilter(cmd.Flag("branch").Value.String()))
+
+			history, err := Repository.FilterExecutedCommands(limit, func(c models.Command) bool {
+				return commandMatches(c, term) && (codes == nil || codes.Contains(c.Code())) && (where == nil || where(c))
+			})
+			if err != nil {
+				Parrot.Println("Error retrieving commands", err)
+				return
+			}
+
+			stored, err := Repository.GetAllStoredCommands()
+			if err != nil {
+				Parrot.Println("Commands not available in the store", err)
+				return
+			}
+
+			chains, err := Repository.GetAllChains()
+			if err != nil {
+				Parrot.Println("Error retrieving chains", err)
+				return
+			}
+
+			environments, err := Repository.ListEnvironments()
+			if err != nil {
+				Parrot.Println("Error retrieving environments", err)
+				return
+			}
+
+			var historyLines = []string{}
+			for _, c := range history {
+				historyLines = append(historyLines, c.AsFlatCommand())
+			}
+
+			var storedLines = []string{}
+			for _, c := range stored {
+				if commandMatches(c, term) {
+					storedLines = append(storedLines, c.AsStoredCommand())
+				}
+			}
+
+			var chainLines = []string{}
+			for _, chain := range chains {
+				var steps = []string{}
+				for i, step := range chain.Steps {
+					// the steps may be stored or executed commands
+					if command, err := findCommand(step.CommandID); err == nil && commandMatches(command, term) {
+						steps = append(steps, strconv.Itoa(i+1))
+					}
+				}
+
+				switch {
+				case len(steps) > 0:
+					chainLines = append(chainLines, chain.AsStoredChain()+" (steps "+strings.Join(steps, ", ")+")")
+				case strings.Contains(strings.ToLower(chain.Name+" "+chain.Description), term):
+					chainLines = append(chainLines, chain.AsStoredChain())
+				}
+			}
+
+			// only the names, the values may be secrets
+			var environmentLines = []string{}
+			for _, e := range environments {
+				var keys = []string{}
+				for key := range e.Variables {
+					if strings.Contains(strings.ToLower(key), term) {
+						keys = append(keys, key)
+					}
+				}
+				sort.Strings(keys)
+
+				switch {
+				case len(keys) > 0:
+					environmentLines = append(environmentLines, e.Name+": "+strings.Join(keys, ", "))
+				case strings.Contains(strings.ToLower(e.Name), term):
+					environmentLines = append(environmentLines, e.Name)
+				}
+			}
+
+			if len(historyLines)+len(storedLines)+len(chainLines)+len(environmentLines) == 0 {
+				Parrot.Println("Nothing found for '" + term + "'")
+				return
+			}
+
+			findGroup("History", historyLines, "'ambros recall <id>' runs again, 'ambros show <id>' shows the details")
+			findGroup("Stored commands", storedLines, "'ambros store --run <id>' runs, 'ambros show <id>' shows the details")
+			findGroup("Chains", chainLines, "'ambros chain exec <name>' runs, 'ambros chain show <name>' shows the steps")
+			findGroup("Environments", environmentLines, "'ambros env show <name>' shows the variables, 'ambros env explain <name> <key>' where a value comes from")
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(findCmd)
+
+	findCmd.Flags().String("exit", "", "keeps the history commands which exited with these codes (e.g. 1 or 1-127)")
+	addCwdFlag(findCmd)
+	findCmd.Flags().String("branch", "", "keeps the history commands run on this git branch")
+	findCmd.Flags().Int("limit", 0, "how many matching history commands are shown (default lastCountDefault)")
+}
@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// defaultHistoryFile returns where the shell keeps its history
+func defaultHistoryFile(shell string) string {
+	home, _ := os.UserHomeDir()
+
+	switch shell {
+	case "bash":
+		return filepath.Join(home, ".bash_history")
+	case "zsh":
+		return filepath.Join(home, ".zsh_history")
+	case "fish":
+		var data = os.Getenv("XDG_DATA_HOME")
+		if data == "" {
+			data = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(data, "fish", "fish_history")
+	}
+
+	return ""
+}
+
+// datedHistory gives the commands their time: those without one are spread before the last change of
+// the file, in the order they were written, and no two commands end at the same instant as another
+// command, the history is indexed by it. Sub-second offsets keep the order of the commands of the same second
+func datedHistory(commands []models.Command, modified time.Time, history []models.Command) {
+	var used = map[int64]bool{}
+	for _, c := range history {
+		used[c.TerminatedAt.UnixNano()] = true
+	}
+
+	for i := range commands {
+		var c = &commands[i]
+
+		if c.TerminatedAt.IsZero() {
+			c.CreatedAt = modified.Truncate(time.Second).Add(-time.Duration(len(commands)-i) * time.Second)
+			c.TerminatedAt = c.CreatedAt
+		}
+
+		var offset = time.Millisecond
+		for used[c.TerminatedAt.Add(offset).UnixNano()] {
+			offset += time.Millisecond
+		}
+		c.TerminatedAt = c.TerminatedAt.Add(offset)
+		used[c.TerminatedAt.UnixNano()] = true
+	}
+}
+
+// importHistoryCmd represents the import-history command
+var importHistoryCmd = &cobra.Command{
+	Use:   "import-history [file]",
+	Short: "Import history",
+	Long: `Import history command, adds the commands of a bash, zsh or fish history file to the history;
+the file defaults to the one of the shell. Commands already imported are skipped, so the import can be
+repeated as the file grows`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Import history command invoked")
+
+			var shell = cmd.Flag("shell").Value.String()
+			if shell == "" {
+				shell = filepath.Base(os.Getenv("SHELL"))
+			}
+
+			var file = defaultHistoryFile(shell)
+			if len(args) > 0 {
+				file = args[0]
+			}
+			if file == "" {
+				Parrot.Println("Please provide a valid --shell: bash, zsh or fish")
+				return
+			}
+
+			fileHandle, err := os.Open(file)
+			if err != nil {
+				Parrot.Println("Impossible to open the required file (" + file + ")")
+				return
+			}
+			defer fileHandle.Close()
+
+			commands, err := Utilities.ParseHistory(shell, fileHandle)
+			if err != nil {
+				Parrot.Println("Error reading the history", err)
+				return
+			}
+
+			info, err := fileHandle.Stat()
+			if err != nil {
+				Parrot.Println("Error reading the history", err)
+				return
+			}
+
+			history, err := Repository.GetAllCommands()
+			if err != nil {
+				Parrot.Println("Error reading the history", err)
+				return
+			}
+
+			// commands with a time are the same when they ran the same line in the same second
+			var known = map[string]bool{}
+			for _, c := range history {
+				known[c.Fingerprint()+" "+strconv.FormatInt(c.CreatedAt.Unix(), 10)] = true
+			}
+
+			// commands without one can only be told apart by their position: the ones already imported
+			// from this file are skipped, unless the file was truncated since
+			absolute, _ := filepath.Abs(file)
+			var key = "history " + shell + " " + absolute
+
+			var undated = 0
+			for _, c := range commands {
+				if c.TerminatedAt.IsZero() {
+					undated++
+				}
+			}
+
+			var seen = 0
+			if value, err := Repository.GetMeta(key); err == nil && value != "" {
+				if seen, _ = strconv.Atoi(value); seen > undated {
+					seen = 0
+				}
+			}
+
+			var imported = []models.Command{}
+			var skipped = 0
+			var position = 0
+
+			for _, c := range commands {
+				if c.TerminatedAt.IsZero() {
+					position++
+					if position <= seen {
+						skipped++
+						continue
+					}
+				} else {
+					var identity = c.Fingerprint() + " " + strconv.FormatInt(c.CreatedAt.Unix(), 10)
+					if known[identity] {
+						skipped++
+						continue
+					}
+					known[identity] = true
+				}
+
+				imported = append(imported, c)
+			}
+
+			datedHistory(imported, info.ModTime(), history)
+
+			if len(imported) > 0 {
+				sequence, err := Repository.ReserveSequences(len(imported))
+				if err != nil {
+					Parrot.Println("Error storing the commands", err)
+					return
+				}
+
+				for i := range imported {
+					imported[i].ID = Utilities.Random()
+					imported[i].Origin = Configuration.Origin
+					imported[i].Sequence = sequence + uint64(i)
+					redactCommand(&imported[i])
+				}
+
+				if err := Repository.PutAll(imported); err != nil {
+					Parrot.Println("Error storing the commands", err)
+					return
+				}
+			}
+
+			Repository.PutMeta(key, strconv.Itoa(undated))
+
+			Parrot.Println(strconv.Itoa(len(imported)) + " commands imported, " + strconv.Itoa(skipped) + " already present")
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(importHistoryCmd)
+
+	importHistoryCmd.Flags().String("shell", "", "the shell which wrote the history: bash, zsh or fish (default the login shell)")
+}
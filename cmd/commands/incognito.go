@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// incognito tells if the session asked ambros not to persist anything; the repository refuses the
+// writes then, see utils.Incognito
+func incognito() bool {
+	return utils.Incognito()
+}
+
+// incognitoCmd represents the incognito command
+var incognitoCmd = &cobra.Command{
+	Use:   "incognito [on|off]",
+	Short: "Incognito",
+	Long: `Incognito command, prints the shell snippet switching recording off or on for the session.
+While it is on the repository is left untouched: the commands run are not recorded, and the commands
+changing the store, the chains, the environments or the aliases fail.
+
+Use it as: eval "$(ambros incognito on)" and eval "$(ambros incognito off)"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Parrot.Debug("Incognito command invoked")
+
+		mode, err := stringFromArguments(args)
+		if err != nil {
+			if incognito() {
+				Parrot.Println("Incognito is on: nothing is recorded in this session")
+			} else {
+				Parrot.Println("Incognito is off: commands are recorded")
+			}
+			return
+		}
+
+		switch mode {
+		case "on":
+			if incognito() {
+				return
+			}
+			fmt.Printf("export %s=1; export AMBROS_SAVED_PS1=\"$PS1\"; export PS1=\"(ambros: rec off) $PS1\"\n", utils.ConstIncognitoEnv)
+		case "off":
+			if !incognito() {
+				return
+			}
+			fmt.Printf("unset %s; export PS1=\"$AMBROS_SAVED_PS1\"; unset AMBROS_SAVED_PS1\n", utils.ConstIncognitoEnv)
+		default:
+			Parrot.Println("Please provide on or off")
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(incognitoCmd)
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+func TestIncognitoLeavesRepositoryUntouched(t *testing.T) {
+	useScriptedExecutor(t, scriptedRule{Pattern: "^make", Stdout: "built"})
+	Configuration.PerfMode = true
+
+	var stored = storeCommand(t, "make test")
+
+	var executed = initializeCommand("make", []string{"build"})
+	executeCommand(&executed)
+	finalizeCommand(&executed)
+
+	// every command below opens the repository by itself
+	Repository.CloseDB()
+
+	before, err := os.ReadFile(Configuration.RepositoryFullName())
+	if err != nil {
+		t.Fatalf("ReadFile returned unexpected error: %v", err)
+	}
+
+	t.Setenv(utils.ConstIncognitoEnv, "1")
+
+	var previous = invokedCommand
+	defer func() { invokedCommand = previous }()
+
+	invokedCommand = "ambros run"
+	runCmd.Run(runCmd, []string{"make", "build"})
+
+	invokedCommand = "ambros output"
+	outputCmd.Run(outputCmd, []string{executed.ID})
+
+	invokedCommand = "ambros store"
+	storeCmd.Flags().Set("run", stored)
+	defer func() {
+		storeCmd.Flags().Set("run", "")
+		storeCmd.Flag("run").Changed = false
+	}()
+	storeCmd.Run(storeCmd, nil)
+
+	invokedCommand = "ambros recall"
+	recallCmd.Flags().Set("history", "true")
+	defer func() {
+		recallCmd.Flags().Set("history", "false")
+		recallCmd.Flag("history").Changed = false
+	}()
+	recallCmd.Run(recallCmd, []string{stored})
+
+	invokedCommand = "ambros alias set"
+	aliasSetCmd.Run(aliasSetCmd, []string{executed.ID, "build"})
+
+	after, err := os.ReadFile(Configuration.RepositoryFullName())
+	if err != nil {
+		t.Fatalf("ReadFile returned unexpected error: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Errorf("the repository changed while incognito was on")
+	}
+
+	// reopened for the cleanup, which closes it
+	if err := Repository.InitDB(); err != nil {
+		t.Fatalf("InitDB returned unexpected error: %v", err)
+	}
+}
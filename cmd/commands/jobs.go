@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// jobsCmd represents the jobs command
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Jobs",
+	Long:  `Jobs command, shows the executions in progress`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Jobs command invoked")
+
+			var commands, err = Repository.GetRunningCommands()
+
+			if err != nil {
+				Parrot.Println("Error retrieving running commands", err)
+				return
+			}
+
+			if len(commands) == 0 {
+				Parrot.Println("No commands running!")
+				return
+			}
+
+			for _, c := range commands {
+				Parrot.Println(c.AsStoredCommand() + " (pid " + strconv.Itoa(c.PID) + ")")
+			}
+		})
+	},
+}
+
+// jobsOrphansCmd represents the jobs orphans command
+var jobsOrphansCmd = &cobra.Command{
+	Use:   "orphans",
+	Short: "Orphans",
+	Long:  `Orphans command, shows the executions interrupted by a crash`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Jobs orphans command invoked")
+
+			var commands, err = Repository.GetAllCommands()
+
+			if err != nil {
+				Parrot.Println("Error retrieving commands in the store", err)
+				return
+			}
+
+			var clean = cmd.Flag("clean").Changed
+			var found = 0
+
+			for _, c := range commands {
+				if !c.Interrupted {
+					continue
+				}
+				found++
+
+				if clean {
+					if err := Repository.DeleteCommand(c.ID); err != nil {
+						Parrot.Println("Error deleting command ("+c.ID+")", err)
+						continue
+					}
+				}
+
+				c.AsExecutedCommand(found).Print(Parrot)
+			}
+
+			if found == 0 {
+				Parrot.Println("No interrupted commands!")
+				return
+			}
+
+			if clean {
+				Parrot.Println("Done!")
+			}
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(jobsCmd)
+	jobsCmd.AddCommand(jobsOrphansCmd)
+
+	jobsOrphansCmd.Flags().BoolP("clean", "c", false, "removes the interrupted commands from the history")
+}
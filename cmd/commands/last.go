@@ -2,13 +2,16 @@ package commands
 
 import (
 	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
 )
 
 // lastCmd represents the output command
 var lastCmd = &cobra.Command{
 	Use:   "last",
 	Short: "Last",
-	Long:  `Last command`,
+	Long: `Last command, --cwd shows only the commands run in a directory or below it, the current one by default,
+--branch only the ones run on a git branch`,
 	Run: func(cmd *cobra.Command, args []string) {
 		commandWrapper(args, func() {
 			Parrot.Debug("Last command invoked")
@@ -19,7 +22,14 @@ var lastCmd = &cobra.Command{
 				limit = Configuration.LastCountDefault
 			}
 
-			var commands, err = Repository.GetExecutedCommands(limit)
+			dir, err := cwdFlag(cmd)
+			if err != nil {
+				Parrot.Println("Please provide a valid directory", err)
+				return
+			}
+
+			var filter = allFilters(severityFilter(cmd), inDirectoryFilter(dir), onBranchFilter(cmd.Flag("branch").Value.String()))
+			commands, err := Repository.FilterExecutedCommands(limit, filter)
 
 			if err != nil {
 				Parrot.Println("Error retrieving commands in the store", err)
@@ -33,6 +43,48 @@ var lastCmd = &cobra.Command{
 	},
 }
 
+// severityFilter selects the commands according to the --has-warnings and --has-errors flags
+func severityFilter(cmd *cobra.Command) func(models.Command) bool {
+	var warnings = cmd.Flag("has-warnings").Changed
+	var errors = cmd.Flag("has-errors").Changed
+
+	if !warnings && !errors {
+		return nil
+	}
+
+	return func(c models.Command) bool {
+		return (!warnings || c.Warnings > 0) && (!errors || c.Errors > 0)
+	}
+}
+
+// allFilters selects the commands selected by every given filter, the missing ones selecting them all
+func allFilters(filters ...func(models.Command) bool) func(models.Command) bool {
+	var set = []func(models.Command) bool{}
+	for _, filter := range filters {
+		if filter != nil {
+			set = append(set, filter)
+		}
+	}
+
+	if len(set) == 0 {
+		return nil
+	}
+
+	return func(c models.Command) bool {
+		for _, filter := range set {
+			if !filter(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 func init() {
 	RootCmd.AddCommand(lastCmd)
+
+	lastCmd.Flags().Bool("has-warnings", false, "shows only commands whose output contains warnings")
+	lastCmd.Flags().Bool("has-errors", false, "shows only commands whose output contains errors")
+	addCwdFlag(lastCmd)
+	lastCmd.Flags().String("branch", "", "shows only commands run on this git branch")
 }
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// lintProblem is an issue found while validating the repository
+type lintProblem struct {
+	Kind    string
+	Subject string
+	Message string
+}
+
+// lintStoredCommands validates the commands in the store
+func lintStoredCommands(commands []models.Command) []lintProblem {
+	var problems = []lintProblem{}
+
+	for _, c := range commands {
+		if _, err := exec.LookPath(c.Name); err != nil && c.Placeholders() == 0 {
+			problems = append(problems, lintProblem{"store", c.ID, "executable " + c.Name + " not found in PATH"})
+		}
+
+		for _, n := range c.MissingPlaceholders() {
+			problems = append(problems, lintProblem{"store", c.ID, "placeholder {" + strconv.Itoa(n) + "} is never used"})
+		}
+	}
+
+	return problems
+}
+
+// lintChains validates the chains and the commands they reference
+func lintChains(chains []models.Chain) []lintProblem {
+	var problems = []lintProblem{}
+
+	for _, chain := range chains {
+		if len(chain.Steps) == 0 {
+			problems = append(problems, lintProblem{"chain", chain.Name, "chain has no steps"})
+		}
+
+		for i, step := range chain.Steps {
+			var where = "step " + strconv.Itoa(i+1) + ": "
+
+			command, err := findCommand(step.CommandID)
+			if err != nil {
+				problems = append(problems, lintProblem{"chain", chain.Name, where + "command " + step.CommandID + " does not exist"})
+				continue
+			}
+
+			if command.Placeholders() > 0 {
+				problems = append(problems, lintProblem{"chain", chain.Name, where + "command " + step.CommandID + " has placeholders"})
+			}
+		}
+	}
+
+	return problems
+}
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Lint",
+	Long:  `Lint command, validates stored commands and chains, exiting with 1 when problems are found`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var problems = []lintProblem{}
+		var failed = false
+
+		commandWrapper(args, func() {
+			Parrot.Debug("Lint command invoked")
+
+			stored, err := Repository.GetAllStoredCommands()
+			if err != nil {
+				Parrot.Println("Commands not available in the store", err)
+				failed = true
+				return
+			}
+
+			chains, err := Repository.GetAllChains()
+			if err != nil {
+				Parrot.Println("Error retrieving chains", err)
+				failed = true
+				return
+			}
+
+			problems = append(problems, lintStoredCommands(stored)...)
+			problems = append(problems, lintChains(chains)...)
+
+			if len(problems) == 0 {
+				Parrot.Println("No problems found!")
+				return
+			}
+
+			var body = [][]string{}
+			for _, p := range problems {
+				body = append(body, []string{p.Kind, p.Subject, p.Message})
+			}
+			Parrot.Tablify([]string{"KIND", "SUBJECT", "PROBLEM"}, body)
+		})
+
+		if failed || len(problems) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(lintCmd)
+}
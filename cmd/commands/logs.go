@@ -1,7 +1,11 @@
 package commands
 
 import (
+	"strconv"
+
 	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
 )
 
 // logsCmd represents the logs command
@@ -16,7 +20,7 @@ var logsCmd = &cobra.Command{
 			var id = cmd.Flag("id").Value.String()
 
 			if id != "" {
-				var command, err = Repository.FindById(id)
+				var command, err = Repository.FindById(resolveID(id))
 
 				if err != nil {
 					Parrot.Println("Error retrieving command in the store ("+id+")", err)
@@ -24,6 +28,28 @@ var logsCmd = &cobra.Command{
 				}
 
 				Parrot.Println(command.String())
+			} else if cmd.Flag("page-size").Changed {
+				size, _ := cmd.Flags().GetInt("page-size")
+				if size <= 0 {
+					Parrot.Println("Please provide a positive page size")
+					return
+				}
+
+				var page, err = Repository.GetCommandsPage(cmd.Flag("cursor").Value.String(), size)
+
+				if err != nil {
+					Parrot.Println("Error retrieving commands in the store", err)
+					return
+				}
+
+				for _, c := range page.Commands {
+					Parrot.Println(c.String())
+				}
+
+				Parrot.Println(strconv.Itoa(len(page.Commands)) + " of " + strconv.Itoa(page.Total) + " commands")
+				if page.Next != "" {
+					Parrot.Println("next page: ambros logs --page-size " + strconv.Itoa(size) + " --cursor " + page.Next)
+				}
 			} else {
 				var commands, err = Repository.GetAllCommands()
 
@@ -32,7 +58,8 @@ var logsCmd = &cobra.Command{
 					return
 				}
 
-				for _, c := range commands {
+				// the order of each machine follows its sequence, timestamps may be skewed
+				for _, c := range models.Timeline(commands) {
 					Parrot.Println(c.String())
 				}
 			}
@@ -45,4 +72,6 @@ func init() {
 	RootCmd.AddCommand(logsCmd)
 
 	logsCmd.Flags().StringP("id", "i", "", "id to show the logs")
+	logsCmd.Flags().Int("page-size", 0, "number of commands to show, from the newest")
+	logsCmd.Flags().String("cursor", "", "cursor of the page to show, as printed by the previous page")
 }
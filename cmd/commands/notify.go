@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	notify "github.com/gi4nks/ambros/internal/notify"
+)
+
+// how much of the error output a notification carries
+const notifiedErrorLength = 500
+
+// notifyFailures is set by --notify-on-failure, or notifyOnFailure in the configuration
+var notifyFailures = false
+
+// notifyFlag enables the failure notifications for the invoked command
+func notifyFlag(cmd *cobra.Command) {
+	notifyFailures = Configuration.NotifyOnFailure || cmd.Flag("notify-on-failure").Changed
+
+	if notifyFailures && len(Configuration.Notifications) == 0 {
+		Parrot.Println("No notification channels configured, see 'notifications' in the configuration")
+	}
+}
+
+// notifyStatePath is where how the deliveries went is kept between the invocations
+func notifyStatePath() string {
+	return filepath.Join(Configuration.RepositoryDirectory, "notify.state")
+}
+
+// configuredChannels returns the channels of the configuration
+func configuredChannels() []notify.Channel {
+	var channels = []notify.Channel{}
+	for _, settings := range Configuration.Notifications {
+		if channel, err := notify.New(settings); err == nil {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// sendNotification delivers the event to all the configured channels, each one retried, with the
+// duplicates skipped and left alone while failing, as the delivery policy says
+func sendNotification(title string, message string) []error {
+	state, err := notify.LoadState(notifyStatePath())
+	if err != nil {
+		Parrot.Debug("--> Unable to read the notification state, starting afresh", err)
+	}
+
+	var channels = []notify.Channel{}
+	for _, settings := range Configuration.Notifications {
+		if channel, err := notify.New(settings); err == nil {
+			channels = append(channels, notify.Reliable(channel, notify.Key(settings), state, notify.DefaultPolicy))
+		}
+	}
+
+	var event = notify.Event{Title: title, Message: message, Origin: Configuration.Origin, At: time.Now()}
+	var errs = notify.Send(channels, event)
+
+	// incognito leaves no trace, of the notifications neither
+	if !incognito() {
+		if err := state.Save(notifyStatePath(), notify.DefaultPolicy); err != nil {
+			Parrot.Debug("--> Unable to save the notification state", err)
+		}
+	}
+
+	return errs
+}
+
+// notifyFailure notifies a failure when enabled, a failed delivery does not fail the command
+func notifyFailure(title string, message string) {
+	if !notifyFailures {
+		return
+	}
+
+	for _, err := range sendNotification(title, message) {
+		Parrot.Error("Error sending the notification", err)
+	}
+}
+
+// notifyCommandFailure notifies the failure of an executed command
+func notifyCommandFailure(command models.Command) {
+	var message = "[" + command.ID + "] " + command.Name + " " + strings.Join(command.Arguments, " ")
+	if errorOutput := strings.TrimSpace(command.Error); errorOutput != "" {
+		if len(errorOutput) > notifiedErrorLength {
+			errorOutput = errorOutput[:notifiedErrorLength] + "..."
+		}
+		message += "\n" + errorOutput
+	}
+	message += "\nafter " + command.TerminatedAt.Sub(command.CreatedAt).Round(time.Millisecond).String()
+
+	notifyFailure("ambros: command failed", message)
+}
+
+// notifyCmd represents the notify command
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Notify",
+	Long:  `Notify command, checks the channels where the failures are notified`,
+}
+
+// notifyTestCmd represents the notify test command
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Test",
+	Long:  `Test command, sends a test notification to all the configured channels`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Parrot.Debug("Notify test command invoked")
+
+		if len(Configuration.Notifications) == 0 {
+			Parrot.Println("No notification channels configured, see 'notifications' in the configuration")
+			return
+		}
+
+		// sent as it is, whatever happened to the failures notified before
+		var event = notify.Event{Title: "ambros: test notification", Message: "Notifications are working", Origin: Configuration.Origin, At: time.Now()}
+		var errs = notify.Send(configuredChannels(), event)
+		for _, err := range errs {
+			Parrot.Println("Error sending the notification", err)
+		}
+
+		Parrot.Println(strconv.Itoa(len(Configuration.Notifications)-len(errs)) + " of " + strconv.Itoa(len(Configuration.Notifications)) + " channels notified")
+	},
+}
+
+// notifyStatusCmd represents the notify status command
+var notifyStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Status",
+	Long: `Status command, shows how the deliveries to each configured channel went: the failures in a row, whether
+its circuit is open, so nothing is sent to it until the cooldown ends, and the duplicates skipped`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Parrot.Debug("Notify status command invoked")
+
+		if len(Configuration.Notifications) == 0 {
+			Parrot.Println("No notification channels configured, see 'notifications' in the configuration")
+			return
+		}
+
+		state, err := notify.LoadState(notifyStatePath())
+		if err != nil {
+			Parrot.Println("Error reading the notification state", err)
+			return
+		}
+
+		var when = func(t time.Time) string {
+			if t.IsZero() {
+				return "-"
+			}
+			return t.Format("02.01.2006 15:04:05")
+		}
+
+		var rows = [][]string{}
+		for _, settings := range Configuration.Notifications {
+			var key = notify.Key(settings)
+			var sink = state.Sink(key)
+
+			var circuit = "closed"
+			if sink.Open(time.Now()) {
+				circuit = "open until " + sink.OpenUntil.Format("15:04:05")
+			}
+
+			rows = append(rows, []string{
+				key,
+				circuit,
+				strconv.Itoa(sink.Failures),
+				when(sink.LastSuccess),
+				when(sink.LastFailure),
+				strings.ReplaceAll(sink.LastError, "%", "%%"),
+				strconv.Itoa(sink.Suppressed),
+			})
+		}
+
+		Parrot.Tablify([]string{"CHANNEL", "CIRCUIT", "FAILURES", "LAST SENT", "LAST FAILED", "LAST ERROR", "DUPLICATES"}, rows)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(notifyCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+	notifyCmd.AddCommand(notifyStatusCmd)
+}
@@ -1,6 +1,10 @@
 package commands
 
 import (
+	"io"
+	"os"
+	"time"
+
 	"github.com/spf13/cobra"
 )
 
@@ -18,13 +22,19 @@ var outputCmd = &cobra.Command{
 				Parrot.Println("Please provide a valid command id")
 				return
 			}
-			var command, err = Repository.FindById(id)
+			var command, err = Repository.FindById(resolveID(id))
 
 			if err != nil {
 				Parrot.Println("Error retrieving command in the store ("+id+")", err)
 				return
 			}
 
+			Repository.TouchCommand(command.ID, time.Now())
+
+			if command.Streamed {
+				io.Copy(os.Stdout, Repository.GetOutputReader(command.ID))
+			}
+
 			if command.Output != "" {
 				Parrot.Println(command.Output)
 			}
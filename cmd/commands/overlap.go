@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// how often a queued command checks if the previous instance terminated
+const overlapPollInterval = time.Second
+
+// sharedRepository is set while commands run in parallel: they share the open repository,
+// released by none of them
+var sharedRepository = false
+
+// detachRepository releases the repository while a command runs, so other ambros
+// invocations are not blocked by the lock of the database in the meantime
+func detachRepository() {
+	if sharedRepository {
+		return
+	}
+
+	if err := Repository.CloseDB(); err != nil {
+		Parrot.Debug("--> Unable to release the repository", err)
+	}
+}
+
+// attachRepository opens the repository again after detachRepository; the command ran in the meantime
+// and is still to be recorded, so it waits as long as another invocation holds the lock
+func attachRepository() error {
+	if sharedRepository {
+		return nil
+	}
+
+	return Repository.InitDBWait()
+}
+
+// reattachRepository is attachRepository for the commands about to be recorded: without the repository
+// nothing is left to record them with, the invocation stops there
+func reattachRepository(command *models.Command) {
+	if err := attachRepository(); err != nil {
+		Parrot.Error("Error opening the repository again, "+command.Name+" is not recorded", err)
+		os.Exit(1)
+	}
+}
+
+// runningInstance returns the alive running command with the same fingerprint, if any
+func runningInstance(command *models.Command) (models.Command, bool) {
+	running, err := Repository.GetRunningCommands()
+	if err != nil {
+		Parrot.Debug("--> Unable to read the running commands", err)
+		return models.Command{}, false
+	}
+
+	for _, r := range running {
+		if r.ID != command.ID && r.Fingerprint() == command.Fingerprint() && Utilities.ProcessAlive(r.PID) {
+			return r, true
+		}
+	}
+
+	return models.Command{}, false
+}
+
+// checkOverlap applies the overlap policy when the same command is still running elsewhere:
+// warn only, queue until the previous instance terminates or block the execution; an invalid policy
+// blocks it too, reporting the valid ones
+func checkOverlap(command *models.Command) error {
+	previous, found := runningInstance(command)
+	if !found {
+		return nil
+	}
+
+	var description = command.Name + " is already running since " + previous.CreatedAt.Format("15:04:05") +
+		" (pid " + strconv.Itoa(previous.PID) + ")"
+
+	policy, err := utils.ParseOverlap(Configuration.Overlap)
+	if err != nil {
+		return errors.New(description + ", not started: " + err.Error())
+	}
+
+	switch policy {
+	case "block":
+		return errors.New(description + ", not started: overlap is set to block")
+	case "queue":
+		Parrot.Println(description + ", waiting for it to terminate")
+		for found {
+			detachRepository()
+			time.Sleep(overlapPollInterval)
+			reattachRepository(command)
+
+			_, found = runningInstance(command)
+		}
+	case "warn":
+		Parrot.Warn(description)
+	}
+
+	return nil
+}
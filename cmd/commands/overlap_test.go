@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	repos "github.com/gi4nks/ambros/internal/repos"
+)
+
+func TestCheckOverlap(t *testing.T) {
+	useScriptedExecutor(t)
+
+	// the test process is the instance still running
+	var previous = initializeCommand("make", []string{"deploy"})
+	previous.PID = os.Getpid()
+	if err := Repository.PutRunning(previous); err != nil {
+		t.Fatalf("PutRunning returned unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		overlap string
+		blocked bool
+	}{
+		{"warn", false},
+		{"Warn", false},
+		{"block", true},
+		{"stop", true},
+	} {
+		Configuration.Overlap = tc.overlap
+
+		var command = initializeCommand("make", []string{"deploy"})
+		err := checkOverlap(&command)
+
+		if (err != nil) != tc.blocked {
+			t.Errorf("checkOverlap() with overlap %s = %v, want blocked %v", tc.overlap, err, tc.blocked)
+		}
+	}
+
+	// an invalid policy names the valid ones
+	Configuration.Overlap = "stop"
+	var command = initializeCommand("make", []string{"deploy"})
+	if err := checkOverlap(&command); err == nil || !strings.Contains(err.Error(), "warn, queue or block") {
+		t.Errorf("checkOverlap() with overlap stop = %v, want the valid policies", err)
+	}
+}
+
+func TestAttachRepositoryWaits(t *testing.T) {
+	useScriptedExecutor(t)
+
+	// another invocation holds the lock longer than lockTimeout
+	Repository.CloseDB()
+	Configuration.LockTimeout = 50 * time.Millisecond
+	Repository = repos.NewRepository(*Parrot, *Configuration)
+
+	other, err := bolt.Open(Configuration.RepositoryFullName(), 0600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open returned unexpected error: %v", err)
+	}
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		other.Close()
+	}()
+
+	if err := attachRepository(); err != nil {
+		t.Fatalf("attachRepository returned unexpected error: %v", err)
+	}
+
+	// the command can be recorded then
+	var command = initializeCommand("make", []string{"deploy"})
+	if recorded, err := recordCommand(&command); err != nil || !recorded {
+		t.Errorf("recordCommand() = %v, %v, want the command recorded", recorded, err)
+	}
+}
@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	tracing "github.com/gi4nks/ambros/internal/tracing"
+)
+
+var perfPhases = map[string]time.Duration{}
+
+// perfLock guards perfPhases, the steps of a chain may run in parallel
+var perfLock sync.Mutex
+var perfReportPhases = []string{"open", "query", "execute", "store", "total"}
+
+// trackPhase starts timing a phase of the invocation, the returned function stops it
+func trackPhase(name string) func() {
+	var start = time.Now()
+
+	var span *tracing.Span
+	if operation, ok := tracedPhases[name]; ok {
+		span = tracer.Start(operation, invocation)
+	}
+
+	return func() {
+		perfLock.Lock()
+		perfPhases[name] += time.Since(start)
+		perfLock.Unlock()
+
+		tracer.End(span)
+	}
+}
+
+// storePerf saves the phases timing of the current invocation when perf mode is on
+func storePerf(started time.Time) {
+	if !Configuration.PerfMode || incognito() {
+		return
+	}
+
+	if err := Repository.PutPerf(perfRecord(started)); err != nil {
+		Parrot.Error("Error storing the perf record", err)
+	}
+}
+
+// storeQueryPerf saves the phases timing of a read only invocation, once its handle is closed: the
+// repository is opened for writing if no other process uses it, else the timings are given up
+func storeQueryPerf(started time.Time) {
+	if !Configuration.PerfMode || incognito() {
+		return
+	}
+
+	var record = perfRecord(started)
+
+	if err := Repository.InitDBNoWait(); err != nil {
+		Parrot.Debug("--> Perf record not stored, the repository is in use")
+		return
+	}
+	defer Repository.CloseDB()
+
+	if err := Repository.PutPerf(record); err != nil {
+		Parrot.Error("Error storing the perf record", err)
+	}
+}
+
+// perfRecord returns the phases timing of the current invocation
+func perfRecord(started time.Time) models.PerfRecord {
+	var phases = map[string]time.Duration{}
+	for k, v := range perfPhases {
+		phases[k] = v
+	}
+
+	// everything which is not execution or storage is spent querying the repository
+	phases["query"] = phases["command"] - phases["execute"] - phases["store"]
+	if phases["query"] < 0 {
+		phases["query"] = 0
+	}
+	delete(phases, "command")
+	phases["total"] = time.Since(started)
+
+	var record = models.PerfRecord{Command: invokedCommand, Phases: phases}
+	record.ID = Utilities.Random()
+	record.CreatedAt = started
+	record.TerminatedAt = time.Now()
+
+	return record
+}
+
+// perfCmd represents the perf command
+var perfCmd = &cobra.Command{
+	Use:   "perf",
+	Short: "Perf",
+	Long:  `Perf command, inspects the timings recorded when perfMode is enabled`,
+}
+
+// perfReportCmd represents the perf report command
+var perfReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report",
+	Long:  `Report command, summarizes where ambros spends its time`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Perf report command invoked")
+
+			records, err := Repository.GetAllPerf()
+			if err != nil {
+				Parrot.Println("Error retrieving perf records", err)
+				return
+			}
+
+			if len(records) == 0 {
+				Parrot.Println("No perf records available, enable perfMode in the configuration")
+				return
+			}
+
+			var grouped = map[string][]models.PerfRecord{}
+			var names = []string{}
+			for _, r := range records {
+				if _, ok := grouped[r.Command]; !ok {
+					names = append(names, r.Command)
+				}
+				grouped[r.Command] = append(grouped[r.Command], r)
+			}
+			sort.Strings(names)
+
+			var header = []string{"COMMAND", "COUNT"}
+			for _, phase := range perfReportPhases {
+				header = append(header, "AVG "+phase, "MAX "+phase)
+			}
+
+			var body = [][]string{}
+			for _, name := range names {
+				var row = []string{name, strconv.Itoa(len(grouped[name]))}
+
+				for _, phase := range perfReportPhases {
+					var sum, max time.Duration
+					for _, r := range grouped[name] {
+						sum += r.Phases[phase]
+						if r.Phases[phase] > max {
+							max = r.Phases[phase]
+						}
+					}
+
+					var avg = sum / time.Duration(len(grouped[name]))
+					row = append(row, avg.Round(time.Microsecond).String(), max.Round(time.Microsecond).String())
+				}
+
+				body = append(body, row)
+			}
+
+			Parrot.Tablify(header, body)
+		})
+	},
+}
+
+// perfClearCmd represents the perf clear command
+var perfClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear",
+	Long:  `Clear command, removes all the perf records`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Perf clear command invoked")
+
+			if err := Repository.DeleteAllPerf(); err != nil {
+				Parrot.Println("Deletion of perf records failed", err)
+				return
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(perfCmd)
+	perfCmd.AddCommand(perfReportCmd)
+	perfCmd.AddCommand(perfClearCmd)
+}
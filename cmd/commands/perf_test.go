@@ -0,0 +1,27 @@
+package commands
+
+import "testing"
+
+func TestStoreQueryPerf(t *testing.T) {
+	useScriptedExecutor(t)
+	Configuration.PerfMode = true
+
+	var previous = invokedCommand
+	invokedCommand = "ambros last"
+	defer func() { invokedCommand = previous }()
+
+	// the command opens the repository read only by itself
+	Repository.CloseDB()
+	commandWrapper(nil, func() {})
+	if err := Repository.InitDB(); err != nil {
+		t.Fatalf("InitDB returned unexpected error: %v", err)
+	}
+
+	records, err := Repository.GetAllPerf()
+	if err != nil {
+		t.Fatalf("GetAllPerf returned unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Command != "ambros last" || records[0].Phases["total"] <= 0 {
+		t.Errorf("GetAllPerf() = %+v, want the timings of ambros last", records)
+	}
+}
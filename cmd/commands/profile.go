@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gi4nks/quant"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// the profile using the repository of the configuration, as before profiles existed
+const defaultProfile = "default"
+
+var profileRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._-]*$`)
+
+// repositoryDirectory returns the repository directory of the configuration, holding the profiles
+func repositoryDirectory(folder string) string {
+	if viper.GetString("repositoryDirectory") != "" {
+		return folder + "/" + viper.GetString("repositoryDirectory")
+	}
+	return folder + "/" + utils.ConstRepositoryDirectory
+}
+
+func profilesDirectory(folder string) string {
+	return filepath.Join(repositoryDirectory(folder), "profiles")
+}
+
+// profileDirectory returns the directory of a profile, holding its repository and its .ambros.yaml
+func profileDirectory(folder string, name string) string {
+	return filepath.Join(profilesDirectory(folder), name)
+}
+
+// the file remembering the profile in use
+func currentProfileFile(folder string) string {
+	return filepath.Join(repositoryDirectory(folder), "profile")
+}
+
+// activeProfile returns the profile given with --profile, else in the environment, else the one in
+// use; none is the default profile
+func activeProfile(folder string) string {
+	var name = profileName
+	if name == "" {
+		name = os.Getenv(utils.ConstProfileEnv)
+	}
+	if name == "" {
+		current, _ := os.ReadFile(currentProfileFile(folder))
+		name = strings.TrimSpace(string(current))
+	}
+
+	if name == defaultProfile {
+		return ""
+	}
+	return name
+}
+
+// mergeProfileSettings applies the settings of the .ambros.yaml of the active profile over the ones of
+// the configuration, but the repository location which is the directory of the profile
+func mergeProfileSettings(folder string) {
+	var name = activeProfile(folder)
+	if name == "" {
+		return
+	}
+
+	var settings = viper.New()
+	settings.SetConfigFile(filepath.Join(profileDirectory(folder, name), ".ambros.yaml"))
+	if err := settings.ReadInConfig(); err != nil {
+		return
+	}
+
+	var values = settings.AllSettings()
+	delete(values, "repositorydirectory")
+	delete(values, "repositoryfile")
+
+	if err := viper.MergeConfigMap(values); err != nil {
+		Parrot.Error("Invalid settings of the profile "+name, err)
+	}
+}
+
+// checkProfile tells when the profile in use was not created, rather than failing to open its repository
+func checkProfile() error {
+	if Configuration.Profile == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(Configuration.RepositoryDirectory); err != nil {
+		return errors.New("Profile " + Configuration.Profile + " not available, see 'ambros profile create " + Configuration.Profile + "'")
+	}
+	return nil
+}
+
+// profileCmd represents the profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Profile",
+	Long: `Profile command, prints the profile in use. Each profile has its own history, store and chains, and
+its own settings in the .ambros.yaml of its directory, applied over the configuration; the default
+profile uses the repository of the configuration. The profile is chosen with --profile, else with
+$` + utils.ConstProfileEnv + `, else with 'ambros profile use'`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Parrot.Debug("Profile command invoked")
+
+		if Configuration.Profile == "" {
+			Parrot.Println(defaultProfile)
+			return
+		}
+		Parrot.Println(Configuration.Profile + " (" + Configuration.RepositoryDirectory + ")")
+	},
+}
+
+// profileCreateCmd represents the profile create command
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create",
+	Long:  `Create command, creates a profile with an empty history`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Parrot.Debug("Profile create command invoked")
+
+		name, err := stringFromArguments(args)
+		if err != nil || !profileRegexp.MatchString(name) || name == defaultProfile {
+			Parrot.Println("Please provide a valid profile name, letters, digits, '.', '_' and '-' starting with a letter")
+			return
+		}
+
+		folder, err := quant.ExecutableFolder()
+		if err != nil {
+			Parrot.Println("Executable folder error", err)
+			return
+		}
+
+		var directory = profileDirectory(folder, name)
+		if _, err := os.Stat(directory); err == nil {
+			Parrot.Println("Profile " + name + " already exists")
+			return
+		}
+
+		if err := os.MkdirAll(directory, 0700); err != nil {
+			Parrot.Println("Error creating the profile", err)
+			return
+		}
+
+		Parrot.Println("Profile " + name + " created, its settings go in " + filepath.Join(directory, ".ambros.yaml"))
+	},
+}
+
+// profileListCmd represents the profile list command
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List",
+	Long:  `List command, lists the profiles, the one in use marked with *`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Parrot.Debug("Profile list command invoked")
+
+		folder, err := quant.ExecutableFolder()
+		if err != nil {
+			Parrot.Println("Executable folder error", err)
+			return
+		}
+
+		var names = []string{}
+		entries, _ := os.ReadDir(profilesDirectory(folder))
+		for _, entry := range entries {
+			if entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range append([]string{defaultProfile}, names...) {
+			var mark = "  "
+			if name == Configuration.Profile || (name == defaultProfile && Configuration.Profile == "") {
+				mark = "* "
+			}
+			Parrot.Println(mark + name)
+		}
+	},
+}
+
+// profileUseCmd represents the profile use command
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Use",
+	Long: `Use command, makes a profile the one in use, default goes back to the repository of the configuration;
+--profile and $` + utils.ConstProfileEnv + ` still choose another one`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Parrot.Debug("Profile use command invoked")
+
+		name, err := stringFromArguments(args)
+		if err != nil {
+			Parrot.Println("Please provide a profile name")
+			return
+		}
+
+		folder, err := quant.ExecutableFolder()
+		if err != nil {
+			Parrot.Println("Executable folder error", err)
+			return
+		}
+
+		if name == defaultProfile {
+			if err := os.Remove(currentProfileFile(folder)); err != nil && !os.IsNotExist(err) {
+				Parrot.Println("Error changing the profile", err)
+				return
+			}
+			Parrot.Println("Done!")
+			return
+		}
+
+		if _, err := os.Stat(profileDirectory(folder, name)); err != nil || !profileRegexp.MatchString(name) {
+			Parrot.Println("Profile " + name + " not available, see 'ambros profile create " + name + "'")
+			return
+		}
+
+		if err := os.WriteFile(currentProfileFile(folder), []byte(name+"\n"), 0600); err != nil {
+			Parrot.Println("Error changing the profile", err)
+			return
+		}
+
+		Parrot.Println("Done!")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+func TestActiveProfile(t *testing.T) {
+	var folder = t.TempDir()
+	var previous = profileName
+	t.Cleanup(func() { profileName = previous })
+
+	profileName = ""
+	t.Setenv(utils.ConstProfileEnv, "")
+
+	if name := activeProfile(folder); name != "" {
+		t.Errorf("activeProfile() = %q, want the default profile", name)
+	}
+
+	if err := os.MkdirAll(filepath.Join(folder, utils.ConstRepositoryDirectory), 0755); err != nil {
+		t.Fatalf("MkdirAll returned unexpected error: %v", err)
+	}
+	if err := os.WriteFile(currentProfileFile(folder), []byte("work\n"), 0600); err != nil {
+		t.Fatalf("WriteFile returned unexpected error: %v", err)
+	}
+	if name := activeProfile(folder); name != "work" {
+		t.Errorf("activeProfile() = %q, want the profile in use", name)
+	}
+
+	t.Setenv(utils.ConstProfileEnv, "personal")
+	if name := activeProfile(folder); name != "personal" {
+		t.Errorf("activeProfile() = %q, want the profile of the environment", name)
+	}
+
+	profileName = "default"
+	if name := activeProfile(folder); name != "" {
+		t.Errorf("activeProfile() = %q, want the default profile given with --profile", name)
+	}
+
+	if directory := profileDirectory(folder, "work"); directory != filepath.Join(folder, utils.ConstRepositoryDirectory, "profiles", "work") {
+		t.Errorf("profileDirectory(work) = %q, want it in the repository directory", directory)
+	}
+}
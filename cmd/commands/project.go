@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// groupStats sums up the executions of a group of commands, e.g. of a project or of a branch
+type groupStats struct {
+	commandStats
+	Key string
+}
+
+// cwdFlag returns the absolute directory given with --cwd, the current one when given without a value,
+// empty when not given
+func cwdFlag(cmd *cobra.Command) (string, error) {
+	if !cmd.Flag("cwd").Changed {
+		return "", nil
+	}
+	return filepath.Abs(cmd.Flag("cwd").Value.String())
+}
+
+// addCwdFlag registers --cwd, keeping the commands run in a directory or below it
+func addCwdFlag(cmd *cobra.Command) {
+	cmd.Flags().String("cwd", "", "keeps the commands run in this directory or below it, the current one by default")
+	cmd.Flags().Lookup("cwd").NoOptDefVal = "."
+}
+
+// inDirectoryFilter selects the commands run in the directory or below it, any of them when empty
+func inDirectoryFilter(dir string) func(models.Command) bool {
+	if dir == "" {
+		return nil
+	}
+	return func(c models.Command) bool { return utils.WithinDirectory(c.Dir, dir) }
+}
+
+// onBranchFilter selects the commands run on the git branch, any of them when empty
+func onBranchFilter(branch string) func(models.Command) bool {
+	if branch == "" {
+		return nil
+	}
+	return func(c models.Command) bool { return c.Git != nil && c.Git.Branch == branch }
+}
+
+// statsBy sums up the executions of each group of commands, the most used first; the commands without
+// a group are left out
+func statsBy(commands []models.Command, group func(models.Command) string) []groupStats {
+	var groups = map[string]*groupStats{}
+
+	for _, c := range commands {
+		var key = group(c)
+		if key == "" {
+			continue
+		}
+		if groups[key] == nil {
+			groups[key] = &groupStats{Key: key}
+		}
+
+		var s = groups[key]
+		s.Count++
+		if !c.Status {
+			s.Failures++
+		}
+		s.Total += c.TerminatedAt.Sub(c.CreatedAt)
+		if c.CreatedAt.After(s.Last.CreatedAt) {
+			s.Last = c
+		}
+	}
+
+	var stats = []groupStats{}
+	for _, s := range groups {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	return stats
+}
+
+// statsByProject sums up the executions of each project
+func statsByProject(commands []models.Command) []groupStats {
+	return statsBy(commands, func(c models.Command) string { return c.Project })
+}
+
+// statsByBranch sums up the executions on each git branch
+func statsByBranch(commands []models.Command) []groupStats {
+	return statsBy(commands, func(c models.Command) string {
+		if c.Git == nil {
+			return ""
+		}
+		return c.Git.Branch
+	})
+}
+
+// currentProject returns the root of the project of the current directory and its commands
+func currentProject(commands []models.Command) (string, []models.Command, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var root = Utilities.ProjectRoot(dir)
+	if root == "" {
+		return "", nil, errors.New("Not in a project")
+	}
+
+	var inProject = []models.Command{}
+	for _, c := range commands {
+		if c.Project == root {
+			inProject = append(inProject, c)
+		}
+	}
+	return root, inProject, nil
+}
+
+// percentage renders a rate as a percentage, escaped for Tablify
+func percentage(rate float64) string {
+	return strconv.Itoa(int(rate*100)) + "%%"
+}
+
+// projectCmd represents the project command
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Project",
+	Long: `Project command, the history of the project of the current directory: the closest directory holding
+it which is a git repository or has an .ambros.yaml`,
+}
+
+// projectStatsCmd represents the project stats command
+var projectStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Stats",
+	Long: `Stats command, sums up the executions of the project of the current directory and lists its most run
+commands with their failures and duration; --all lists the projects instead`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Project stats command invoked")
+
+			commands, err := Repository.GetAllCommands()
+			if err != nil {
+				Parrot.Println("Error retrieving commands in the store", err)
+				return
+			}
+
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			if cmd.Flag("all").Changed {
+				var projects = statsByProject(commands)
+				if len(projects) == 0 {
+					Parrot.Println("No commands recorded in a project yet!")
+					return
+				}
+				if limit > 0 && len(projects) > limit {
+					projects = projects[:limit]
+				}
+
+				var rows = [][]string{}
+				for _, p := range projects {
+					rows = append(rows, []string{strings.ReplaceAll(p.Key, "%", "%%"), strconv.Itoa(p.Count),
+						percentage(p.FailureRate()), p.Last.CreatedAt.Format("02.01.2006 15:04:05")})
+				}
+				Parrot.Tablify([]string{"PROJECT", "RUNS", "FAILED", "LAST RUN"}, rows)
+				return
+			}
+
+			root, inProject, err := currentProject(commands)
+			if err != nil {
+				Parrot.Println(err.Error() + ", see 'ambros project stats --all'")
+				return
+			}
+
+			var projects = statsByProject(inProject)
+			if len(projects) == 0 {
+				Parrot.Println("No commands recorded in " + root + " yet!")
+				return
+			}
+
+			var project = projects[0]
+			Parrot.Println(root + ": " + strconv.Itoa(project.Count) + " runs, " + strconv.Itoa(project.Failures) +
+				" failed, " + project.Total.Round(time.Millisecond).String() + " spent running")
+
+			var ranked = []commandStats{}
+			for _, s := range statsByFingerprint(inProject) {
+				ranked = append(ranked, s)
+			}
+			sort.Slice(ranked, func(i, j int) bool {
+				if ranked[i].Count != ranked[j].Count {
+					return ranked[i].Count > ranked[j].Count
+				}
+				return commandLine(ranked[i].Last) < commandLine(ranked[j].Last)
+			})
+			if limit > 0 && len(ranked) > limit {
+				ranked = ranked[:limit]
+			}
+
+			var rows = [][]string{}
+			for _, s := range ranked {
+				rows = append(rows, []string{
+					// rows are printed as format strings by Tablify
+					strings.ReplaceAll(commandLine(s.Last), "%", "%%"),
+					strconv.Itoa(s.Count),
+					percentage(s.FailureRate()),
+					s.Average().Round(time.Millisecond).String(),
+					s.Last.CreatedAt.Format("02.01.2006 15:04:05"),
+				})
+			}
+			Parrot.Tablify([]string{"COMMAND", "RUNS", "FAILED", "AVG DURATION", "LAST RUN"}, rows)
+		})
+	},
+}
+
+// projectBranchesCmd represents the project branches command
+var projectBranchesCmd = &cobra.Command{
+	Use:   "branches",
+	Short: "Branches",
+	Long: `Branches command, lists the git branches the commands of the project of the current directory ran on,
+with how often they failed there, the most failing first; --all counts the commands of every project`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Project branches command invoked")
+
+			commands, err := Repository.GetAllCommands()
+			if err != nil {
+				Parrot.Println("Error retrieving commands in the store", err)
+				return
+			}
+
+			if !cmd.Flag("all").Changed {
+				if _, commands, err = currentProject(commands); err != nil {
+					Parrot.Println(err.Error() + ", see 'ambros project branches --all'")
+					return
+				}
+			}
+
+			var branches = statsByBranch(commands)
+			if len(branches) == 0 {
+				Parrot.Println("No commands recorded on a git branch yet!")
+				return
+			}
+			sort.SliceStable(branches, func(i, j int) bool { return branches[i].FailureRate() > branches[j].FailureRate() })
+
+			var rows = [][]string{}
+			for _, b := range branches {
+				rows = append(rows, []string{strings.ReplaceAll(b.Key, "%", "%%"), strconv.Itoa(b.Count),
+					strconv.Itoa(b.Failures), percentage(b.FailureRate()), b.Last.CreatedAt.Format("02.01.2006 15:04:05")})
+			}
+			Parrot.Tablify([]string{"BRANCH", "RUNS", "FAILED", "FAILURE RATE", "LAST RUN"}, rows)
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(projectCmd)
+	projectCmd.AddCommand(projectStatsCmd)
+	projectCmd.AddCommand(projectBranchesCmd)
+
+	projectStatsCmd.Flags().Bool("all", false, "lists the projects with their runs")
+	projectStatsCmd.Flags().Int("limit", 10, "how many commands or projects are listed")
+	projectBranchesCmd.Flags().Bool("all", false, "counts the commands of every project")
+}
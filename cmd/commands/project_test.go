@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+func TestInDirectory(t *testing.T) {
+	var root = t.TempDir()
+	var nested = filepath.Join(root, "cmd", "tool")
+	for _, dir := range []string{filepath.Join(root, ".git"), nested} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("MkdirAll returned unexpected error: %v", err)
+		}
+	}
+
+	var command = initializeCommand("make", nil)
+	if wd, _ := os.Getwd(); command.Dir != wd {
+		t.Errorf("initializeCommand recorded %q as directory, want the current one %q", command.Dir, wd)
+	}
+
+	inDirectory(&command, nested)
+	if command.Dir != nested || command.Project != root {
+		t.Errorf("inDirectory(%s) recorded %q in project %q, want project %q", nested, command.Dir, command.Project, root)
+	}
+
+	var filter = inDirectoryFilter(root)
+	if !filter(command) {
+		t.Errorf("inDirectoryFilter(%s) left out a command run in %s", root, nested)
+	}
+	if filter(models.Command{Dir: filepath.Dir(root)}) || filter(models.Command{}) {
+		t.Errorf("inDirectoryFilter(%s) kept a command run out of it", root)
+	}
+	if inDirectoryFilter("") != nil {
+		t.Errorf("inDirectoryFilter() returned a filter, want none")
+	}
+}
+
+func TestStatsByProject(t *testing.T) {
+	var start = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	var run = func(project string, status bool, minutes int) models.Command {
+		var command = models.Command{Name: "make", Status: status, Project: project}
+		command.CreatedAt = start.Add(time.Duration(minutes) * time.Minute)
+		command.TerminatedAt = command.CreatedAt.Add(time.Second)
+		return command
+	}
+
+	var stats = statsByProject([]models.Command{
+		run("/src/api", true, 0),
+		run("/src/web", false, 1),
+		run("/src/api", false, 2),
+		run("/src/api", true, 3),
+		run("", true, 4),
+	})
+
+	if len(stats) != 2 {
+		t.Fatalf("statsByProject returned %d projects, want 2", len(stats))
+	}
+
+	var api = stats[0]
+	if api.Key != "/src/api" || api.Count != 3 || api.Failures != 1 || api.Total != 3*time.Second ||
+		!api.Last.CreatedAt.Equal(start.Add(3*time.Minute)) {
+		t.Errorf("statsByProject()[0] = %+v, want /src/api with 3 runs, 1 failure, the last at 10:03", api)
+	}
+	if stats[1].Key != "/src/web" || stats[1].Failures != 1 {
+		t.Errorf("statsByProject()[1] = %+v, want /src/web with 1 failure", stats[1])
+	}
+}
+
+func TestStatsByBranch(t *testing.T) {
+	var run = func(branch string, status bool) models.Command {
+		var command = models.Command{Name: "go", Arguments: []string{"test"}, Status: status}
+		if branch != "" {
+			command.Git = &models.Git{Branch: branch, Commit: "4f2a9c1"}
+		}
+		return command
+	}
+
+	var commands = []models.Command{run("main", true), run("login", false), run("main", false), run("login", false), run("", false)}
+
+	var failures = map[string]int{}
+	for _, s := range statsByBranch(commands) {
+		failures[s.Key] = s.Failures
+	}
+	if len(failures) != 2 || failures["main"] != 1 || failures["login"] != 2 {
+		t.Errorf("statsByBranch() failures = %v, want 1 on main and 2 on login", failures)
+	}
+
+	var filter = allFilters(nil, onBranchFilter("login"), func(c models.Command) bool { return !c.Status })
+	var selected = 0
+	for _, c := range commands {
+		if filter(c) {
+			selected++
+		}
+	}
+	if selected != 2 {
+		t.Errorf("allFilters(login, failed) selected %d commands, want 2", selected)
+	}
+
+	if allFilters(nil, onBranchFilter("")) != nil {
+		t.Errorf("allFilters() returned a filter, want none")
+	}
+}
@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// first file descriptor passed by socket activation (sd_listen_fds)
+const listenFdsStart = 3
+
+// queryRequest is a single line sent to queryd: Op is last, search, show, diff (of ID with With), compare
+// (of the chain run ID with With) or reload
+type queryRequest struct {
+	Op    string `json:"Op"`
+	Count int    `json:"Count,omitempty"`
+	Text  string `json:"Text,omitempty"`
+	ID    string `json:"ID,omitempty"`
+	With  string `json:"With,omitempty"`
+
+	// RequestID correlates the request with the log of queryd, one is generated when missing
+	RequestID string `json:"RequestID,omitempty"`
+}
+
+// queryResponse is the line answered by queryd
+type queryResponse struct {
+	Commands []models.Command `json:"Commands,omitempty"`
+	Command  *models.Command  `json:"Command,omitempty"`
+	Diff     *commandDiff     `json:"Diff,omitempty"`
+	Compare  *chainComparison `json:"Compare,omitempty"`
+	Error    string           `json:"Error,omitempty"`
+
+	RequestID string `json:"RequestID,omitempty"`
+
+	// the settings changed by a reload, applied and waiting for a restart
+	Applied []string `json:"Applied,omitempty"`
+	Restart []string `json:"Restart,omitempty"`
+}
+
+// queryLock serializes the queries, each one opens the repository read only while it runs
+var queryLock sync.Mutex
+
+func answerQuery(request queryRequest) queryResponse {
+	queryLock.Lock()
+	defer queryLock.Unlock()
+
+	if request.Op == "reload" {
+		applied, restart, err := reloadConfiguration()
+		reportReload(applied, restart, err)
+		if err != nil {
+			return queryResponse{Error: err.Error()}
+		}
+		return queryResponse{Applied: applied, Restart: restart}
+	}
+
+	if err := Repository.InitReadOnlyDB(); err != nil {
+		return queryResponse{Error: err.Error()}
+	}
+	defer Repository.CloseDB()
+
+	var count = request.Count
+	if count <= 0 {
+		count = Configuration.LastCountDefault
+	}
+
+	switch request.Op {
+	case "last":
+		commands, err := Repository.GetLimitCommands(count)
+		if err != nil {
+			return queryResponse{Error: err.Error()}
+		}
+		return queryResponse{Commands: commands}
+
+	case "search":
+		var text = strings.ToLower(request.Text)
+		commands, err := Repository.FilterLimitCommands(count, func(c models.Command) bool {
+			return strings.Contains(strings.ToLower(c.Name+" "+strings.Join(c.Arguments, " ")), text)
+		})
+		if err != nil {
+			return queryResponse{Error: err.Error()}
+		}
+		return queryResponse{Commands: commands}
+
+	case "show":
+		command, err := findCommand(resolveID(request.ID))
+		if err != nil {
+			return queryResponse{Error: "command not available (" + request.ID + ")"}
+		}
+		return queryResponse{Command: &command}
+
+	case "diff":
+		from, err := Repository.FindById(resolveID(request.ID))
+		if err != nil {
+			return queryResponse{Error: "command not available (" + request.ID + ")"}
+		}
+		to, err := Repository.FindById(resolveID(request.With))
+		if err != nil {
+			return queryResponse{Error: "command not available (" + request.With + ")"}
+		}
+
+		var diff = diffCommands(from, to, 3)
+		return queryResponse{Diff: &diff}
+
+	case "compare":
+		from, to, err := findChainRuns(request.ID, request.With)
+		if err != nil {
+			return queryResponse{Error: err.Error()}
+		}
+
+		var comparison = compareChainRuns(from, to)
+		return queryResponse{Compare: &comparison}
+
+	default:
+		return queryResponse{Error: "unknown op '" + request.Op + "', use last, search, show, diff, compare or reload"}
+	}
+}
+
+// recoverQuery answers a request turning a panic into an error response, so a faulty query does not
+// stop queryd; the stack is logged with the id of the request
+func recoverQuery(request queryRequest) (response queryResponse) {
+	defer func() {
+		if r := recover(); r != nil {
+			Parrot.Error("Query "+request.RequestID+" ("+request.Op+") failed:", r, "\n"+string(debug.Stack()))
+			response = queryResponse{Error: "internal error, see the log of queryd for request " + request.RequestID}
+		}
+	}()
+
+	Parrot.Debug("--> Query " + request.RequestID + " (" + request.Op + ")")
+	return answerQuery(request)
+}
+
+func serveQueries(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		var request = queryRequest{}
+		var response queryResponse
+
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response = queryResponse{Error: "invalid request: " + err.Error()}
+		} else {
+			if request.RequestID == "" {
+				request.RequestID = Utilities.Random()
+			}
+			response = recoverQuery(request)
+		}
+		response.RequestID = request.RequestID
+
+		if err := encoder.Encode(response); err != nil {
+			return
+		}
+	}
+}
+
+// activatedListener returns the socket passed by systemd socket activation, if any
+func activatedListener() (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	if fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS")); err != nil || fds < 1 {
+		return nil, errors.New("socket activation without sockets")
+	}
+
+	return net.FileListener(os.NewFile(listenFdsStart, "LISTEN_FD_3"))
+}
+
+// querydCmd represents the queryd command
+var querydCmd = &cobra.Command{
+	Use:   "queryd",
+	Short: "Queryd",
+	Long: `Queryd command, answers history queries on a unix socket for editors and shell widgets;
+each line sent is a json request ({"Op": "last", "Count": 10}, {"Op": "search", "Text": "docker"},
+{"Op": "show", "ID": "..."} or {"Op": "diff", "ID": "...", "With": "..."}) answered by a json line, carrying the RequestID of the request or a
+generated one. It can be started by systemd socket activation.
+SIGHUP or {"Op": "reload"} reads the configuration again, the repository location needs a restart`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Parrot.Debug("Queryd command invoked")
+
+		listener, err := activatedListener()
+		if err != nil {
+			Parrot.Println("Error reading the activated socket", err)
+			return
+		}
+
+		if listener == nil {
+			var socket = cmd.Flag("socket").Value.String()
+			if socket == "" {
+				socket = filepath.Join(Configuration.RepositoryDirectory, "query.sock")
+			}
+
+			// a socket nobody answers on is left by an instance which did not stop cleanly
+			if conn, err := net.Dial("unix", socket); err == nil {
+				conn.Close()
+				Parrot.Println("Queryd is already listening on " + socket)
+				return
+			}
+			os.Remove(socket)
+
+			if listener, err = net.Listen("unix", socket); err != nil {
+				Parrot.Println("Impossible to listen on "+socket, err)
+				return
+			}
+			os.Chmod(socket, 0600)
+
+			Parrot.Println("Listening on " + socket)
+		}
+
+		var signals = make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		go func() {
+			for s := range signals {
+				if s == syscall.SIGHUP {
+					recoverQuery(queryRequest{Op: "reload", RequestID: "SIGHUP"})
+					continue
+				}
+				listener.Close()
+				return
+			}
+		}()
+
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveQueries(conn)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(querydCmd)
+
+	querydCmd.Flags().String("socket", "", "path of the unix socket (default query.sock in the repository directory)")
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestServeQueriesRecoversPanic(t *testing.T) {
+	useScriptedExecutor(t)
+
+	// queryd opens the repository for each query
+	Repository.CloseDB()
+
+	var repository = Repository
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go serveQueries(server)
+
+	var reader = bufio.NewReader(client)
+	var ask = func(line string) queryResponse {
+		t.Helper()
+
+		if _, err := client.Write([]byte(line + "\n")); err != nil {
+			t.Fatalf("writing %s returned unexpected error: %v", line, err)
+		}
+
+		answer, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("reading the answer to %s returned unexpected error: %v", line, err)
+		}
+
+		var response = queryResponse{}
+		if err := json.Unmarshal(answer, &response); err != nil {
+			t.Fatalf("answer %s is not a response: %v", answer, err)
+		}
+		return response
+	}
+
+	// without a repository the query panics
+	Repository = nil
+	response := ask(`{"Op": "last", "RequestID": "req-1"}`)
+	Repository = repository
+
+	if response.RequestID != "req-1" || !strings.Contains(response.Error, "internal error") || !strings.Contains(response.Error, "req-1") {
+		t.Errorf("panicking query answered %+v, want an internal error of request req-1", response)
+	}
+
+	// queryd still answers, with a generated request id
+	response = ask(`{"Op": "last"}`)
+	if response.Error != "" || response.RequestID == "" {
+		t.Errorf("query after the panic answered %+v, want the commands and a request id", response)
+	}
+}
@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 
 	models "github.com/gi4nks/ambros/internal/models"
@@ -25,9 +27,9 @@ var recallCmd = &cobra.Command{
 			var err error
 
 			if cmd.Flag("history").Changed == true {
-				stored, err = Repository.FindInStoreById(id)
+				stored, err = Repository.FindInStoreById(resolveID(id))
 			} else {
-				stored, err = Repository.FindById(id)
+				stored, err = Repository.FindById(resolveID(id))
 			}
 
 			if err != nil {
@@ -36,10 +38,28 @@ var recallCmd = &cobra.Command{
 			}
 
 			var command = initializeCommand(stored.Name, stored.Arguments)
+			command.AllowedExitCodes = stored.AllowedExitCodes
+
+			command.Environment = chooseEnvironment(cmd, stored.Environment)
+			if command.Environment != "" {
+				if err := applyEnvironment(command.Environment); err != nil {
+					Parrot.Println("Error applying the environment "+command.Environment, err)
+					return
+				}
+			}
+
+			assessRisk(&command)
+			if !confirmRisk(command, cmd.Flag("yes").Changed, os.Stdin) {
+				return
+			}
 
 			executeCommand(&command)
 			finalizeCommand(&command)
 
+			if cmd.Flag("history").Changed == true {
+				Repository.TouchStoredCommand(stored.ID, command.TerminatedAt)
+			}
+
 			if cmd.Flag("store").Changed == true {
 				//Parrot.Println("Storing command")
 				pushCommand(&command, false)
@@ -52,4 +72,7 @@ func init() {
 	RootCmd.AddCommand(recallCmd)
 	recallCmd.Flags().BoolP("history", "y", false, "Recalls a command from history")
 	recallCmd.Flags().BoolP("store", "s", false, "Store the results")
+	recallCmd.Flags().String("env", "", "Runs the command with the given environment instead of the one it ran with")
+	recallCmd.Flags().Bool("yes", false, "Runs a high risk command without asking for confirmation")
+	recallCmd.Flags().Bool("no-env", false, "Runs the command without any environment")
 }
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// parseEpoch reads an instant as epoch seconds, as $EPOCHREALTIME writes it
+func parseEpoch(value string) (time.Time, error) {
+	// the decimal separator of $EPOCHREALTIME follows the locale
+	seconds, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, int64(seconds*float64(time.Second))), nil
+}
+
+// recordCmd represents the record command
+var recordCmd = &cobra.Command{
+	Use:   "record -- <command line>",
+	Short: "Record",
+	Long: `Record command, stores a command executed by the shell without running it again;
+the hooks printed by 'ambros shell-init' call it after each command. The ambros commands are not
+recorded, they record themselves`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Record command invoked")
+
+			// the hooks pass the line as typed, a single argument
+			var parts = args
+			if len(args) == 1 {
+				parts = utils.SplitCommandLine(args[0])
+			}
+
+			if len(parts) == 0 {
+				Parrot.Println("Please provide the command line to record")
+				return
+			}
+
+			if filepath.Base(parts[0]) == filepath.Base(os.Args[0]) {
+				return
+			}
+
+			exitCode, _ := cmd.Flags().GetInt("exit-code")
+			duration, _ := cmd.Flags().GetDuration("duration")
+
+			var command = initializeCommand(parts[0], parts[1:])
+			command.TerminatedAt = time.Now()
+
+			// the hooks run in the background, the recording may come later than the end of the command
+			if value := cmd.Flag("finished").Value.String(); value != "" {
+				finished, err := parseEpoch(value)
+				if err != nil || finished.After(command.TerminatedAt) {
+					Parrot.Println("Please provide a valid --finished, in epoch seconds")
+					return
+				}
+				command.TerminatedAt = finished
+			}
+
+			command.CreatedAt = command.TerminatedAt.Add(-duration)
+
+			if value := cmd.Flag("started").Value.String(); value != "" {
+				started, err := parseEpoch(value)
+				if err != nil || started.After(command.TerminatedAt) {
+					Parrot.Println("Please provide a valid --started, in epoch seconds")
+					return
+				}
+				command.CreatedAt = started
+			}
+
+			command.ExitCode = exitCode
+			command.Status = exitCode == 0 || allowedExitCodes(&command).Contains(exitCode)
+			if !command.Status {
+				command.Error = "exit status " + strconv.Itoa(exitCode)
+			}
+
+			assessRisk(&command)
+
+			recorded, err := recordCommand(&command)
+			if err != nil {
+				Parrot.Error("Error storing the command", err)
+				return
+			}
+
+			printRecorded(&command, recorded)
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(recordCmd)
+
+	recordCmd.Flags().Int("exit-code", 0, "the exit code of the command")
+	recordCmd.Flags().Duration("duration", 0, "how long the command ran")
+	recordCmd.Flags().String("started", "", "when the command started, in epoch seconds (overrides --duration)")
+	recordCmd.Flags().String("finished", "", "when the command finished, in epoch seconds (default now)")
+}
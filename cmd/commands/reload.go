@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/gi4nks/quant"
+	"github.com/spf13/viper"
+)
+
+// settings the long running commands keep until they are restarted, the repository they work on
+var restartSettings = map[string]bool{"RepositoryDirectory": true, "RepositoryFile": true, "Profile": true}
+
+// configurationKey returns the key of a setting in the configuration file: DebugMode is debugMode,
+// OTLPEndpoint is otlpEndpoint
+func configurationKey(field string) string {
+	var runes = []rune(field)
+
+	var upper = 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) {
+		upper--
+	}
+
+	return strings.ToLower(string(runes[:upper])) + string(runes[upper:])
+}
+
+// reloadConfiguration reads the configuration file again and applies what changed, but the settings
+// which need a restart; it returns the keys of the settings applied and of the ones left as they were
+func reloadConfiguration() ([]string, []string, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, nil, err
+	}
+
+	folder, err := quant.ExecutableFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mergeProfileSettings(folder)
+	var next = readConfiguration(folder)
+	var applied, restart = []string{}, []string{}
+
+	var current = reflect.ValueOf(Configuration).Elem()
+	var updated = reflect.ValueOf(next).Elem()
+
+	for i := 0; i < current.NumField(); i++ {
+		var field = current.Type().Field(i)
+		if !field.IsExported() || reflect.DeepEqual(current.Field(i).Interface(), updated.Field(i).Interface()) {
+			continue
+		}
+
+		if restartSettings[field.Name] {
+			restart = append(restart, configurationKey(field.Name))
+			updated.Field(i).Set(current.Field(i))
+			continue
+		}
+
+		applied = append(applied, configurationKey(field.Name))
+	}
+
+	Configuration = next
+
+	if Configuration.DebugMode {
+		Parrot = quant.NewVerboseParrot("ambros")
+	} else {
+		Parrot = quant.NewParrot("ambros")
+	}
+
+	return applied, restart, nil
+}
+
+// reportReload tells what a reload changed
+func reportReload(applied []string, restart []string, err error) {
+	if err != nil {
+		Parrot.Println("Error reloading the configuration", err)
+		return
+	}
+
+	if len(applied) == 0 && len(restart) == 0 {
+		Parrot.Println("Configuration reloaded, nothing changed")
+		return
+	}
+
+	if len(applied) > 0 {
+		Parrot.Println("Configuration reloaded, applied: " + strings.Join(applied, ", "))
+	}
+	if len(restart) > 0 {
+		Parrot.Println("Changed but applied only after a restart: " + strings.Join(restart, ", "))
+	}
+}
@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// how many matching commands the picker lists
+const pickerSize = 15
+
+// how many lines of the output the picker previews
+const previewLines = 20
+
+func commandLine(c models.Command) string {
+	return strings.TrimSpace(c.Name + " " + strings.Join(c.Arguments, " "))
+}
+
+// uniqueCommandLines keeps the latest execution of each command line, the commands come the latest first
+func uniqueCommandLines(commands []models.Command) []models.Command {
+	var seen = map[string]bool{}
+	var unique = []models.Command{}
+
+	for _, c := range commands {
+		if seen[commandLine(c)] {
+			continue
+		}
+		seen[commandLine(c)] = true
+		unique = append(unique, c)
+	}
+
+	return unique
+}
+
+// rankCommands returns the commands matching the query, the best matches first and the latest among equals
+func rankCommands(commands []models.Command, query string) []models.Command {
+	var ranked = []models.Command{}
+	var scores = map[string]int{}
+
+	for _, c := range commands {
+		if score, matched := utils.FuzzyScore(commandLine(c), query); matched {
+			ranked = append(ranked, c)
+			scores[c.ID] = score
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].ID] > scores[ranked[j].ID]
+	})
+
+	return ranked
+}
+
+// previewCommand prints the first lines of the output of a command
+func previewCommand(c models.Command) {
+	var output = c.Output + c.Error
+	if c.Streamed || c.Cold {
+		streamed, _ := io.ReadAll(Repository.GetOutputReader(c.ID))
+		output = string(streamed) + c.Error
+	}
+
+	var lines = strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if strings.TrimSpace(output) == "" {
+		lines = []string{"<no output>"}
+	}
+	if len(lines) > previewLines {
+		lines = append(lines[:previewLines], "... "+strconv.Itoa(len(lines)-previewLines)+" more lines, see 'ambros output "+c.ID+"'")
+	}
+
+	Parrot.Println("--- " + c.AsStoredCommand() + " (status: " + strconv.FormatBool(c.Status) + ")")
+	for _, line := range lines {
+		Parrot.Println(line)
+	}
+	Parrot.Println("---")
+}
+
+// pickCommand lists the commands matching what the user types, until one is chosen by its number
+func pickCommand(commands []models.Command, query string, reader *bufio.Reader) (models.Command, bool) {
+	for {
+		var matching = rankCommands(commands, query)
+		if len(matching) > pickerSize {
+			matching = matching[:pickerSize]
+		}
+
+		if len(matching) == 0 {
+			Parrot.Println("No commands match '" + query + "'")
+		}
+		for i, c := range matching {
+			Parrot.Println(strconv.Itoa(i+1) + ") " + c.AsStoredCommand())
+		}
+
+		os.Stdout.WriteString("[" + query + "] text filters, a number runs, p <number> previews, q quits: ")
+
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" && err != nil {
+			return models.Command{}, false
+		}
+
+		var preview = strings.HasPrefix(line, "p ")
+		if n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "p "))); err == nil && n >= 1 && n <= len(matching) {
+			if !preview {
+				return matching[n-1], true
+			}
+
+			previewCommand(matching[n-1])
+			continue
+		}
+
+		switch line {
+		case "q":
+			return models.Command{}, false
+		case "":
+			continue
+		}
+
+		query = line
+	}
+}
+
+// editCommandLine shows the command line and returns it as changed by the user, the same when left empty
+func editCommandLine(c models.Command, reader *bufio.Reader) ([]string, error) {
+	Parrot.Println(utils.JoinCommandLine(append([]string{c.Name}, c.Arguments...)))
+	os.Stdout.WriteString("Edit the command line (empty keeps it): ")
+
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if strings.TrimSpace(line) == "" {
+		return append([]string{c.Name}, c.Arguments...), nil
+	}
+
+	var parts = utils.SplitCommandLine(line)
+	if len(parts) == 0 {
+		return nil, errors.New("empty command line")
+	}
+	return parts, nil
+}
+
+// editInEditor opens the command line in the editor and returns it as saved, the lines starting with #
+// left out; the editor may have arguments, e.g. "code -w"
+func editInEditor(c models.Command, editor string) ([]string, error) {
+	file, err := os.CreateTemp("", "ambros-rerun-*.sh")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+
+	var content = "# Edit the command line of " + c.ID + ", the lines starting with # are ignored\n" +
+		utils.JoinCommandLine(append([]string{c.Name}, c.Arguments...)) + "\n"
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return nil, err
+	}
+	file.Close()
+
+	var edit = exec.Command("sh", "-c", editor+` "$1"`, "sh", file.Name())
+	edit.Stdin, edit.Stdout, edit.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := edit.Run(); err != nil {
+		return nil, err
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	var lines = []string{}
+	for _, line := range strings.Split(string(edited), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	var parts = utils.SplitCommandLine(strings.Join(lines, " "))
+	if len(parts) == 0 {
+		return nil, errors.New("empty command line")
+	}
+	return parts, nil
+}
+
+// replaceArguments applies the old=new substitutions to the arguments of the command line, in order
+func replaceArguments(parts []string, replacements []string) ([]string, error) {
+	var replaced = append([]string{}, parts...)
+
+	for _, replacement := range replacements {
+		old, with, found := strings.Cut(replacement, "=")
+		if !found || old == "" {
+			return nil, errors.New("invalid replacement " + replacement + ", use old=new")
+		}
+
+		for i := 1; i < len(replaced); i++ {
+			replaced[i] = strings.ReplaceAll(replaced[i], old, with)
+		}
+	}
+
+	return replaced, nil
+}
+
+// rerunCommand runs again a command of the history with its environment and allowed exit codes, as
+// recall does, with the given command line; the new execution links to the one it runs again
+func rerunCommand(cmd *cobra.Command, parts []string, chosen models.Command, input io.Reader) {
+	var command = initializeCommand(parts[0], parts[1:])
+	command.AllowedExitCodes = chosen.AllowedExitCodes
+	command.ParentID = chosen.ID
+
+	command.Environment = chooseEnvironment(cmd, chosen.Environment)
+	if command.Environment != "" {
+		if err := applyEnvironment(command.Environment); err != nil {
+			Parrot.Println("Error applying the environment "+command.Environment, err)
+			return
+		}
+	}
+
+	assessRisk(&command)
+	if !confirmRisk(command, cmd.Flag("yes").Changed, input) {
+		return
+	}
+
+	executeCommand(&command)
+	finalizeCommand(&command)
+}
+
+// rerunCmd represents the rerun command
+var rerunCmd = &cobra.Command{
+	Use:   "rerun [id | -i [text]]",
+	Short: "Rerun",
+	Long: `Rerun command, runs again the command of the history with the given id, the latest by default;
+with -i it lists the history filtered by what is typed, as fzf does, to preview the output of a command
+and run it. --edit changes its command line first, in $EDITOR when set, and --replace old=new substitutes
+text in its arguments. The new execution links to the one it runs again, see 'ambros show <id>'`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Rerun command invoked")
+
+			var reader = bufio.NewReader(os.Stdin)
+			var chosen models.Command
+			var err error
+
+			if len(args) > 0 && !cmd.Flag("interactive").Changed {
+				if chosen, err = Repository.FindById(resolveID(args[0])); err != nil {
+					Parrot.Println("Id not available in the history (" + args[0] + ")")
+					return
+				}
+			} else {
+				limit, _ := cmd.Flags().GetInt("limit")
+
+				commands, err := Repository.GetLimitCommands(limit)
+				if err != nil {
+					Parrot.Println("Error retrieving commands", err)
+					return
+				}
+
+				commands = uniqueCommandLines(commands)
+				if len(commands) == 0 {
+					Parrot.Println("No commands in the history!")
+					return
+				}
+
+				chosen = commands[0]
+				if cmd.Flag("interactive").Changed {
+					var picked bool
+					if chosen, picked = pickCommand(commands, strings.Join(args, " "), reader); !picked {
+						return
+					}
+				}
+			}
+
+			var parts = append([]string{chosen.Name}, chosen.Arguments...)
+
+			replacements, _ := cmd.Flags().GetStringArray("replace")
+			if parts, err = replaceArguments(parts, replacements); err != nil {
+				Parrot.Println("Please provide valid replacements", err)
+				return
+			}
+
+			if cmd.Flag("edit").Changed {
+				var edited = chosen
+				edited.Name, edited.Arguments = parts[0], parts[1:]
+
+				if editor := os.Getenv("EDITOR"); editor != "" {
+					parts, err = editInEditor(edited, editor)
+				} else {
+					parts, err = editCommandLine(edited, reader)
+				}
+				if err != nil {
+					Parrot.Println("Error reading the command line", err)
+					return
+				}
+			}
+
+			rerunCommand(cmd, parts, chosen, reader)
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(rerunCmd)
+
+	rerunCmd.Flags().BoolP("interactive", "i", false, "chooses the command from the history, filtering it by typing")
+	rerunCmd.Flags().BoolP("edit", "e", false, "changes the command line before running it, in $EDITOR when set")
+	rerunCmd.Flags().StringArray("replace", []string{}, "replaces text in the arguments before running the command, as old=new (repeatable)")
+	rerunCmd.Flags().Int("limit", 1000, "how many history commands are searched")
+	rerunCmd.Flags().String("env", "", "Runs the command with the given environment instead of the one it ran with")
+	rerunCmd.Flags().Bool("yes", false, "Runs a high risk command without asking for confirmation")
+	rerunCmd.Flags().Bool("no-env", false, "Runs the command without any environment")
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+func TestPickCommand(t *testing.T) {
+	// the latest first, as the history returns them
+	var commands = uniqueCommandLines([]models.Command{
+		{Entity: models.Entity{ID: "4"}, Name: "docker", Arguments: []string{"ps"}},
+		{Entity: models.Entity{ID: "3"}, Name: "git", Arguments: []string{"status"}},
+		{Entity: models.Entity{ID: "2"}, Name: "docker", Arguments: []string{"compose", "up", "-d"}},
+		{Entity: models.Entity{ID: "1"}, Name: "docker", Arguments: []string{"ps"}},
+	})
+
+	if len(commands) != 3 {
+		t.Fatalf("uniqueCommandLines kept %d commands, want 3", len(commands))
+	}
+
+	var tests = []struct {
+		query  string
+		input  string
+		id     string
+		picked bool
+	}{
+		{"", "2\n", "3", true},
+		{"", "dcu\n1\n", "2", true},
+		{"docker", "p 1\n2\n", "2", true},
+		{"", "kubectl\nq\n", "", false},
+		{"", "git", "", false},
+		{"", "7\n", "", false},
+	}
+
+	for _, test := range tests {
+		chosen, picked := pickCommand(commands, test.query, bufio.NewReader(strings.NewReader(test.input)))
+		if picked != test.picked || chosen.ID != test.id {
+			t.Errorf("pickCommand(%q, %q) = %s %v, want %s %v", test.query, test.input, chosen.ID, picked, test.id, test.picked)
+		}
+	}
+}
+
+func TestEditCommandLine(t *testing.T) {
+	var command = models.Command{Name: "ls", Arguments: []string{"-la"}}
+
+	var tests = []struct {
+		input string
+		parts []string
+	}{
+		{"\n", []string{"ls", "-la"}},
+		{"", []string{"ls", "-la"}},
+		{"ls -la 'my dir'\n", []string{"ls", "-la", "my dir"}},
+	}
+
+	for _, test := range tests {
+		parts, err := editCommandLine(command, bufio.NewReader(strings.NewReader(test.input)))
+		if err != nil || strings.Join(parts, "|") != strings.Join(test.parts, "|") {
+			t.Errorf("editCommandLine(%q) = %q %v, want %q", test.input, parts, err, test.parts)
+		}
+	}
+}
+
+func TestReplaceArguments(t *testing.T) {
+	var parts = []string{"kubectl", "get", "pods", "-n", "staging", "--context=staging-eu"}
+
+	replaced, err := replaceArguments(parts, []string{"staging=production", "-eu=-us"})
+	if err != nil || strings.Join(replaced, " ") != "kubectl get pods -n production --context=production-us" {
+		t.Errorf("replaceArguments() = %q %v, want the staging arguments on production-us", replaced, err)
+	}
+	if strings.Join(parts, " ") != "kubectl get pods -n staging --context=staging-eu" {
+		t.Errorf("replaceArguments() changed its input to %q", parts)
+	}
+
+	// the command name is left as it is
+	if replaced, _ := replaceArguments([]string{"kubectl", "kubectl"}, []string{"kubectl=oc"}); strings.Join(replaced, " ") != "kubectl oc" {
+		t.Errorf("replaceArguments() = %q, want only the arguments replaced", replaced)
+	}
+
+	for _, replacement := range []string{"staging", "=production"} {
+		if _, err := replaceArguments(parts, []string{replacement}); err == nil {
+			t.Errorf("replaceArguments(%q) returned no error, want one", replacement)
+		}
+	}
+}
+
+func TestEditInEditor(t *testing.T) {
+	var command = models.Command{Name: "ls", Arguments: []string{"-la", "my dir"}}
+
+	parts, err := editInEditor(command, "sed -i 's/-la/-l/'")
+	if err != nil || strings.Join(parts, "|") != "ls|-l|my dir" {
+		t.Errorf("editInEditor() = %q %v, want the edited command line", parts, err)
+	}
+
+	if _, err := editInEditor(command, "sed -i '/^ls/d'"); err == nil {
+		t.Errorf("editInEditor() of an emptied file returned no error, want one")
+	}
+}
+
+func TestRerunCommandLinksParent(t *testing.T) {
+	useScriptedExecutor(t, scriptedRule{Pattern: "^deploy"})
+
+	var original = initializeCommand("deploy", []string{"staging"})
+	executeCommand(&original)
+	finalizeCommand(&original)
+
+	rerunCommand(rerunCmd, []string{"deploy", "production"}, original, strings.NewReader(""))
+
+	commands, err := Repository.GetLimitCommands(1)
+	if err != nil || len(commands) != 1 {
+		t.Fatalf("GetLimitCommands(1) = %v %v, want the rerun", commands, err)
+	}
+	if commands[0].ParentID != original.ID || commandLine(commands[0]) != "deploy production" {
+		t.Errorf("rerun recorded %q with parent %q, want 'deploy production' with parent %q", commandLine(commands[0]),
+			commands[0].ParentID, original.ID)
+	}
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// assessRisk scores the command with the environment it is about to run in
+func assessRisk(command *models.Command) {
+	var environ = append(os.Environ(), environmentVariables...)
+
+	var risk = Utilities.Risk(command.Name, command.Arguments, environ, command.Environment, Configuration.RiskPatterns)
+	if risk.Score > 0 {
+		command.Risk = &risk
+	} else {
+		command.Risk = nil
+	}
+}
+
+func describeRisk(risk models.Risk) string {
+	return risk.Level() + " (" + strconv.Itoa(risk.Score) + "): " + strings.Join(risk.Reasons, ", ")
+}
+
+// confirmRisk shows the risk of running a command again and, when it is high, asks to confirm
+func confirmRisk(command models.Command, yes bool, input io.Reader) bool {
+	if command.Risk == nil || command.Risk.Score < models.RiskMedium {
+		return true
+	}
+
+	Parrot.Println("Risk " + describeRisk(*command.Risk))
+
+	if command.Risk.Score < models.RiskHigh || yes {
+		return true
+	}
+
+	os.Stdout.WriteString(command.Name + " " + strings.Join(command.Arguments, " ") + "\nRun it anyway? [y/N] ")
+
+	answer, _ := bufio.NewReader(input).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		Parrot.Println("Not executed")
+		return false
+	}
+}
@@ -16,17 +16,23 @@ package commands
 
 import (
 	"os"
+	"regexp"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/gi4nks/quant"
 
+	notify "github.com/gi4nks/ambros/internal/notify"
 	repos "github.com/gi4nks/ambros/internal/repos"
 	utils "github.com/gi4nks/ambros/internal/utils"
 )
 
 var cfgFile string
+var noRedact bool
+var invokedCommand string
+var profileName string
 
 var Parrot = quant.NewParrot("ambros")
 var Utilities = utils.NewUtilities(*Parrot)
@@ -41,6 +47,9 @@ var RootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		invokedCommand = cmd.CommandPath()
+	},
 }
 
 // Execute adds all child commands to the root command sets flags appropriately.
@@ -60,6 +69,8 @@ func init() {
 	// will be global for your application.
 
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is <executable folder>/.ambros.yaml)")
+	RootCmd.PersistentFlags().BoolVar(&noRedact, "no-redact", false, "records commands and outputs without redacting the secrets found")
+	RootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "the profile whose history and settings are used (default $"+utils.ConstProfileEnv+" or the one in use)")
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	RootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
@@ -90,26 +101,144 @@ func initConfig() {
 		Parrot.Debug("Using config file:", viper.ConfigFileUsed())
 	}
 
-	if viper.GetString("repositoryDirectory") != "" {
-		Configuration.RepositoryDirectory = folder + "/" + viper.GetString("repositoryDirectory")
-	} else {
-		Configuration.RepositoryDirectory = folder + "/" + Configuration.RepositoryDirectory
+	mergeProfileSettings(folder)
+	Configuration = readConfiguration(folder)
+
+	if Configuration.DebugMode {
+		Parrot = quant.NewVerboseParrot("ambros")
+	}
+
+	Repository = repos.NewRepository(*Parrot, *Configuration)
+
+}
+
+// readConfiguration builds the configuration from the settings read by viper
+func readConfiguration(folder string) *utils.Configuration {
+	var configuration = utils.NewConfiguration(*Parrot)
+
+	configuration.RepositoryDirectory = repositoryDirectory(folder)
+	if configuration.Profile = activeProfile(folder); configuration.Profile != "" {
+		configuration.RepositoryDirectory = profileDirectory(folder, configuration.Profile)
 	}
 
 	if viper.GetString("repositoryFile") != "" {
-		Configuration.RepositoryFile = viper.GetString("repositoryFile")
+		configuration.RepositoryFile = viper.GetString("repositoryFile")
 	}
 
 	if viper.GetInt("lastCountDefault") >= 0 {
-		Configuration.LastCountDefault = viper.GetInt("lastCountDefault")
+		configuration.LastCountDefault = viper.GetInt("lastCountDefault")
 	}
 
-	Configuration.DebugMode = viper.GetBool("debugMode")
+	configuration.DebugMode = viper.GetBool("debugMode")
+	configuration.PerfMode = viper.GetBool("perfMode")
+	configuration.HostSnapshot = viper.GetBool("hostSnapshot")
+	configuration.ReproProbe = viper.GetBool("reproProbe")
+	configuration.NotifyOnFailure = viper.GetBool("notifyOnFailure")
 
-	if Configuration.DebugMode {
-		Parrot = quant.NewVerboseParrot("ambros")
+	if viper.IsSet("gitContext") {
+		configuration.GitContext = viper.GetBool("gitContext")
 	}
 
-	Repository = repos.NewRepository(*Parrot, *Configuration)
+	if viper.IsSet("undoDepth") {
+		configuration.UndoDepth = viper.GetInt("undoDepth")
+	}
+
+	for key, value := range map[string]*time.Duration{"staleAfter": &configuration.StaleAfter, "coldAfter": &configuration.ColdAfter, "lockTimeout": &configuration.LockTimeout,
+		"backupEvery": &configuration.BackupEvery} {
+		if viper.GetString(key) == "" {
+			continue
+		}
+
+		d, err := Utilities.ParseDuration(viper.GetString(key))
+		if err != nil {
+			Parrot.Error("Invalid "+key+" value", err)
+			continue
+		}
+		*value = d
+	}
+
+	for key, value := range map[string]*int64{"minFreeDisk": &configuration.MinFreeDisk, "maxRepositorySize": &configuration.MaxRepositorySize} {
+		if viper.GetString(key) == "" {
+			continue
+		}
+
+		size, err := Utilities.ParseSize(viper.GetString(key))
+		if err != nil {
+			Parrot.Error("Invalid "+key+" value", err)
+			continue
+		}
+		*value = size
+	}
+
+	if viper.IsSet("samplingInterval") {
+		configuration.SamplingInterval = viper.GetDuration("samplingInterval")
+	}
+
+	for name, value := range viper.GetStringMapString("samplingRules") {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			Parrot.Error("Invalid sampling interval for "+name, err)
+			continue
+		}
+		configuration.SamplingRules[name] = d
+	}
+
+	for name, value := range viper.GetStringMapString("allowedExitCodes") {
+		if _, err := utils.ParseExitCodes(value); err != nil {
+			Parrot.Error("Invalid allowed exit codes for "+name, err)
+			continue
+		}
+		configuration.AllowedExitCodes[name] = value
+	}
+
+	// an invalid policy is kept, the executions overlapping refuse to guess it
+	if overlap := viper.GetString("overlap"); overlap != "" {
+		policy, err := utils.ParseOverlap(overlap)
+		if err != nil {
+			Parrot.Error("Invalid overlap value", err)
+			policy = overlap
+		}
+		configuration.Overlap = policy
+	}
+
+	var channels = []utils.NotificationChannel{}
+	if err := viper.UnmarshalKey("notifications", &channels); err != nil {
+		Parrot.Error("Invalid notifications value", err)
+	}
+
+	for _, channel := range channels {
+		if _, err := notify.New(channel); err != nil {
+			Parrot.Error("Invalid notification channel", err)
+			continue
+		}
+		configuration.Notifications = append(configuration.Notifications, channel)
+	}
+
+	configuration.OTLPEndpoint = viper.GetString("otlpEndpoint")
+
+	if viper.GetString("origin") != "" {
+		configuration.Origin = viper.GetString("origin")
+	}
+
+	if viper.IsSet("redact") {
+		configuration.Redact = viper.GetBool("redact")
+	}
+
+	for _, pattern := range viper.GetStringSlice("redactPatterns") {
+		if _, err := regexp.Compile(pattern); err != nil {
+			Parrot.Error("Invalid redact pattern "+pattern, err)
+			continue
+		}
+		configuration.RedactPatterns = append(configuration.RedactPatterns, pattern)
+	}
+
+	for _, pattern := range viper.GetStringSlice("riskPatterns") {
+		if _, err := regexp.Compile(pattern); err != nil {
+			Parrot.Error("Invalid risk pattern "+pattern, err)
+			continue
+		}
+		configuration.RiskPatterns = append(configuration.RiskPatterns, pattern)
+	}
 
+	return configuration
 }
@@ -21,15 +21,62 @@ var runCmd = &cobra.Command{
 				return
 			}
 
+			if image := cmd.Flag("docker").Value.String(); image != "" {
+				backend, err := newDockerExecutor(image)
+				if err != nil {
+					Parrot.Println("Impossible to run in a container", err)
+					return
+				}
+				commandExecutor = backend
+			}
+
+			notifyFlag(cmd)
+
+			var environment = chooseEnvironment(cmd, "")
+			if environment != "" {
+				if err := applyEnvironment(environment); err != nil {
+					Parrot.Println("Error applying the environment "+environment, err)
+					return
+				}
+			}
+
+			if cmd.Flag("tempdir").Changed {
+				artifacts, _ := cmd.Flags().GetStringArray("artifact")
+
+				scratch, err := newScratchExecutor(commandExecutor, artifacts)
+				if err != nil {
+					Parrot.Println("Impossible to create the temporary directory", err)
+					return
+				}
+				defer scratch.Close()
+
+				commandExecutor = scratch
+			}
+
 			var commands = initializeCommands(cmds)
+			for i := range commands {
+				commands[i].Environment = environment
+			}
 
 			var commandPointers []*models.Command
 			for i := range commands {
 				commandPointers = append(commandPointers, &commands[i])
 			}
 
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			if timeout < 0 {
+				Parrot.Println("Please provide a positive --timeout")
+				return
+			}
+
 			// Now call executeCommands with []*models.Command
-			executeCommands(commandPointers)
+			executeCommands(commandPointers, cmd.Flag("stream").Changed && !incognito(), timeout)
+
+			for _, command := range commandPointers {
+				if !command.Status {
+					notifyCommandFailure(*command)
+				}
+			}
 
 			/*
 				var command = initializeCommand(c, as)
@@ -49,6 +96,16 @@ var runCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(runCmd)
 
+	runCmd.ValidArgsFunction = completeFromHistory
+
 	runCmd.Flags().BoolP("store", "s", false, "Store the results")
+	runCmd.Flags().Bool("stream", false, "Stream the output to the repository in chunks instead of keeping it in memory")
+	runCmd.Flags().String("env", "", "Runs the command with the variables of the given environment (default from the project .ambros.yaml)")
+	runCmd.Flags().Bool("no-env", false, "Runs the command without the environment of the project")
+	runCmd.Flags().Bool("tempdir", false, "Runs the command in a temporary directory deleted afterwards")
+	runCmd.Flags().StringArray("artifact", []string{}, "Copies the files matching the pattern from the temporary directory to the current one")
+	runCmd.Flags().Bool("notify-on-failure", false, "Notifies the configured channels when the command fails")
+	runCmd.Flags().String("docker", "", "Runs the command inside a container of the given image, with the current directory mounted")
+	runCmd.Flags().Duration("timeout", 0, "Kills each command, with the processes it started, when it runs longer than this (e.g. 30s, 5m)")
 
 }
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// scratchExecutor runs the commands of another backend in a temporary directory,
+// copying the declared artifacts back to the current directory after each command
+type scratchExecutor struct {
+	executor
+	dir       string
+	artifacts []string
+	// workdir is where the commands run, the temporary directory or a directory given to in
+	workdir string
+}
+
+func newScratchExecutor(backend executor, artifacts []string) (*scratchExecutor, error) {
+	dir, err := os.MkdirTemp("", "ambros-")
+	if err != nil {
+		return nil, err
+	}
+
+	return &scratchExecutor{executor: backend.in(dir), dir: dir, artifacts: artifacts, workdir: dir}, nil
+}
+
+// in runs the commands in the given directory, relative to the temporary one when not absolute
+func (s *scratchExecutor) in(dir string) executor {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.dir, dir)
+	}
+
+	return &scratchExecutor{executor: s.executor.in(dir), dir: s.dir, artifacts: s.artifacts, workdir: dir}
+}
+
+func (s *scratchExecutor) with(environ []string) executor {
+	return &scratchExecutor{executor: s.executor.with(environ), dir: s.dir, artifacts: s.artifacts, workdir: s.workdir}
+}
+
+func (s *scratchExecutor) command(c *models.Command) *exec.Cmd {
+	return s.executor.command(c)
+}
+
+func (s *scratchExecutor) record(c *models.Command, err error) {
+	s.executor.record(c, err)
+
+	// the command ran there, not in the directory ambros was invoked from
+	c.Dir = s.workdir
+
+	var scratch = models.Scratch{Dir: s.dir, Artifacts: []string{}}
+
+	copied, copyErr := s.collect()
+	if copyErr != nil {
+		Parrot.Error("Error copying the artifacts", copyErr)
+	}
+	scratch.Artifacts = copied
+
+	c.Scratch = &scratch
+}
+
+// collect copies the files matching the artifact patterns to the current directory
+func (s *scratchExecutor) collect() ([]string, error) {
+	var copied = []string{}
+
+	for _, pattern := range s.artifacts {
+		matches, err := filepath.Glob(filepath.Join(s.dir, pattern))
+		if err != nil {
+			return copied, err
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+
+			relative, err := filepath.Rel(s.dir, match)
+			if err != nil {
+				return copied, err
+			}
+
+			if err := copyFile(match, relative); err != nil {
+				return copied, err
+			}
+			copied = append(copied, relative)
+		}
+	}
+
+	return copied, nil
+}
+
+// Close deletes the temporary directory
+func (s *scratchExecutor) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+func copyFile(from string, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(destination, source); err != nil {
+		destination.Close()
+		return err
+	}
+
+	return destination.Close()
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScratchExecutorKeepsTemporaryDirectory(t *testing.T) {
+	useScriptedExecutor(t)
+
+	// the artifacts are copied to the current directory
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd returned unexpected error: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Chdir returned unexpected error: %v", err)
+	}
+	defer os.Chdir(previous)
+
+	scratch, err := newScratchExecutor(localExecutor{}, []string{"out.txt"})
+	if err != nil {
+		t.Fatalf("newScratchExecutor returned unexpected error: %v", err)
+	}
+	defer scratch.Close()
+
+	// the environment of a step wraps the backend again, it must stay in the temporary directory
+	var backend = scratch.with([]string{"SCRATCH_VALUE=kept"})
+
+	var command = initializeCommand("sh", []string{"-c", "echo $SCRATCH_VALUE > out.txt"})
+	executeCommandOn(nil, backend, &command, 0, nil)
+
+	if !command.Status {
+		t.Fatalf("executeCommandOn(sh) failed: %s", command.Error)
+	}
+	if command.Dir != scratch.dir {
+		t.Errorf("executeCommandOn(sh) recorded the directory %s, want %s", command.Dir, scratch.dir)
+	}
+	if command.Scratch == nil || len(command.Scratch.Artifacts) != 1 {
+		t.Fatalf("executeCommandOn(sh) recorded the scratch %+v, want out.txt copied", command.Scratch)
+	}
+
+	content, err := os.ReadFile("out.txt")
+	if err != nil || strings.TrimSpace(string(content)) != "kept" {
+		t.Errorf("out.txt = %q (%v), want kept", content, err)
+	}
+
+	// a relative directory is taken inside the temporary one
+	if err := os.Mkdir(filepath.Join(scratch.dir, "sub"), 0755); err != nil {
+		t.Fatalf("Mkdir returned unexpected error: %v", err)
+	}
+
+	command = initializeCommand("sh", []string{"-c", "pwd"})
+	executeCommandOn(nil, scratch.in("sub"), &command, 0, nil)
+
+	if command.Dir != filepath.Join(scratch.dir, "sub") || command.Scratch == nil {
+		t.Errorf("executeCommandOn(sh) ran in %s with scratch %+v, want %s/sub", command.Dir, command.Scratch, scratch.dir)
+	}
+}
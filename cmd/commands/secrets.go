@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// secretFinding is a command whose record contains secrets
+type secretFinding struct {
+	Where     string
+	Command   models.Command
+	Detectors []string
+}
+
+// scanSecrets looks for secrets in the recorded commands, returning them already redacted
+func scanSecrets(where string, commands []models.Command) []secretFinding {
+	var findings = []secretFinding{}
+
+	for _, c := range commands {
+		var found, names []string
+		var redacted = c
+
+		redacted.Arguments, found = Utilities.RedactArguments(c.Arguments, Configuration.RedactPatterns)
+		redacted.Output, names = Utilities.Redact(c.Output, Configuration.RedactPatterns)
+		found = append(found, names...)
+		redacted.Error, names = Utilities.Redact(c.Error, Configuration.RedactPatterns)
+		found = append(found, names...)
+
+		if len(found) > 0 {
+			findings = append(findings, secretFinding{where, redacted, found})
+		}
+	}
+
+	return findings
+}
+
+// scanStreamedOutputs looks for secrets in the outputs streamed by the executed commands, in the
+// repository or in the cold tier; the outputs found are returned redacted as the output of the commands
+func scanStreamedOutputs(history []models.Command) []secretFinding {
+	var findings = []secretFinding{}
+
+	for _, c := range history {
+		if !c.Streamed {
+			continue
+		}
+
+		var builder strings.Builder
+		if _, err := io.Copy(&builder, Repository.GetOutputReader(c.ID)); err != nil {
+			Parrot.Debug("--> Unable to read the output of "+c.ID, err)
+			continue
+		}
+
+		redacted, found := Utilities.Redact(builder.String(), Configuration.RedactPatterns)
+		if len(found) > 0 {
+			var command = c
+			command.Output = redacted
+			findings = append(findings, secretFinding{"history output", command, found})
+		}
+	}
+
+	return findings
+}
+
+// scanVersions looks for secrets in the versions of the stored commands, deleted ones included, keyed
+// by command id
+func scanVersions() (map[string][]models.Version, []secretFinding) {
+	var redacted = map[string][]models.Version{}
+	var findings = []secretFinding{}
+
+	ids, err := Repository.GetVersionedCommands()
+	if err != nil {
+		return redacted, findings
+	}
+
+	for _, id := range ids {
+		versions, err := Repository.GetCommandVersions(id)
+		if err != nil {
+			continue
+		}
+
+		for _, v := range versions {
+			if v.Command == nil {
+				continue
+			}
+
+			var found = scanSecrets("store v"+strconv.Itoa(v.Number), []models.Command{*v.Command})
+			if len(found) == 0 {
+				continue
+			}
+
+			v.Command = &found[0].Command
+			redacted[id] = append(redacted[id], v)
+			findings = append(findings, found...)
+		}
+	}
+
+	return redacted, findings
+}
+
+// secretsCmd represents the secrets command
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Secrets",
+	Long:  `Secrets command, manages the secrets recorded in the repository`,
+}
+
+// secretsScanCmd represents the secrets scan command
+var secretsScanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Scan",
+	Long: `Scan command, audits the history, the outputs streamed or moved to the cold tier, the store and its
+versions for secrets, redacting them with --fix`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Secrets scan command invoked")
+
+			history, err := Repository.GetAllCommands()
+			if err != nil {
+				Parrot.Println("Error retrieving commands", err)
+				return
+			}
+
+			stored, err := Repository.GetAllStoredCommands()
+			if err != nil {
+				Parrot.Println("Commands not available in the store", err)
+				return
+			}
+
+			var findings = append(scanSecrets("history", history), scanStreamedOutputs(history)...)
+			findings = append(findings, scanSecrets("store", stored)...)
+			_, versions := scanVersions()
+			findings = append(findings, versions...)
+			if len(findings) == 0 {
+				Parrot.Println("No secrets found!")
+				return
+			}
+
+			var body = [][]string{}
+			for _, f := range findings {
+				body = append(body, []string{f.Where, f.Command.ID, f.Command.Name + " " + strings.Join(f.Command.Arguments, " "),
+					strings.Join(f.Detectors, ", ")})
+			}
+			Parrot.Tablify([]string{"WHERE", "ID", "COMMAND", "DETECTED"}, body)
+
+			if !cmd.Flag("fix").Changed {
+				Parrot.Println("Run 'ambros secrets scan --fix' to redact them")
+				return
+			}
+
+			for _, f := range findings {
+				switch f.Where {
+				case "store":
+					err = Repository.Push(f.Command)
+				case "history":
+					err = Repository.Put(f.Command)
+				case "history output":
+					err = Repository.ReplaceOutput(f.Command.ID, f.Command.Output)
+				default:
+					// the versions are redacted below
+					continue
+				}
+
+				if err != nil {
+					Parrot.Println("Error storing the command ("+f.Command.ID+")", err)
+					return
+				}
+			}
+
+			// after the fixes above, whose previous variants became versions too
+			redacted, _ := scanVersions()
+			for id, versions := range redacted {
+				for _, v := range versions {
+					if err := Repository.PutCommandVersion(id, v); err != nil {
+						Parrot.Println("Error storing the version ("+id+")", err)
+						return
+					}
+				}
+			}
+
+			Parrot.Println("Done!")
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(secretsCmd)
+	secretsCmd.AddCommand(secretsScanCmd)
+
+	secretsScanCmd.Flags().Bool("fix", false, "redacts the secrets found")
+}
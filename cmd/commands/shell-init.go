@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// the hooks record in the background, the prompt never waits for the repository
+const bashHooks = `__ambros_preexec() {
+    [ "$__ambros_ready" = 1 ] || return
+    [ -n "$COMP_LINE" ] && return
+    __ambros_ready=0
+
+    local number line
+    read -r number line <<< "$(HISTTIMEFORMAT= builtin history 1)"
+    # lines left out of the history (ignorespace, ignoredups) leave the previous one there
+    [ "$number" = "$__ambros_number" ] && return
+    __ambros_number=$number
+    __ambros_line=$line
+    __ambros_started=${EPOCHREALTIME:-$(date +%s)}
+}
+
+__ambros_precmd() {
+    local code=$?
+    if [ -n "$__ambros_line" ]; then
+        (AMBROS record --exit-code "$code" --started "$__ambros_started" --finished "${EPOCHREALTIME:-$(date +%s)}" -- "$__ambros_line" >/dev/null 2>&1 &)
+    fi
+    __ambros_line=
+    __ambros_ready=1
+}
+
+trap '__ambros_preexec' DEBUG
+PROMPT_COMMAND="__ambros_precmd${PROMPT_COMMAND:+; $PROMPT_COMMAND}"
+`
+
+const zshHooks = `zmodload zsh/datetime 2>/dev/null
+
+__ambros_preexec() {
+    __ambros_line=$1
+    __ambros_started=${EPOCHREALTIME:-$(date +%s)}
+}
+
+__ambros_precmd() {
+    local code=$?
+    if [ -n "$__ambros_line" ]; then
+        (AMBROS record --exit-code "$code" --started "$__ambros_started" --finished "${EPOCHREALTIME:-$(date +%s)}" -- "$__ambros_line" >/dev/null 2>&1 &)
+    fi
+    __ambros_line=
+}
+
+autoload -Uz add-zsh-hook
+add-zsh-hook preexec __ambros_preexec
+add-zsh-hook precmd __ambros_precmd
+`
+
+const fishHooks = `function __ambros_postexec --on-event fish_postexec
+    set -l code $status
+    test -n "$argv[1]"; or return
+    AMBROS record --exit-code $code --duration {$CMD_DURATION}ms -- $argv[1] >/dev/null 2>&1 &
+    disown 2>/dev/null
+end
+`
+
+// shellInitCmd represents the shell-init command
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init bash|zsh|fish",
+	Short: "Shell init",
+	Long: `Shell init command, prints the hooks recording every command executed by the shell, with its
+exit code and duration, through 'ambros record'. Nothing is recorded while incognito is on.
+
+Use it as: eval "$(ambros shell-init bash)" in .bashrc, eval "$(ambros shell-init zsh)" in .zshrc
+or ambros shell-init fish | source in config.fish. The bash hooks take over the DEBUG trap`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Parrot.Debug("Shell init command invoked")
+
+		shell, err := stringFromArguments(args)
+		if err != nil {
+			Parrot.Println("Please provide a shell: bash, zsh or fish")
+			return
+		}
+
+		var hooks string
+		switch shell {
+		case "bash":
+			hooks = bashHooks
+		case "zsh":
+			hooks = zshHooks
+		case "fish":
+			hooks = fishHooks
+		default:
+			Parrot.Println("Please provide a shell: bash, zsh or fish")
+			return
+		}
+
+		// the hooks keep working when ambros is not in the PATH
+		executable, err := os.Executable()
+		if err != nil {
+			executable = "ambros"
+		}
+
+		fmt.Print(strings.ReplaceAll(hooks, "AMBROS", "'"+strings.ReplaceAll(executable, "'", `'\''`)+"'"))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(shellInitCmd)
+}
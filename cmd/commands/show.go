@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show",
+	Long: `Show command, shows the details of an executed command; for a stored command --history lists
+the variants it replaced and --rollback restores one of them`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Show command invoked")
+
+			id, err := stringFromArguments(args)
+			if err != nil {
+				Parrot.Println("Please provide a valid command id")
+				return
+			}
+
+			command, err := findCommand(resolveID(id))
+			if err != nil {
+				Parrot.Println("Id not available in the store (" + id + ")")
+				return
+			}
+
+			if cmd.Flag("repro").Changed {
+				showRepro(command)
+				return
+			}
+
+			if cmd.Flag("history").Changed || cmd.Flag("rollback").Changed {
+				if _, err := Repository.FindInStoreById(command.ID); err != nil {
+					Parrot.Println("Only the stored commands have versions")
+					return
+				}
+
+				if cmd.Flag("history").Changed {
+					showCommandVersions(command)
+				} else {
+					number, _ := cmd.Flags().GetInt("rollback")
+					rollbackCommand(command, number)
+				}
+				return
+			}
+
+			var duration = command.TerminatedAt.Sub(command.CreatedAt)
+			var body = [][]string{
+				{"ID", command.ID},
+				{"Command", command.Name + " " + strings.Join(command.Arguments, " ")},
+				{"Status", strconv.FormatBool(command.Status)},
+				{"Exit code", strconv.Itoa(command.Code())},
+				{"Started", command.CreatedAt.Format("02.01.2006 15:04:05")},
+				{"Terminated", command.TerminatedAt.Format("02.01.2006 15:04:05")},
+				{"Duration", duration.Round(time.Millisecond).String()},
+			}
+
+			if history, err := Repository.GetAllCommands(); err == nil {
+				var s = statsByFingerprint(history)[command.Fingerprint()]
+				if s.Count > 1 && s.Average() > 0 {
+					var ratio = float64(duration) / float64(s.Average())
+					body = append(body, []string{"Average duration", s.Average().Round(time.Millisecond).String() +
+						" over " + strconv.Itoa(s.Count) + " runs (this run x" + strconv.FormatFloat(ratio, 'f', 1, 64) + ")"})
+				}
+			}
+
+			if command.Warnings > 0 || command.Errors > 0 {
+				body = append(body, []string{"Warnings / errors", strconv.Itoa(command.Warnings) + " / " + strconv.Itoa(command.Errors)})
+			}
+
+			if command.ParentID != "" {
+				body = append(body, []string{"Rerun of", command.ParentID})
+			}
+
+			if command.Dir != "" {
+				body = append(body, []string{"Directory", command.Dir})
+			}
+
+			if command.Project != "" {
+				body = append(body, []string{"Project", command.Project})
+			}
+
+			if command.Git != nil {
+				body = append(body, []string{"Git", command.Git.String()})
+			}
+
+			if command.AllowedExitCodes != "" {
+				body = append(body, []string{"Allowed exit codes", command.AllowedExitCodes})
+			}
+
+			if command.Environment != "" {
+				body = append(body, []string{"Environment", command.Environment})
+			}
+
+			if command.Risk != nil {
+				body = append(body, []string{"Risk", describeRisk(*command.Risk)})
+			}
+
+			if command.Container != nil {
+				body = append(body, []string{"Container", command.Container.Name + " (" + command.Container.Image +
+					"), exit code " + strconv.Itoa(command.Container.ExitCode)})
+			}
+
+			if command.Scratch != nil {
+				var artifacts = "no artifacts"
+				if len(command.Scratch.Artifacts) > 0 {
+					artifacts = "artifacts " + strings.Join(command.Scratch.Artifacts, ", ")
+				}
+				body = append(body, []string{"Temporary directory", command.Scratch.Dir + " (" + artifacts + ")"})
+			}
+
+			if command.Interrupted {
+				body = append(body, []string{"Interrupted", "true"})
+			}
+
+			if command.TimedOut {
+				body = append(body, []string{"Timed out", "true"})
+			}
+
+			if command.Usage != nil {
+				body = append(body, []string{"Resources", usageSummary(*command.Usage)})
+			}
+
+			body = append(body, hostSnapshotRows("Host at start", command.HostStart)...)
+			body = append(body, hostSnapshotRows("Host at end", command.HostEnd)...)
+
+			Parrot.Tablify([]string{"FIELD", "VALUE"}, body)
+		})
+	},
+}
+
+func hostSnapshotRows(label string, snapshot *models.HostSnapshot) [][]string {
+	if snapshot == nil {
+		return [][]string{}
+	}
+
+	var load = strconv.FormatFloat(snapshot.Load1, 'f', 2, 64) + " " +
+		strconv.FormatFloat(snapshot.Load5, 'f', 2, 64) + " " +
+		strconv.FormatFloat(snapshot.Load15, 'f', 2, 64)
+
+	return [][]string{{label, "load " + load + ", mem available " + Utilities.FormatSize(snapshot.MemAvailable) +
+		", disk free " + Utilities.FormatSize(snapshot.DiskFree)}}
+}
+
+// usageSummary describes the resources consumed by a command
+func usageSummary(usage models.Usage) string {
+	return "cpu " + usage.CPU().Round(time.Millisecond).String() + " (user " + usage.UserTime.Round(time.Millisecond).String() +
+		", system " + usage.SystemTime.Round(time.Millisecond).String() + "), max rss " + Utilities.FormatSize(usage.MaxRSS) +
+		", disk read " + Utilities.FormatSize(usage.ReadBytes) + ", written " + Utilities.FormatSize(usage.WrittenBytes)
+}
+
+// showRepro compares the fingerprint recorded with the command with the current machine
+func showRepro(command models.Command) {
+	if command.Repro == nil {
+		Parrot.Println("No fingerprint recorded for the command [" + command.ID + "]")
+		return
+	}
+
+	var recorded = *command.Repro
+	var current = reproFingerprint(command.Name, recorded.Version != "")
+
+	Parrot.Tablify([]string{"FIELD", "THEN", "NOW"}, [][]string{
+		{"Platform", recorded.OS + "/" + recorded.Arch, current.OS + "/" + current.Arch},
+		{"Executable", recorded.Path, current.Path},
+		{"Version", recorded.Version, current.Version},
+		{"Environment", recorded.EnvHash, current.EnvHash},
+	})
+
+	var differences = recorded.Differences(current)
+	if len(differences) == 0 {
+		Parrot.Println("The environment matches the one of the run")
+		return
+	}
+
+	Parrot.Println("Differences with the run:")
+	for _, d := range differences {
+		Parrot.Println("  - " + d)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(showCmd)
+
+	showCmd.Flags().Bool("repro", false, "compares the environment of the run with the current machine")
+	showCmd.Flags().Bool("history", false, "lists the previous versions of a stored command, the last undoDepth (0 keeps them all)")
+	showCmd.Flags().Int("rollback", 0, "restores a previous version of a stored command")
+}
@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// commandStats aggregates the past executions of a command fingerprint
+type commandStats struct {
+	Count    int
+	Failures int
+	Total    time.Duration
+	Last     models.Command
+
+	// the resources consumed by the executions which recorded them, Measured of them
+	Measured int
+	CPU      time.Duration
+	MaxRSS   int64
+	IO       int64
+}
+
+func (s commandStats) Average() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+func (s commandStats) FailureRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Count)
+}
+
+// statsByFingerprint groups the executed commands by fingerprint
+func statsByFingerprint(commands []models.Command) map[string]commandStats {
+	var stats = map[string]commandStats{}
+
+	for _, c := range commands {
+		var s = stats[c.Fingerprint()]
+
+		s.Count++
+		if !c.Status {
+			s.Failures++
+		}
+		s.Total += c.TerminatedAt.Sub(c.CreatedAt)
+
+		if c.Usage != nil {
+			s.Measured++
+			s.CPU += c.Usage.CPU()
+			s.IO += c.Usage.ReadBytes + c.Usage.WrittenBytes
+			if c.Usage.MaxRSS > s.MaxRSS {
+				s.MaxRSS = c.Usage.MaxRSS
+			}
+		}
+
+		if c.CreatedAt.After(s.Last.CreatedAt) {
+			s.Last = c
+		}
+
+		stats[c.Fingerprint()] = s
+	}
+
+	return stats
+}
+
+// mostExpensive returns the stats of the commands, the most expensive first by total processor time
+// or by cpu, memory (the peak resident memory), io (the bytes read and written on disk) or duration
+func mostExpensive(stats map[string]commandStats, by string) ([]commandStats, error) {
+	var cost func(s commandStats) int64
+
+	switch by {
+	case "cpu":
+		cost = func(s commandStats) int64 { return int64(s.CPU) }
+	case "memory":
+		cost = func(s commandStats) int64 { return s.MaxRSS }
+	case "io":
+		cost = func(s commandStats) int64 { return s.IO }
+	case "duration":
+		cost = func(s commandStats) int64 { return int64(s.Total) }
+	default:
+		return nil, errors.New("unknown cost " + by)
+	}
+
+	var ranked = []commandStats{}
+	for _, s := range stats {
+		if by == "duration" || s.Measured > 0 {
+			ranked = append(ranked, s)
+		}
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if cost(ranked[i]) != cost(ranked[j]) {
+			return cost(ranked[i]) > cost(ranked[j])
+		}
+		return ranked[i].Last.Fingerprint() < ranked[j].Last.Fingerprint()
+	})
+
+	return ranked, nil
+}
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Stats",
+	Long: `Stats command, lists the most expensive commands of the history by the processor time they used
+overall; --by memory ranks them by peak resident memory, --by io by bytes read and written on disk and
+--by duration by time spent running, --exit counts only the executions which exited with the given codes
+(e.g. 0 or 1-127), --cwd only the ones run in a directory or below it, the current one by default.
+The resources are recorded for the commands run on this machine`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Stats command invoked")
+
+			commands, err := Repository.GetAllCommands()
+			if err != nil {
+				Parrot.Println("Error retrieving commands in the store", err)
+				return
+			}
+
+			codes, err := exitCodesFlag(cmd)
+			if err != nil {
+				Parrot.Println("Please provide valid exit codes (e.g. 0 or 1-127)")
+				return
+			}
+
+			dir, err := cwdFlag(cmd)
+			if err != nil {
+				Parrot.Println("Please provide a valid directory", err)
+				return
+			}
+
+			if dir != "" {
+				var inDirectory = []models.Command{}
+				for _, c := range commands {
+					if utils.WithinDirectory(c.Dir, dir) {
+						inDirectory = append(inDirectory, c)
+					}
+				}
+				commands = inDirectory
+			}
+
+			if codes != nil {
+				var exited = []models.Command{}
+				for _, c := range commands {
+					if codes.Contains(c.Code()) {
+						exited = append(exited, c)
+					}
+				}
+				commands = exited
+			}
+
+			ranked, err := mostExpensive(statsByFingerprint(commands), cmd.Flag("by").Value.String())
+			if err != nil {
+				Parrot.Println("Please provide a valid --by value (cpu, memory, io or duration)")
+				return
+			}
+
+			if len(ranked) == 0 {
+				Parrot.Println("No resources recorded yet!")
+				return
+			}
+
+			limit, _ := cmd.Flags().GetInt("limit")
+			if limit > 0 && len(ranked) > limit {
+				ranked = ranked[:limit]
+			}
+
+			var rows = [][]string{}
+			for _, s := range ranked {
+				var cpu, average, rss, io = "-", "-", "-", "-"
+				if s.Measured > 0 {
+					cpu = s.CPU.Round(time.Millisecond).String()
+					average = (s.CPU / time.Duration(s.Measured)).Round(time.Millisecond).String()
+					rss = Utilities.FormatSize(s.MaxRSS)
+					io = Utilities.FormatSize(s.IO)
+				}
+
+				rows = append(rows, []string{
+					// rows are printed as format strings by Tablify
+					strings.ReplaceAll(commandLine(s.Last), "%", "%%"),
+					strconv.Itoa(s.Count),
+					cpu,
+					average,
+					rss,
+					io,
+					s.Total.Round(time.Millisecond).String(),
+				})
+			}
+
+			Parrot.Tablify([]string{"COMMAND", "RUNS", "CPU", "AVG CPU", "MAX RSS", "DISK I/O", "DURATION"}, rows)
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().String("by", "cpu", "what ranks the commands: cpu, memory, io or duration")
+	statsCmd.Flags().String("exit", "", "counts only the executions which exited with these codes (e.g. 0 or 1-127)")
+	addCwdFlag(statsCmd)
+	statsCmd.Flags().Int("limit", 10, "how many commands are listed")
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+func TestMostExpensive(t *testing.T) {
+	var run = func(line string, duration time.Duration, usage *models.Usage) models.Command {
+		var command = initializeCommand(line, nil)
+		command.TerminatedAt = command.CreatedAt.Add(duration)
+		command.Usage = usage
+		return command
+	}
+
+	var commands = []models.Command{
+		run("make", time.Second, &models.Usage{UserTime: 3 * time.Second, MaxRSS: 100, ReadBytes: 10}),
+		run("make", time.Second, &models.Usage{UserTime: time.Second, SystemTime: time.Second, MaxRSS: 300}),
+		run("webpack", 2*time.Second, &models.Usage{UserTime: 4 * time.Second, MaxRSS: 200, WrittenBytes: 50}),
+		run("ssh", 10*time.Second, nil),
+	}
+
+	var stats = statsByFingerprint(commands)
+
+	for by, want := range map[string][]string{
+		"cpu":      {"make", "webpack"},
+		"memory":   {"make", "webpack"},
+		"io":       {"webpack", "make"},
+		"duration": {"ssh", "webpack", "make"},
+	} {
+		ranked, err := mostExpensive(stats, by)
+		if err != nil {
+			t.Fatalf("mostExpensive(%s) returned unexpected error: %v", by, err)
+		}
+
+		var names = []string{}
+		for _, s := range ranked {
+			names = append(names, s.Last.Name)
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("mostExpensive(%s) = %v, want %v", by, names, want)
+		}
+	}
+
+	if s := stats[commands[0].Fingerprint()]; s.CPU != 5*time.Second || s.MaxRSS != 300 || s.IO != 10 || s.Measured != 2 {
+		t.Errorf("statsByFingerprint(make) = %+v, want 5s of cpu, 300 bytes of rss, 10 of io over 2 runs", s)
+	}
+
+	if _, err := mostExpensive(stats, "network"); err == nil {
+		t.Errorf("mostExpensive(network) returned no error, want one")
+	}
+}
+
+func TestExecuteCommandUsage(t *testing.T) {
+	useScriptedExecutor(t)
+
+	var command = initializeCommand("sh", []string{"-c", "true"})
+	executeCommandOn(nil, localExecutor{}, &command, 0, nil)
+
+	if command.Usage == nil || command.Usage.MaxRSS <= 0 {
+		t.Errorf("executeCommandOn(sh) usage = %+v, want the resources of the process", command.Usage)
+	}
+
+	// the scripted executor does not run on this machine, as a container does not
+	var scripted = initializeCommand("true", nil)
+	executeCommand(&scripted)
+
+	if scripted.Usage != nil {
+		t.Errorf("executeCommand(true) usage = %+v, want none for a command not run locally", scripted.Usage)
+	}
+}
+
+func TestExecuteCommandExitCodes(t *testing.T) {
+	useScriptedExecutor(t,
+		scriptedRule{Pattern: "^grep missing", ExitCode: 1},
+		scriptedRule{Pattern: "^grep broken", ExitCode: 2},
+		scriptedRule{Pattern: "^make", ExitCode: 1})
+	Configuration.AllowedExitCodes["grep"] = "1"
+
+	tests := []struct {
+		line    string
+		allowed string
+		status  bool
+		code    int
+	}{
+		{"grep missing file", "", true, 1},
+		{"grep broken file", "", false, 2},
+		{"make", "", false, 1},
+		{"make", "1-2", true, 1},
+		{"unknown", "", false, 127},
+	}
+
+	for _, test := range tests {
+		var parts = strings.Fields(test.line)
+		var command = initializeCommand(parts[0], parts[1:])
+		command.AllowedExitCodes = test.allowed
+		executeCommand(&command)
+
+		if command.Status != test.status || command.ExitCode != test.code {
+			t.Errorf("executeCommand(%q) with allowed %q = status %v, exit code %d, want %v, %d", test.line, test.allowed,
+				command.Status, command.ExitCode, test.status, test.code)
+		}
+	}
+}
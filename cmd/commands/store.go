@@ -1,9 +1,20 @@
 package commands
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
 )
 
 // storeCmd represents the output command
@@ -27,10 +38,65 @@ var storeCmd = &cobra.Command{
 				}
 
 				var command = initializeCommand(c, as)
+				command.Environment = cmd.Flag("env").Value.String()
+				if command.AllowedExitCodes, err = allowedExitCodesFlag(cmd); err != nil {
+					Parrot.Println("Please provide valid allowed exit codes (e.g. 1 or 1,126-128)")
+					return
+				}
 				pushCommand(&command, true)
 				return
 			}
 
+			if eid := cmd.Flag("edit").Value.String(); eid != "" {
+				var stored, err = Repository.FindInStoreById(resolveID(eid))
+				if err != nil {
+					Parrot.Println("Command ("+eid+") not available in the store", err)
+					return
+				}
+
+				c, as, err := commandFromArguments(args)
+				if err != nil {
+					Parrot.Println("Please provide the new command line")
+					return
+				}
+
+				// the replaced variant is kept, see 'ambros show <id> --history'
+				stored.Name = c
+				stored.Arguments = as
+				if cmd.Flag("env").Changed {
+					stored.Environment = cmd.Flag("env").Value.String()
+				}
+				if cmd.Flag("allow-exit").Changed {
+					if stored.AllowedExitCodes, err = allowedExitCodesFlag(cmd); err != nil {
+						Parrot.Println("Please provide valid allowed exit codes (e.g. 1 or 1,126-128)")
+						return
+					}
+				}
+				stored.Risk = nil
+
+				pushCommand(&stored, true)
+				return
+			}
+
+			if uid := cmd.Flag("undo").Value.String(); uid != "" {
+				var id = resolveID(uid)
+
+				version, err := Repository.UndoStoredCommand(id)
+				if err != nil {
+					Parrot.Println("Error undoing the last change of the command ("+uid+")", err)
+					return
+				}
+
+				if version.Command == nil {
+					Parrot.Println("Command " + id + " removed from the store, it was not stored before")
+					return
+				}
+
+				Parrot.Println(version.Command.AsStoredCommand())
+				Parrot.Println("Done!")
+				return
+			}
+
 			var sh = cmd.Flag("show").Changed
 			if sh {
 				var commands, err = Repository.GetAllStoredCommands()
@@ -40,10 +106,21 @@ var storeCmd = &cobra.Command{
 					return
 				}
 
+				var st = cmd.Flag("stale").Value.String()
+				if st != "" {
+					age, err := Utilities.ParseDuration(st)
+					if err != nil {
+						Parrot.Println("Please provide a valid stale duration (e.g. 90d)")
+						return
+					}
+
+					commands = staleStoredCommands(commands, age)
+				}
+
 				if len(commands) > 0 {
 
 					for _, c := range commands {
-						Parrot.Println(c.AsStoredCommand())
+						Parrot.Println(c.AsStoredCommand() + " (last used " + c.LastUsed().Format("02.01.2006") + ")")
 					}
 				} else {
 					Parrot.Println("No commands available in the store!")
@@ -54,24 +131,72 @@ var storeCmd = &cobra.Command{
 			var rid = cmd.Flag("run").Value.String()
 
 			if rid != "" {
-				var stored, err = Repository.FindInStoreById(rid)
+				var stored, err = Repository.FindInStoreById(resolveID(rid))
 				if err != nil {
 					Parrot.Println("Command ("+rid+") not available in the store", err)
 					return
 				}
 
-				var command = initializeCommand(stored.Name, stored.Arguments)
+				var values = args
+				if cmd.Flag("interactive").Changed {
+					if values, err = promptPlaceholders(stored, args, os.Stdin); err != nil {
+						Parrot.Println("Error reading the positional arguments", err)
+						return
+					}
+				}
+
+				substituted, err := stored.Substitute(values)
+				if err != nil {
+					Parrot.Println("Command ("+rid+") requires positional arguments:", err)
+					return
+				}
+
+				var command = initializeCommand(substituted.Name, substituted.Arguments)
+				command.AllowedExitCodes = stored.AllowedExitCodes
+
+				command.Environment = chooseEnvironment(cmd, stored.Environment)
+				if command.Environment != "" {
+					if err := applyEnvironment(command.Environment); err != nil {
+						Parrot.Println("Error applying the environment "+command.Environment, err)
+						return
+					}
+				}
+
+				assessRisk(&command)
+				if !confirmRisk(command, cmd.Flag("yes").Changed, os.Stdin) {
+					return
+				}
 
 				executeCommand(&command)
 				finalizeCommand(&command)
 
+				Repository.TouchStoredCommand(stored.ID, command.TerminatedAt)
+				return
+			}
+
+			if out := cmd.Flag("export").Value.String(); out != "" {
+				if err := exportStoredCommands(out, args); err != nil {
+					Parrot.Println("Error exporting the stored commands", err)
+					return
+				}
+				Parrot.Println("Done!")
+				return
+			}
+
+			if in := cmd.Flag("import").Value.String(); in != "" {
+				imported, skipped, err := importStoredCommands(in)
+				if err != nil {
+					Parrot.Println("Error importing the stored commands", err)
+					return
+				}
+				Parrot.Println(strconv.Itoa(imported) + " commands imported, " + strconv.Itoa(skipped) + " already in the store")
 				return
 			}
 
 			var did = cmd.Flag("delete").Value.String()
 
 			if did != "" {
-				var err = Repository.DeleteStoredCommand(did)
+				var err = Repository.DeleteStoredCommand(resolveID(did))
 				if err != nil {
 					Parrot.Println("Command ("+did+") not available in the store", err)
 					return
@@ -100,10 +225,174 @@ var storeCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(storeCmd)
 
-	storeCmd.Flags().StringP("push", "p", "", "pushed the given command to the store")
-	storeCmd.Flags().StringP("run", "r", "", "run a command stored in the store")
+	storeCmd.Flags().StringP("push", "p", "", "pushed the given command to the store, {1} {2}... are positional placeholders")
+	storeCmd.Flags().StringP("run", "r", "", "run a command stored in the store, the arguments fill its placeholders")
 	storeCmd.Flags().StringP("delete", "d", "", "delete a command stored from the store")
+	storeCmd.Flags().String("edit", "", "replaces the command line of a stored command with the arguments, keeping the previous one as a version")
+	storeCmd.Flags().String("undo", "", "reverts the last push, edit, rollback or delete of a stored command, again for the previous ones up to undoDepth")
 	storeCmd.Flags().BoolP("show", "s", false, "shows all the commands in the store")
 	storeCmd.Flags().BoolP("clear", "c", false, "removes all the commands in the store")
+	storeCmd.Flags().String("env", "", "with --push, the environment applied when the command runs; with --run, overrides it")
+	storeCmd.Flags().String("allow-exit", "", "with --push or --edit, the non zero exit codes meaning success (e.g. 1 for grep), none clears them")
+	storeCmd.Flags().Bool("no-env", false, "with --run, runs the command without any environment")
+	storeCmd.Flags().BoolP("interactive", "i", false, "with --run, prompts for the placeholders not given as arguments")
+	storeCmd.Flags().String("export", "", "writes the stored commands given as arguments (default all) to a json file")
+	storeCmd.Flags().String("import", "", "adds the commands of a file or URL written by --export to the store")
+	storeCmd.Flags().Bool("yes", false, "with --run, runs a high risk command without asking for confirmation")
+	storeCmd.Flags().String("stale", "", "with --show, lists only the commands unused for the given duration (e.g. 90d)")
+
+	storeCmd.RegisterFlagCompletionFunc("run", completeStored)
+	storeCmd.RegisterFlagCompletionFunc("edit", completeStored)
+	storeCmd.RegisterFlagCompletionFunc("delete", completeStored)
+
+}
+
+// staleStoredCommands returns the stored commands not used since the given age
+func staleStoredCommands(commands []models.Command, age time.Duration) []models.Command {
+	var stale = []models.Command{}
+	var limit = time.Now().Add(-age)
+
+	for _, c := range commands {
+		if c.LastUsed().Before(limit) {
+			stale = append(stale, c)
+		}
+	}
+
+	return stale
+}
+
+// allowedExitCodesFlag returns the allowed exit codes given with --allow-exit, checking they are valid
+func allowedExitCodesFlag(cmd *cobra.Command) (string, error) {
+	var spec = cmd.Flag("allow-exit").Value.String()
+	if spec == "" {
+		return "", nil
+	}
+
+	if _, err := utils.ParseExitCodes(spec); err != nil {
+		return "", err
+	}
+	return spec, nil
+}
+
+// staleHint reminds, at most once a day, the stored commands unused for long
+func staleHint() {
+	if Configuration.StaleAfter <= 0 || incognito() {
+		return
+	}
+
+	last, err := Repository.GetMeta("staleHintAt")
+	if err != nil {
+		return
+	}
+
+	if t, err := time.Parse(time.RFC3339, last); err == nil && time.Since(t) < 24*time.Hour {
+		return
+	}
+
+	commands, err := Repository.GetAllStoredCommands()
+	if err != nil {
+		return
+	}
+
+	Repository.PutMeta("staleHintAt", time.Now().Format(time.RFC3339))
+
+	var stale = staleStoredCommands(commands, Configuration.StaleAfter)
+	if len(stale) > 0 {
+		Parrot.Println(strconv.Itoa(len(stale)) + " stored commands unused for a long time, see 'ambros store --show --stale " + Configuration.StaleAfter.String() + "'")
+	}
+}
+
+// promptPlaceholders asks for the positional values of a stored command not already given
+func promptPlaceholders(stored models.Command, given []string, input io.Reader) ([]string, error) {
+	var values = append([]string{}, given...)
+	if len(values) >= stored.Placeholders() {
+		return values, nil
+	}
+
+	Parrot.Println(stored.Name + " " + strings.Join(stored.Arguments, " "))
+
+	reader := bufio.NewReader(input)
+	for i := len(values) + 1; i <= stored.Placeholders(); i++ {
+		for {
+			os.Stdout.WriteString("{" + strconv.Itoa(i) + "}: ")
+
+			line, err := reader.ReadString('\n')
+			if line = strings.TrimSpace(line); line != "" {
+				values = append(values, line)
+				break
+			}
+			if err == io.EOF {
+				return nil, errors.New("no value given for {" + strconv.Itoa(i) + "}")
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// exportStoredCommands writes the stored commands with the given ids, or all of them, as a snapshot
+func exportStoredCommands(file string, ids []string) error {
+	var commands = []models.Command{}
+
+	if len(ids) == 0 {
+		all, err := Repository.GetAllStoredCommands()
+		if err != nil {
+			return err
+		}
+		commands = all
+	}
+
+	for _, id := range ids {
+		stored, err := Repository.FindInStoreById(id)
+		if err != nil {
+			return err
+		}
+		commands = append(commands, stored)
+	}
+
+	var snapshot = models.Snapshot{Kind: "store", CreatedAt: time.Now(), Commands: commands}
+
+	buf := new(bytes.Buffer)
+	json.Indent(buf, []byte(Utilities.AsJson(snapshot)), "", "  ")
+
+	return os.WriteFile(file, buf.Bytes(), 0644)
+}
+
+// importStoredCommands adds the commands of a snapshot to the store, skipping the ones already there
+func importStoredCommands(location string) (int, int, error) {
+	snapshot, err := readSnapshot(location)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	stored, err := Repository.GetAllStoredCommands()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var known = map[string]bool{}
+	for _, c := range stored {
+		known[c.ID] = true
+		known[c.Fingerprint()] = true
+	}
+
+	var imported, skipped = 0, 0
+	for _, c := range snapshot.Commands {
+		if known[c.ID] || known[c.Fingerprint()] {
+			skipped++
+			continue
+		}
+
+		if err := Repository.Push(c); err != nil {
+			return imported, skipped, err
+		}
+		known[c.ID] = true
+		known[c.Fingerprint()] = true
+		imported++
+	}
 
+	return imported, skipped, nil
 }
@@ -0,0 +1,57 @@
+package commands
+
+// size of the chunks written to the repository by streamed executions
+const outputChunkSize = 64 * 1024
+
+// outputWriter buffers a streamed output and appends it to the repository in chunks
+type outputWriter struct {
+	id     string
+	buffer []byte
+}
+
+func newOutputWriter(id string) *outputWriter {
+	return &outputWriter{id: id, buffer: make([]byte, 0, outputChunkSize)}
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	var written = len(p)
+
+	for len(p) > 0 {
+		n := copy(w.buffer[len(w.buffer):cap(w.buffer)], p)
+		w.buffer = w.buffer[:len(w.buffer)+n]
+		p = p[n:]
+
+		if len(w.buffer) == cap(w.buffer) {
+			if err := w.flush(); err != nil {
+				return written - len(p), err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+func (w *outputWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	// secrets spanning two chunks are not detected
+	var chunk = []byte(redactEnvironmentSecrets(string(w.buffer)))
+	if redacting() {
+		redacted, _ := Utilities.Redact(string(chunk), Configuration.RedactPatterns)
+		chunk = []byte(redacted)
+	}
+
+	if err := Repository.AppendOutput(w.id, chunk); err != nil {
+		return err
+	}
+
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// Close stores the last partial chunk
+func (w *outputWriter) Close() error {
+	return w.flush()
+}
@@ -0,0 +1,278 @@
+package commands
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// the lengths of the sequences of commands looked for in the history
+const (
+	sequenceMinLength = 2
+	sequenceMaxLength = 4
+)
+
+// sequencePattern is a sequence of command lines run one after the other several times
+type sequencePattern struct {
+	Lines []string
+	// the commands of the latest run of the sequence, the steps of a chain made of it
+	IDs   []string
+	Count int
+}
+
+// historySessions splits the history, the oldest first, in the runs of commands of a same machine
+// started at most gap after the previous one terminated
+func historySessions(history []models.Command, gap time.Duration) [][]models.Command {
+	var sessions = [][]models.Command{}
+	var current = []models.Command{}
+
+	for _, c := range history {
+		if len(current) > 0 {
+			var previous = current[len(current)-1]
+			if c.Origin != previous.Origin || c.CreatedAt.Sub(previous.TerminatedAt) > gap {
+				sessions = append(sessions, current)
+				current = []models.Command{}
+			}
+		}
+		current = append(current, c)
+	}
+
+	if len(current) > 0 {
+		sessions = append(sessions, current)
+	}
+	return sessions
+}
+
+// detectSequences returns the sequences of different command lines run at least runs times in a session,
+// the ones automating the most commands first; a sequence is left out when a longer one holding it ran
+// as often
+func detectSequences(history []models.Command, gap time.Duration, runs int) []sequencePattern {
+	var patterns = map[string]*sequencePattern{}
+
+	for _, session := range historySessions(history, gap) {
+		for length := sequenceMinLength; length <= sequenceMaxLength; length++ {
+			for start := 0; start+length <= len(session); start++ {
+				var window = session[start : start+length]
+
+				var lines, ids = []string{}, []string{}
+				var seen = map[string]bool{}
+				for _, c := range window {
+					seen[commandLine(c)] = true
+					lines = append(lines, commandLine(c))
+					ids = append(ids, c.ID)
+				}
+				if len(seen) < length {
+					continue
+				}
+
+				var key = strings.Join(lines, "\n")
+				if patterns[key] == nil {
+					patterns[key] = &sequencePattern{Lines: lines}
+				}
+				patterns[key].Count++
+				patterns[key].IDs = ids
+			}
+		}
+	}
+
+	var detected = []sequencePattern{}
+	for key, p := range patterns {
+		if p.Count < runs {
+			continue
+		}
+
+		var held = false
+		for other, o := range patterns {
+			if len(o.Lines) > len(p.Lines) && o.Count >= p.Count && strings.Contains("\n"+other+"\n", "\n"+key+"\n") {
+				held = true
+				break
+			}
+		}
+		if !held {
+			detected = append(detected, *p)
+		}
+	}
+
+	sort.Slice(detected, func(i, j int) bool {
+		var a, b = detected[i].Count * len(detected[i].Lines), detected[j].Count * len(detected[j].Lines)
+		if a != b {
+			return a > b
+		}
+		return strings.Join(detected[i].Lines, "\n") < strings.Join(detected[j].Lines, "\n")
+	})
+
+	return detected
+}
+
+// chainLines returns the command lines of the steps of a chain
+func chainLines(chain models.Chain) string {
+	var lines = []string{}
+	for _, step := range chain.Steps {
+		if c, err := findCommand(step.CommandID); err == nil {
+			lines = append(lines, commandLine(c))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sequenceChainName proposes a chain name made of the names of the commands, numbered when taken
+func sequenceChainName(p sequencePattern, taken map[string]bool) string {
+	var names = []string{}
+	for _, line := range p.Lines {
+		names = append(names, strings.Fields(line)[0])
+	}
+
+	var base = strings.Join(names, "-")
+	var name = base
+	for n := 2; taken[name]; n++ {
+		name = base + "-" + strconv.Itoa(n)
+	}
+	return name
+}
+
+// applySequences asks for each sequence whether to store it as a chain: y stores it with the proposed
+// name, any other word stores it with that name, nothing or n skips it
+func applySequences(patterns []sequencePattern, taken map[string]bool, input io.Reader) []models.Chain {
+	var reader = bufio.NewReader(input)
+	var chains = []models.Chain{}
+
+	for _, p := range patterns {
+		var name = sequenceChainName(p, taken)
+
+		Parrot.Println(strings.Join(p.Lines, "\n  -> "))
+		os.Stdout.WriteString("Create the chain " + name + "? [y/N/other name] ")
+
+		answer, err := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+		case "", "n", "no":
+			if err != nil {
+				return chains
+			}
+			continue
+		default:
+			if taken[answer] {
+				Parrot.Println("Chain " + answer + " already exists, skipped")
+				continue
+			}
+			name = answer
+		}
+
+		var chain = models.Chain{Name: name, Description: "Detected from the history, run " + strconv.Itoa(p.Count) + " times"}
+		chain.ID = Utilities.Random()
+		chain.CreatedAt = time.Now()
+		for _, id := range p.IDs {
+			chain.Steps = append(chain.Steps, models.ChainStep{CommandID: id})
+		}
+
+		taken[name] = true
+		chains = append(chains, chain)
+	}
+
+	return chains
+}
+
+// suggestCmd represents the suggest command
+var suggestCmd = &cobra.Command{
+	Use:   "suggest",
+	Short: "Suggest",
+	Long:  `Suggest command, proposes automations learned from the history`,
+}
+
+// suggestChainsCmd represents the suggest chains command
+var suggestChainsCmd = &cobra.Command{
+	Use:   "chains",
+	Short: "Chains",
+	Long: `Chains command, lists the sequences of commands run one after the other several times, within --gap
+of each other, which no chain automates yet; --apply asks for each one whether to store it as a chain`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Suggest chains command invoked")
+
+			gap, err := cmd.Flags().GetDuration("gap")
+			if err != nil || gap <= 0 {
+				Parrot.Println("Please provide a valid gap (e.g. 5m)")
+				return
+			}
+			runs, _ := cmd.Flags().GetInt("runs")
+
+			history, err := Repository.GetAllCommands()
+			if err != nil {
+				Parrot.Println("Error retrieving commands", err)
+				return
+			}
+			sort.Slice(history, func(i, j int) bool { return history[i].CreatedAt.Before(history[j].CreatedAt) })
+
+			chains, err := Repository.GetAllChains()
+			if err != nil {
+				Parrot.Println("Error retrieving chains", err)
+				return
+			}
+
+			var taken, automated = map[string]bool{}, map[string]bool{}
+			for _, chain := range chains {
+				taken[chain.Name] = true
+				automated[chainLines(chain)] = true
+			}
+
+			var patterns = []sequencePattern{}
+			for _, p := range detectSequences(history, gap, runs) {
+				if !automated[strings.Join(p.Lines, "\n")] {
+					patterns = append(patterns, p)
+				}
+			}
+
+			limit, _ := cmd.Flags().GetInt("limit")
+			if limit > 0 && len(patterns) > limit {
+				patterns = patterns[:limit]
+			}
+
+			if len(patterns) == 0 {
+				Parrot.Println("No sequences to suggest!")
+				return
+			}
+
+			if !cmd.Flag("apply").Changed {
+				var rows = [][]string{}
+				for _, p := range patterns {
+					var name = sequenceChainName(p, taken)
+					taken[name] = true
+
+					// rows are printed as format strings by Tablify
+					rows = append(rows, []string{name, strconv.Itoa(p.Count),
+						strings.ReplaceAll(strings.Join(p.Lines, " -> "), "%", "%%")})
+				}
+				Parrot.Tablify([]string{"CHAIN", "RUNS", "STEPS"}, rows)
+				return
+			}
+
+			for _, chain := range applySequences(patterns, taken, os.Stdin) {
+				if err := Repository.PutChain(chain); err != nil {
+					Parrot.Println("Error storing the chain", err)
+					return
+				}
+				Parrot.Println(chain.AsStoredChain())
+			}
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(suggestCmd)
+	suggestCmd.AddCommand(suggestChainsCmd)
+
+	suggestChainsCmd.Flags().Bool("apply", false, "asks for each sequence whether to store it as a chain")
+	suggestChainsCmd.Flags().Duration("gap", 5*time.Minute, "the longest pause between two commands of a sequence")
+	suggestChainsCmd.Flags().Int("runs", 3, "how many times a sequence must have run")
+	suggestChainsCmd.Flags().Int("limit", 10, "how many sequences are listed")
+}
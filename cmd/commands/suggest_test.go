@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+func TestDetectSequences(t *testing.T) {
+	var history = []models.Command{}
+	var at = time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	var run = func(lines ...string) {
+		for _, line := range lines {
+			var parts = strings.Fields(line)
+			var c = models.Command{Entity: models.Entity{ID: line + "@" + at.Format("15:04"), CreatedAt: at, TerminatedAt: at.Add(time.Second)},
+				Name: parts[0], Arguments: parts[1:]}
+			history = append(history, c)
+			at = at.Add(time.Minute)
+		}
+		// the next run is another session
+		at = at.Add(time.Hour)
+	}
+
+	run("git pull", "make build", "make test")
+	run("ls", "git pull", "make build", "make test", "ls")
+	run("git pull", "make build", "make test")
+	run("git pull", "make build", "vim main.go")
+	run("ls", "ls", "ls")
+
+	var patterns = detectSequences(history, 5*time.Minute, 3)
+
+	// git pull, make build ran 4 times, more than the whole sequence
+	var expected = [][]string{
+		{"git pull", "make build", "make test"},
+		{"git pull", "make build"},
+	}
+	if len(patterns) != len(expected) {
+		t.Fatalf("detectSequences() = %+v, want %v", patterns, expected)
+	}
+	for i, p := range patterns {
+		if !reflect.DeepEqual(p.Lines, expected[i]) {
+			t.Errorf("detectSequences()[%d] = %v, want %v", i, p.Lines, expected[i])
+		}
+	}
+	if patterns[0].Count != 3 || patterns[1].Count != 4 || patterns[0].IDs[0] != "git pull@11:08" {
+		t.Errorf("detectSequences() = %+v, want the counts and the ids of the latest run", patterns)
+	}
+
+	var taken = map[string]bool{"git-make-make": true}
+	var chains = applySequences(patterns, taken, strings.NewReader("y\nupdate\n"))
+	if len(chains) != 2 || chains[0].Name != "git-make-make-2" || chains[1].Name != "update" || len(chains[0].Steps) != 3 ||
+		chains[0].Steps[2].CommandID != "make test@11:10" {
+		t.Errorf("applySequences() = %+v, want the chains git-make-make-2 and update", chains)
+	}
+
+	if chains := applySequences(patterns, map[string]bool{}, strings.NewReader("n\n")); len(chains) != 0 {
+		t.Errorf("applySequences() = %+v, want none refused", chains)
+	}
+}
@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// coldCandidates returns the executed commands whose output was not accessed for the given duration
+func coldCandidates(commands []models.Command, unusedFor time.Duration) []models.Command {
+	var candidates = []models.Command{}
+	var limit = time.Now().Add(-unusedFor)
+
+	for _, c := range commands {
+		if c.Cold || (!c.Streamed && c.Output == "") {
+			continue
+		}
+
+		if c.LastUsed().Before(limit) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	return candidates
+}
+
+// evictOutputs moves the outputs unused for the given duration to the cold tier
+func evictOutputs(unusedFor time.Duration) (int, int64, error) {
+	commands, err := Repository.GetAllCommands()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var count = 0
+	var size int64
+	for _, c := range coldCandidates(commands, unusedFor) {
+		n, err := Repository.EvictOutput(c.ID)
+		if err != nil {
+			return count, size, err
+		}
+		count++
+		size += n
+	}
+
+	return count, size, nil
+}
+
+// evictColdOutputs applies the coldAfter policy, at most once a day
+func evictColdOutputs() {
+	if Configuration.ColdAfter <= 0 || incognito() {
+		return
+	}
+
+	last, err := Repository.GetMeta("coldEvictionAt")
+	if err != nil {
+		return
+	}
+
+	if t, err := time.Parse(time.RFC3339, last); err == nil && time.Since(t) < 24*time.Hour {
+		return
+	}
+
+	Repository.PutMeta("coldEvictionAt", time.Now().Format(time.RFC3339))
+
+	count, size, err := evictOutputs(Configuration.ColdAfter)
+	if err != nil {
+		Parrot.Error("Error moving outputs to the cold tier", err)
+		return
+	}
+
+	if count > 0 {
+		Parrot.Debug("--> " + strconv.Itoa(count) + " outputs (" + Utilities.FormatSize(size) + ") moved to the cold tier")
+	}
+}
+
+// tierCmd represents the tier command
+var tierCmd = &cobra.Command{
+	Use:   "tier",
+	Short: "Tier",
+	Long:  `Tier command, manages the cold tier where unused outputs are compressed out of the repository`,
+}
+
+// tierStatusCmd represents the tier status command
+var tierStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Status",
+	Long:  `Status command, shows how the outputs are split between the repository and the cold tier`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Tier status command invoked")
+
+			commands, err := Repository.GetAllCommands()
+			if err != nil {
+				Parrot.Println("Error retrieving commands", err)
+				return
+			}
+
+			var hot = 0
+			for _, c := range commands {
+				if !c.Cold && (c.Streamed || c.Output != "") {
+					hot++
+				}
+			}
+
+			cold, coldSize, err := Repository.ColdSize()
+			if err != nil {
+				Parrot.Println("Error reading the cold tier", err)
+				return
+			}
+
+			size, _ := Repository.Size()
+
+			var policy = "disabled"
+			if Configuration.ColdAfter > 0 {
+				policy = "outputs unused for " + Configuration.ColdAfter.String()
+			}
+
+			Parrot.Tablify([]string{"TIER", "OUTPUTS", "SIZE"}, [][]string{
+				{"hot", strconv.Itoa(hot), Utilities.FormatSize(size)},
+				{"cold", strconv.Itoa(cold), Utilities.FormatSize(coldSize)},
+			})
+			Parrot.Println("Eviction policy: " + policy)
+		})
+	},
+}
+
+// tierEvictCmd represents the tier evict command
+var tierEvictCmd = &cobra.Command{
+	Use:   "evict",
+	Short: "Evict",
+	Long:  `Evict command, moves the outputs not accessed recently to the cold tier`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Tier evict command invoked")
+
+			var unusedFor = Configuration.ColdAfter
+			if value := cmd.Flag("older-than").Value.String(); value != "" {
+				d, err := Utilities.ParseDuration(value)
+				if err != nil {
+					Parrot.Println("Please provide a valid --older-than value (e.g. 30d)")
+					return
+				}
+				unusedFor = d
+			}
+
+			if unusedFor <= 0 {
+				Parrot.Println("Please provide --older-than or set coldAfter in the configuration")
+				return
+			}
+
+			count, size, err := evictOutputs(unusedFor)
+			if err != nil {
+				Parrot.Println("Error moving outputs to the cold tier", err)
+				return
+			}
+
+			Parrot.Println(strconv.Itoa(count) + " outputs (" + Utilities.FormatSize(size) + ") moved to the cold tier")
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(tierCmd)
+	tierCmd.AddCommand(tierStatusCmd)
+	tierCmd.AddCommand(tierEvictCmd)
+
+	tierEvictCmd.Flags().String("older-than", "", "evicts the outputs not accessed for this long (default coldAfter)")
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvictColdOutputs(t *testing.T) {
+	useScriptedExecutor(t)
+	Configuration.ColdAfter = 30 * 24 * time.Hour
+
+	// the outputs are evicted once unused for longer than coldAfter, the last use counting over the creation
+	var put = func(output string, created time.Duration, used time.Duration) string {
+		var command = initializeCommand("make", []string{"build"})
+		command.Output = output
+		command.CreatedAt = time.Now().Add(-created)
+		if used > 0 {
+			command.LastUsedAt = time.Now().Add(-used)
+		}
+		if err := Repository.Put(command); err != nil {
+			t.Fatalf("Put returned unexpected error: %v", err)
+		}
+		return command.ID
+	}
+
+	var old = put("old\n", Configuration.ColdAfter+time.Minute, 0)
+	var recent = put("recent\n", Configuration.ColdAfter-time.Minute, 0)
+	var touched = put("touched\n", 2*Configuration.ColdAfter, Configuration.ColdAfter-time.Minute)
+	var empty = put("", 2*Configuration.ColdAfter, 0)
+
+	evictColdOutputs()
+
+	for id, cold := range map[string]bool{old: true, recent: false, touched: false, empty: false} {
+		command, err := Repository.FindById(id)
+		if err != nil || command.Cold != cold {
+			t.Errorf("FindById(%s) = cold %v, %v, want cold %v", id, command.Cold, err, cold)
+		}
+	}
+
+	// the evicted output is read back as a streamed one
+	var builder strings.Builder
+	io.Copy(&builder, Repository.GetOutputReader(old))
+	if builder.String() != "old\n" {
+		t.Errorf("GetOutputReader(%s) = %q, want the evicted output", old, builder.String())
+	}
+
+	// the policy runs at most once a day
+	var later = put("later\n", 2*Configuration.ColdAfter, 0)
+	evictColdOutputs()
+
+	if command, err := Repository.FindById(later); err != nil || command.Cold {
+		t.Errorf("FindById(%s) = cold %v, %v, want the output kept until the next day", later, command.Cold, err)
+	}
+}
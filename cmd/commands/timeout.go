@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"os/exec"
+	"time"
+)
+
+// startWithTimeout starts the command, killing it when it runs longer than the timeout (0 lets it run
+// as long as it takes), then calling stop to end what it left running elsewhere, e.g. its container;
+// the returned function stops the timer and tells if the command timed out, once it is fully stopped.
+// With a timeout the command leads its own process group where the system has them, killed as a whole
+// so that the processes it started do not survive it and keep its output open
+func startWithTimeout(cmd *exec.Cmd, timeout time.Duration, stop func()) (func() bool, error) {
+	if timeout <= 0 {
+		return func() bool { return false }, cmd.Start()
+	}
+
+	ownProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return func() bool { return false }, err
+	}
+
+	var stopped = make(chan struct{})
+	var timer = time.AfterFunc(timeout, func() {
+		killProcessGroup(cmd)
+		stop()
+		close(stopped)
+	})
+
+	return func() bool {
+		if timer.Stop() {
+			return false
+		}
+		<-stopped
+		return true
+	}, nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package commands
+
+import "os/exec"
+
+// ownProcessGroup leaves the command in the group of ambros, there are no process groups to kill here
+func ownProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command only, the processes it started may survive it
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
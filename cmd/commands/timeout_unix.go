@@ -0,0 +1,21 @@
+//go:build unix
+
+package commands
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// ownProcessGroup makes the command the leader of a new process group
+func ownProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// killProcessGroup kills the command and the processes it started
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	tracing "github.com/gi4nks/ambros/internal/tracing"
+)
+
+// tracer records the spans of the invocation when an OTLP endpoint is configured, it is nil otherwise
+var tracer *tracing.Tracer
+
+// invocation is the span of the invocation, the parent of the spans not started by a chain step
+var invocation *tracing.Span
+
+// phases of the invocation traced as repository operations
+var tracedPhases = map[string]string{"open": "repository open", "store": "repository store"}
+
+// tracingEndpoint follows the OpenTelemetry variables, falling back to otlpEndpoint in the configuration
+func tracingEndpoint() string {
+	if os.Getenv("OTEL_SDK_DISABLED") == "true" {
+		return ""
+	}
+
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+
+	var endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		endpoint = Configuration.OTLPEndpoint
+	}
+	if endpoint == "" {
+		return ""
+	}
+
+	return strings.TrimRight(endpoint, "/") + "/v1/traces"
+}
+
+// startTracing opens the span of the invocation
+func startTracing() {
+	var endpoint = tracingEndpoint()
+	if endpoint == "" || incognito() {
+		tracer, invocation = nil, nil
+		return
+	}
+
+	var service = os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = "ambros"
+	}
+
+	tracer = tracing.New(endpoint, tracing.ParseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")), service, os.Getenv("TRACEPARENT"))
+
+	invocation = tracer.Start(invokedCommand, nil)
+	invocation.SetAttribute("ambros.origin", Configuration.Origin)
+}
+
+// stopTracing closes the span of the invocation and exports the spans
+func stopTracing() {
+	tracer.End(invocation)
+
+	if err := tracer.Flush(); err != nil {
+		Parrot.Debug("--> Spans not exported: " + err.Error())
+	}
+}
+
+// traceExecution opens the span of an execution, child of the parent, the child process joins the trace
+// through TRACEPARENT; the returned function closes it once the command status is known
+func traceExecution(parent *tracing.Span, command *models.Command, cmd *exec.Cmd) func() {
+	if tracer == nil {
+		return func() {}
+	}
+
+	var span = tracer.Start("exec "+command.Name, parent)
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, "TRACEPARENT="+tracer.Traceparent(span))
+
+	return func() {
+		var redacted = *command
+		redactCommand(&redacted)
+
+		span.SetAttribute("ambros.command.id", command.ID)
+		span.SetAttribute("process.executable.name", command.Name)
+		span.SetAttribute("process.command_line", strings.TrimSpace(redacted.Name+" "+strings.Join(redacted.Arguments, " ")))
+		if command.Environment != "" {
+			span.SetAttribute("ambros.environment", command.Environment)
+		}
+
+		if cmd.ProcessState != nil {
+			span.SetAttribute("process.exit_code", cmd.ProcessState.ExitCode())
+		}
+
+		if !command.Status {
+			span.SetError(strings.TrimSpace(redacted.Error))
+		}
+
+		tracer.End(span)
+	}
+}
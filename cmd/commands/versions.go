@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// findVersion returns the version with the given number
+func findVersion(versions []models.Version, number int) (models.Version, bool) {
+	for _, v := range versions {
+		if v.Number == number {
+			return v, true
+		}
+	}
+	return models.Version{}, false
+}
+
+func chainSteps(chain models.Chain) string {
+	var ids = []string{}
+	for _, step := range chain.Steps {
+		ids = append(ids, step.CommandID)
+	}
+	return strings.Join(ids, " ")
+}
+
+// showCommandVersions lists the previous variants of a stored command
+func showCommandVersions(command models.Command) {
+	versions, err := Repository.GetCommandVersions(command.ID)
+	if err != nil {
+		Parrot.Println("Error retrieving the versions", err)
+		return
+	}
+
+	var body = [][]string{{"current", "", command.Name + " " + strings.Join(command.Arguments, " ")}}
+	for i := len(versions) - 1; i >= 0; i-- {
+		var v = versions[i]
+		if v.Command == nil {
+			body = append(body, []string{strconv.Itoa(v.Number), v.SavedAt.Format("02.01.2006 15:04:05"), "(not stored)"})
+			continue
+		}
+		body = append(body, []string{strconv.Itoa(v.Number), v.SavedAt.Format("02.01.2006 15:04:05"),
+			v.Command.Name + " " + strings.Join(v.Command.Arguments, " ")})
+	}
+
+	Parrot.Tablify([]string{"VERSION", "REPLACED", "COMMAND"}, body)
+}
+
+// rollbackCommand restores a previous variant of a stored command, the current one becomes a version
+func rollbackCommand(command models.Command, number int) {
+	versions, err := Repository.GetCommandVersions(command.ID)
+	if err != nil {
+		Parrot.Println("Error retrieving the versions", err)
+		return
+	}
+
+	version, ok := findVersion(versions, number)
+	if !ok || version.Command == nil {
+		Parrot.Println("Version not available (" + strconv.Itoa(number) + "), see 'ambros show " + command.ID + " --history'")
+		return
+	}
+
+	var restored = *version.Command
+	restored.LastUsedAt = command.LastUsedAt
+
+	if err := Repository.Push(restored); err != nil {
+		Parrot.Println("Error storing the command", err)
+		return
+	}
+
+	Parrot.Println("Done!")
+}
+
+// showChainVersions lists the previous variants of a chain
+func showChainVersions(chain models.Chain) {
+	versions, err := Repository.GetChainVersions(chain.Name)
+	if err != nil {
+		Parrot.Println("Error retrieving the versions", err)
+		return
+	}
+
+	var body = [][]string{{"current", "", chainSteps(chain), strings.Join(chain.Triggers, " ")}}
+	for i := len(versions) - 1; i >= 0; i-- {
+		var v = versions[i]
+		body = append(body, []string{strconv.Itoa(v.Number), v.SavedAt.Format("02.01.2006 15:04:05"),
+			chainSteps(*v.Chain), strings.Join(v.Chain.Triggers, " ")})
+	}
+
+	Parrot.Tablify([]string{"VERSION", "REPLACED", "STEPS", "TRIGGERS"}, body)
+}
+
+// rollbackChain restores a previous variant of a chain, the current one becomes a version
+func rollbackChain(chain models.Chain, number int) {
+	versions, err := Repository.GetChainVersions(chain.Name)
+	if err != nil {
+		Parrot.Println("Error retrieving the versions", err)
+		return
+	}
+
+	version, ok := findVersion(versions, number)
+	if !ok {
+		Parrot.Println("Version not available (" + strconv.Itoa(number) + "), see 'ambros chain show " + chain.Name + " --history'")
+		return
+	}
+
+	if err := Repository.PutChain(*version.Chain); err != nil {
+		Parrot.Println("Error storing the chain", err)
+		return
+	}
+
+	Parrot.Println("Done!")
+}
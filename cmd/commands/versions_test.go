@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"testing"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+func TestRollbackCommand(t *testing.T) {
+	useScriptedExecutor(t)
+
+	var id = storeCommand(t, "make build")
+
+	var command, _ = Repository.FindInStoreById(id)
+	command.Arguments = []string{"test"}
+	if err := Repository.Push(command); err != nil {
+		t.Fatalf("Push returned unexpected error: %v", err)
+	}
+
+	// version 1 marks the command as not stored yet, there is nothing to restore
+	rollbackCommand(command, 1)
+	if current, _ := Repository.FindInStoreById(id); current.Arguments[0] != "test" {
+		t.Fatalf("rollbackCommand(1) restored %+v, want nothing", current)
+	}
+
+	rollbackCommand(command, 2)
+
+	restored, err := Repository.FindInStoreById(id)
+	if err != nil || len(restored.Arguments) != 1 || restored.Arguments[0] != "build" {
+		t.Fatalf("FindInStoreById(%s) = %+v, %v, want make build back", id, restored, err)
+	}
+
+	// the replaced variant is kept as the next version, so the rollback can be undone
+	versions, err := Repository.GetCommandVersions(id)
+	if err != nil || len(versions) != 3 || versions[2].Number != 3 || versions[2].Command.Arguments[0] != "test" {
+		t.Errorf("GetCommandVersions(%s) = %+v, %v, want make test as version 3", id, versions, err)
+	}
+
+	// an unknown version changes nothing
+	rollbackCommand(restored, 5)
+	if versions, _ := Repository.GetCommandVersions(id); len(versions) != 3 {
+		t.Errorf("rollbackCommand(5) added a version")
+	}
+}
+
+func TestRollbackChain(t *testing.T) {
+	useScriptedExecutor(t)
+
+	var chain = models.Chain{Name: "deploy", Steps: []models.ChainStep{{CommandID: "a1"}}}
+	if err := Repository.PutChain(chain); err != nil {
+		t.Fatalf("PutChain returned unexpected error: %v", err)
+	}
+	chain.Steps = append(chain.Steps, models.ChainStep{CommandID: "b2"})
+	if err := Repository.PutChain(chain); err != nil {
+		t.Fatalf("PutChain returned unexpected error: %v", err)
+	}
+
+	rollbackChain(chain, 1)
+
+	restored, err := Repository.FindChainByName("deploy")
+	if err != nil || chainSteps(restored) != "a1" {
+		t.Errorf("FindChainByName(deploy) = %+v, %v, want the step a1 only", restored, err)
+	}
+	if versions, err := Repository.GetChainVersions("deploy"); err != nil || len(versions) != 2 || chainSteps(*versions[1].Chain) != "a1 b2" {
+		t.Errorf("GetChainVersions(deploy) = %+v, %v, want a1 b2 as version 2", versions, err)
+	}
+}
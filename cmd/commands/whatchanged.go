@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// a command is slower when its average duration grew by this factor, and at least by regressionMinimum
+const regressionFactor = 1.5
+const regressionMinimum = time.Second
+
+// historyChanges are the notable differences of the history since an instant
+type historyChanges struct {
+	New          []string
+	Failing      []string
+	Fixed        []string
+	Slower       []string
+	Hosts        []string
+	Environments []string
+}
+
+// whatChanged compares the commands executed since the instant with the ones executed before
+func whatChanged(commands []models.Command, since time.Time) historyChanges {
+	var before, after = []models.Command{}, []models.Command{}
+	for _, c := range commands {
+		if c.CreatedAt.Before(since) {
+			before = append(before, c)
+		} else {
+			after = append(after, c)
+		}
+	}
+
+	var previous, recent = statsByFingerprint(before), statsByFingerprint(after)
+	var changes = historyChanges{}
+
+	for fingerprint, r := range recent {
+		var line = commandLine(r.Last)
+
+		p, seen := previous[fingerprint]
+		if !seen {
+			var runs = " runs)"
+			if r.Count == 1 {
+				runs = " run)"
+			}
+			changes.New = append(changes.New, line+" ("+strconv.Itoa(r.Count)+runs)
+			continue
+		}
+
+		switch {
+		case p.Last.Status && !r.Last.Status:
+			changes.Failing = append(changes.Failing, r.Last.AsStoredCommand())
+		case !p.Last.Status && r.Last.Status:
+			changes.Fixed = append(changes.Fixed, r.Last.AsStoredCommand())
+		}
+
+		var growth = r.Average() - p.Average()
+		if growth >= regressionMinimum && float64(r.Average()) >= float64(p.Average())*regressionFactor {
+			changes.Slower = append(changes.Slower, line+" ("+p.Average().Round(time.Millisecond).String()+
+				" -> "+r.Average().Round(time.Millisecond).String()+")")
+		}
+	}
+
+	var hosts, environments = map[string]bool{}, map[string]bool{}
+	for _, c := range before {
+		hosts[c.Origin] = true
+		environments[c.Environment] = true
+	}
+
+	// the commands recorded before the origins, or run without environment, have an empty one
+	for _, c := range after {
+		if c.Origin != "" && !hosts[c.Origin] {
+			hosts[c.Origin] = true
+			changes.Hosts = append(changes.Hosts, c.Origin)
+		}
+		if c.Environment != "" && !environments[c.Environment] {
+			environments[c.Environment] = true
+			changes.Environments = append(changes.Environments, c.Environment)
+		}
+	}
+
+	for _, lines := range [][]string{changes.New, changes.Failing, changes.Fixed, changes.Slower, changes.Hosts, changes.Environments} {
+		sort.Strings(lines)
+	}
+
+	return changes
+}
+
+// reportChanges prints a section of the report, nothing when it is empty
+func reportChanges(title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	Parrot.Println(title + " (" + strconv.Itoa(len(lines)) + ")")
+	for _, line := range lines {
+		Parrot.Println("  " + line)
+	}
+}
+
+// whatchangedCmd represents the whatchanged command
+var whatchangedCmd = &cobra.Command{
+	Use:   "whatchanged",
+	Short: "Whatchanged",
+	Long: `Whatchanged command, summarizes how the history changed since an instant (default yesterday):
+the commands never run before, the ones which started or stopped failing, the ones getting slower
+and the hosts and environments recording commands for the first time`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commandWrapper(args, func() {
+			Parrot.Debug("Whatchanged command invoked")
+
+			since, err := sinceFromFlag(cmd.Flag("since").Value.String())
+			if err != nil {
+				Parrot.Println("Please provide a valid --since value (e.g. yesterday, 12h or 2024-05-01)")
+				return
+			}
+
+			commands, err := Repository.GetAllCommands()
+			if err != nil {
+				Parrot.Println("Error retrieving commands in the store", err)
+				return
+			}
+
+			var changes = whatChanged(commands, since)
+
+			var sections = [][]string{changes.New, changes.Failing, changes.Fixed, changes.Slower, changes.Hosts, changes.Environments}
+			var empty = true
+			for _, lines := range sections {
+				empty = empty && len(lines) == 0
+			}
+
+			if empty {
+				Parrot.Println("Nothing notable since " + since.Format("02.01.2006 15:04"))
+				return
+			}
+
+			Parrot.Println("Since " + since.Format("02.01.2006 15:04"))
+			reportChanges("New commands", changes.New)
+			reportChanges("Now failing", changes.Failing)
+			reportChanges("Fixed", changes.Fixed)
+			reportChanges("Slower", changes.Slower)
+			reportChanges("New hosts", changes.Hosts)
+			reportChanges("New environments", changes.Environments)
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(whatchangedCmd)
+
+	whatchangedCmd.Flags().String("since", "yesterday", "the instant compared: today, yesterday, a duration back from now (e.g. 12h, 7d) or a date")
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+func TestWhatChanged(t *testing.T) {
+	var since = time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+
+	var run = func(id string, line string, day int, duration time.Duration, status bool, origin string, environment string) models.Command {
+		var started = time.Date(2024, 5, day, 10, 0, 0, 0, time.UTC)
+		var c = models.Command{Entity: models.Entity{ID: id, CreatedAt: started, TerminatedAt: started.Add(duration)},
+			Status: status, Origin: origin, Environment: environment}
+		c.Name, c.Arguments = line, nil
+		return c
+	}
+
+	var commands = []models.Command{
+		run("1", "make", 1, time.Second, true, "laptop", ""),
+		run("2", "make", 2, time.Second, false, "laptop", ""),
+		run("3", "test", 1, time.Second, false, "laptop", "dev"),
+		run("4", "test", 2, time.Second, true, "laptop", "dev"),
+		run("5", "build", 1, time.Second, true, "laptop", ""),
+		run("6", "build", 2, 3*time.Second, true, "laptop", ""),
+		run("7", "lint", 1, time.Second, true, "laptop", ""),
+		run("8", "lint", 2, 1200*time.Millisecond, true, "laptop", ""),
+		run("9", "deploy", 2, time.Second, true, "ci", "prod"),
+		run("10", "deploy", 3, time.Second, true, "ci", "prod"),
+	}
+
+	var changes = whatChanged(commands, since)
+
+	var want = historyChanges{
+		New:          []string{"deploy (2 runs)"},
+		Failing:      []string{"[2] make "},
+		Fixed:        []string{"[4] test "},
+		Slower:       []string{"build (1s -> 3s)"},
+		Hosts:        []string{"ci"},
+		Environments: []string{"prod"},
+	}
+
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("whatChanged() = %+v, want %+v", changes, want)
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import "errors"
+
+// EnvironmentLayer is one of the sources merged into an effective environment
+type EnvironmentLayer struct {
+	Source    string
+	Variables map[string]string
+	Secrets   []string
+}
+
+// Layers returns the layers of an environment from the weakest to the strongest:
+// the inherited environments first, depth first and in order, then the environment itself
+func Layers(name string, lookup func(string) (Environment, error)) ([]EnvironmentLayer, error) {
+	var layers = []EnvironmentLayer{}
+	var visiting = map[string]bool{}
+	var visited = map[string]bool{}
+
+	var visit func(string, []string) error
+	visit = func(name string, path []string) error {
+		if visiting[name] {
+			return errors.New("environment inheritance cycle: " + joinPath(append(path, name)))
+		}
+		if visited[name] {
+			return nil
+		}
+
+		environment, err := lookup(name)
+		if err != nil {
+			return err
+		}
+
+		visiting[name] = true
+		for _, parent := range environment.Inherits {
+			if err := visit(parent, append(path, name)); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+
+		layers = append(layers, EnvironmentLayer{Source: name, Variables: environment.Variables, Secrets: environment.Secrets})
+		return nil
+	}
+
+	err := visit(name, []string{})
+	return layers, err
+}
+
+// Merge returns the effective variables of the layers, the strongest layer winning
+func Merge(layers []EnvironmentLayer) map[string]string {
+	var merged = map[string]string{}
+
+	for _, layer := range layers {
+		for key, value := range layer.Variables {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+func joinPath(path []string) string {
+	var joined = ""
+	for i, name := range path {
+		if i > 0 {
+			joined += " -> "
+		}
+		joined += name
+	}
+	return joined
+}
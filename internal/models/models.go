@@ -1,7 +1,12 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -12,29 +17,385 @@ import (
 )
 
 type Entity struct {
-	ID           string
-	CreatedAt    time.Time
-	TerminatedAt time.Time
+	ID           string    `json:"ID"`
+	CreatedAt    time.Time `json:"CreatedAt"`
+	TerminatedAt time.Time `json:"TerminatedAt"`
 }
 
 type Command struct {
 	Entity
 
-	Name      string
-	Arguments []string
-	Status    bool
-	Output    string
-	Error     string
+	Name      string   `json:"Name"`
+	Arguments []string `json:"Arguments"`
+	Status    bool     `json:"Status"`
+	Output    string   `json:"Output"`
+	Error     string   `json:"Error"`
+
+	PID         int           `json:"PID"`
+	Interrupted bool          `json:"Interrupted"`
+	LastUsedAt  time.Time     `json:"LastUsedAt"`
+	Warnings    int           `json:"Warnings"`
+	Errors      int           `json:"Errors"`
+	Streamed    bool          `json:"Streamed"`
+	HostStart   *HostSnapshot `json:"HostStart,omitempty"`
+	HostEnd     *HostSnapshot `json:"HostEnd,omitempty"`
+
+	// Origin is the machine that recorded the command, Sequence its position in the history of that machine
+	Origin   string `json:"Origin,omitempty"`
+	Sequence uint64 `json:"Sequence,omitempty"`
+
+	Repro *Repro `json:"Repro,omitempty"`
+
+	// Cold tells the output was moved out of the database, it is read back as a streamed one
+	Cold bool `json:"Cold,omitempty"`
+
+	Container *Container `json:"Container,omitempty"`
+	Scratch   *Scratch   `json:"Scratch,omitempty"`
+
+	// Environment is the name of the environment whose variables were injected
+	Environment string `json:"Environment,omitempty"`
+
+	// Risk is set when running the command again could do damage
+	Risk *Risk `json:"Risk,omitempty"`
+
+	// TimedOut tells the command failed because it was killed after running longer than allowed
+	TimedOut bool `json:"TimedOut,omitempty"`
+
+	// Usage is what the process of the command consumed, when it ran on this machine
+	Usage *Usage `json:"Usage,omitempty"`
+
+	// ExitCode is the code the command exited with, -1 when it did not exit by itself
+	ExitCode int `json:"ExitCode,omitempty"`
+
+	// AllowedExitCodes are the non zero exit codes (e.g. 1 or 1,126-128) meaning success for a stored command
+	AllowedExitCodes string `json:"AllowedExitCodes,omitempty"`
+
+	// ParentID is the execution this one runs again, possibly modified
+	ParentID string `json:"ParentID,omitempty"`
+
+	// Dir is the working directory of the command, Project the root of the project holding it, if any
+	Dir     string `json:"Dir,omitempty"`
+	Project string `json:"Project,omitempty"`
+
+	// Git is the state of the git repository of the directory when the command started
+	Git *Git `json:"Git,omitempty"`
+}
+
+// Code returns the exit code of the command, -1 when it failed before the exit codes were recorded
+func (c Command) Code() int {
+	if c.ExitCode == 0 && !c.Status {
+		return -1
+	}
+	return c.ExitCode
+}
+
+// Usage describes the resources consumed by the process of a command and by its children
+type Usage struct {
+	UserTime     time.Duration `json:"UserTime"`
+	SystemTime   time.Duration `json:"SystemTime"`
+	MaxRSS       int64         `json:"MaxRSS"`
+	ReadBytes    int64         `json:"ReadBytes"`
+	WrittenBytes int64         `json:"WrittenBytes"`
+}
+
+// CPU is the processor time the command used, in user and system mode
+func (u Usage) CPU() time.Duration {
+	return u.UserTime + u.SystemTime
+}
+
+// Scratch describes the temporary directory a command was executed in and the artifacts copied back
+type Scratch struct {
+	Dir       string   `json:"Dir"`
+	Artifacts []string `json:"Artifacts"`
+}
+
+// Container describes the container a command was executed in
+type Container struct {
+	Image    string `json:"Image"`
+	Name     string `json:"Name"`
+	ExitCode int    `json:"ExitCode"`
+}
+
+// Git describes the state of a git repository: its branch, HEAD when detached, its short commit hash,
+// empty before the first commit, and whether tracked files were modified
+type Git struct {
+	Branch string `json:"Branch"`
+	Commit string `json:"Commit"`
+	Dirty  bool   `json:"Dirty"`
+}
+
+func (g Git) String() string {
+	var s = g.Branch
+	if g.Commit != "" {
+		s += "@" + g.Commit
+	}
+	if g.Dirty {
+		s += " (dirty)"
+	}
+	return s
+}
+
+// HostSnapshot describes how busy the host was at a given moment
+type HostSnapshot struct {
+	Load1        float64 `json:"Load1"`
+	Load5        float64 `json:"Load5"`
+	Load15       float64 `json:"Load15"`
+	MemAvailable int64   `json:"MemAvailable"`
+	DiskFree     int64   `json:"DiskFree"`
 }
 
 type ExecutedCommand struct {
 	parrot *quant.Parrot
 
-	Order   int
-	ID      string
+	Order    int
+	ID       string
+	Command  string
+	Status   bool
+	When     time.Time
+	Warnings int
+	Errors   int
+}
+
+// CommandsPage is a page of the history, from the newest command to the oldest
+type CommandsPage struct {
+	Commands []Command
+	Total    int
+	Next     string
+}
+
+// ChainStep is a single command executed by a chain
+type ChainStep struct {
+	CommandID string   `json:"CommandID"`
+	Inputs    []string `json:"Inputs"`
+	// Needs lists the numbers of the steps which must succeed before this one starts
+	Needs []int `json:"Needs,omitempty"`
+
+	// Environment, Dir and Timeout override how the step runs: the environment applied, the working
+	// directory and how long it may run before being killed
+	Environment string        `json:"Environment,omitempty"`
+	Dir         string        `json:"Dir,omitempty"`
+	Timeout     time.Duration `json:"Timeout,omitempty"`
+	// Retries is how many times the step runs again when it fails, ContinueOnError lets the chain
+	// go on as if it succeeded when it still fails
+	Retries         int  `json:"Retries,omitempty"`
+	ContinueOnError bool `json:"ContinueOnError,omitempty"`
+	// Pipe feeds the output of the previous step, or of the steps it needs in a graph, to its input
+	Pipe bool `json:"Pipe,omitempty"`
+	// When is the condition the step runs on, e.g. {{steps.1.status}} == success
+	When string `json:"When,omitempty"`
+}
+
+// Settings describes what the step overrides, empty when it runs as the chain does
+func (s ChainStep) Settings() string {
+	var settings = []string{}
+
+	if len(s.Needs) > 0 {
+		var needs = make([]string, len(s.Needs))
+		for i, n := range s.Needs {
+			needs[i] = strconv.Itoa(n)
+		}
+		settings = append(settings, "needs "+strings.Join(needs, ", "))
+	}
+	if s.Environment != "" {
+		settings = append(settings, "env "+s.Environment)
+	}
+	if s.Dir != "" {
+		settings = append(settings, "dir "+s.Dir)
+	}
+	if s.Timeout > 0 {
+		settings = append(settings, "timeout "+s.Timeout.String())
+	}
+	if s.Retries > 0 {
+		settings = append(settings, "retries "+strconv.Itoa(s.Retries))
+	}
+	if s.ContinueOnError {
+		settings = append(settings, "continues on error")
+	}
+	if s.Pipe {
+		settings = append(settings, "reads the previous output")
+	}
+	if s.When != "" {
+		settings = append(settings, "when "+s.When)
+	}
+
+	return strings.Join(settings, "; ")
+}
+
+// Chain is a named sequence of stored or executed commands
+type Chain struct {
+	Entity
+
+	Name        string      `json:"Name"`
+	Description string      `json:"Description"`
+	Steps       []ChainStep `json:"Steps"`
+	Triggers    []string    `json:"Triggers"`
+}
+
+// ChainExecution is a run of a chain and how each of its steps went; Resumed is the id of the
+// failed run it resumes, if any
+type ChainExecution struct {
+	Entity
+
+	Chain   string               `json:"Chain"`
+	Status  bool                 `json:"Status"`
+	Steps   []ChainStepExecution `json:"Steps"`
+	Resumed string               `json:"Resumed,omitempty"`
+}
+
+// ChainStepExecution is how a step went in a run of a chain, Command is the id of the command it
+// executed in the history; a skipped step did not run and has none
+type ChainStepExecution struct {
+	Step        int           `json:"Step"`
+	CommandID   string        `json:"CommandID"`
+	Command     string        `json:"Command,omitempty"`
+	Environment string        `json:"Environment,omitempty"`
+	Status      bool          `json:"Status"`
+	Skipped     bool          `json:"Skipped,omitempty"`
+	Duration    time.Duration `json:"Duration"`
+}
+
+// Failed returns the numbers of the steps which ran and failed
+func (e ChainExecution) Failed() []int {
+	var failed = []int{}
+	for _, s := range e.Steps {
+		if !s.Skipped && !s.Status {
+			failed = append(failed, s.Step)
+		}
+	}
+	return failed
+}
+
+// Version is a previous variant of a stored command, of a chain or of an environment, kept when it was
+// modified or deleted; a version without any of them means the entity did not exist
+type Version struct {
+	Number      int          `json:"Number"`
+	SavedAt     time.Time    `json:"SavedAt"`
+	Command     *Command     `json:"Command,omitempty"`
+	Chain       *Chain       `json:"Chain,omitempty"`
+	Environment *Environment `json:"Environment,omitempty"`
+}
+
+// Environment is a named set of variables injected in the executed commands
+type Environment struct {
+	Entity
+
+	Name      string            `json:"Name"`
+	Variables map[string]string `json:"Variables"`
+	// Inherits lists the environments whose variables are applied first, in order
+	Inherits []string `json:"Inherits,omitempty"`
+	// Secrets lists the variables whose value is stored encrypted
+	Secrets []string `json:"Secrets,omitempty"`
+}
+
+// IsSecret tells if the variable is a secret
+func (e Environment) IsSecret(key string) bool {
+	for _, s := range e.Secrets {
+		if s == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Environ returns the variables in the KEY=VALUE form used by processes, sorted by name
+func (e Environment) Environ() []string {
+	var names = make([]string, 0, len(e.Variables))
+	for name := range e.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var environ = make([]string, 0, len(names))
+	for _, name := range names {
+		environ = append(environ, name+"="+e.Variables[name])
+	}
+
+	return environ
+}
+
+// ArchiveVersion is the version of the export archive layout
+const ArchiveVersion = 1
+
+// Archive is the portable dump of a repository
+type Archive struct {
+	Version    int       `json:"Version"`
+	ExportedAt time.Time `json:"ExportedAt"`
+	Commands   []Command `json:"Commands"`
+	Stored     []Command `json:"Stored"`
+	Chains     []Chain   `json:"Chains"`
+
+	Environments []Environment `json:"Environments,omitempty"`
+}
+
+// ArchiveRecord is a single line of an archive exported as ndjson
+type ArchiveRecord struct {
+	Kind    string   `json:"Kind"`
+	Version int      `json:"Version,omitempty"`
+	Command *Command `json:"Command,omitempty"`
+	Chain   *Chain   `json:"Chain,omitempty"`
+
+	Environment *Environment `json:"Environment,omitempty"`
+}
+
+// Snapshot is the portable copy of a single environment or chain, with the commands of the chain
+type Snapshot struct {
+	Kind      string    `json:"Kind,omitempty"`
+	CreatedAt time.Time `json:"CreatedAt,omitempty"`
+
+	Environment *Environment `json:"Environment,omitempty"`
+	// Salt derives, with a passphrase, the key encrypting the secrets; empty when they are left out
+	Salt string `json:"Salt,omitempty"`
+
+	Chain    *Chain    `json:"Chain,omitempty"`
+	Commands []Command `json:"Commands,omitempty"`
+}
+
+// Risk tells how dangerous it is to run a command again, from 0 to 100
+type Risk struct {
+	Score   int      `json:"Score"`
+	Reasons []string `json:"Reasons,omitempty"`
+}
+
+// thresholds of the risk levels
+const (
+	RiskMedium = 30
+	RiskHigh   = 60
+)
+
+// Level names the risk: low, medium or high
+func (r Risk) Level() string {
+	switch {
+	case r.Score >= RiskHigh:
+		return "high"
+	case r.Score >= RiskMedium:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// Backup is a link of the backup chain: a full copy of the repository or the changes since the previous link
+type Backup struct {
+	Name      string    `json:"Name"`
+	Kind      string    `json:"Kind"`
+	Base      string    `json:"Base,omitempty"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	// Checksum is the sha256 of the backup file
+	Checksum string `json:"Checksum"`
+	// State is the hash of the repository content the backup restores
+	State   string `json:"State"`
+	Changes int    `json:"Changes"`
+	Size    int64  `json:"Size"`
+	// Compressed tells if the copy of a full backup is gzipped
+	Compressed bool `json:"Compressed,omitempty"`
+}
+
+// PerfRecord keeps the time spent by a single ambros invocation in each phase
+type PerfRecord struct {
+	Entity
+
 	Command string
-	Status  bool
-	When    time.Time
+	Phases  map[string]time.Duration
 }
 
 func (c *Command) Clone() *Command {
@@ -50,6 +411,32 @@ func (c *Command) Clone() *Command {
 		Status:    c.Status,
 		Output:    c.Output,
 		Error:     c.Error,
+
+		PID:         c.PID,
+		Interrupted: c.Interrupted,
+		LastUsedAt:  c.LastUsedAt,
+		Warnings:    c.Warnings,
+		Errors:      c.Errors,
+		Streamed:    c.Streamed,
+		HostStart:   c.HostStart,
+		HostEnd:     c.HostEnd,
+		Origin:      c.Origin,
+		Sequence:    c.Sequence,
+		Repro:       c.Repro,
+		Cold:        c.Cold,
+		Container:   c.Container,
+		Scratch:     c.Scratch,
+		Environment: c.Environment,
+		Risk:        c.Risk,
+		TimedOut:    c.TimedOut,
+		Usage:       c.Usage,
+
+		ExitCode:         c.ExitCode,
+		AllowedExitCodes: c.AllowedExitCodes,
+		ParentID:         c.ParentID,
+		Dir:              c.Dir,
+		Project:          c.Project,
+		Git:              c.Git,
 	}
 
 	// Copy the elements of the Arguments slice to the clone's Arguments slice
@@ -73,7 +460,7 @@ func (c *Command) AsHistory() string {
 
 func (c *Command) AsExecutedCommand(order int) ExecutedCommand {
 	s := c.Name + " " + strings.Join(c.Arguments, " ")
-	return ExecutedCommand{Order: order, ID: c.ID, Command: s, Status: c.Status, When: c.CreatedAt}
+	return ExecutedCommand{Order: order, ID: c.ID, Command: s, Status: c.Status, When: c.CreatedAt, Warnings: c.Warnings, Errors: c.Errors}
 }
 
 func (c Command) ToMap() map[string]interface{} {
@@ -86,6 +473,31 @@ func (c Command) ToMap() map[string]interface{} {
 		"Error":        c.Error,
 		"CreatedAt":    c.CreatedAt,
 		"TerminatedAt": c.TerminatedAt,
+		"PID":          c.PID,
+		"Interrupted":  c.Interrupted,
+		"LastUsedAt":   c.LastUsedAt,
+		"Warnings":     c.Warnings,
+		"Errors":       c.Errors,
+		"Streamed":     c.Streamed,
+		"HostStart":    c.HostStart,
+		"HostEnd":      c.HostEnd,
+		"Origin":       c.Origin,
+		"Sequence":     c.Sequence,
+		"Repro":        c.Repro,
+		"Cold":         c.Cold,
+		"Container":    c.Container,
+		"Scratch":      c.Scratch,
+		"Environment":  c.Environment,
+		"Risk":         c.Risk,
+		"TimedOut":     c.TimedOut,
+		"Usage":        c.Usage,
+
+		"ExitCode":         c.ExitCode,
+		"AllowedExitCodes": c.AllowedExitCodes,
+		"ParentID":         c.ParentID,
+		"Dir":              c.Dir,
+		"Project":          c.Project,
+		"Git":              c.Git,
 	}
 }
 
@@ -98,6 +510,107 @@ func (c *Command) FromMap(frommap map[string]interface{}) {
 	c.Error = frommap["Error"].(string)
 	c.CreatedAt = frommap["CreatedAt"].(time.Time)
 	c.TerminatedAt = frommap["TerminatedAt"].(time.Time)
+	c.PID = frommap["PID"].(int)
+	c.Interrupted = frommap["Interrupted"].(bool)
+	c.LastUsedAt = frommap["LastUsedAt"].(time.Time)
+	c.Warnings = frommap["Warnings"].(int)
+	c.Errors = frommap["Errors"].(int)
+	c.Streamed = frommap["Streamed"].(bool)
+	c.HostStart = frommap["HostStart"].(*HostSnapshot)
+	c.HostEnd = frommap["HostEnd"].(*HostSnapshot)
+	c.Origin = frommap["Origin"].(string)
+	c.Sequence = frommap["Sequence"].(uint64)
+	c.Repro = frommap["Repro"].(*Repro)
+	c.Cold = frommap["Cold"].(bool)
+	c.Container = frommap["Container"].(*Container)
+	c.Scratch = frommap["Scratch"].(*Scratch)
+	c.Environment = frommap["Environment"].(string)
+	c.Risk = frommap["Risk"].(*Risk)
+	c.TimedOut = frommap["TimedOut"].(bool)
+	c.Usage = frommap["Usage"].(*Usage)
+	c.ExitCode = frommap["ExitCode"].(int)
+	c.AllowedExitCodes = frommap["AllowedExitCodes"].(string)
+	c.ParentID = frommap["ParentID"].(string)
+	c.Dir = frommap["Dir"].(string)
+	c.Project = frommap["Project"].(string)
+	c.Git = frommap["Git"].(*Git)
+}
+
+// Fingerprint identifies a command line independently of its executions
+func (c Command) Fingerprint() string {
+	sum := sha256.Sum256([]byte(c.Name + " " + strings.Join(c.Arguments, " ")))
+	return hex.EncodeToString(sum[:8])
+}
+
+var placeholderRegexp = regexp.MustCompile(`\{(\d+)\}`)
+
+// Placeholders returns how many positional values ({1}, {2}, ...) the command expects
+func (c Command) Placeholders() int {
+	var max = 0
+
+	for _, a := range append([]string{c.Name}, c.Arguments...) {
+		for _, m := range placeholderRegexp.FindAllStringSubmatch(a, -1) {
+			if n, _ := strconv.Atoi(m[1]); n > max {
+				max = n
+			}
+		}
+	}
+
+	return max
+}
+
+// MissingPlaceholders returns the positional placeholders skipped in the numbering (e.g. {2} in "{1} {3}")
+func (c Command) MissingPlaceholders() []int {
+	var used = map[int]bool{}
+
+	for _, a := range append([]string{c.Name}, c.Arguments...) {
+		for _, m := range placeholderRegexp.FindAllStringSubmatch(a, -1) {
+			n, _ := strconv.Atoi(m[1])
+			used[n] = true
+		}
+	}
+
+	var missing = []int{}
+	for i := 1; i <= c.Placeholders(); i++ {
+		if !used[i] {
+			missing = append(missing, i)
+		}
+	}
+
+	return missing
+}
+
+// Substitute replaces the positional placeholders with the given values
+func (c Command) Substitute(values []string) (Command, error) {
+	if len(values) != c.Placeholders() {
+		return c, errors.New("expected " + strconv.Itoa(c.Placeholders()) + " arguments, got " + strconv.Itoa(len(values)))
+	}
+
+	var replace = func(s string) string {
+		return placeholderRegexp.ReplaceAllStringFunc(s, func(m string) string {
+			n, _ := strconv.Atoi(m[1 : len(m)-1])
+			if n < 1 {
+				return m
+			}
+			return values[n-1]
+		})
+	}
+
+	var substituted = *c.Clone()
+	substituted.Name = replace(c.Name)
+	for i, a := range substituted.Arguments {
+		substituted.Arguments[i] = replace(a)
+	}
+
+	return substituted, nil
+}
+
+// LastUsed returns when a stored command was last run, falling back to its creation
+func (c Command) LastUsed() time.Time {
+	if c.LastUsedAt.IsZero() {
+		return c.CreatedAt
+	}
+	return c.LastUsedAt
 }
 
 func (c Command) AsStoredCommand() string {
@@ -116,5 +629,78 @@ func (c ExecutedCommand) Print(parrot *quant.Parrot) {
 	} else {
 		parrot.Print("[", chalk.Red, c.ID, chalk.Reset, "] ")
 	}
+
+	if c.Warnings > 0 || c.Errors > 0 {
+		parrot.Print("(", chalk.Yellow, strconv.Itoa(c.Warnings)+"w", chalk.Reset, "/", chalk.Red, strconv.Itoa(c.Errors)+"e", chalk.Reset, ") ")
+	}
 	parrot.Println(c.Command)
 }
+
+func (c Chain) String() (string, error) {
+	b, err := json.Marshal(c)
+
+	if err != nil {
+		return "{}", err
+	}
+	return string(b), nil
+}
+
+// Graph tells if the steps declare the steps they need: they run as soon as those succeeded,
+// the independent ones in parallel; otherwise the steps run one after the other
+func (c Chain) Graph() bool {
+	for _, s := range c.Steps {
+		if len(s.Needs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckNeeds returns an error when a step needs a step which does not exist or which, directly or not, needs it
+func (c Chain) CheckNeeds() error {
+	for i, s := range c.Steps {
+		for _, n := range s.Needs {
+			if n < 1 || n > len(c.Steps) || n == i+1 {
+				return errors.New("step " + strconv.Itoa(i+1) + " needs step " + strconv.Itoa(n) + ", which is not another step of the chain")
+			}
+		}
+	}
+
+	// 0 not visited, 1 being visited, 2 done
+	var visits = make([]int, len(c.Steps))
+
+	var visit func(step int) error
+	visit = func(step int) error {
+		switch visits[step] {
+		case 1:
+			return errors.New("step " + strconv.Itoa(step+1) + " needs itself through the steps it needs")
+		case 2:
+			return nil
+		}
+
+		visits[step] = 1
+		for _, n := range c.Steps[step].Needs {
+			if err := visit(n - 1); err != nil {
+				return err
+			}
+		}
+		visits[step] = 2
+		return nil
+	}
+
+	for i := range c.Steps {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c Chain) AsStoredChain() string {
+	ids := make([]string, len(c.Steps))
+	for i, s := range c.Steps {
+		ids[i] = s.CommandID
+	}
+	return "[" + c.Name + "] " + strings.Join(ids, " -> ")
+}
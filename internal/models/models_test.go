@@ -0,0 +1,166 @@
+package models_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/models"
+)
+
+func TestCommand_Placeholders(t *testing.T) {
+	command := models.Command{Name: "kubectl", Arguments: []string{"logs", "-n", "{1}", "{2}"}}
+
+	if result := command.Placeholders(); result != 2 {
+		t.Errorf("Placeholders() returned unexpected result: got %d, want %d", result, 2)
+	}
+
+	command = models.Command{Name: "ls", Arguments: []string{"-la"}}
+	if result := command.Placeholders(); result != 0 {
+		t.Errorf("Placeholders() returned unexpected result: got %d, want %d", result, 0)
+	}
+}
+
+func TestCommand_Substitute(t *testing.T) {
+	command := models.Command{Name: "kubectl", Arguments: []string{"logs", "-n", "{1}", "{2}", "--prefix={1}"}}
+
+	result, err := command.Substitute([]string{"prod", "api-pod"})
+	if err != nil {
+		t.Fatalf("Substitute() returned unexpected error: %v", err)
+	}
+
+	expected := "logs -n prod api-pod --prefix=prod"
+	if strings.Join(result.Arguments, " ") != expected {
+		t.Errorf("Substitute() returned unexpected result: got %v, want %s", result.Arguments, expected)
+	}
+
+	// The original command must be left untouched
+	if command.Arguments[2] != "{1}" {
+		t.Errorf("Substitute() modified the original command: %v", command.Arguments)
+	}
+
+	// Test case: Wrong arity
+	if _, err := command.Substitute([]string{"prod"}); err == nil {
+		t.Errorf("Substitute() did not fail on wrong arity")
+	}
+}
+
+func TestCommand_MissingPlaceholders(t *testing.T) {
+	command := models.Command{Name: "scp", Arguments: []string{"{1}", "{3}:/tmp"}}
+
+	if result := command.MissingPlaceholders(); len(result) != 1 || result[0] != 2 {
+		t.Errorf("MissingPlaceholders() returned unexpected result: got %v, want [2]", result)
+	}
+}
+
+func TestRepro_Differences(t *testing.T) {
+	recorded := models.Repro{OS: "linux", Arch: "amd64", Path: "/usr/bin/go", Version: "go1.21", EnvHash: "a",
+		Env: map[string]string{"PATH": "1", "GOPATH": "2"}}
+
+	if result := recorded.Differences(recorded); len(result) != 0 {
+		t.Errorf("Differences() returned unexpected result for the same fingerprint: %v", result)
+	}
+
+	current := models.Repro{OS: "linux", Arch: "amd64", Path: "/usr/local/bin/go", Version: "go1.22", EnvHash: "b",
+		Env: map[string]string{"PATH": "3", "GOFLAGS": "4"}}
+
+	expected := []string{
+		"the executable resolves to /usr/local/bin/go instead of /usr/bin/go",
+		"version changed from 'go1.21' to 'go1.22'",
+		"GOFLAGS is now set",
+		"GOPATH is not set anymore",
+		"PATH changed",
+	}
+
+	result := recorded.Differences(current)
+	if strings.Join(result, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Differences() returned unexpected result: got %v, want %v", result, expected)
+	}
+
+	// Only untracked variables changed
+	current = recorded
+	current.EnvHash = "b"
+	if result := recorded.Differences(current); len(result) != 1 || result[0] != "other environment variables changed" {
+		t.Errorf("Differences() returned unexpected result: %v", result)
+	}
+}
+
+func TestEnvironment_Environ(t *testing.T) {
+	environment := models.Environment{Name: "prod", Variables: map[string]string{"REGION": "eu", "API_URL": "https://x?a=b"}}
+
+	result := strings.Join(environment.Environ(), " ")
+	if expected := "API_URL=https://x?a=b REGION=eu"; result != expected {
+		t.Errorf("Environ() returned unexpected result: got %q, want %q", result, expected)
+	}
+}
+
+func TestLayers(t *testing.T) {
+	environments := map[string]models.Environment{
+		"base":    {Name: "base", Variables: map[string]string{"REGION": "eu", "LOG": "info"}},
+		"secrets": {Name: "secrets", Variables: map[string]string{"TOKEN": "t"}},
+		"prod":    {Name: "prod", Variables: map[string]string{"LOG": "warn"}, Inherits: []string{"base", "secrets"}},
+		"loop":    {Name: "loop", Inherits: []string{"prod", "loop"}},
+	}
+
+	lookup := func(name string) (models.Environment, error) {
+		if e, ok := environments[name]; ok {
+			return e, nil
+		}
+		return models.Environment{}, errors.New("not found")
+	}
+
+	layers, err := models.Layers("prod", lookup)
+	if err != nil {
+		t.Fatalf("Layers() returned unexpected error: %v", err)
+	}
+
+	var sources = []string{}
+	for _, layer := range layers {
+		sources = append(sources, layer.Source)
+	}
+	if strings.Join(sources, ",") != "base,secrets,prod" {
+		t.Errorf("Layers() returned unexpected order: %v", sources)
+	}
+
+	merged := models.Merge(layers)
+	if merged["LOG"] != "warn" || merged["REGION"] != "eu" || merged["TOKEN"] != "t" {
+		t.Errorf("Merge() returned unexpected result: %v", merged)
+	}
+
+	if _, err := models.Layers("loop", lookup); err == nil {
+		t.Errorf("Layers() did not detect the inheritance cycle")
+	}
+}
+
+func TestChain_CheckNeeds(t *testing.T) {
+	var steps = func(needs ...[]int) models.Chain {
+		var chain = models.Chain{Name: "build"}
+		for _, n := range needs {
+			chain.Steps = append(chain.Steps, models.ChainStep{CommandID: "x", Needs: n})
+		}
+		return chain
+	}
+
+	tests := []struct {
+		chain models.Chain
+		graph bool
+		valid bool
+	}{
+		{steps(nil, nil, nil), false, true},
+		{steps(nil, nil, []int{1, 2}), true, true},
+		{steps([]int{3}, nil, nil), true, true},
+		{steps(nil, []int{2}), true, false},
+		{steps(nil, []int{4}), true, false},
+		{steps(nil, []int{0}), true, false},
+		{steps([]int{3}, []int{1}, []int{2}), true, false},
+	}
+
+	for i, test := range tests {
+		if result := test.chain.Graph(); result != test.graph {
+			t.Errorf("Graph() of chain %d returned unexpected result: got %v, want %v", i, result, test.graph)
+		}
+		if err := test.chain.CheckNeeds(); (err == nil) != test.valid {
+			t.Errorf("CheckNeeds() of chain %d returned unexpected result: got %v, want valid %v", i, err, test.valid)
+		}
+	}
+}
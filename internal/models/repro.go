@@ -0,0 +1,69 @@
+package models
+
+import "sort"
+
+// Repro fingerprints the environment a command ran in, to explain why it behaves differently elsewhere;
+// environment variables are stored as hashes so their values never reach the repository
+type Repro struct {
+	OS      string            `json:"OS"`
+	Arch    string            `json:"Arch"`
+	Path    string            `json:"Path"`
+	Version string            `json:"Version,omitempty"`
+	EnvHash string            `json:"EnvHash"`
+	Env     map[string]string `json:"Env,omitempty"`
+}
+
+// Differences describes what changed from the recorded fingerprint to the current one
+func (r Repro) Differences(current Repro) []string {
+	var differences = []string{}
+
+	if r.OS != current.OS || r.Arch != current.Arch {
+		differences = append(differences, "platform changed from "+r.OS+"/"+r.Arch+" to "+current.OS+"/"+current.Arch)
+	}
+
+	switch {
+	case current.Path == "":
+		differences = append(differences, "the executable is not found anymore (was "+r.Path+")")
+	case r.Path != current.Path:
+		differences = append(differences, "the executable resolves to "+current.Path+" instead of "+r.Path)
+	}
+
+	if r.Version != "" && r.Version != current.Version {
+		differences = append(differences, "version changed from '"+r.Version+"' to '"+current.Version+"'")
+	}
+
+	var names = []string{}
+	for name := range r.Env {
+		names = append(names, name)
+	}
+	for name := range current.Env {
+		if _, ok := r.Env[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changed = 0
+	for _, name := range names {
+		before, wasSet := r.Env[name]
+		after, isSet := current.Env[name]
+
+		switch {
+		case !wasSet:
+			differences = append(differences, name+" is now set")
+		case !isSet:
+			differences = append(differences, name+" is not set anymore")
+		case before != after:
+			differences = append(differences, name+" changed")
+		default:
+			continue
+		}
+		changed++
+	}
+
+	if r.EnvHash != current.EnvHash && changed == 0 {
+		differences = append(differences, "other environment variables changed")
+	}
+
+	return differences
+}
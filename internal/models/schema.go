@@ -0,0 +1,121 @@
+package models
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaID identifies the published JSON Schema of the current archive version
+var SchemaID = "https://github.com/gi4nks/ambros/schema/archive-v" + strconv.Itoa(ArchiveVersion) + ".json"
+
+// ArchiveSchema generates the JSON Schema of the exported data from the json tags of the models,
+// so the published schema cannot drift from what export actually writes
+func ArchiveSchema() map[string]interface{} {
+	var definitions = map[string]interface{}{}
+
+	var schema = map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         SchemaID,
+		"title":       "Ambros archive v" + strconv.Itoa(ArchiveVersion),
+		"description": "Archive written by 'ambros export --all', as a json document; ndjson archives contain one ArchiveRecord per line",
+		"oneOf": []interface{}{
+			map[string]interface{}{"$ref": "#/$defs/Archive"},
+			map[string]interface{}{"$ref": "#/$defs/ArchiveRecord"},
+		},
+	}
+
+	schemaOf(reflect.TypeOf(Archive{}), definitions)
+	schemaOf(reflect.TypeOf(ArchiveRecord{}), definitions)
+	schema["$defs"] = definitions
+
+	return schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// schemaOf returns the schema of a type, registering named structs in the definitions
+func schemaOf(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return map[string]interface{}{"type": "integer", "description": "nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return map[string]interface{}{"oneOf": []interface{}{schemaOf(t.Elem(), definitions), map[string]interface{}{"type": "null"}}}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": schemaOf(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": []string{"object", "null"}, "additionalProperties": schemaOf(t.Elem(), definitions)}
+	case reflect.Struct:
+		if _, ok := definitions[t.Name()]; !ok {
+			// placeholder first, so recursive types terminate
+			definitions[t.Name()] = map[string]interface{}{}
+			definitions[t.Name()] = structSchema(t, definitions)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	}
+
+	return map[string]interface{}{}
+}
+
+func structSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	var properties = map[string]interface{}{}
+	var required = []string{}
+
+	collectFields(t, properties, &required, definitions)
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// collectFields adds the json fields of a struct, flattening the embedded ones like encoding/json
+func collectFields(t reflect.Type, properties map[string]interface{}, required *[]string, definitions map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		var field = t.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectFields(field.Type, properties, required, definitions)
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		var tag = field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		var name = field.Name
+		var parts = strings.Split(tag, ",")
+		if parts[0] != "" {
+			name = parts[0]
+		}
+
+		properties[name] = schemaOf(field.Type, definitions)
+
+		if !strings.Contains(tag, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
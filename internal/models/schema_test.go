@@ -0,0 +1,35 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/models"
+)
+
+func TestArchiveSchema(t *testing.T) {
+	schema := models.ArchiveSchema()
+
+	if schema["$id"] != models.SchemaID {
+		t.Errorf("ArchiveSchema() returned unexpected id: got %v, want %s", schema["$id"], models.SchemaID)
+	}
+
+	definitions := schema["$defs"].(map[string]interface{})
+	for _, name := range []string{"Archive", "ArchiveRecord", "Command", "Chain", "ChainStep", "HostSnapshot", "Environment"} {
+		if _, ok := definitions[name]; !ok {
+			t.Errorf("ArchiveSchema() is missing the definition of %s", name)
+		}
+	}
+
+	// Every field written by encoding/json must be described by the schema
+	encoded, _ := json.Marshal(models.Command{})
+	var fields map[string]interface{}
+	json.Unmarshal(encoded, &fields)
+
+	properties := definitions["Command"].(map[string]interface{})["properties"].(map[string]interface{})
+	for name := range fields {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("ArchiveSchema() does not describe the Command field %s", name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// ClockSkew is a point where the clock of an origin went backwards between two consecutive commands
+type ClockSkew struct {
+	Origin   string
+	Sequence uint64
+	Drift    time.Duration
+}
+
+// byOrigin groups the commands per origin, each group ordered by sequence;
+// commands recorded before origins existed are grouped together by creation time
+func byOrigin(commands []Command) map[string][]Command {
+	var groups = map[string][]Command{}
+
+	for _, c := range commands {
+		groups[c.Origin] = append(groups[c.Origin], c)
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].Sequence != group[j].Sequence {
+				return group[i].Sequence < group[j].Sequence
+			}
+			return group[i].CreatedAt.Before(group[j].CreatedAt)
+		})
+	}
+
+	return groups
+}
+
+// Timeline orders commands coming from several machines: the order of each origin is given by its
+// sequence and is never broken, timestamps only decide how the origins interleave
+func Timeline(commands []Command) []Command {
+	var groups = byOrigin(commands)
+	var origins = make([]string, 0, len(groups))
+	for origin := range groups {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+
+	var timeline = make([]Command, 0, len(commands))
+	for len(timeline) < len(commands) {
+		var next = ""
+		var found = false
+
+		for _, origin := range origins {
+			if len(groups[origin]) == 0 {
+				continue
+			}
+			if !found || groups[origin][0].CreatedAt.Before(groups[next][0].CreatedAt) {
+				next = origin
+				found = true
+			}
+		}
+
+		timeline = append(timeline, groups[next][0])
+		groups[next] = groups[next][1:]
+	}
+
+	return timeline
+}
+
+// ClockSkews finds, for each origin, the commands created before the ones preceding them in sequence
+func ClockSkews(commands []Command) []ClockSkew {
+	var skews = []ClockSkew{}
+
+	for origin, group := range byOrigin(commands) {
+		if origin == "" {
+			continue
+		}
+
+		var latest time.Time
+		for _, c := range group {
+			if c.CreatedAt.Before(latest) {
+				skews = append(skews, ClockSkew{Origin: origin, Sequence: c.Sequence, Drift: latest.Sub(c.CreatedAt)})
+				continue
+			}
+			latest = c.CreatedAt
+		}
+	}
+
+	sort.Slice(skews, func(i, j int) bool {
+		if skews[i].Origin != skews[j].Origin {
+			return skews[i].Origin < skews[j].Origin
+		}
+		return skews[i].Sequence < skews[j].Sequence
+	})
+
+	return skews
+}
@@ -0,0 +1,45 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gi4nks/ambros/internal/models"
+)
+
+func command(id string, origin string, sequence uint64, created time.Time) models.Command {
+	c := models.Command{Origin: origin, Sequence: sequence}
+	c.ID = id
+	c.CreatedAt = created
+	return c
+}
+
+func TestTimeline(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	// laptop's clock went back five minutes between its second and third command
+	commands := []models.Command{
+		command("l3", "laptop", 3, base.Add(-4*time.Minute)),
+		command("s1", "server", 1, base.Add(30*time.Second)),
+		command("l1", "laptop", 1, base),
+		command("l2", "laptop", 2, base.Add(time.Minute)),
+		command("s2", "server", 2, base.Add(2*time.Minute)),
+	}
+
+	result := models.Timeline(commands)
+	expected := []string{"l1", "s1", "l2", "l3", "s2"}
+
+	if len(result) != len(expected) {
+		t.Fatalf("Timeline() returned %d commands, want %d", len(result), len(expected))
+	}
+	for i, id := range expected {
+		if result[i].ID != id {
+			t.Errorf("Timeline()[%d] returned unexpected command: got %s, want %s", i, result[i].ID, id)
+		}
+	}
+
+	skews := models.ClockSkews(commands)
+	if len(skews) != 1 || skews[0].Origin != "laptop" || skews[0].Sequence != 3 || skews[0].Drift != 5*time.Minute {
+		t.Errorf("ClockSkews() returned unexpected result: got %+v", skews)
+	}
+}
@@ -0,0 +1,227 @@
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gi4nks/ambros/internal/utils"
+)
+
+// Policy is how a channel is delivered to: each event is tried again after a backoff doubling every
+// time, the same event is sent once within the dedup window and a channel failing threshold events
+// in a row is left alone, its circuit open, for the cooldown
+type Policy struct {
+	Retries     int
+	Backoff     time.Duration
+	DedupWindow time.Duration
+	Threshold   int
+	Cooldown    time.Duration
+}
+
+// DefaultPolicy is the policy of the configured channels
+var DefaultPolicy = Policy{Retries: 2, Backoff: time.Second, DedupWindow: 10 * time.Minute, Threshold: 3, Cooldown: 15 * time.Minute}
+
+// ErrCircuitOpen is returned without sending while the circuit of a channel is open
+var ErrCircuitOpen = errors.New("circuit open, the channel failed too often")
+
+// SinkState is how the deliveries to a channel went
+type SinkState struct {
+	Failures    int       `json:"Failures"`
+	OpenUntil   time.Time `json:"OpenUntil,omitempty"`
+	LastSuccess time.Time `json:"LastSuccess,omitempty"`
+	LastFailure time.Time `json:"LastFailure,omitempty"`
+	LastError   string    `json:"LastError,omitempty"`
+	Suppressed  int       `json:"Suppressed,omitempty"`
+}
+
+// Open tells if the circuit is open at the instant, nothing is sent then
+func (s SinkState) Open(now time.Time) bool {
+	return now.Before(s.OpenUntil)
+}
+
+// State is how the deliveries went across the invocations, by channel key, with the events recently
+// sent by digest
+type State struct {
+	Sinks  map[string]*SinkState `json:"Sinks"`
+	Recent map[string]time.Time  `json:"Recent"`
+
+	lock sync.Mutex
+}
+
+// NewState returns a state with no deliveries
+func NewState() *State {
+	return &State{Sinks: map[string]*SinkState{}, Recent: map[string]time.Time{}}
+}
+
+// LoadState reads the state saved in the file, a missing file is a state with no deliveries
+func LoadState(path string) (*State, error) {
+	var state = NewState()
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	if err := json.Unmarshal(b, state); err != nil {
+		return NewState(), err
+	}
+	if state.Sinks == nil {
+		state.Sinks = map[string]*SinkState{}
+	}
+	if state.Recent == nil {
+		state.Recent = map[string]time.Time{}
+	}
+
+	return state, nil
+}
+
+// Save writes the state to the file, forgetting the events older than the dedup window
+func (s *State) Save(path string, policy Policy) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var now = time.Now()
+	for digest, sent := range s.Recent {
+		if now.Sub(sent) > policy.DedupWindow {
+			delete(s.Recent, digest)
+		}
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0600)
+}
+
+// Sink returns the state of a channel, none when nothing was delivered to it yet
+func (s *State) Sink(key string) SinkState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if sink, found := s.Sinks[key]; found {
+		return *sink
+	}
+	return SinkState{}
+}
+
+func (s *State) sink(key string) *SinkState {
+	if _, found := s.Sinks[key]; !found {
+		s.Sinks[key] = &SinkState{}
+	}
+	return s.Sinks[key]
+}
+
+// Key names a configured channel in the state, two channels of the same type apart
+func Key(settings utils.NotificationChannel) string {
+	switch {
+	case settings.URL != "":
+		// the url of a webhook is a secret, only its digest is kept
+		var digest = sha256.Sum256([]byte(settings.URL))
+		return settings.Type + " " + hex.EncodeToString(digest[:4])
+	case settings.Host != "":
+		return settings.Type + " " + settings.Host
+	}
+	return settings.Type
+}
+
+// reliableChannel delivers to a channel as its policy says, recording how it went in the state
+type reliableChannel struct {
+	channel Channel
+	key     string
+	state   *State
+	policy  Policy
+}
+
+// Reliable wraps a channel so its deliveries follow the policy, recorded in the state under the key
+func Reliable(channel Channel, key string, state *State, policy Policy) Channel {
+	return reliableChannel{channel: channel, key: key, state: state, policy: policy}
+}
+
+func (r reliableChannel) Name() string {
+	return r.channel.Name()
+}
+
+func (r reliableChannel) digest(e Event) string {
+	var digest = sha256.Sum256([]byte(r.key + "\n" + e.Title + "\n" + e.Message + "\n" + e.Origin))
+	return hex.EncodeToString(digest[:])
+}
+
+// admit tells if the event is to be sent: it was not sent lately and the circuit is not open
+func (r reliableChannel) admit(e Event, now time.Time) error {
+	r.state.lock.Lock()
+	defer r.state.lock.Unlock()
+
+	var sink = r.state.sink(r.key)
+
+	if sent, found := r.state.Recent[r.digest(e)]; found && now.Sub(sent) < r.policy.DedupWindow {
+		sink.Suppressed++
+		return errDuplicate
+	}
+
+	if sink.Open(now) {
+		return fmt.Errorf("%w (%d in a row), closing at %s", ErrCircuitOpen, sink.Failures, sink.OpenUntil.Format("15:04:05"))
+	}
+
+	return nil
+}
+
+// errDuplicate is an event sent lately, skipped without an error for the caller
+var errDuplicate = errors.New("duplicate")
+
+func (r reliableChannel) Send(e Event) error {
+	switch err := r.admit(e, time.Now()); err {
+	case nil:
+	case errDuplicate:
+		return nil
+	default:
+		return err
+	}
+
+	var err error
+	var wait = r.policy.Backoff
+	for attempt := 0; attempt <= r.policy.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+
+		if err = r.channel.Send(e); err == nil {
+			break
+		}
+	}
+
+	r.state.lock.Lock()
+	defer r.state.lock.Unlock()
+
+	var now = time.Now()
+	var sink = r.state.sink(r.key)
+
+	if err == nil {
+		sink.Failures = 0
+		sink.OpenUntil = time.Time{}
+		sink.LastSuccess = now
+		r.state.Recent[r.digest(e)] = now
+		return nil
+	}
+
+	// once the cooldown is over a single failure opens the circuit again
+	sink.Failures++
+	sink.LastFailure = now
+	sink.LastError = err.Error()
+	if sink.Failures >= r.policy.Threshold {
+		sink.OpenUntil = now.Add(r.policy.Cooldown)
+	}
+
+	return err
+}
@@ -0,0 +1,178 @@
+// Package notify delivers the failures of the executed commands and chains to the channels
+// configured under 'notifications'
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/gi4nks/ambros/internal/utils"
+)
+
+// how long a channel may take to accept a notification
+const sendTimeout = 10 * time.Second
+
+// Event is a single notification
+type Event struct {
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	Origin  string    `json:"origin,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Channel delivers events to a destination
+type Channel interface {
+	Name() string
+	Send(e Event) error
+}
+
+// New returns the channel described by the configuration
+func New(settings utils.NotificationChannel) (Channel, error) {
+	switch settings.Type {
+	case "slack":
+		if settings.URL == "" {
+			return nil, errors.New("slack channel requires url")
+		}
+		return slackChannel{url: settings.URL}, nil
+	case "webhook":
+		if settings.URL == "" {
+			return nil, errors.New("webhook channel requires url")
+		}
+		return webhookChannel{url: settings.URL}, nil
+	case "smtp":
+		if settings.Host == "" || settings.From == "" || len(settings.To) == 0 {
+			return nil, errors.New("smtp channel requires host, from and to")
+		}
+		return smtpChannel{settings: settings}, nil
+	case "desktop":
+		return desktopChannel{}, nil
+	default:
+		return nil, errors.New("unknown notification channel type '" + settings.Type + "', use slack, webhook, smtp or desktop")
+	}
+}
+
+// Send delivers the event to every channel, returning the errors of the ones which failed
+func Send(channels []Channel, e Event) []error {
+	var errs = []error{}
+
+	for _, channel := range channels {
+		if err := channel.Send(e); err != nil {
+			errs = append(errs, errors.New(channel.Name()+": "+err.Error()))
+		}
+	}
+
+	return errs
+}
+
+func (e Event) text() string {
+	var text = e.Title + "\n" + e.Message
+	if e.Origin != "" {
+		text += "\n(on " + e.Origin + ")"
+	}
+	return text
+}
+
+func post(url string, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: sendTimeout}
+
+	response, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.New("server answered " + response.Status)
+	}
+
+	return nil
+}
+
+// slackChannel posts to a Slack incoming webhook
+type slackChannel struct {
+	url string
+}
+
+func (s slackChannel) Name() string {
+	return "slack"
+}
+
+func (s slackChannel) Send(e Event) error {
+	return post(s.url, map[string]string{"text": e.text()})
+}
+
+// webhookChannel posts the event as json
+type webhookChannel struct {
+	url string
+}
+
+func (w webhookChannel) Name() string {
+	return "webhook"
+}
+
+func (w webhookChannel) Send(e Event) error {
+	return post(w.url, e)
+}
+
+// smtpChannel sends an email
+type smtpChannel struct {
+	settings utils.NotificationChannel
+}
+
+func (s smtpChannel) Name() string {
+	return "smtp"
+}
+
+func (s smtpChannel) Send(e Event) error {
+	var message = "From: " + s.settings.From + "\r\n" +
+		"To: " + strings.Join(s.settings.To, ", ") + "\r\n" +
+		"Subject: " + e.Title + "\r\n" +
+		"Date: " + e.At.Format(time.RFC1123Z) + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + strings.ReplaceAll(e.text(), "\n", "\r\n") + "\r\n"
+
+	var auth smtp.Auth
+	if s.settings.Username != "" {
+		host, _, err := net.SplitHostPort(s.settings.Host)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.settings.Username, s.settings.Password, host)
+	}
+
+	return smtp.SendMail(s.settings.Host, auth, s.settings.From, s.settings.To, []byte(message))
+}
+
+// desktopChannel shows a notification on the local desktop
+type desktopChannel struct{}
+
+func (d desktopChannel) Name() string {
+	return "desktop"
+}
+
+func (d desktopChannel) Send(e Event) error {
+	switch runtime.GOOS {
+	case "darwin":
+		var quote = func(s string) string {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		}
+		return exec.Command("osascript", "-e", "display notification "+quote(e.Message)+" with title "+quote(e.Title)).Run()
+	case "linux", "freebsd", "openbsd":
+		return exec.Command("notify-send", e.Title, e.Message).Run()
+	default:
+		return errors.New("desktop notifications are not supported on " + runtime.GOOS)
+	}
+}
@@ -0,0 +1,149 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gi4nks/ambros/internal/notify"
+	"github.com/gi4nks/ambros/internal/utils"
+)
+
+func TestNew(t *testing.T) {
+	var valid = []utils.NotificationChannel{
+		{Type: "slack", URL: "https://hooks.example.com/x"},
+		{Type: "webhook", URL: "https://example.com/hook"},
+		{Type: "smtp", Host: "smtp.example.com:587", From: "ambros@example.com", To: []string{"me@example.com"}},
+		{Type: "desktop"},
+	}
+
+	for _, settings := range valid {
+		channel, err := notify.New(settings)
+		if err != nil {
+			t.Errorf("New(%s) returned unexpected error: %v", settings.Type, err)
+			continue
+		}
+		if channel.Name() != settings.Type {
+			t.Errorf("New(%s) returned the %s channel", settings.Type, channel.Name())
+		}
+	}
+
+	var invalid = []utils.NotificationChannel{
+		{Type: "slack"},
+		{Type: "webhook"},
+		{Type: "smtp", Host: "smtp.example.com:587"},
+		{Type: "pager"},
+	}
+
+	for _, settings := range invalid {
+		if _, err := notify.New(settings); err == nil {
+			t.Errorf("New(%+v) accepted an invalid channel", settings)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	var received = map[string]map[string]interface{}{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var body = map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		received[r.URL.Path] = body
+	}))
+	defer server.Close()
+
+	var channels = []notify.Channel{}
+	for _, settings := range []utils.NotificationChannel{
+		{Type: "slack", URL: server.URL + "/slack"},
+		{Type: "webhook", URL: server.URL + "/webhook"},
+		{Type: "webhook", URL: server.URL + "/broken"},
+	} {
+		channel, _ := notify.New(settings)
+		channels = append(channels, channel)
+	}
+
+	errs := notify.Send(channels, notify.Event{Title: "build failed", Message: "exit status 2", Origin: "laptop", At: time.Now()})
+
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "500") {
+		t.Errorf("Send() returned unexpected errors: %v", errs)
+	}
+
+	if text, _ := received["/slack"]["text"].(string); text != "build failed\nexit status 2\n(on laptop)" {
+		t.Errorf("slack channel posted unexpected text: %q", text)
+	}
+
+	if received["/webhook"]["title"] != "build failed" || received["/webhook"]["origin"] != "laptop" {
+		t.Errorf("webhook channel posted unexpected event: %v", received["/webhook"])
+	}
+}
+
+func TestReliable(t *testing.T) {
+	var failing, calls = 0, 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if failing > 0 {
+			failing--
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var settings = utils.NotificationChannel{Type: "webhook", URL: server.URL}
+	var policy = notify.Policy{Retries: 2, Backoff: time.Millisecond, DedupWindow: time.Minute, Threshold: 2, Cooldown: time.Minute}
+	var state = notify.NewState()
+
+	webhook, _ := notify.New(settings)
+	var channel = notify.Reliable(webhook, notify.Key(settings), state, policy)
+
+	var event = func(message string) notify.Event {
+		return notify.Event{Title: "build failed", Message: message, At: time.Now()}
+	}
+
+	// a delivery failing twice succeeds at the third attempt
+	failing = 2
+	if err := channel.Send(event("exit status 1")); err != nil || calls != 3 {
+		t.Fatalf("Send() = %v after %d calls, want delivered at the third", err, calls)
+	}
+
+	// the same event again is skipped
+	if err := channel.Send(event("exit status 1")); err != nil || calls != 3 {
+		t.Errorf("Send() of a duplicate = %v after %d calls, want skipped", err, calls)
+	}
+
+	// failing threshold events in a row opens the circuit, nothing is sent then
+	failing = 100
+	for i := 0; i < 2; i++ {
+		if err := channel.Send(event("exit status " + strconv.Itoa(i+2))); err == nil {
+			t.Fatalf("Send() to a failing channel returned no error")
+		}
+	}
+	calls = 0
+	if err := channel.Send(event("exit status 9")); !errors.Is(err, notify.ErrCircuitOpen) || calls != 0 {
+		t.Errorf("Send() with the circuit open = %v after %d calls, want refused", err, calls)
+	}
+
+	// the state is kept between the invocations
+	var path = filepath.Join(t.TempDir(), "notify.state")
+	if err := state.Save(path, policy); err != nil {
+		t.Fatalf("Save returned unexpected error: %v", err)
+	}
+	loaded, err := notify.LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState returned unexpected error: %v", err)
+	}
+	if sink := loaded.Sink(notify.Key(settings)); !sink.Open(time.Now()) || sink.Failures != 2 || sink.Suppressed != 1 || sink.LastSuccess.IsZero() {
+		t.Errorf("LoadState() sink = %+v, want the circuit open after 2 failures and 1 duplicate", sink)
+	}
+}
@@ -0,0 +1,597 @@
+package repos
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// the backup chain lives in the backups directory next to the database: a full copy of the
+// database starts it, then every incremental backup keeps only the entries changed since the
+// previous link. A manifest with the hash of every entry is kept for each link, to compute the
+// next changes and to verify what a restore produces
+
+const backupChainFile = "chain.json"
+
+// value of the manifest for the nested buckets
+const manifestBucket = "bucket"
+
+// backupEntry is an entry of the database, addressed by the buckets containing it and its key
+type backupEntry struct {
+	Path   [][]byte `json:"Path"`
+	Value  []byte   `json:"Value"`
+	Bucket bool     `json:"Bucket,omitempty"`
+}
+
+// backupChanges is the content of an incremental backup
+type backupChanges struct {
+	Base   string        `json:"Base"`
+	Put    []backupEntry `json:"Put"`
+	Delete []backupEntry `json:"Delete"`
+}
+
+// manifest maps the path of every entry to the hash of its value
+type manifest map[string]string
+
+func manifestKey(path [][]byte) string {
+	var elements = make([]string, len(path))
+	for i, element := range path {
+		elements[i] = hex.EncodeToString(element)
+	}
+	return strings.Join(elements, "/")
+}
+
+func manifestPath(key string) [][]byte {
+	var path = [][]byte{}
+	for _, element := range strings.Split(key, "/") {
+		decoded, _ := hex.DecodeString(element)
+		path = append(path, decoded)
+	}
+	return path
+}
+
+// state hashes the whole manifest, two databases with the same content have the same state
+func (m manifest) state() string {
+	var keys = make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		io.WriteString(hash, key+"="+m[key]+"\n")
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// walk calls fn for every bucket and value of the database, the buckets before their content
+func walk(tx *bolt.Tx, fn func(path [][]byte, value []byte, bucket bool) error) error {
+	return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		var path = [][]byte{append([]byte{}, name...)}
+		if err := fn(path, nil, true); err != nil {
+			return err
+		}
+		return walkBucket(b, path, fn)
+	})
+}
+
+func walkBucket(b *bolt.Bucket, parent [][]byte, fn func(path [][]byte, value []byte, bucket bool) error) error {
+	return b.ForEach(func(k, v []byte) error {
+		var path = append(append([][]byte{}, parent...), append([]byte{}, k...))
+
+		if v == nil {
+			if nested := b.Bucket(k); nested != nil {
+				if err := fn(path, nil, true); err != nil {
+					return err
+				}
+				return walkBucket(nested, path, fn)
+			}
+		}
+
+		return fn(path, v, false)
+	})
+}
+
+func hashValue(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:16])
+}
+
+func currentManifest(tx *bolt.Tx) (manifest, error) {
+	var m = manifest{}
+
+	err := walk(tx, func(path [][]byte, value []byte, bucket bool) error {
+		if bucket {
+			m[manifestKey(path)] = manifestBucket
+		} else {
+			m[manifestKey(path)] = hashValue(value)
+		}
+		return nil
+	})
+
+	return m, err
+}
+
+func (r *Repository) backupDirectory() string {
+	return filepath.Join(r.configuration.RepositoryDirectory, "backups")
+}
+
+func (r *Repository) backupPath(b models.Backup) string {
+	if b.Kind == "full" && b.Compressed {
+		return filepath.Join(r.backupDirectory(), b.Name+".db.gz")
+	}
+	if b.Kind == "full" {
+		return filepath.Join(r.backupDirectory(), b.Name+".db")
+	}
+	return filepath.Join(r.backupDirectory(), b.Name+".json.gz")
+}
+
+func (r *Repository) manifestPath(name string) string {
+	return filepath.Join(r.backupDirectory(), name+".manifest.gz")
+}
+
+// ListBackups returns the backup chain, oldest first
+func (r *Repository) ListBackups() ([]models.Backup, error) {
+	var backups = []models.Backup{}
+
+	b, err := os.ReadFile(filepath.Join(r.backupDirectory(), backupChainFile))
+	if os.IsNotExist(err) {
+		return backups, nil
+	}
+	if err != nil {
+		return backups, err
+	}
+
+	err = json.Unmarshal(b, &backups)
+	return backups, err
+}
+
+func (r *Repository) writeBackups(backups []models.Backup) error {
+	b, err := json.MarshalIndent(backups, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	var file = filepath.Join(r.backupDirectory(), backupChainFile)
+	if err := os.WriteFile(file+".tmp", b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+func writeGzipJson(path string, value interface{}) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	if err := json.NewEncoder(writer).Encode(value); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
+
+func readGzipJson(path string, value interface{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+
+	return json.NewDecoder(reader).Decode(value)
+}
+
+func fileChecksum(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// writeDatabase copies the database as seen by the transaction to a new file, gzipped when asked
+func writeDatabase(tx *bolt.Tx, path string, compress bool) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if !compress {
+		if _, err := tx.WriteTo(file); err != nil {
+			return err
+		}
+		return file.Sync()
+	}
+
+	writer := gzip.NewWriter(file)
+	if _, err := tx.WriteTo(writer); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
+
+// CreateBackup adds a link to the backup chain: a full copy, or the changes since the previous link;
+// the changes are always gzipped, the full copy only when asked
+func (r *Repository) CreateBackup(incremental bool, compress bool) (models.Backup, error) {
+	if utils.Incognito() {
+		return models.Backup{}, ErrIncognito
+	}
+
+	backups, err := r.ListBackups()
+	if err != nil {
+		return models.Backup{}, err
+	}
+
+	var backup = models.Backup{Name: time.Now().UTC().Format("20060102-150405.000"), Kind: "full", CreatedAt: time.Now(), Compressed: compress && !incremental}
+	var previous = manifest{}
+
+	if incremental {
+		if len(backups) == 0 {
+			return backup, errors.New("there is no backup to start from, create a full one first")
+		}
+
+		backup.Kind = "incremental"
+		backup.Base = backups[len(backups)-1].Name
+
+		if err := readGzipJson(r.manifestPath(backup.Base), &previous); err != nil {
+			return backup, errors.New("manifest of " + backup.Base + " not readable: " + err.Error())
+		}
+	}
+
+	if err := os.MkdirAll(r.backupDirectory(), 0700); err != nil {
+		return backup, err
+	}
+
+	var current manifest
+
+	// the manifest and the copy come from the same transaction, so they describe the same state
+	err = r.DB.View(func(tx *bolt.Tx) error {
+		if current, err = currentManifest(tx); err != nil {
+			return err
+		}
+
+		if !incremental {
+			backup.Changes = len(current)
+			return writeDatabase(tx, r.backupPath(backup), backup.Compressed)
+		}
+
+		var changes = backupChanges{Base: backup.Base, Put: []backupEntry{}, Delete: []backupEntry{}}
+
+		err := walk(tx, func(path [][]byte, value []byte, bucket bool) error {
+			var key = manifestKey(path)
+			if previous[key] == current[key] {
+				return nil
+			}
+
+			changes.Put = append(changes.Put, backupEntry{Path: path, Value: append([]byte{}, value...), Bucket: bucket})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for key, hash := range previous {
+			if _, ok := current[key]; !ok {
+				changes.Delete = append(changes.Delete, backupEntry{Path: manifestPath(key), Bucket: hash == manifestBucket})
+			}
+		}
+
+		backup.Changes = len(changes.Put) + len(changes.Delete)
+		return writeGzipJson(r.backupPath(backup), changes)
+	})
+	if err != nil {
+		os.Remove(r.backupPath(backup))
+		return backup, err
+	}
+
+	if err := writeGzipJson(r.manifestPath(backup.Name), current); err != nil {
+		return backup, err
+	}
+
+	backup.State = current.state()
+	if backup.Checksum, backup.Size, err = fileChecksum(r.backupPath(backup)); err != nil {
+		return backup, err
+	}
+
+	return backup, r.writeBackups(append(backups, backup))
+}
+
+// backupLinks returns the links needed to restore the named backup, from its full backup
+func backupLinks(backups []models.Backup, name string) ([]models.Backup, error) {
+	var byName = map[string]models.Backup{}
+	for _, b := range backups {
+		byName[b.Name] = b
+	}
+
+	var links = []models.Backup{}
+	for current := name; ; {
+		b, ok := byName[current]
+		if !ok {
+			return nil, errors.New("backup not available: " + current)
+		}
+
+		links = append([]models.Backup{b}, links...)
+		if b.Kind == "full" {
+			return links, nil
+		}
+
+		current = b.Base
+	}
+}
+
+// VerifyBackups checks every link of the chain, returning the problems found
+func (r *Repository) VerifyBackups() ([]string, error) {
+	backups, err := r.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems = []string{}
+	var seen = map[string]bool{}
+
+	for _, b := range backups {
+		if b.Kind == "incremental" && !seen[b.Base] {
+			problems = append(problems, b.Name+": base "+b.Base+" is not an earlier backup")
+		}
+		seen[b.Name] = true
+
+		checksum, _, err := fileChecksum(r.backupPath(b))
+		switch {
+		case err != nil:
+			problems = append(problems, b.Name+": "+err.Error())
+		case checksum != b.Checksum:
+			problems = append(problems, b.Name+": checksum mismatch, the backup file changed")
+		}
+
+		var m = manifest{}
+		if err := readGzipJson(r.manifestPath(b.Name), &m); err != nil {
+			problems = append(problems, b.Name+": manifest not readable: "+err.Error())
+		} else if m.state() != b.State {
+			problems = append(problems, b.Name+": manifest does not match the recorded state")
+		}
+	}
+
+	return problems, nil
+}
+
+// RestoreBackup rebuilds, in a new file, the database as it was when the named backup was created
+func (r *Repository) RestoreBackup(name string, path string) error {
+	backups, err := r.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	links, err := backupLinks(backups, name)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range links {
+		if checksum, _, err := fileChecksum(r.backupPath(b)); err != nil || checksum != b.Checksum {
+			return errors.New("backup " + b.Name + " is missing or corrupted, see backup verify")
+		}
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return errors.New(path + " already exists")
+	}
+
+	if err := copyDatabase(r.backupPath(links[0]), path); err != nil {
+		return err
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, b := range links[1:] {
+		var changes = backupChanges{}
+		if err := readGzipJson(r.backupPath(b), &changes); err != nil {
+			return err
+		}
+
+		if err := db.Update(func(tx *bolt.Tx) error { return applyChanges(tx, changes) }); err != nil {
+			return errors.New("applying " + b.Name + ": " + err.Error())
+		}
+	}
+
+	return db.View(func(tx *bolt.Tx) error {
+		m, err := currentManifest(tx)
+		if err != nil {
+			return err
+		}
+
+		if m.state() != links[len(links)-1].State {
+			return errors.New("the restored repository does not match the backup")
+		}
+		return nil
+	})
+}
+
+// ExportBackup copies the whole repository to a file outside the backup chain, returning its size
+func (r *Repository) ExportBackup(path string, compress bool) (int64, error) {
+	if err := r.DB.View(func(tx *bolt.Tx) error { return writeDatabase(tx, path, compress) }); err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// RestoreFile rebuilds, in a new file, the database exported to the given file
+func (r *Repository) RestoreFile(from string, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return errors.New(path + " already exists")
+	}
+
+	err := copyDatabase(from, path)
+
+	var db *bolt.DB
+	if err == nil {
+		db, err = bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	}
+	if err == nil {
+		err = db.View(func(tx *bolt.Tx) error {
+			_, err := currentManifest(tx)
+			return err
+		})
+		db.Close()
+	}
+
+	if err != nil {
+		os.Remove(path)
+		return errors.New(from + " is not a readable ambros repository: " + err.Error())
+	}
+
+	return nil
+}
+
+// copyDatabase copies a database file to a new file, uncompressing it when gzipped
+func copyDatabase(from string, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	var reader = bufio.NewReader(source)
+
+	var content io.Reader = reader
+	if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		uncompressed, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		content = uncompressed
+	}
+
+	destination, err := os.OpenFile(to, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer destination.Close()
+
+	if _, err := io.Copy(destination, content); err != nil {
+		return err
+	}
+
+	return destination.Sync()
+}
+
+// bucketAt returns the bucket at the given path, creating it when asked
+func bucketAt(tx *bolt.Tx, path [][]byte, create bool) (*bolt.Bucket, error) {
+	var b *bolt.Bucket
+
+	for i, name := range path {
+		var next *bolt.Bucket
+		var err error
+
+		switch {
+		case i == 0 && create:
+			next, err = tx.CreateBucketIfNotExists(name)
+		case i == 0:
+			next = tx.Bucket(name)
+		case create:
+			next, err = b.CreateBucketIfNotExists(name)
+		default:
+			next = b.Bucket(name)
+		}
+
+		if err != nil || next == nil {
+			return nil, err
+		}
+		b = next
+	}
+
+	return b, nil
+}
+
+func applyChanges(tx *bolt.Tx, changes backupChanges) error {
+	for _, entry := range changes.Delete {
+		var parent, key = entry.Path[:len(entry.Path)-1], entry.Path[len(entry.Path)-1]
+
+		if len(parent) == 0 {
+			if err := tx.DeleteBucket(key); err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+			continue
+		}
+
+		// the parent may have been deleted with its content already
+		b, err := bucketAt(tx, parent, false)
+		if err != nil || b == nil {
+			continue
+		}
+
+		if entry.Bucket {
+			err = b.DeleteBucket(key)
+		} else {
+			err = b.Delete(key)
+		}
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+	}
+
+	for _, entry := range changes.Put {
+		if entry.Bucket {
+			if _, err := bucketAt(tx, entry.Path, true); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := bucketAt(tx, entry.Path[:len(entry.Path)-1], true)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(entry.Path[len(entry.Path)-1], entry.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
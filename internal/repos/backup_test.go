@@ -0,0 +1,167 @@
+package repos_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	repos "github.com/gi4nks/ambros/internal/repos"
+	utils "github.com/gi4nks/ambros/internal/utils"
+	"github.com/gi4nks/quant"
+)
+
+func executedCommand(id string, name string) models.Command {
+	return models.Command{Entity: models.Entity{ID: id, CreatedAt: time.Now(), TerminatedAt: time.Now()}, Name: name, Arguments: []string{"build"}, Status: true}
+}
+
+// restoredRepository opens, read only, the repository restored by the function in a new directory
+func restoredRepository(t *testing.T, restore func(path string) error) *repos.Repository {
+	t.Helper()
+
+	var dir = t.TempDir()
+	var configuration = utils.NewConfiguration(quant.Parrot{})
+	configuration.RepositoryDirectory = dir
+
+	if err := restore(configuration.RepositoryFullName()); err != nil {
+		t.Fatalf("restoring the repository returned unexpected error: %v", err)
+	}
+
+	return openRepository(t, dir, true)
+}
+
+// nextBackup waits for the backup names, made of the time in milliseconds, to change
+func nextBackup() {
+	time.Sleep(2 * time.Millisecond)
+}
+
+func TestBackupChain(t *testing.T) {
+	var dir = t.TempDir()
+	var repository = openRepository(t, dir, false)
+	if err := repository.InitSchema(); err != nil {
+		t.Fatalf("InitSchema returned unexpected error: %v", err)
+	}
+
+	if _, err := repository.CreateBackup(true, false); err == nil {
+		t.Errorf("CreateBackup(incremental) returned no error, want a full backup needed first")
+	}
+
+	if err := repository.Put(executedCommand("a1", "make")); err != nil {
+		t.Fatalf("Put returned unexpected error: %v", err)
+	}
+
+	full, err := repository.CreateBackup(false, false)
+	if err != nil {
+		t.Fatalf("CreateBackup(full) returned unexpected error: %v", err)
+	}
+
+	// the next link keeps an added command, an alias and a deleted command
+	if err := repository.Put(executedCommand("b2", "go")); err != nil {
+		t.Fatalf("Put returned unexpected error: %v", err)
+	}
+	if err := repository.PutAlias("build", "b2"); err != nil {
+		t.Fatalf("PutAlias returned unexpected error: %v", err)
+	}
+	if err := repository.DeleteCommand("a1"); err != nil {
+		t.Fatalf("DeleteCommand returned unexpected error: %v", err)
+	}
+
+	nextBackup()
+	incremental, err := repository.CreateBackup(true, false)
+	if err != nil {
+		t.Fatalf("CreateBackup(incremental) returned unexpected error: %v", err)
+	}
+	if incremental.Kind != "incremental" || incremental.Base != full.Name || incremental.Changes == 0 {
+		t.Errorf("CreateBackup(incremental) = %+v, want the changes since %s", incremental, full.Name)
+	}
+
+	nextBackup()
+	compressed, err := repository.CreateBackup(false, true)
+	if err != nil {
+		t.Fatalf("CreateBackup(compressed) returned unexpected error: %v", err)
+	}
+
+	backups, err := repository.ListBackups()
+	if err != nil || len(backups) != 3 || backups[0].Name != full.Name || backups[1].Name != incremental.Name || backups[2].Name != compressed.Name {
+		t.Fatalf("ListBackups() = %+v, %v, want the three backups oldest first", backups, err)
+	}
+
+	if problems, err := repository.VerifyBackups(); err != nil || len(problems) != 0 {
+		t.Errorf("VerifyBackups() = %v, %v, want no problems", problems, err)
+	}
+
+	// the full backup is the repository before the changes
+	var restored = restoredRepository(t, func(path string) error { return repository.RestoreBackup(full.Name, path) })
+	if _, err := restored.FindById("a1"); err != nil {
+		t.Errorf("FindById(a1) in %s returned unexpected error: %v", full.Name, err)
+	}
+	if _, err := restored.FindById("b2"); err == nil {
+		t.Errorf("FindById(b2) in %s returned no error, want not found", full.Name)
+	}
+
+	// the incremental one applies the changes on top of it
+	for _, name := range []string{incremental.Name, compressed.Name} {
+		restored = restoredRepository(t, func(path string) error { return repository.RestoreBackup(name, path) })
+		if _, err := restored.FindById("a1"); err == nil {
+			t.Errorf("FindById(a1) in %s returned no error, want not found", name)
+		}
+		if command, err := restored.FindById("b2"); err != nil || command.Name != "go" {
+			t.Errorf("FindById(b2) in %s = %+v, %v, want go", name, command, err)
+		}
+		if id, err := restored.GetAlias("build"); err != nil || id != "b2" {
+			t.Errorf("GetAlias(build) in %s = %s, %v, want b2", name, id, err)
+		}
+	}
+
+	// a restore never overwrites a file
+	var configuration = utils.NewConfiguration(quant.Parrot{})
+	configuration.RepositoryDirectory = dir
+	if err := repository.RestoreBackup(full.Name, configuration.RepositoryFullName()); err == nil {
+		t.Errorf("RestoreBackup() over the repository returned no error, want already exists")
+	}
+
+	// a changed link breaks the chain from there on
+	var link = filepath.Join(dir, "backups", incremental.Name+".json.gz")
+	if err := os.WriteFile(link, []byte("corrupted"), 0600); err != nil {
+		t.Fatalf("WriteFile returned unexpected error: %v", err)
+	}
+
+	if problems, err := repository.VerifyBackups(); err != nil || len(problems) != 1 {
+		t.Errorf("VerifyBackups() = %v, %v, want the checksum mismatch of %s", problems, err, incremental.Name)
+	}
+	if err := repository.RestoreBackup(incremental.Name, filepath.Join(t.TempDir(), "restored.db")); err == nil {
+		t.Errorf("RestoreBackup(%s) returned no error, want corrupted", incremental.Name)
+	}
+}
+
+func TestExportRestoreFile(t *testing.T) {
+	var repository = newRepository(t)
+	if err := repository.Put(executedCommand("a1", "make")); err != nil {
+		t.Fatalf("Put returned unexpected error: %v", err)
+	}
+
+	for _, compress := range []bool{false, true} {
+		var exported = filepath.Join(t.TempDir(), "export.db")
+		if size, err := repository.ExportBackup(exported, compress); err != nil || size == 0 {
+			t.Fatalf("ExportBackup(%v) = %d, %v, want the repository copied", compress, size, err)
+		}
+
+		var restored = restoredRepository(t, func(path string) error { return repository.RestoreFile(exported, path) })
+		if command, err := restored.FindById("a1"); err != nil || command.Name != "make" {
+			t.Errorf("FindById(a1) after RestoreFile(%v) = %+v, %v, want make", compress, command, err)
+		}
+	}
+
+	// a file which is not a repository is refused and nothing is left behind
+	var invalid = filepath.Join(t.TempDir(), "invalid.db")
+	os.WriteFile(invalid, []byte("not a database"), 0600)
+
+	var path = filepath.Join(t.TempDir(), "restored.db")
+	if err := repository.RestoreFile(invalid, path); err == nil {
+		t.Errorf("RestoreFile(invalid) returned no error, want not readable")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("RestoreFile(invalid) left %s behind", path)
+	}
+}
@@ -0,0 +1,224 @@
+package repos
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/boltdb/bolt"
+	models "github.com/gi4nks/ambros/internal/models"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// the cold tier keeps the evicted outputs as gzip files next to the database,
+// one per command, so the database only holds the outputs still in use
+
+func (r *Repository) coldDirectory() string {
+	return filepath.Join(r.configuration.RepositoryDirectory, "cold")
+}
+
+func (r *Repository) coldPath(id string) string {
+	return filepath.Join(r.coldDirectory(), id+".gz")
+}
+
+// EvictOutput moves the output of an executed command to the cold tier, returning its size
+func (r *Repository) EvictOutput(id string) (int64, error) {
+	if utils.Incognito() {
+		return 0, ErrIncognito
+	}
+
+	command, err := r.FindById(id)
+	if err != nil {
+		return 0, err
+	}
+
+	if command.Cold || (!command.Streamed && command.Output == "") {
+		return 0, nil
+	}
+
+	if err := os.MkdirAll(r.coldDirectory(), 0755); err != nil {
+		return 0, err
+	}
+
+	// the file is complete before the database forgets the output
+	var temporary = r.coldPath(id) + ".tmp"
+	size, err := r.writeCold(temporary, command)
+	if err != nil {
+		os.Remove(temporary)
+		return 0, err
+	}
+
+	if err := os.Rename(temporary, r.coldPath(id)); err != nil {
+		os.Remove(temporary)
+		return 0, err
+	}
+
+	err = r.update(func(tx *bolt.Tx) error {
+		cc := tx.Bucket([]byte("Commands"))
+		v := cc.Get([]byte(id))
+		if v == nil {
+			return errors.New("Command not found: " + id)
+		}
+
+		var stored = models.Command{}
+		if err := json.Unmarshal(v, &stored); err != nil {
+			return err
+		}
+
+		stored.Output = ""
+		stored.Streamed = true
+		stored.Cold = true
+
+		encoded1, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+
+		if oo := tx.Bucket([]byte("Outputs")); oo != nil && oo.Bucket([]byte(id)) != nil {
+			if err := oo.DeleteBucket([]byte(id)); err != nil {
+				return err
+			}
+		}
+
+		return cc.Put([]byte(id), encoded1)
+	})
+
+	if err != nil {
+		os.Remove(r.coldPath(id))
+		return 0, err
+	}
+
+	return size, nil
+}
+
+func (r *Repository) writeCold(path string, command models.Command) (int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+
+	var size int64
+	if command.Streamed {
+		if size, err = io.Copy(writer, r.GetOutputReader(command.ID)); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := io.WriteString(writer, command.Output)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	return size + int64(n), file.Sync()
+}
+
+// ReplaceOutput rewrites the output streamed by a command, in the cold tier when it was evicted there,
+// as the redaction of its secrets does
+func (r *Repository) ReplaceOutput(id string, output string) error {
+	if utils.Incognito() {
+		return ErrIncognito
+	}
+
+	if _, err := os.Stat(r.coldPath(id)); err == nil {
+		var temporary = r.coldPath(id) + ".tmp"
+		if _, err := r.writeCold(temporary, models.Command{Output: output}); err != nil {
+			os.Remove(temporary)
+			return err
+		}
+
+		return os.Rename(temporary, r.coldPath(id))
+	}
+
+	return r.update(func(tx *bolt.Tx) error {
+		oo, err := tx.CreateBucketIfNotExists([]byte("Outputs"))
+		if err != nil {
+			return err
+		}
+
+		if oo.Bucket([]byte(id)) != nil {
+			if err := oo.DeleteBucket([]byte(id)); err != nil {
+				return err
+			}
+		}
+
+		cc, err := oo.CreateBucket([]byte(id))
+		if err != nil {
+			return err
+		}
+
+		seq, err := cc.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+
+		return cc.Put(key, []byte(output))
+	})
+}
+
+// ColdSize returns the number of outputs in the cold tier and the space they take on disk
+func (r *Repository) ColdSize() (int, int64, error) {
+	entries, err := os.ReadDir(r.coldDirectory())
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var count = 0
+	var size int64
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".gz" {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			count++
+			size += info.Size()
+		}
+	}
+
+	return count, size, nil
+}
+
+func (r *Repository) coldReader(id string) (io.Reader, error) {
+	file, err := os.Open(r.coldPath(id))
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &coldOutputReader{file: file, reader: reader}, nil
+}
+
+// coldOutputReader closes the cold file once it is read to the end
+type coldOutputReader struct {
+	file   *os.File
+	reader *gzip.Reader
+}
+
+func (c *coldOutputReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	if err != nil {
+		c.file.Close()
+	}
+	return n, err
+}
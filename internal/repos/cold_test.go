@@ -0,0 +1,89 @@
+package repos_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+func readOutput(t *testing.T, reader io.Reader) string {
+	t.Helper()
+
+	var builder strings.Builder
+	if _, err := io.Copy(&builder, reader); err != nil {
+		t.Fatalf("reading the output returned unexpected error: %v", err)
+	}
+	return builder.String()
+}
+
+func TestEvictOutput(t *testing.T) {
+	var dir = t.TempDir()
+	var repository = openRepository(t, dir, false)
+	if err := repository.InitSchema(); err != nil {
+		t.Fatalf("InitSchema returned unexpected error: %v", err)
+	}
+
+	var plain = executedCommand("a1", "make")
+	plain.Output = "built\n"
+
+	var streamed = executedCommand("b2", "make")
+	streamed.Streamed = true
+
+	for _, command := range []models.Command{plain, streamed, executedCommand("c3", "true")} {
+		if err := repository.Put(command); err != nil {
+			t.Fatalf("Put(%s) returned unexpected error: %v", command.ID, err)
+		}
+	}
+	if err := repository.AppendOutput("b2", []byte("line 1\n")); err != nil {
+		t.Fatalf("AppendOutput returned unexpected error: %v", err)
+	}
+	if err := repository.AppendOutput("b2", []byte("line 2\n")); err != nil {
+		t.Fatalf("AppendOutput returned unexpected error: %v", err)
+	}
+
+	for id, want := range map[string]string{"a1": "built\n", "b2": "line 1\nline 2\n"} {
+		size, err := repository.EvictOutput(id)
+		if err != nil || size != int64(len(want)) {
+			t.Fatalf("EvictOutput(%s) = %d, %v, want %d", id, size, err, len(want))
+		}
+
+		command, err := repository.FindById(id)
+		if err != nil || !command.Cold || !command.Streamed || command.Output != "" {
+			t.Errorf("FindById(%s) = %+v, %v, want its output out of the database", id, command, err)
+		}
+
+		// the output is read back from the cold tier
+		if output := readOutput(t, repository.GetOutputReader(id)); output != want {
+			t.Errorf("GetOutputReader(%s) = %q, want %q", id, output, want)
+		}
+
+		// evicting it again does nothing
+		if size, err := repository.EvictOutput(id); err != nil || size != 0 {
+			t.Errorf("EvictOutput(%s) again = %d, %v, want 0", id, size, err)
+		}
+	}
+
+	// a command without output has nothing to move
+	if size, err := repository.EvictOutput("c3"); err != nil || size != 0 {
+		t.Errorf("EvictOutput(c3) = %d, %v, want 0", size, err)
+	}
+	if _, err := repository.EvictOutput("d4"); err == nil {
+		t.Errorf("EvictOutput(d4) returned no error, want not found")
+	}
+
+	if count, size, err := repository.ColdSize(); err != nil || count != 2 || size == 0 {
+		t.Errorf("ColdSize() = %d, %d, %v, want the two outputs", count, size, err)
+	}
+
+	// deleting the command deletes its cold output
+	if err := repository.DeleteCommand("a1"); err != nil {
+		t.Fatalf("DeleteCommand returned unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cold", "a1.gz")); !os.IsNotExist(err) {
+		t.Errorf("DeleteCommand(a1) left its cold output behind")
+	}
+}
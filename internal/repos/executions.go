@@ -0,0 +1,106 @@
+package repos
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/boltdb/bolt"
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// the runs of the chains are kept in the ChainExecutions bucket, one nested bucket per chain
+// holding its runs by the instant they started
+
+// PutChainExecution stores a run of a chain
+func (r *Repository) PutChainExecution(e models.ChainExecution) error {
+	return r.update(func(tx *bolt.Tx) error {
+		ee, err := tx.CreateBucketIfNotExists([]byte("ChainExecutions"))
+		if err != nil {
+			return err
+		}
+
+		b, err := ee.CreateBucketIfNotExists([]byte(e.Chain))
+		if err != nil {
+			return err
+		}
+
+		encoded, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(e.CreatedAt.Format(time.RFC3339Nano)), encoded)
+	})
+}
+
+// GetChainExecutions returns the runs of a chain, the oldest first
+func (r *Repository) GetChainExecutions(name string) ([]models.ChainExecution, error) {
+	executions := []models.ChainExecution{}
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		ee := tx.Bucket([]byte("ChainExecutions"))
+		if ee == nil || ee.Bucket([]byte(name)) == nil {
+			return nil
+		}
+
+		return ee.Bucket([]byte(name)).ForEach(func(k, v []byte) error {
+			var execution = models.ChainExecution{}
+			if err := json.Unmarshal(v, &execution); err != nil {
+				return err
+			}
+
+			executions = append(executions, execution)
+			return nil
+		})
+	})
+
+	return executions, err
+}
+
+// FindChainExecution returns a run of any chain by its id
+func (r *Repository) FindChainExecution(id string) (models.ChainExecution, error) {
+	var execution = models.ChainExecution{}
+	var found = false
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		ee := tx.Bucket([]byte("ChainExecutions"))
+		if ee == nil {
+			return nil
+		}
+
+		return ee.ForEach(func(name, v []byte) error {
+			return ee.Bucket(name).ForEach(func(k, v []byte) error {
+				if found {
+					return nil
+				}
+
+				var candidate = models.ChainExecution{}
+				if err := json.Unmarshal(v, &candidate); err != nil {
+					return err
+				}
+
+				if candidate.ID == id {
+					execution, found = candidate, true
+				}
+				return nil
+			})
+		})
+	})
+
+	if err == nil && !found {
+		err = errors.New("Chain execution not found: " + id)
+	}
+
+	return execution, err
+}
+
+// deleteChainExecutions forgets the runs of a chain which is gone
+func deleteChainExecutions(tx *bolt.Tx, name string) error {
+	ee := tx.Bucket([]byte("ChainExecutions"))
+	if ee == nil || ee.Bucket([]byte(name)) == nil {
+		return nil
+	}
+
+	return ee.DeleteBucket([]byte(name))
+}
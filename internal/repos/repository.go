@@ -1,8 +1,13 @@
 package repos
 
 import (
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
+	"io"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/boltdb/bolt"
@@ -16,15 +21,60 @@ type Repository struct {
 	configuration *utils.Configuration
 
 	DB *bolt.DB
+
+	// standIn is the empty database opened instead of a missing one while incognito
+	standIn string
 }
 
 func NewRepository(p quant.Parrot, c utils.Configuration) *Repository {
 	return &Repository{parrot: &p, configuration: &c}
 }
 
+// ErrIncognito is returned by every write while incognito is on, nothing is persisted then
+var ErrIncognito = errors.New("Incognito is on, nothing is written to the repository")
+
+// update runs a write transaction, unless incognito is on
+func (r *Repository) update(fn func(*bolt.Tx) error) error {
+	if utils.Incognito() {
+		return ErrIncognito
+	}
+	return r.DB.Update(fn)
+}
+
+// how long the first attempt to lock the repository waits, each retry waits twice as long up to openRetryMax
+const openRetryStart = 50 * time.Millisecond
+const openRetryMax = time.Second
+
 func (r *Repository) InitDB() error {
+	return r.open(false, r.configuration.LockTimeout)
+}
+
+// InitReadOnlyDB opens the repository for reading, alongside the other readers
+func (r *Repository) InitReadOnlyDB() error {
+	return r.open(true, r.configuration.LockTimeout)
+}
+
+// InitDBNoWait opens the repository for writing only when no other process is using it, for the
+// writes which can be given up rather than wait
+func (r *Repository) InitDBNoWait() error {
+	return r.open(false, 0)
+}
+
+// InitDBWait opens the repository for writing, waiting as long as another process is using it, for the
+// writes which can be neither given up nor refused
+func (r *Repository) InitDBWait() error {
+	return r.open(false, -1)
+}
+
+func (r *Repository) open(readOnly bool, lockTimeout time.Duration) error {
 	var err error
 
+	// incognito leaves no trace, nor a repository still to be created: an empty one stands in for it,
+	// with nothing to read
+	if _, err := os.Stat(r.configuration.RepositoryFullName()); os.IsNotExist(err) && utils.Incognito() {
+		return r.openStandIn()
+	}
+
 	b, err := quant.ExistsPath(r.configuration.RepositoryDirectory)
 	if err != nil {
 		return errors.New("Ambros repository path does not exist, please ckeck if following path exists: " + r.configuration.RepositoryDirectory)
@@ -34,9 +84,44 @@ func (r *Repository) InitDB() error {
 		quant.CreatePath(r.configuration.RepositoryDirectory)
 	}
 
-	r.DB, err = bolt.Open(r.configuration.RepositoryFullName(), 0600, nil)
+	var path = r.configuration.RepositoryFullName()
+
+	// incognito leaves the file as it is, a repository still to be created can only be opened for writing
+	if utils.Incognito() {
+		readOnly = true
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		readOnly = false
+	}
+
+	// another ambros process may hold the lock, it is asked again for up to LockTimeout, or forever
+	// when negative
+	var wait = openRetryStart
+	var deadline = time.Now().Add(lockTimeout)
+
+	for {
+		r.DB, err = bolt.Open(path, 0600, &bolt.Options{Timeout: wait, ReadOnly: readOnly})
+		if err != bolt.ErrTimeout {
+			break
+		}
+
+		if lockTimeout >= 0 && time.Now().After(deadline) {
+			return errors.New("Ambros repository is busy, another ambros process is using " + path +
+				": try again when it is done or raise lockTimeout in the configuration")
+		}
+
+		r.parrot.Debug("--> Repository locked by another process, waiting " + wait.String())
+		if wait *= 2; wait > openRetryMax {
+			wait = openRetryMax
+		}
+	}
+
+	if os.IsPermission(err) {
+		return errors.New("Ambros repository is not writable (" + path + "), only the query commands (last, logs, find, stats) can read it")
+	}
+
 	if err != nil {
-		return errors.New("Ambros was not able to open db: please check if following path exists: " + r.configuration.RepositoryFullName())
+		return errors.New("Ambros was not able to open db: please check if following path exists: " + path)
 	}
 
 	//r.parrot.Println(r.DB)
@@ -44,7 +129,7 @@ func (r *Repository) InitDB() error {
 }
 
 func (r *Repository) InitSchema() error {
-	err := r.DB.Update(func(tx *bolt.Tx) error {
+	err := r.update(func(tx *bolt.Tx) error {
 		_, err := tx.CreateBucketIfNotExists([]byte("Commands"))
 		if err != nil {
 			//r.parrot.Println(">err", err)
@@ -60,6 +145,30 @@ func (r *Repository) InitSchema() error {
 			//r.parrot.Println(">err", err)
 			return err
 		}
+		_, err = tx.CreateBucketIfNotExists([]byte("CommandsSampling"))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte("CommandsRunning"))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte("Chains"))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte("Environments"))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte("Aliases"))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte("Versions"))
+		if err != nil {
+			return err
+		}
 
 		return nil
 	})
@@ -68,7 +177,7 @@ func (r *Repository) InitSchema() error {
 }
 
 func (r *Repository) DeleteSchema(complete bool) error {
-	err := r.DB.Update(func(tx *bolt.Tx) error {
+	err := r.update(func(tx *bolt.Tx) error {
 		err := tx.DeleteBucket([]byte("Commands"))
 		if err != nil {
 			return err
@@ -80,6 +189,26 @@ func (r *Repository) DeleteSchema(complete bool) error {
 			if err != nil {
 				return err
 			}
+
+			err = tx.DeleteBucket([]byte("Chains"))
+			if err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+
+			err = tx.DeleteBucket([]byte("Environments"))
+			if err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+
+			err = tx.DeleteBucket([]byte("Versions"))
+			if err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+
+			err = tx.DeleteBucket([]byte("ChainExecutions"))
+			if err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
 		}
 
 		err = tx.DeleteBucket([]byte("CommandsIndex"))
@@ -87,16 +216,68 @@ func (r *Repository) DeleteSchema(complete bool) error {
 			return err
 		}
 
+		err = tx.DeleteBucket([]byte("CommandsSampling"))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+
+		err = tx.DeleteBucket([]byte("CommandsRunning"))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+
+		err = tx.DeleteBucket([]byte("Outputs"))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+
+		// the aliased history is gone
+		err = tx.DeleteBucket([]byte("Aliases"))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+
 		return nil
 	})
 
+	if err == nil {
+		os.RemoveAll(r.coldDirectory())
+	}
+
 	return err
 }
 
+// openStandIn opens an empty temporary database, removed when closed
+func (r *Repository) openStandIn() error {
+	file, err := os.CreateTemp("", "ambros-incognito-*.db")
+	if err != nil {
+		return err
+	}
+	file.Close()
+
+	if r.DB, err = bolt.Open(file.Name(), 0600, nil); err != nil {
+		os.Remove(file.Name())
+		return errors.New("Ambros was not able to open db: " + err.Error())
+	}
+
+	r.standIn = file.Name()
+	return nil
+}
+
 func (r *Repository) CloseDB() error {
+	// never opened, or its opening failed
+	if r.DB == nil {
+		return nil
+	}
+
 	if err := r.DB.Close(); err != nil {
 		return errors.New("Error closing DB")
 	}
+
+	if r.standIn != "" {
+		os.Remove(r.standIn)
+		r.standIn = ""
+	}
 	return nil
 }
 
@@ -113,10 +294,22 @@ func (r *Repository) BackupSchema() error {
 	return err
 }
 
+// Size returns the size in bytes of the repository file
+func (r *Repository) Size() (int64, error) {
+	var size int64
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		size = tx.Size()
+		return nil
+	})
+
+	return size, err
+}
+
 // functionalities
 
 func (r *Repository) Push(c models.Command) error {
-	return r.DB.Update(func(tx *bolt.Tx) error {
+	return r.update(func(tx *bolt.Tx) error {
 		cc, err := tx.CreateBucketIfNotExists([]byte("CommandsStored"))
 
 		if err != nil {
@@ -128,46 +321,313 @@ func (r *Repository) Push(c models.Command) error {
 			return err
 		}
 
+		if err := keepVersion(tx, commandVersions(c.ID), cc.Get([]byte(c.ID)), encoded1, r.configuration.UndoDepth); err != nil {
+			return err
+		}
+
 		return cc.Put([]byte(c.ID), encoded1)
 	})
 }
 
 func (r *Repository) Put(c models.Command) error {
-	return r.DB.Update(func(tx *bolt.Tx) error {
-		cc, err := tx.CreateBucketIfNotExists([]byte("Commands"))
+	return r.update(func(tx *bolt.Tx) error {
+		return putCommand(tx, c)
+	})
+}
+
+// PutAll stores many commands in a single transaction, as imports do
+func (r *Repository) PutAll(commands []models.Command) error {
+	return r.update(func(tx *bolt.Tx) error {
+		for _, c := range commands {
+			if err := putCommand(tx, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func putCommand(tx *bolt.Tx, c models.Command) error {
+	cc, err := tx.CreateBucketIfNotExists([]byte("Commands"))
+
+	if err != nil {
+		return err
+	}
 
+	encoded1, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err = cc.Put([]byte(c.ID), encoded1); err != nil {
+		return err
+	}
+
+	ii, err := tx.CreateBucketIfNotExists([]byte("CommandsIndex"))
+
+	if err != nil {
+		return err
+	}
+
+	if err := ii.Put([]byte(c.TerminatedAt.Format(time.RFC3339Nano)), []byte(c.ID)); err != nil {
+		return err
+	}
+
+	ss, err := tx.CreateBucketIfNotExists([]byte("CommandsSampling"))
+
+	if err != nil {
+		return err
+	}
+
+	return ss.Put([]byte(c.Fingerprint()), []byte(c.TerminatedAt.Format(time.RFC3339Nano)))
+}
+
+// ShouldRecord tells if the command has to be stored according to the sampling interval:
+// failures are always recorded, successes at most once per fingerprint in the interval
+func (r *Repository) ShouldRecord(c models.Command, interval time.Duration) (bool, error) {
+	if !c.Status || interval <= 0 {
+		return true, nil
+	}
+
+	record := true
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		ss := tx.Bucket([]byte("CommandsSampling"))
+		if ss == nil {
+			return nil
+		}
+
+		v := ss.Get([]byte(c.Fingerprint()))
+		if v == nil {
+			return nil
+		}
+
+		last, err := time.Parse(time.RFC3339Nano, string(v))
 		if err != nil {
 			return err
 		}
 
-		encoded1, err := json.Marshal(c)
+		record = c.TerminatedAt.Sub(last) >= interval
+		return nil
+	})
+
+	return record, err
+}
+
+// TouchStoredCommand records that a stored command has been used
+func (r *Repository) TouchStoredCommand(id string, when time.Time) error {
+	return r.touch(id, when, "CommandsStored")
+}
+
+// TouchCommand records that the output of an executed command was accessed
+func (r *Repository) TouchCommand(id string, when time.Time) error {
+	return r.touch(id, when, "Commands")
+}
+
+func (r *Repository) touch(id string, when time.Time, collection string) error {
+	return r.update(func(tx *bolt.Tx) error {
+		cc := tx.Bucket([]byte(collection))
+		v := cc.Get([]byte(id))
+		if v == nil {
+			return errors.New("Command not found: " + id)
+		}
+
+		var command = models.Command{}
+		if err := json.Unmarshal(v, &command); err != nil {
+			return err
+		}
+
+		command.LastUsedAt = when
+
+		encoded1, err := json.Marshal(command)
+		if err != nil {
+			return err
+		}
+
+		return cc.Put([]byte(id), encoded1)
+	})
+}
+
+// GetMeta returns a value from the internal metadata bucket
+func (r *Repository) GetMeta(key string) (string, error) {
+	var value string
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("Meta"))
+		if b == nil {
+			return nil
+		}
+
+		value = string(b.Get([]byte(key)))
+		return nil
+	})
+
+	return value, err
+}
+
+// PutMeta stores a value in the internal metadata bucket
+func (r *Repository) PutMeta(key string, value string) error {
+	return r.update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("Meta"))
 		if err != nil {
 			return err
 		}
 
-		if err = cc.Put([]byte(c.ID), encoded1); err != nil {
+		return b.Put([]byte(key), []byte(value))
+	})
+}
+
+// NextSequence returns the next number of the local history, it never goes backwards whatever the clock does
+func (r *Repository) NextSequence() (uint64, error) {
+	return r.ReserveSequences(1)
+}
+
+// ReserveSequences takes count numbers of the local history at once and returns the first one
+func (r *Repository) ReserveSequences(count int) (uint64, error) {
+	var sequence uint64
+
+	err := r.update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("Meta"))
+		if err != nil {
 			return err
 		}
 
-		ii, err := tx.CreateBucketIfNotExists([]byte("CommandsIndex"))
+		if value := b.Get([]byte("sequence")); value != nil {
+			if sequence, err = strconv.ParseUint(string(value), 10, 64); err != nil {
+				return err
+			}
+		}
+
+		return b.Put([]byte("sequence"), []byte(strconv.FormatUint(sequence+uint64(count), 10)))
+	})
+
+	return sequence + 1, err
+}
+
+// PutRunning persists a command whose execution is in progress
+func (r *Repository) PutRunning(c models.Command) error {
+	return r.update(func(tx *bolt.Tx) error {
+		rr, err := tx.CreateBucketIfNotExists([]byte("CommandsRunning"))
 
 		if err != nil {
 			return err
 		}
 
-		if err := ii.Put([]byte(c.TerminatedAt.Format(time.RFC3339Nano)), []byte(c.ID)); err != nil {
+		encoded1, err := json.Marshal(c)
+		if err != nil {
 			return err
 		}
 
+		return rr.Put([]byte(c.ID), encoded1)
+	})
+}
+
+func (r *Repository) DeleteRunning(id string) error {
+	return r.deleteById(id, "CommandsRunning")
+}
+
+func (r *Repository) GetRunningCommands() ([]models.Command, error) {
+	return r.getAllCommands("CommandsRunning")
+}
+
+// PutPerf stores the phases timing of an ambros invocation
+func (r *Repository) PutPerf(p models.PerfRecord) error {
+	return r.update(func(tx *bolt.Tx) error {
+		pp, err := tx.CreateBucketIfNotExists([]byte("Perf"))
+
+		if err != nil {
+			return err
+		}
+
+		encoded1, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+
+		return pp.Put([]byte(p.CreatedAt.Format(time.RFC3339Nano)), encoded1)
+	})
+}
+
+func (r *Repository) GetAllPerf() ([]models.PerfRecord, error) {
+	records := []models.PerfRecord{}
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("Perf"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var record = models.PerfRecord{}
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+
+			records = append(records, record)
+			return nil
+		})
+	})
+
+	return records, err
+}
+
+func (r *Repository) DeleteAllPerf() error {
+	return r.update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte("Perf"))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
 		return nil
 	})
 }
 
+// DeleteCommand removes an executed command from the history and its index
+func (r *Repository) DeleteCommand(id string) error {
+	err := r.update(func(tx *bolt.Tx) error {
+		cc := tx.Bucket([]byte("Commands"))
+		v := cc.Get([]byte(id))
+		if v == nil {
+			return errors.New("Command not found: " + id)
+		}
+
+		var command = models.Command{}
+		if err := json.Unmarshal(v, &command); err != nil {
+			return err
+		}
+
+		ii := tx.Bucket([]byte("CommandsIndex"))
+		key := []byte(command.TerminatedAt.Format(time.RFC3339Nano))
+		if string(ii.Get(key)) == id {
+			if err := ii.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		if oo := tx.Bucket([]byte("Outputs")); oo != nil && oo.Bucket([]byte(id)) != nil {
+			if err := oo.DeleteBucket([]byte(id)); err != nil {
+				return err
+			}
+		}
+
+		return cc.Delete([]byte(id))
+	})
+
+	if err == nil {
+		os.Remove(r.coldPath(id))
+	}
+
+	return err
+}
+
 func (r *Repository) findById(id string, collection string) (models.Command, error) {
 	var command = models.Command{}
 
 	err := r.DB.View(func(tx *bolt.Tx) error {
+		// a repository opened read only may predate the bucket
 		b := tx.Bucket([]byte(collection))
+		if b == nil {
+			return errors.New("Command not found: " + id)
+		}
 		v := b.Get([]byte(id))
 
 		err := json.Unmarshal(v, &command)
@@ -182,7 +642,7 @@ func (r *Repository) findById(id string, collection string) (models.Command, err
 }
 
 func (r *Repository) deleteById(id string, collection string) error {
-	return r.DB.Update(func(tx *bolt.Tx) error {
+	return r.update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(collection))
 		return b.Delete([]byte(id))
 	})
@@ -197,12 +657,27 @@ func (r *Repository) FindInStoreById(id string) (models.Command, error) {
 }
 
 func (r *Repository) DeleteStoredCommand(id string) error {
-	return r.deleteById(id, "CommandsStored")
+	return r.update(func(tx *bolt.Tx) error {
+		cc := tx.Bucket([]byte("CommandsStored"))
+		// the versions stay, the command can be brought back
+		if err := keepVersion(tx, commandVersions(id), cc.Get([]byte(id)), nil, r.configuration.UndoDepth); err != nil {
+			return err
+		}
+		return cc.Delete([]byte(id))
+	})
 }
 
 func (r *Repository) DeleteAllStoredCommands() error {
-	err := r.DB.Update(func(tx *bolt.Tx) error {
-		err := tx.DeleteBucket([]byte("CommandsStored"))
+	err := r.update(func(tx *bolt.Tx) error {
+		// each command can be brought back on its own
+		err := tx.Bucket([]byte("CommandsStored")).ForEach(func(k, v []byte) error {
+			return keepVersion(tx, commandVersions(string(k)), v, nil, r.configuration.UndoDepth)
+		})
+		if err != nil {
+			return err
+		}
+
+		err = tx.DeleteBucket([]byte("CommandsStored"))
 		if err != nil {
 			r.parrot.Error("delete bucket: ", err)
 			return err
@@ -225,6 +700,9 @@ func (r *Repository) getAllCommands(collection string) ([]models.Command, error)
 
 	err := r.DB.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(collection))
+		if b == nil {
+			return nil
+		}
 		c := b.Cursor()
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
@@ -252,11 +730,20 @@ func (r *Repository) GetAllCommands() ([]models.Command, error) {
 }
 
 func (r *Repository) GetLimitCommands(limit int) ([]models.Command, error) {
+	return r.FilterLimitCommands(limit, nil)
+}
+
+// FilterLimitCommands returns the latest executed commands accepted by the filter (nil accepts all)
+func (r *Repository) FilterLimitCommands(limit int, filter func(models.Command) bool) ([]models.Command, error) {
 	commands := []models.Command{}
 
 	err := r.DB.View(func(tx *bolt.Tx) error {
 		cc := tx.Bucket([]byte("Commands"))
-		ii := tx.Bucket([]byte("CommandsIndex")).Cursor()
+		index := tx.Bucket([]byte("CommandsIndex"))
+		if cc == nil || index == nil {
+			return nil
+		}
+		ii := index.Cursor()
 
 		var i = limit
 
@@ -269,6 +756,10 @@ func (r *Repository) GetLimitCommands(limit int) ([]models.Command, error) {
 			if err != nil {
 				return err
 			}
+
+			if filter != nil && !filter(command) {
+				continue
+			}
 			commands = append(commands, command)
 
 			i--
@@ -280,8 +771,71 @@ func (r *Repository) GetLimitCommands(limit int) ([]models.Command, error) {
 	return commands, err
 }
 
+// GetCommandsPage returns up to limit commands older than the cursor (empty for the newest ones);
+// the cursor wraps the time index key so pages stay stable while new commands are recorded
+func (r *Repository) GetCommandsPage(cursor string, limit int) (models.CommandsPage, error) {
+	page := models.CommandsPage{Commands: []models.Command{}}
+
+	var from []byte
+	if cursor != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return page, errors.New("Invalid cursor: " + cursor)
+		}
+		from = decoded
+	}
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		cc := tx.Bucket([]byte("Commands"))
+		index := tx.Bucket([]byte("CommandsIndex"))
+		if cc == nil || index == nil {
+			return nil
+		}
+		ii := index.Cursor()
+
+		page.Total = index.Stats().KeyN
+
+		var k, v []byte
+		if from == nil {
+			k, v = ii.Last()
+		} else {
+			// the cursor is the key of the last command returned, start right before it
+			k, _ = ii.Seek(from)
+			if k == nil {
+				k, v = ii.Last()
+			} else {
+				k, v = ii.Prev()
+			}
+		}
+
+		for ; k != nil && len(page.Commands) < limit; k, v = ii.Prev() {
+			var command = models.Command{}
+
+			if err := json.Unmarshal(cc.Get(v), &command); err != nil {
+				return err
+			}
+			page.Commands = append(page.Commands, command)
+
+			if len(page.Commands) == limit {
+				if prev, _ := ii.Prev(); prev != nil {
+					page.Next = base64.RawURLEncoding.EncodeToString(k)
+				}
+				break
+			}
+		}
+
+		return nil
+	})
+
+	return page, err
+}
+
 func (r *Repository) GetExecutedCommands(count int) ([]models.ExecutedCommand, error) {
-	commands, err := r.GetLimitCommands(count)
+	return r.FilterExecutedCommands(count, nil)
+}
+
+func (r *Repository) FilterExecutedCommands(count int, filter func(models.Command) bool) ([]models.ExecutedCommand, error) {
+	commands, err := r.FilterLimitCommands(count, filter)
 
 	executedCommands := make([]models.ExecutedCommand, len(commands))
 
@@ -292,6 +846,331 @@ func (r *Repository) GetExecutedCommands(count int) ([]models.ExecutedCommand, e
 	return executedCommands, err
 }
 
+// streamed outputs
+
+// AppendOutput adds a chunk to the output streamed by a command
+func (r *Repository) AppendOutput(id string, chunk []byte) error {
+	return r.update(func(tx *bolt.Tx) error {
+		oo, err := tx.CreateBucketIfNotExists([]byte("Outputs"))
+		if err != nil {
+			return err
+		}
+
+		cc, err := oo.CreateBucketIfNotExists([]byte(id))
+		if err != nil {
+			return err
+		}
+
+		seq, err := cc.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+
+		return cc.Put(key, chunk)
+	})
+}
+
+// DeleteOutput forgets the output streamed by a command which is not stored
+func (r *Repository) DeleteOutput(id string) error {
+	return r.update(func(tx *bolt.Tx) error {
+		oo := tx.Bucket([]byte("Outputs"))
+		if oo == nil || oo.Bucket([]byte(id)) == nil {
+			return nil
+		}
+
+		return oo.DeleteBucket([]byte(id))
+	})
+}
+
+// GetOutputReader returns a reader over the output streamed by a command,
+// chunks are loaded one at a time so the whole output is never in memory
+func (r *Repository) GetOutputReader(id string) io.Reader {
+	if reader, err := r.coldReader(id); err == nil {
+		return reader
+	}
+
+	return &outputReader{repository: r, id: id}
+}
+
+type outputReader struct {
+	repository *Repository
+	id         string
+	next       uint64
+	buffer     []byte
+	done       bool
+}
+
+func (o *outputReader) Read(p []byte) (int, error) {
+	for len(o.buffer) == 0 {
+		if o.done {
+			return 0, io.EOF
+		}
+
+		err := o.repository.DB.View(func(tx *bolt.Tx) error {
+			oo := tx.Bucket([]byte("Outputs"))
+			if oo == nil || oo.Bucket([]byte(o.id)) == nil {
+				o.done = true
+				return nil
+			}
+
+			key := make([]byte, 8)
+			binary.BigEndian.PutUint64(key, o.next)
+
+			k, v := oo.Bucket([]byte(o.id)).Cursor().Seek(key)
+			if k == nil {
+				o.done = true
+				return nil
+			}
+
+			// values are only valid inside the transaction
+			o.buffer = append([]byte{}, v...)
+			o.next = binary.BigEndian.Uint64(k) + 1
+			return nil
+		})
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, o.buffer)
+	o.buffer = o.buffer[n:]
+	return n, nil
+}
+
+// chains
+
+func (r *Repository) PutChain(c models.Chain) error {
+	return r.update(func(tx *bolt.Tx) error {
+		cc, err := tx.CreateBucketIfNotExists([]byte("Chains"))
+
+		if err != nil {
+			return err
+		}
+
+		encoded1, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+
+		// chains have no undo, their history starts with the first change
+		if previous := cc.Get([]byte(c.Name)); previous != nil {
+			if err := keepVersion(tx, chainVersions(c.Name), previous, encoded1, r.configuration.UndoDepth); err != nil {
+				return err
+			}
+		}
+
+		return cc.Put([]byte(c.Name), encoded1)
+	})
+}
+
+func (r *Repository) FindChainByName(name string) (models.Chain, error) {
+	var chain = models.Chain{}
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		// a repository opened read only may predate the chains
+		b := tx.Bucket([]byte("Chains"))
+		if b == nil {
+			return errors.New("Chain not found: " + name)
+		}
+
+		v := b.Get([]byte(name))
+		if v == nil {
+			return errors.New("Chain not found: " + name)
+		}
+
+		return json.Unmarshal(v, &chain)
+	})
+
+	return chain, err
+}
+
+func (r *Repository) GetAllChains() ([]models.Chain, error) {
+	chains := []models.Chain{}
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("Chains"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var chain = models.Chain{}
+			if err := json.Unmarshal(v, &chain); err != nil {
+				return err
+			}
+
+			chains = append(chains, chain)
+			return nil
+		})
+	})
+
+	return chains, err
+}
+
+func (r *Repository) DeleteChain(name string) error {
+	return r.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("Chains"))
+		if b == nil || b.Get([]byte(name)) == nil {
+			return errors.New("Chain not found: " + name)
+		}
+		if err := deleteVersions(tx, chainVersions(name)); err != nil {
+			return err
+		}
+		if err := deleteChainExecutions(tx, name); err != nil {
+			return err
+		}
+		return b.Delete([]byte(name))
+	})
+}
+
+// environments
+
+func (r *Repository) PutEnvironment(e models.Environment) error {
+	return r.update(func(tx *bolt.Tx) error {
+		ee, err := tx.CreateBucketIfNotExists([]byte("Environments"))
+
+		if err != nil {
+			return err
+		}
+
+		encoded1, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		if err := keepVersion(tx, environmentVersions(e.Name), ee.Get([]byte(e.Name)), encoded1, r.configuration.UndoDepth); err != nil {
+			return err
+		}
+
+		return ee.Put([]byte(e.Name), encoded1)
+	})
+}
+
+func (r *Repository) GetEnvironment(name string) (models.Environment, error) {
+	var environment = models.Environment{}
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		// a repository opened read only may predate the environments
+		b := tx.Bucket([]byte("Environments"))
+		if b == nil {
+			return errors.New("Environment not found: " + name)
+		}
+
+		v := b.Get([]byte(name))
+		if v == nil {
+			return errors.New("Environment not found: " + name)
+		}
+
+		return json.Unmarshal(v, &environment)
+	})
+
+	return environment, err
+}
+
+func (r *Repository) ListEnvironments() ([]models.Environment, error) {
+	environments := []models.Environment{}
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("Environments"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var environment = models.Environment{}
+			if err := json.Unmarshal(v, &environment); err != nil {
+				return err
+			}
+
+			environments = append(environments, environment)
+			return nil
+		})
+	})
+
+	return environments, err
+}
+
+func (r *Repository) DeleteEnvironment(name string) error {
+	return r.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("Environments"))
+		if b == nil || b.Get([]byte(name)) == nil {
+			return errors.New("Environment not found: " + name)
+		}
+		if err := keepVersion(tx, environmentVersions(name), b.Get([]byte(name)), nil, r.configuration.UndoDepth); err != nil {
+			return err
+		}
+		return b.Delete([]byte(name))
+	})
+}
+
+// PutAlias names a command of the history or of the store
+func (r *Repository) PutAlias(alias string, id string) error {
+	return r.update(func(tx *bolt.Tx) error {
+		aa, err := tx.CreateBucketIfNotExists([]byte("Aliases"))
+		if err != nil {
+			return err
+		}
+
+		return aa.Put([]byte(alias), []byte(id))
+	})
+}
+
+// GetAlias returns the id of the command with the given alias
+func (r *Repository) GetAlias(alias string) (string, error) {
+	var id string
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		// a repository opened read only may predate the aliases
+		b := tx.Bucket([]byte("Aliases"))
+		if b == nil {
+			return errors.New("Alias not found: " + alias)
+		}
+
+		v := b.Get([]byte(alias))
+		if v == nil {
+			return errors.New("Alias not found: " + alias)
+		}
+
+		id = string(v)
+		return nil
+	})
+
+	return id, err
+}
+
+// GetAllAliases returns the ids of the commands by alias
+func (r *Repository) GetAllAliases() (map[string]string, error) {
+	var aliases = map[string]string{}
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("Aliases"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			aliases[string(k)] = string(v)
+			return nil
+		})
+	})
+
+	return aliases, err
+}
+
+func (r *Repository) DeleteAlias(alias string) error {
+	return r.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("Aliases"))
+		if b == nil || b.Get([]byte(alias)) == nil {
+			return errors.New("Alias not found: " + alias)
+		}
+		return b.Delete([]byte(alias))
+	})
+}
+
 func (r *Repository) extend(slice []models.Command, element models.Command) []models.Command {
 	n := len(slice)
 	if n == cap(slice) {
@@ -0,0 +1,230 @@
+package repos_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gi4nks/quant"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	repos "github.com/gi4nks/ambros/internal/repos"
+	utils "github.com/gi4nks/ambros/internal/utils"
+)
+
+// newRepository returns a repository with its schema in a temporary directory, closed at the end of the test
+func newRepository(t *testing.T) *repos.Repository {
+	t.Helper()
+
+	var repository = openRepository(t, t.TempDir(), false)
+	if err := repository.InitSchema(); err != nil {
+		t.Fatalf("InitSchema returned unexpected error: %v", err)
+	}
+	return repository
+}
+
+func openRepository(t *testing.T, dir string, readOnly bool) *repos.Repository {
+	t.Helper()
+
+	var configuration = utils.NewConfiguration(quant.Parrot{})
+	configuration.RepositoryDirectory = dir
+
+	var repository = repos.NewRepository(quant.Parrot{}, *configuration)
+
+	var err error
+	if readOnly {
+		err = repository.InitReadOnlyDB()
+	} else {
+		err = repository.InitDB()
+	}
+	if err != nil {
+		t.Fatalf("opening the repository returned unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() { repository.CloseDB() })
+	return repository
+}
+
+// oldSchema creates in the directory a repository with the buckets of the first versions of ambros,
+// before chains, environments and aliases
+func oldSchema(t *testing.T, dir string) {
+	t.Helper()
+
+	var configuration = utils.NewConfiguration(quant.Parrot{})
+	configuration.RepositoryDirectory = dir
+	db, err := bolt.Open(configuration.RepositoryFullName(), 0600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open returned unexpected error: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{"Commands", "CommandsStored", "CommandsIndex"} {
+			if _, err := tx.CreateBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	db.Close()
+	if err != nil {
+		t.Fatalf("creating the old schema returned unexpected error: %v", err)
+	}
+}
+
+func TestReadOnlyOldSchema(t *testing.T) {
+	var dir = t.TempDir()
+	oldSchema(t, dir)
+
+	var repository = openRepository(t, dir, true)
+
+	if chains, err := repository.GetAllChains(); err != nil || len(chains) != 0 {
+		t.Errorf("GetAllChains() = %v, %v, want no chains", chains, err)
+	}
+	if _, err := repository.FindChainByName("deploy"); err == nil {
+		t.Errorf("FindChainByName(deploy) returned no error, want not found")
+	}
+	if environments, err := repository.ListEnvironments(); err != nil || len(environments) != 0 {
+		t.Errorf("ListEnvironments() = %v, %v, want no environments", environments, err)
+	}
+	if _, err := repository.GetEnvironment("prod"); err == nil {
+		t.Errorf("GetEnvironment(prod) returned no error, want not found")
+	}
+	if aliases, err := repository.GetAllAliases(); err != nil || len(aliases) != 0 {
+		t.Errorf("GetAllAliases() = %v, %v, want no aliases", aliases, err)
+	}
+	if running, err := repository.GetRunningCommands(); err != nil || len(running) != 0 {
+		t.Errorf("GetRunningCommands() = %v, %v, want no commands", running, err)
+	}
+	if _, err := repository.FindChainExecution("a1b2"); err == nil {
+		t.Errorf("FindChainExecution(a1b2) returned no error, want not found")
+	}
+}
+
+func TestReadOnlyEmpty(t *testing.T) {
+	// a first invocation opening the repository read only creates it without any bucket
+	var repository = openRepository(t, t.TempDir(), true)
+
+	if commands, err := repository.GetLimitCommands(10); err != nil || len(commands) != 0 {
+		t.Errorf("GetLimitCommands(10) = %v, %v, want no commands", commands, err)
+	}
+	if page, err := repository.GetCommandsPage("", 10); err != nil || len(page.Commands) != 0 {
+		t.Errorf("GetCommandsPage() = %+v, %v, want no commands", page, err)
+	}
+	if stored, err := repository.GetAllStoredCommands(); err != nil || len(stored) != 0 {
+		t.Errorf("GetAllStoredCommands() = %v, %v, want no commands", stored, err)
+	}
+	if _, err := repository.FindById("a1b2"); err == nil {
+		t.Errorf("FindById(a1b2) returned no error, want not found")
+	}
+}
+
+func TestChains(t *testing.T) {
+	var repository = newRepository(t)
+
+	for _, name := range []string{"deploy", "build"} {
+		var chain = models.Chain{Name: name, Steps: []models.ChainStep{{CommandID: "a1"}}}
+		if err := repository.PutChain(chain); err != nil {
+			t.Fatalf("PutChain(%s) returned unexpected error: %v", name, err)
+		}
+	}
+
+	// a chain is replaced by name
+	var chain = models.Chain{Name: "deploy", Steps: []models.ChainStep{{CommandID: "a1"}, {CommandID: "b2"}}}
+	if err := repository.PutChain(chain); err != nil {
+		t.Fatalf("PutChain(deploy) returned unexpected error: %v", err)
+	}
+
+	if found, err := repository.FindChainByName("deploy"); err != nil || len(found.Steps) != 2 {
+		t.Errorf("FindChainByName(deploy) = %+v, %v, want its two steps", found, err)
+	}
+	if chains, err := repository.GetAllChains(); err != nil || len(chains) != 2 {
+		t.Errorf("GetAllChains() = %+v, %v, want two chains", chains, err)
+	}
+
+	if err := repository.DeleteChain("deploy"); err != nil {
+		t.Fatalf("DeleteChain(deploy) returned unexpected error: %v", err)
+	}
+	if _, err := repository.FindChainByName("deploy"); err == nil {
+		t.Errorf("FindChainByName(deploy) after the delete returned no error, want not found")
+	}
+	if err := repository.DeleteChain("deploy"); err == nil {
+		t.Errorf("DeleteChain(deploy) again returned no error, want not found")
+	}
+	if chains, err := repository.GetAllChains(); err != nil || len(chains) != 1 || chains[0].Name != "build" {
+		t.Errorf("GetAllChains() = %+v, %v, want build only", chains, err)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	var repository = newRepository(t)
+
+	if err := repository.PutAlias("build", "a1"); err != nil {
+		t.Fatalf("PutAlias(build) returned unexpected error: %v", err)
+	}
+	if err := repository.PutAlias("test", "b2"); err != nil {
+		t.Fatalf("PutAlias(test) returned unexpected error: %v", err)
+	}
+
+	// an alias is moved by setting it again
+	if err := repository.PutAlias("build", "c3"); err != nil {
+		t.Fatalf("PutAlias(build) returned unexpected error: %v", err)
+	}
+
+	if id, err := repository.GetAlias("build"); err != nil || id != "c3" {
+		t.Errorf("GetAlias(build) = %s, %v, want c3", id, err)
+	}
+	if aliases, err := repository.GetAllAliases(); err != nil || len(aliases) != 2 || aliases["test"] != "b2" {
+		t.Errorf("GetAllAliases() = %v, %v, want build and test", aliases, err)
+	}
+
+	if err := repository.DeleteAlias("build"); err != nil {
+		t.Fatalf("DeleteAlias(build) returned unexpected error: %v", err)
+	}
+	if _, err := repository.GetAlias("build"); err == nil {
+		t.Errorf("GetAlias(build) after the delete returned no error, want not found")
+	}
+	if err := repository.DeleteAlias("build"); err == nil {
+		t.Errorf("DeleteAlias(build) again returned no error, want not found")
+	}
+}
+
+func TestDeleteOldSchema(t *testing.T) {
+	var dir = t.TempDir()
+	oldSchema(t, dir)
+
+	// the deletes find nothing in the buckets not created yet, before the schema is initialized
+	var repository = openRepository(t, dir, false)
+
+	if err := repository.DeleteChain("deploy"); err == nil {
+		t.Errorf("DeleteChain(deploy) returned no error, want not found")
+	}
+	if err := repository.DeleteAlias("build"); err == nil {
+		t.Errorf("DeleteAlias(build) returned no error, want not found")
+	}
+	if err := repository.DeleteEnvironment("prod"); err == nil {
+		t.Errorf("DeleteEnvironment(prod) returned no error, want not found")
+	}
+}
+
+func TestIncognitoMissingRepository(t *testing.T) {
+	t.Setenv(utils.ConstIncognitoEnv, "1")
+
+	// incognito creates neither the directory nor the file, everything reads empty
+	var dir = filepath.Join(t.TempDir(), "repository")
+	var repository = openRepository(t, dir, false)
+
+	if history, err := repository.GetAllCommands(); err != nil || len(history) != 0 {
+		t.Errorf("GetAllCommands() = %v, %v, want none", history, err)
+	}
+	if _, err := repository.FindById("a1"); err == nil {
+		t.Errorf("FindById(a1) returned no error, want not found")
+	}
+	if err := repository.Put(executedCommand("a1", "make")); err != repos.ErrIncognito {
+		t.Errorf("Put() = %v, want ErrIncognito", err)
+	}
+
+	repository.CloseDB()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Stat(%s) = %v, want the repository not created", dir, err)
+	}
+}
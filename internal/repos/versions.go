@@ -0,0 +1,268 @@
+package repos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// the previous variants of the stored commands, of the chains and of the environments are kept in the
+// Versions bucket, one nested bucket per entity ("command <id>", "chain <name>", "environment <name>")
+// holding the versions by number; the oldest are forgotten beyond the undoDepth of the configuration,
+// and undoing a change takes the last version back
+
+func commandVersions(id string) []byte {
+	return []byte("command " + id)
+}
+
+func chainVersions(name string) []byte {
+	return []byte("chain " + name)
+}
+
+func environmentVersions(name string) []byte {
+	return []byte("environment " + name)
+}
+
+func versionKey(number int) []byte {
+	return []byte(fmt.Sprintf("%010d", number))
+}
+
+// keepVersion saves the previous encoding of an entity about to be replaced, nil when it did not exist,
+// unless nothing changed
+func keepVersion(tx *bolt.Tx, entity []byte, previous []byte, current []byte, depth int) error {
+	if string(previous) == string(current) {
+		return nil
+	}
+
+	vv, err := tx.CreateBucketIfNotExists([]byte("Versions"))
+	if err != nil {
+		return err
+	}
+
+	b, err := vv.CreateBucketIfNotExists(entity)
+	if err != nil {
+		return err
+	}
+
+	sequence, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	var version = models.Version{Number: int(sequence), SavedAt: time.Now()}
+
+	switch {
+	case previous == nil:
+	case strings.HasPrefix(string(entity), "chain "):
+		version.Chain = &models.Chain{}
+		err = json.Unmarshal(previous, version.Chain)
+	case strings.HasPrefix(string(entity), "environment "):
+		version.Environment = &models.Environment{}
+		err = json.Unmarshal(previous, version.Environment)
+	default:
+		version.Command = &models.Command{}
+		err = json.Unmarshal(previous, version.Command)
+	}
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+
+	if err := b.Put(versionKey(version.Number), encoded); err != nil {
+		return err
+	}
+
+	if depth <= 0 {
+		return nil
+	}
+
+	// the keys are ordered by number, the oldest come first
+	var count = 0
+	b.ForEach(func(k, v []byte) error {
+		count++
+		return nil
+	})
+
+	for c := b.Cursor(); count > depth; count-- {
+		k, _ := c.First()
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// takeVersion removes the last version of an entity and returns it
+func takeVersion(tx *bolt.Tx, entity []byte) (models.Version, error) {
+	var version = models.Version{}
+
+	vv := tx.Bucket([]byte("Versions"))
+	if vv == nil || vv.Bucket(entity) == nil {
+		return version, errors.New("Nothing to undo")
+	}
+
+	b := vv.Bucket(entity)
+	k, v := b.Cursor().Last()
+	if k == nil {
+		return version, errors.New("Nothing to undo")
+	}
+
+	if err := json.Unmarshal(v, &version); err != nil {
+		return version, err
+	}
+
+	return version, b.Delete(k)
+}
+
+func (r *Repository) getVersions(entity []byte) ([]models.Version, error) {
+	versions := []models.Version{}
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		vv := tx.Bucket([]byte("Versions"))
+		if vv == nil || vv.Bucket(entity) == nil {
+			return nil
+		}
+
+		return vv.Bucket(entity).ForEach(func(k, v []byte) error {
+			var version = models.Version{}
+			if err := json.Unmarshal(v, &version); err != nil {
+				return err
+			}
+
+			versions = append(versions, version)
+			return nil
+		})
+	})
+
+	return versions, err
+}
+
+// GetCommandVersions returns the previous variants of a stored command, the oldest first
+func (r *Repository) GetCommandVersions(id string) ([]models.Version, error) {
+	return r.getVersions(commandVersions(id))
+}
+
+// GetVersionedCommands returns the ids of the commands with versions, stored or deleted
+func (r *Repository) GetVersionedCommands() ([]string, error) {
+	ids := []string{}
+
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		vv := tx.Bucket([]byte("Versions"))
+		if vv == nil {
+			return nil
+		}
+
+		return vv.ForEach(func(k, v []byte) error {
+			if strings.HasPrefix(string(k), "command ") {
+				ids = append(ids, strings.TrimPrefix(string(k), "command "))
+			}
+			return nil
+		})
+	})
+
+	return ids, err
+}
+
+// GetChainVersions returns the previous variants of a chain, the oldest first
+func (r *Repository) GetChainVersions(name string) ([]models.Version, error) {
+	return r.getVersions(chainVersions(name))
+}
+
+// PutCommandVersion replaces a version of a stored command, as the redaction of its secrets does
+func (r *Repository) PutCommandVersion(id string, version models.Version) error {
+	return r.update(func(tx *bolt.Tx) error {
+		vv := tx.Bucket([]byte("Versions"))
+		if vv == nil || vv.Bucket(commandVersions(id)) == nil || vv.Bucket(commandVersions(id)).Get(versionKey(version.Number)) == nil {
+			return errors.New("Version not found: " + id + " " + fmt.Sprint(version.Number))
+		}
+
+		encoded, err := json.Marshal(version)
+		if err != nil {
+			return err
+		}
+
+		return vv.Bucket(commandVersions(id)).Put(versionKey(version.Number), encoded)
+	})
+}
+
+// deleteVersions forgets the versions of an entity which is gone
+func deleteVersions(tx *bolt.Tx, entity []byte) error {
+	vv := tx.Bucket([]byte("Versions"))
+	if vv == nil || vv.Bucket(entity) == nil {
+		return nil
+	}
+
+	return vv.DeleteBucket(entity)
+}
+
+// UndoStoredCommand reverts the last change of a stored command, returning the version restored; the
+// command is removed when it was not stored before
+func (r *Repository) UndoStoredCommand(id string) (models.Version, error) {
+	var version models.Version
+
+	err := r.update(func(tx *bolt.Tx) error {
+		var err error
+		if version, err = takeVersion(tx, commandVersions(id)); err != nil {
+			return err
+		}
+
+		cc, err := tx.CreateBucketIfNotExists([]byte("CommandsStored"))
+		if err != nil {
+			return err
+		}
+
+		if version.Command == nil {
+			return cc.Delete([]byte(id))
+		}
+
+		encoded1, err := json.Marshal(version.Command)
+		if err != nil {
+			return err
+		}
+
+		return cc.Put([]byte(id), encoded1)
+	})
+
+	return version, err
+}
+
+// UndoEnvironment reverts the last change of an environment, returning the version restored; the
+// environment is removed when it did not exist before
+func (r *Repository) UndoEnvironment(name string) (models.Version, error) {
+	var version models.Version
+
+	err := r.update(func(tx *bolt.Tx) error {
+		var err error
+		if version, err = takeVersion(tx, environmentVersions(name)); err != nil {
+			return err
+		}
+
+		ee, err := tx.CreateBucketIfNotExists([]byte("Environments"))
+		if err != nil {
+			return err
+		}
+
+		if version.Environment == nil {
+			return ee.Delete([]byte(name))
+		}
+
+		encoded1, err := json.Marshal(version.Environment)
+		if err != nil {
+			return err
+		}
+
+		return ee.Put([]byte(name), encoded1)
+	})
+
+	return version, err
+}
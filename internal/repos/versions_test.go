@@ -0,0 +1,221 @@
+package repos_test
+
+import (
+	"strconv"
+	"testing"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+func TestCommandVersions(t *testing.T) {
+	var repository = newRepository(t)
+
+	// more than nine edits, the versions stay in numeric order; the first one marks the command as not
+	// stored, only the last undoDepth, 10 by default, are kept
+	var command = executedCommand("a1", "make")
+	for i := 0; i < 12; i++ {
+		command.Arguments = []string{"build-" + strconv.Itoa(i)}
+		if err := repository.Push(command); err != nil {
+			t.Fatalf("Push returned unexpected error: %v", err)
+		}
+	}
+
+	// storing the same command again keeps no version
+	if err := repository.Push(command); err != nil {
+		t.Fatalf("Push returned unexpected error: %v", err)
+	}
+
+	versions, err := repository.GetCommandVersions("a1")
+	if err != nil || len(versions) != 10 {
+		t.Fatalf("GetCommandVersions(a1) = %d versions, %v, want 10", len(versions), err)
+	}
+	for i, v := range versions {
+		if v.Number != i+3 || v.Command == nil || v.Command.Arguments[0] != "build-"+strconv.Itoa(i+1) {
+			t.Errorf("GetCommandVersions(a1)[%d] = %d %+v, want version %d of build-%d", i, v.Number, v.Command, i+3, i+1)
+		}
+	}
+
+	// the versions of another command are apart
+	if versions, err := repository.GetCommandVersions("b2"); err != nil || len(versions) != 0 {
+		t.Errorf("GetCommandVersions(b2) = %v, %v, want none", versions, err)
+	}
+
+	// a version is replaced in place
+	var redacted = versions[0]
+	redacted.Command.Arguments = []string{"****"}
+	if err := repository.PutCommandVersion("a1", redacted); err != nil {
+		t.Fatalf("PutCommandVersion returned unexpected error: %v", err)
+	}
+	if versions, _ = repository.GetCommandVersions("a1"); versions[0].Command.Arguments[0] != "****" || len(versions) != 10 {
+		t.Errorf("GetCommandVersions(a1)[0] = %+v, want the redacted version", versions[0].Command)
+	}
+	if err := repository.PutCommandVersion("a1", models.Version{Number: 99}); err == nil {
+		t.Errorf("PutCommandVersion(99) returned no error, want not found")
+	}
+
+	// deleting the command keeps the last variant as a version
+	if err := repository.DeleteStoredCommand("a1"); err != nil {
+		t.Fatalf("DeleteStoredCommand returned unexpected error: %v", err)
+	}
+	versions, err = repository.GetCommandVersions("a1")
+	if err != nil || len(versions) != 10 || versions[9].Command.Arguments[0] != "build-11" {
+		t.Errorf("GetCommandVersions(a1) after the delete = %d versions, %v, want build-11 last", len(versions), err)
+	}
+	if ids, err := repository.GetVersionedCommands(); err != nil || len(ids) != 1 || ids[0] != "a1" {
+		t.Errorf("GetVersionedCommands() = %v, %v, want a1", ids, err)
+	}
+}
+
+func TestChainVersions(t *testing.T) {
+	var repository = newRepository(t)
+
+	for _, steps := range [][]string{{"a1"}, {"a1", "b2"}, {"b2"}} {
+		var chain = models.Chain{Name: "deploy"}
+		for _, id := range steps {
+			chain.Steps = append(chain.Steps, models.ChainStep{CommandID: id})
+		}
+		if err := repository.PutChain(chain); err != nil {
+			t.Fatalf("PutChain returned unexpected error: %v", err)
+		}
+	}
+
+	versions, err := repository.GetChainVersions("deploy")
+	if err != nil || len(versions) != 2 || len(versions[0].Chain.Steps) != 1 || len(versions[1].Chain.Steps) != 2 {
+		t.Fatalf("GetChainVersions(deploy) = %+v, %v, want the two previous variants oldest first", versions, err)
+	}
+
+	if err := repository.DeleteChain("deploy"); err != nil {
+		t.Fatalf("DeleteChain returned unexpected error: %v", err)
+	}
+	if versions, err := repository.GetChainVersions("deploy"); err != nil || len(versions) != 0 {
+		t.Errorf("GetChainVersions(deploy) after the delete = %v, %v, want none", versions, err)
+	}
+}
+
+func TestUndoEnvironment(t *testing.T) {
+	var repository = newRepository(t)
+
+	var put = func(variables map[string]string) {
+		t.Helper()
+		if err := repository.PutEnvironment(models.Environment{Name: "prod", Variables: variables}); err != nil {
+			t.Fatalf("PutEnvironment returned unexpected error: %v", err)
+		}
+	}
+
+	put(map[string]string{"STAGE": "prod"})
+	put(map[string]string{"STAGE": "prod", "REGION": "eu"})
+	put(map[string]string{"REGION": "eu"})
+	if err := repository.DeleteEnvironment("prod"); err != nil {
+		t.Fatalf("DeleteEnvironment returned unexpected error: %v", err)
+	}
+
+	// each undo reverts one more change, the last one the creation
+	for _, want := range []int{1, 2, 1} {
+		if _, err := repository.UndoEnvironment("prod"); err != nil {
+			t.Fatalf("UndoEnvironment(prod) returned unexpected error: %v", err)
+		}
+		if environment, err := repository.GetEnvironment("prod"); err != nil || len(environment.Variables) != want {
+			t.Errorf("GetEnvironment(prod) after an undo = %v, %v, want %d variables", environment.Variables, err, want)
+		}
+	}
+
+	version, err := repository.UndoEnvironment("prod")
+	if err != nil || version.Environment != nil {
+		t.Fatalf("UndoEnvironment(prod) = %+v, %v, want the environment removed", version, err)
+	}
+	if _, err := repository.GetEnvironment("prod"); err == nil {
+		t.Errorf("GetEnvironment(prod) returned no error, want not found")
+	}
+
+	if _, err := repository.UndoEnvironment("prod"); err == nil {
+		t.Errorf("UndoEnvironment(prod) returned no error, want nothing to undo")
+	}
+}
+
+func TestUndoDepth(t *testing.T) {
+	var repository = newRepository(t)
+
+	// only the last undoDepth changes, 10 by default, are kept
+	for i := 0; i < 15; i++ {
+		if err := repository.PutEnvironment(models.Environment{Name: "prod", Variables: map[string]string{"N": strconv.Itoa(i)}}); err != nil {
+			t.Fatalf("PutEnvironment returned unexpected error: %v", err)
+		}
+	}
+
+	var undone = 0
+	for ; undone < 20; undone++ {
+		if _, err := repository.UndoEnvironment("prod"); err != nil {
+			break
+		}
+	}
+
+	if undone != 10 {
+		t.Errorf("UndoEnvironment(prod) reverted %d changes, want 10", undone)
+	}
+	if environment, err := repository.GetEnvironment("prod"); err != nil || environment.Variables["N"] != "4" {
+		t.Errorf("GetEnvironment(prod) = %v, %v, want N=4", environment.Variables, err)
+	}
+}
+
+func TestUndoStoredCommand(t *testing.T) {
+	var repository = newRepository(t)
+
+	var command = executedCommand("a1", "make")
+	if err := repository.Push(command); err != nil {
+		t.Fatalf("Push returned unexpected error: %v", err)
+	}
+	command.Arguments = []string{"test"}
+	if err := repository.Push(command); err != nil {
+		t.Fatalf("Push returned unexpected error: %v", err)
+	}
+	if err := repository.DeleteStoredCommand("a1"); err != nil {
+		t.Fatalf("DeleteStoredCommand returned unexpected error: %v", err)
+	}
+
+	// the delete, then the edit are reverted
+	for _, want := range []string{"test", "build"} {
+		if _, err := repository.UndoStoredCommand("a1"); err != nil {
+			t.Fatalf("UndoStoredCommand(a1) returned unexpected error: %v", err)
+		}
+		if stored, err := repository.FindInStoreById("a1"); err != nil || stored.Arguments[0] != want {
+			t.Errorf("FindInStoreById(a1) after an undo = %+v, %v, want make %s", stored, err, want)
+		}
+	}
+
+	// the versions undone are taken back, the creation is left
+	if versions, err := repository.GetCommandVersions("a1"); err != nil || len(versions) != 1 || versions[0].Command != nil {
+		t.Errorf("GetCommandVersions(a1) = %+v, %v, want the creation only", versions, err)
+	}
+
+	// then the push, the command was not stored before
+	if version, err := repository.UndoStoredCommand("a1"); err != nil || version.Command != nil {
+		t.Fatalf("UndoStoredCommand(a1) = %+v, %v, want the command removed", version, err)
+	}
+	if _, err := repository.FindInStoreById("a1"); err == nil {
+		t.Errorf("FindInStoreById(a1) returned no error, want not found")
+	}
+
+	// every command cleared can be brought back
+	for _, id := range []string{"b2", "c3"} {
+		if err := repository.Push(executedCommand(id, "go")); err != nil {
+			t.Fatalf("Push returned unexpected error: %v", err)
+		}
+	}
+	if err := repository.DeleteAllStoredCommands(); err != nil {
+		t.Fatalf("DeleteAllStoredCommands returned unexpected error: %v", err)
+	}
+	if _, err := repository.UndoStoredCommand("c3"); err != nil {
+		t.Fatalf("UndoStoredCommand(c3) returned unexpected error: %v", err)
+	}
+	if stored, err := repository.GetAllStoredCommands(); err != nil || len(stored) != 1 || stored[0].ID != "c3" {
+		t.Errorf("GetAllStoredCommands() = %+v, %v, want c3 only", stored, err)
+	}
+
+	// as the undo of the others brings them back, nothing is left to undo once created again
+	if _, err := repository.UndoStoredCommand("c3"); err != nil {
+		t.Fatalf("UndoStoredCommand(c3) returned unexpected error: %v", err)
+	}
+	if _, err := repository.UndoStoredCommand("c3"); err == nil {
+		t.Errorf("UndoStoredCommand(c3) returned no error, want nothing to undo")
+	}
+}
@@ -0,0 +1,280 @@
+// Package tracing records the spans of an ambros invocation and exports them to an OpenTelemetry
+// collector with the OTLP/HTTP json protocol
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// how long the export may take, the invocation waits for it
+const exportTimeout = 2 * time.Second
+
+// status codes of the OTLP spans
+const (
+	statusOk    = 1
+	statusError = 2
+)
+
+// Span is a timed operation of the invocation
+type Span struct {
+	traceID    string
+	spanID     string
+	parentID   string
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	failed     bool
+	message    string
+}
+
+// ID returns the id of the span, empty for a nil span
+func (s *Span) ID() string {
+	if s == nil {
+		return ""
+	}
+	return s.spanID
+}
+
+// SetAttribute adds an attribute to the span, the values are strings, ints or bools
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.attributes[key] = value
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.failed = true
+	s.message = message
+}
+
+// Tracer collects the spans of an invocation, a nil tracer records nothing
+type Tracer struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+
+	lock     sync.Mutex
+	traceID  string
+	remoteID string
+	ended    []*Span
+}
+
+// New returns a tracer exporting to the endpoint; traceparent, when valid, makes the spans part of
+// the trace of the caller
+func New(endpoint string, headers map[string]string, service string, traceparent string) *Tracer {
+	var t = &Tracer{endpoint: endpoint, headers: headers, service: service, traceID: randomHex(16)}
+
+	if traceID, parentID, ok := ParseTraceparent(traceparent); ok {
+		t.traceID = traceID
+		t.remoteID = parentID
+	}
+
+	return t
+}
+
+// ParseTraceparent reads a W3C traceparent header: version-traceid-parentid-flags
+func ParseTraceparent(value string) (string, string, bool) {
+	var parts = strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+
+	for _, part := range parts[1:3] {
+		if _, err := hex.DecodeString(part); err != nil || strings.Trim(part, "0") == "" {
+			return "", "", false
+		}
+	}
+
+	return parts[1], parts[2], true
+}
+
+// ParseHeaders reads the OTEL_EXPORTER_OTLP_HEADERS format: key1=value1,key2=value2
+func ParseHeaders(value string) map[string]string {
+	var headers = map[string]string{}
+
+	for _, pair := range strings.Split(value, ",") {
+		var kv = strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) != "" {
+			headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	return headers
+}
+
+// Start opens a span, child of the parent or, when nil, of the span of the caller given by traceparent;
+// the parent is passed explicitly so the spans started by parallel goroutines never cross
+func (t *Tracer) Start(name string, parent *Span) *Span {
+	if t == nil {
+		return nil
+	}
+
+	var span = &Span{traceID: t.traceID, spanID: randomHex(8), parentID: t.remoteID, name: name, start: time.Now(), attributes: map[string]interface{}{}}
+	if parent != nil {
+		span.parentID = parent.spanID
+	}
+
+	return span
+}
+
+// End closes the span
+func (t *Tracer) End(span *Span) {
+	if t == nil || span == nil {
+		return
+	}
+
+	span.end = time.Now()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.ended = append(t.ended, span)
+}
+
+// Traceparent returns the W3C traceparent of the span, to pass to child processes
+func (t *Tracer) Traceparent(span *Span) string {
+	if t == nil {
+		return ""
+	}
+
+	var parent = t.remoteID
+	if span != nil {
+		parent = span.spanID
+	}
+	if parent == "" {
+		return ""
+	}
+
+	return "00-" + t.traceID + "-" + parent + "-01"
+}
+
+// Flush exports the ended spans
+func (t *Tracer) Flush() error {
+	if t == nil {
+		return nil
+	}
+
+	t.lock.Lock()
+	var spans = t.ended
+	t.ended = nil
+	t.lock.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(t.payload(spans))
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		request.Header.Set(k, v)
+	}
+
+	client := http.Client{Timeout: exportTimeout}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.New("collector answered " + response.Status)
+	}
+
+	return nil
+}
+
+// payload builds the OTLP json export request
+func (t *Tracer) payload(spans []*Span) map[string]interface{} {
+	var encoded = []map[string]interface{}{}
+
+	for _, s := range spans {
+		var span = map[string]interface{}{
+			"traceId":           s.traceID,
+			"spanId":            s.spanID,
+			"name":              s.name,
+			"kind":              1,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        attributes(s.attributes),
+			"status":            map[string]interface{}{"code": statusOk},
+		}
+
+		if s.parentID != "" {
+			span["parentSpanId"] = s.parentID
+		}
+
+		if s.failed {
+			span["status"] = map[string]interface{}{"code": statusError, "message": s.message}
+		}
+
+		encoded = append(encoded, span)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": attributes(map[string]interface{}{"service.name": t.service}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "ambros"},
+				"spans": encoded,
+			}},
+		}},
+	}
+}
+
+func attributes(values map[string]interface{}) []interface{} {
+	var encoded = []interface{}{}
+
+	for k, v := range values {
+		var value map[string]interface{}
+
+		switch typed := v.(type) {
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(typed)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(typed, 10)}
+		case bool:
+			value = map[string]interface{}{"boolValue": typed}
+		case string:
+			value = map[string]interface{}{"stringValue": typed}
+		default:
+			continue
+		}
+
+		encoded = append(encoded, map[string]interface{}{"key": k, "value": value})
+	}
+
+	return encoded
+}
+
+func randomHex(size int) string {
+	var b = make([]byte, size)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
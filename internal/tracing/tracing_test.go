@@ -0,0 +1,141 @@
+package tracing_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gi4nks/ambros/internal/tracing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	traceID, parentID, ok := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok || traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || parentID != "00f067aa0ba902b7" {
+		t.Errorf("ParseTraceparent() returned unexpected result: %s %s %v", traceID, parentID, ok)
+	}
+
+	for _, invalid := range []string{"", "00-abc-def-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01"} {
+		if _, _, ok := tracing.ParseTraceparent(invalid); ok {
+			t.Errorf("ParseTraceparent(%q) accepted an invalid value", invalid)
+		}
+	}
+}
+
+func TestTracer(t *testing.T) {
+	var received map[string]interface{}
+	var authorization string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	tracer := tracing.New(server.URL, tracing.ParseHeaders("Authorization=Bearer abc"), "ambros",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	root := tracer.Start("ambros run", nil)
+	child := tracer.Start("exec false", root)
+	child.SetAttribute("process.exit_code", 1)
+	child.SetError("exit status 1")
+
+	if traceparent := tracer.Traceparent(child); traceparent != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+child.ID()+"-01" {
+		t.Errorf("Traceparent() returned unexpected value: %s", traceparent)
+	}
+
+	tracer.End(child)
+	tracer.End(root)
+
+	if err := tracer.Flush(); err != nil {
+		t.Fatalf("Flush() returned unexpected error: %v", err)
+	}
+
+	if authorization != "Bearer abc" {
+		t.Errorf("Flush() sent unexpected headers: %q", authorization)
+	}
+
+	spans := received["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("Flush() exported %d spans, want 2", len(spans))
+	}
+
+	exec, run := spans[0].(map[string]interface{}), spans[1].(map[string]interface{})
+	if run["parentSpanId"] != "00f067aa0ba902b7" || exec["parentSpanId"] != run["spanId"] {
+		t.Errorf("Flush() exported unexpected parents: %v and %v", run["parentSpanId"], exec["parentSpanId"])
+	}
+
+	if exec["status"].(map[string]interface{})["code"].(float64) != 2 {
+		t.Errorf("Flush() exported unexpected status: %v", exec["status"])
+	}
+
+	// Test case: Nil tracer
+	var disabled *tracing.Tracer
+	disabled.End(disabled.Start("nothing", nil))
+	if err := disabled.Flush(); err != nil || disabled.Traceparent(nil) != "" {
+		t.Errorf("nil tracer recorded something")
+	}
+}
+
+func TestTracerParallelSteps(t *testing.T) {
+	var received map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	tracer := tracing.New(server.URL, nil, "ambros", "")
+	chain := tracer.Start("chain build", nil)
+
+	// the steps run in parallel: each one opens its span and the span of its execution
+	var wg sync.WaitGroup
+	var parents = map[string]string{}
+	var lock sync.Mutex
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			step := tracer.Start("chain step", chain)
+			exec := tracer.Start("exec make", step)
+			time.Sleep(time.Millisecond)
+			tracer.End(exec)
+			tracer.End(step)
+
+			lock.Lock()
+			parents[exec.ID()] = step.ID()
+			parents[step.ID()] = chain.ID()
+			lock.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	tracer.End(chain)
+
+	if err := tracer.Flush(); err != nil {
+		t.Fatalf("Flush() returned unexpected error: %v", err)
+	}
+
+	spans := received["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 17 {
+		t.Fatalf("Flush() exported %d spans, want 17", len(spans))
+	}
+
+	for _, s := range spans {
+		span := s.(map[string]interface{})
+		if span["spanId"] == chain.ID() {
+			if _, ok := span["parentSpanId"]; ok {
+				t.Errorf("Flush() exported the chain with parent %v, want none", span["parentSpanId"])
+			}
+			continue
+		}
+
+		if span["parentSpanId"] != parents[span["spanId"].(string)] {
+			t.Errorf("Flush() exported %v %v with parent %v, want %v", span["name"], span["spanId"], span["parentSpanId"], parents[span["spanId"].(string)])
+		}
+	}
+}
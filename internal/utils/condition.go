@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// a condition compares operands, e.g. {{steps.1.status}} == success && {{env.TARGET}} =~ "^prod":
+// the operands are references in double braces, quoted strings or bare words, the comparisons are
+// == and != on the text and =~ and !~ on a regular expression, && binds them before ||. A single
+// operand holds when it is neither empty nor false
+var conditionOperators = []string{"==", "!=", "=~", "!~", "&&", "||"}
+
+type conditionToken struct {
+	text      string
+	operator  bool
+	reference bool
+}
+
+// EvaluateCondition tells if the condition holds, resolving each reference (the text in double braces)
+// with the given function
+func EvaluateCondition(condition string, resolve func(reference string) (string, error)) (bool, error) {
+	tokens, err := conditionTokens(condition)
+	if err != nil {
+		return false, err
+	}
+	if len(tokens) == 0 {
+		return false, errors.New("empty condition")
+	}
+
+	var result = false
+	for _, alternative := range splitTokens(tokens, "||") {
+		var all = true
+		for _, comparison := range splitTokens(alternative, "&&") {
+			holds, err := evaluateComparison(comparison, resolve)
+			if err != nil {
+				return false, err
+			}
+			all = all && holds
+		}
+		result = result || all
+	}
+
+	return result, nil
+}
+
+func conditionTokens(condition string) ([]conditionToken, error) {
+	var tokens = []conditionToken{}
+	var rest = strings.TrimSpace(condition)
+
+	for rest != "" {
+		var token conditionToken
+		var operator = ""
+		for _, o := range conditionOperators {
+			if strings.HasPrefix(rest, o) {
+				operator = o
+			}
+		}
+
+		switch {
+		case operator != "":
+			token = conditionToken{text: operator, operator: true}
+			rest = rest[len(operator):]
+		case strings.HasPrefix(rest, "{{"):
+			end := strings.Index(rest, "}}")
+			if end < 0 {
+				return nil, errors.New("unterminated reference: " + rest)
+			}
+			token = conditionToken{text: strings.TrimSpace(rest[2:end]), reference: true}
+			rest = rest[end+2:]
+		case rest[0] == '"' || rest[0] == '\'':
+			end := strings.IndexByte(rest[1:], rest[0])
+			if end < 0 {
+				return nil, errors.New("unterminated string: " + rest)
+			}
+			token = conditionToken{text: rest[1 : end+1]}
+			rest = rest[end+2:]
+		default:
+			end := strings.IndexAny(rest, " \t=!&|")
+			if end == 0 {
+				return nil, errors.New("unexpected " + rest)
+			}
+			if end < 0 {
+				end = len(rest)
+			}
+			token = conditionToken{text: rest[:end]}
+			rest = rest[end:]
+		}
+
+		tokens = append(tokens, token)
+		rest = strings.TrimSpace(rest)
+	}
+
+	return tokens, nil
+}
+
+func splitTokens(tokens []conditionToken, operator string) [][]conditionToken {
+	var parts = [][]conditionToken{{}}
+	for _, token := range tokens {
+		if token.operator && token.text == operator {
+			parts = append(parts, []conditionToken{})
+			continue
+		}
+		parts[len(parts)-1] = append(parts[len(parts)-1], token)
+	}
+	return parts
+}
+
+func evaluateComparison(tokens []conditionToken, resolve func(string) (string, error)) (bool, error) {
+	var values = make([]string, len(tokens))
+	for i, token := range tokens {
+		values[i] = token.text
+		if token.reference {
+			value, err := resolve(token.text)
+			if err != nil {
+				return false, err
+			}
+			values[i] = value
+		}
+	}
+
+	switch {
+	case len(tokens) == 1 && !tokens[0].operator:
+		return values[0] != "" && values[0] != "false", nil
+	case len(tokens) != 3 || tokens[0].operator || !tokens[1].operator || tokens[2].operator:
+		var texts = make([]string, len(tokens))
+		for i, token := range tokens {
+			texts[i] = token.text
+		}
+		return false, errors.New("invalid comparison: " + strings.Join(texts, " "))
+	}
+
+	switch tokens[1].text {
+	case "==":
+		return values[0] == values[2], nil
+	case "!=":
+		return values[0] != values[2], nil
+	}
+
+	re, err := regexp.Compile(values[2])
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(values[0]) == (tokens[1].text == "=~"), nil
+}
@@ -0,0 +1,61 @@
+package utils_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/utils"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	var references = map[string]string{
+		"steps.1.status": "success",
+		"steps.2.status": "failure",
+		"steps.1.output": "3 tests passed\nok",
+		"env.TARGET":     "production",
+		"env.EMPTY":      "",
+	}
+	var resolve = func(reference string) (string, error) {
+		value, found := references[reference]
+		if !found {
+			return "", errors.New("unknown reference " + reference)
+		}
+		return value, nil
+	}
+
+	tests := []struct {
+		condition string
+		holds     bool
+	}{
+		{"{{steps.1.status}} == success", true},
+		{"{{ steps.2.status }} == success", false},
+		{"{{steps.2.status}}!=success", true},
+		{`{{steps.1.output}} =~ "\d+ tests passed"`, true},
+		{`{{steps.1.output}} !~ 'failed'`, true},
+		{"{{env.TARGET}} =~ ^prod && {{steps.1.status}} == success", true},
+		{"{{env.TARGET}} == staging && {{steps.1.status}} == success", false},
+		{"{{env.TARGET}} == staging || {{steps.2.status}} == failure", true},
+		{"{{env.TARGET}} == staging || {{steps.2.status}} == failure && {{env.EMPTY}}", false},
+		{"{{env.TARGET}}", true},
+		{"{{env.EMPTY}}", false},
+		{`"a b" == "a b"`, true},
+	}
+
+	for _, test := range tests {
+		holds, err := utils.EvaluateCondition(test.condition, resolve)
+		if err != nil {
+			t.Errorf("EvaluateCondition(%q) returned unexpected error: %v", test.condition, err)
+			continue
+		}
+		if holds != test.holds {
+			t.Errorf("EvaluateCondition(%q) = %v, want %v", test.condition, holds, test.holds)
+		}
+	}
+
+	for _, condition := range []string{"", "{{steps.1.status", `{{env.TARGET}} == "prod`, "{{env.TARGET}} ==", "== success",
+		"a b", "{{steps.3.status}} == success", "{{env.TARGET}} =~ ("} {
+		if _, err := utils.EvaluateCondition(condition, resolve); err == nil {
+			t.Errorf("EvaluateCondition(%q) returned no error, want one", condition)
+		}
+	}
+}
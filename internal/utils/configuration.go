@@ -2,7 +2,11 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gi4nks/quant"
 )
@@ -12,8 +16,42 @@ type Configuration struct {
 
 	RepositoryDirectory string
 	RepositoryFile      string
+	Profile             string
 	LastCountDefault    int
 	DebugMode           bool
+	PerfMode            bool
+	StaleAfter          time.Duration
+	MinFreeDisk         int64
+	MaxRepositorySize   int64
+	HostSnapshot        bool
+	SamplingInterval    time.Duration
+	SamplingRules       map[string]time.Duration
+	AllowedExitCodes    map[string]string
+	Redact              bool
+	RedactPatterns      []string
+	RiskPatterns        []string
+	Origin              string
+	ReproProbe          bool
+	GitContext          bool
+	ColdAfter           time.Duration
+	Overlap             string
+	NotifyOnFailure     bool
+	OTLPEndpoint        string
+	LockTimeout         time.Duration
+	BackupEvery         time.Duration
+	UndoDepth           int
+	Notifications       []NotificationChannel
+}
+
+// NotificationChannel describes where the failures are notified: type is slack, webhook, smtp or desktop
+type NotificationChannel struct {
+	Type     string
+	URL      string
+	Host     string
+	Username string
+	Password string `json:"-"`
+	From     string
+	To       []string
 }
 
 func NewConfiguration(p quant.Parrot) *Configuration {
@@ -24,6 +62,27 @@ func NewConfiguration(p quant.Parrot) *Configuration {
 	c.RepositoryFile = ConstRepositoryFile
 	c.LastCountDefault = ConstLastCountDefault
 	c.DebugMode = ConstDebugMode
+	c.PerfMode = ConstPerfMode
+	c.StaleAfter = ConstStaleAfter
+	c.MinFreeDisk = ConstMinFreeDisk
+	c.MaxRepositorySize = ConstMaxRepositorySize
+	c.HostSnapshot = ConstHostSnapshot
+	c.SamplingInterval = ConstSamplingInterval
+	c.SamplingRules = map[string]time.Duration{}
+	c.AllowedExitCodes = map[string]string{}
+	c.Redact = ConstRedact
+	c.RedactPatterns = []string{}
+	c.RiskPatterns = []string{}
+	c.Origin, _ = os.Hostname()
+	c.ReproProbe = ConstReproProbe
+	c.GitContext = ConstGitContext
+	c.ColdAfter = ConstColdAfter
+	c.Overlap = ConstOverlap
+	c.NotifyOnFailure = ConstNotifyOnFailure
+	c.Notifications = []NotificationChannel{}
+	c.LockTimeout = ConstLockTimeout
+	c.BackupEvery = ConstBackupEvery
+	c.UndoDepth = ConstUndoDepth
 
 	return &c
 }
@@ -46,3 +105,25 @@ func (c Configuration) RepositoryFullName() string {
 	*/
 	return c.RepositoryDirectory + string(filepath.Separator) + c.RepositoryFile
 }
+
+// SamplingIntervalFor returns the sampling interval applied to the given command name
+func (c Configuration) SamplingIntervalFor(name string) time.Duration {
+	if d, ok := c.SamplingRules[name]; ok {
+		return d
+	}
+	return c.SamplingInterval
+}
+
+// AllowedExitCodesFor returns the non zero exit codes meaning success for the given command name, e.g. 1 for grep
+func (c Configuration) AllowedExitCodesFor(name string) string {
+	return c.AllowedExitCodes[name]
+}
+
+// ParseOverlap reads the policy applied when a command is still running elsewhere: warn, queue or block
+func ParseOverlap(value string) (string, error) {
+	switch policy := strings.ToLower(strings.TrimSpace(value)); policy {
+	case "warn", "queue", "block":
+		return policy, nil
+	}
+	return "", errors.New("invalid overlap value " + value + ", use warn, queue or block")
+}
@@ -2,7 +2,9 @@ package utils_test
 
 import (
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gi4nks/ambros/internal/utils"
 	"github.com/gi4nks/quant"
@@ -39,3 +41,31 @@ func TestConfiguration_RepositoryFullName(t *testing.T) {
 
 	// Add more tests for specific cases if needed
 }
+
+func TestConfiguration_SamplingIntervalFor(t *testing.T) {
+	config := utils.NewConfiguration(quant.Parrot{})
+	config.SamplingInterval = time.Minute
+	config.SamplingRules["kubectl"] = 5 * time.Minute
+
+	if result := config.SamplingIntervalFor("kubectl"); result != 5*time.Minute {
+		t.Errorf("Expected sampling interval %v, got %v", 5*time.Minute, result)
+	}
+
+	if result := config.SamplingIntervalFor("ls"); result != time.Minute {
+		t.Errorf("Expected sampling interval %v, got %v", time.Minute, result)
+	}
+}
+
+func TestParseOverlap(t *testing.T) {
+	for value, want := range map[string]string{"warn": "warn", "queue": "queue", " Block ": "block"} {
+		if policy, err := utils.ParseOverlap(value); err != nil || policy != want {
+			t.Errorf("ParseOverlap(%q) = %q, %v, want %q", value, policy, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "stop", "warn,block"} {
+		if _, err := utils.ParseOverlap(value); err == nil || !strings.Contains(err.Error(), "warn, queue or block") {
+			t.Errorf("ParseOverlap(%q) = %v, want an error naming the valid values", value, err)
+		}
+	}
+}
@@ -1,6 +1,25 @@
 package utils
 
+import "time"
+
 const ConstRepositoryDirectory string = "./.ambros"
 const ConstRepositoryFile string = "ambros.db"
 const ConstLastCountDefault int = 10
 const ConstDebugMode bool = false
+const ConstPerfMode bool = false
+const ConstStaleAfter time.Duration = 90 * 24 * time.Hour
+const ConstMinFreeDisk int64 = 50 << 20
+const ConstMaxRepositorySize int64 = 0
+const ConstHostSnapshot bool = false
+const ConstSamplingInterval time.Duration = 0
+const ConstRedact bool = true
+const ConstReproProbe bool = false
+const ConstGitContext bool = true
+const ConstOverlap string = "warn"
+const ConstNotifyOnFailure bool = false
+const ConstColdAfter time.Duration = 0
+const ConstLockTimeout time.Duration = 10 * time.Second
+const ConstBackupEvery time.Duration = 0
+const ConstUndoDepth int = 10
+const ConstIncognitoEnv string = "AMBROS_INCOGNITO"
+const ConstProfileEnv string = "AMBROS_PROFILE"
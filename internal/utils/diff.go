@@ -0,0 +1,194 @@
+package utils
+
+import "strconv"
+
+// the operations of a line of a diff
+const (
+	DiffEqual  byte = ' '
+	DiffDelete byte = '-'
+	DiffInsert byte = '+'
+)
+
+// beyond this many changed lines the diff stops looking for the shortest edit and replaces the whole text
+const maxDiffEdits = 4000
+
+// DiffLine is a line of a diff, kept, removed from the first text or added by the second one
+type DiffLine struct {
+	Op   byte
+	Text string
+}
+
+// DiffLines returns the shortest sequence of kept, removed and added lines turning a into b (Myers' algorithm)
+func DiffLines(a, b []string) []DiffLine {
+	// the common prefix and suffix are kept as they are, they are most of the lines of similar outputs
+	var prefix = 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	var suffix = 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines = []DiffLine{}
+	for _, text := range a[:prefix] {
+		lines = append(lines, DiffLine{DiffEqual, text})
+	}
+	lines = append(lines, shortestEdit(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{DiffEqual, text})
+	}
+
+	return lines
+}
+
+func shortestEdit(a, b []string) []DiffLine {
+	var n, m = len(a), len(b)
+	var max = n + m
+	if max > maxDiffEdits {
+		max = maxDiffEdits
+	}
+
+	// v[k] is the furthest x reached on the diagonal k, trace keeps v[-d..d] before each step d
+	var offset = max + 1
+	var v = make([]int, 2*max+3)
+	var trace = [][]int{}
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int{}, v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			var y = x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrackEdit(a, b, trace)
+			}
+		}
+	}
+
+	var lines = []DiffLine{}
+	for _, text := range a {
+		lines = append(lines, DiffLine{DiffDelete, text})
+	}
+	for _, text := range b {
+		lines = append(lines, DiffLine{DiffInsert, text})
+	}
+	return lines
+}
+
+func backtrackEdit(a, b []string, trace [][]int) []DiffLine {
+	var reversed = []DiffLine{}
+	var x, y = len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		// trace[d] holds the diagonals -d..d, the ones reached after d-1 steps
+		var at = func(k int) int { return trace[d][k+d] }
+
+		var k = x - y
+		var previous int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			previous = k + 1
+		} else {
+			previous = k - 1
+		}
+
+		var previousX = 0
+		if d > 0 {
+			previousX = at(previous)
+		}
+		var previousY = previousX - previous
+
+		for x > previousX && y > previousY {
+			reversed = append(reversed, DiffLine{DiffEqual, a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == previousX {
+				reversed = append(reversed, DiffLine{DiffInsert, b[y-1]})
+			} else {
+				reversed = append(reversed, DiffLine{DiffDelete, a[x-1]})
+			}
+		}
+
+		x, y = previousX, previousY
+	}
+
+	var lines = make([]DiffLine, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+// UnifiedDiff renders the changes turning a into b as the hunks of a unified diff, each with the given
+// lines of context around the changes; equal texts have no hunks
+func UnifiedDiff(a, b []string, context int) []string {
+	var lines = DiffLines(a, b)
+
+	var changes = []int{}
+	for i, line := range lines {
+		if line.Op != DiffEqual {
+			changes = append(changes, i)
+		}
+	}
+
+	// the position of each line in a and in b
+	var positionsA, positionsB = make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for i, line := range lines {
+		positionsA[i+1], positionsB[i+1] = positionsA[i], positionsB[i]
+		if line.Op != DiffInsert {
+			positionsA[i+1]++
+		}
+		if line.Op != DiffDelete {
+			positionsB[i+1]++
+		}
+	}
+
+	var hunks = []string{}
+	for i := 0; i < len(changes); {
+		// the changes closer than twice the context share a hunk
+		var j = i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*context+1 {
+			j++
+		}
+
+		var start, end = changes[i] - context, changes[j] + context + 1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		hunks = append(hunks, "@@ -"+hunkRange(positionsA[start], positionsA[end]-positionsA[start])+
+			" +"+hunkRange(positionsB[start], positionsB[end]-positionsB[start])+" @@")
+		for _, line := range lines[start:end] {
+			hunks = append(hunks, string(line.Op)+line.Text)
+		}
+
+		i = j + 1
+	}
+
+	return hunks
+}
+
+// hunkRange renders the range of a hunk, whose lines are numbered from 1; an empty range names the line before it
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return strconv.Itoa(start) + ",0"
+	}
+	return strconv.Itoa(start+1) + "," + strconv.Itoa(count)
+}
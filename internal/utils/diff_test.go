@@ -0,0 +1,72 @@
+package utils_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/utils"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"", ""},
+		{"a b c", "a b c"},
+		{"a b c", "a x c"},
+		{"a b c a b b a", "c b a b a c"},
+		{"", "a b"},
+		{"a b", ""},
+		{"x a b", "a b y"},
+	}
+
+	for _, test := range tests {
+		var a, b = strings.Fields(test.a), strings.Fields(test.b)
+		var lines = utils.DiffLines(a, b)
+
+		// the kept and removed lines are a, the kept and added ones are b
+		var fromA, fromB = []string{}, []string{}
+		var edits = 0
+		for _, line := range lines {
+			if line.Op != utils.DiffInsert {
+				fromA = append(fromA, line.Text)
+			}
+			if line.Op != utils.DiffDelete {
+				fromB = append(fromB, line.Text)
+			}
+			if line.Op != utils.DiffEqual {
+				edits++
+			}
+		}
+
+		if strings.Join(fromA, " ") != test.a || strings.Join(fromB, " ") != test.b {
+			t.Errorf("DiffLines(%q, %q) = %v, does not turn a into b", test.a, test.b, lines)
+		}
+		if test.a == "a b c a b b a" && edits != 5 {
+			t.Errorf("DiffLines(%q, %q) has %d edits, want the shortest 5", test.a, test.b, edits)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	var a = strings.Fields("1 2 3 4 5 6 7 8 9 10 11 12")
+	var b = strings.Fields("1 2 x 4 5 6 7 8 9 10 11 12 13")
+
+	var expected = []string{
+		"@@ -2,3 +2,3 @@", " 2", "-3", "+x", " 4",
+		"@@ -12,1 +12,2 @@", " 12", "+13",
+	}
+
+	if result := utils.UnifiedDiff(a, b, 1); !reflect.DeepEqual(result, expected) {
+		t.Errorf("UnifiedDiff() = %q, want %q", result, expected)
+	}
+
+	if result := utils.UnifiedDiff(a, b, 5); len(result) == 0 || result[0] != "@@ -1,12 +1,13 @@" {
+		t.Errorf("UnifiedDiff() with close changes = %q, want a single hunk", result)
+	}
+
+	if result := utils.UnifiedDiff(a, a, 3); len(result) != 0 {
+		t.Errorf("UnifiedDiff() of equal texts = %q, want no hunks", result)
+	}
+}
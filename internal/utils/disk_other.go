@@ -0,0 +1,10 @@
+//go:build !unix
+
+package utils
+
+import "errors"
+
+// FreeDiskSpace tells the free space is unknown, the disk checks are skipped on this system
+func (u *Utilities) FreeDiskSpace(path string) (int64, error) {
+	return 0, errors.New("free disk space unknown on this system")
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package utils
+
+import "syscall"
+
+// FreeDiskSpace returns the bytes available to the user on the filesystem holding path
+func (u *Utilities) FreeDiskSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ExitCodes is a set of exit codes written as a list of codes and ranges, e.g. 1,126-128
+type ExitCodes [][2]int
+
+// ParseExitCodes parses a list of exit codes and ranges separated by commas, e.g. 0, 1-127 or 1,126-128
+func ParseExitCodes(spec string) (ExitCodes, error) {
+	var codes = ExitCodes{}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, errors.New("invalid exit codes " + spec)
+		}
+
+		// a leading minus is the sign of -1, the code of the commands which did not exit by themselves
+		var from, to = part, part
+		if i := strings.Index(part[1:], "-"); i >= 0 {
+			from, to = part[:i+1], part[i+2:]
+		}
+
+		low, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, errors.New("invalid exit code " + from)
+		}
+		high, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			return nil, errors.New("invalid exit code " + to)
+		}
+		if high < low {
+			return nil, errors.New("invalid exit code range " + part)
+		}
+
+		codes = append(codes, [2]int{low, high})
+	}
+
+	return codes, nil
+}
+
+// Contains tells if the code is one of the set
+func (e ExitCodes) Contains(code int) bool {
+	for _, r := range e {
+		if code >= r[0] && code <= r[1] {
+			return true
+		}
+	}
+	return false
+}
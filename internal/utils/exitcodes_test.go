@@ -0,0 +1,45 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/utils"
+)
+
+func TestParseExitCodes(t *testing.T) {
+	tests := []struct {
+		spec     string
+		contains []int
+		excludes []int
+	}{
+		{"1", []int{1}, []int{0, 2}},
+		{"1-127", []int{1, 64, 127}, []int{0, 128}},
+		{"1, 126-128", []int{1, 126, 128}, []int{2, 129}},
+		{"-1", []int{-1}, []int{0, 1}},
+		{"-1-0", []int{-1, 0}, []int{1}},
+	}
+
+	for _, test := range tests {
+		codes, err := utils.ParseExitCodes(test.spec)
+		if err != nil {
+			t.Errorf("ParseExitCodes(%q) returned unexpected error: %v", test.spec, err)
+			continue
+		}
+		for _, code := range test.contains {
+			if !codes.Contains(code) {
+				t.Errorf("ParseExitCodes(%q) does not contain %d", test.spec, code)
+			}
+		}
+		for _, code := range test.excludes {
+			if codes.Contains(code) {
+				t.Errorf("ParseExitCodes(%q) contains %d", test.spec, code)
+			}
+		}
+	}
+
+	for _, spec := range []string{"", "a", "1,", "3-1", "1-x"} {
+		if _, err := utils.ParseExitCodes(spec); err == nil {
+			t.Errorf("ParseExitCodes(%q) returned no error, want one", spec)
+		}
+	}
+}
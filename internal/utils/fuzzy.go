@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FuzzyScore tells if the text contains the letters of every word of the pattern in order, ignoring case,
+// as fzf does; the higher the score the better the match: consecutive letters and letters starting a
+// word score more, the letters between the matching ones score less. An empty pattern matches everything
+// with score 0
+func FuzzyScore(text string, pattern string) (int, bool) {
+	var runes = []rune(strings.ToLower(text))
+	var score = 0
+
+	for _, word := range strings.Fields(strings.ToLower(pattern)) {
+		var wordScore, matched = fuzzyWordScore(runes, []rune(word))
+		if !matched {
+			return 0, false
+		}
+		score += wordScore
+	}
+
+	return score, true
+}
+
+// fuzzyWordScore returns the best score of the matches of the word starting at each of its first letter
+func fuzzyWordScore(text []rune, word []rune) (int, bool) {
+	var best, found = 0, false
+
+	for start := range text {
+		if text[start] != word[0] {
+			continue
+		}
+
+		if score, matched := fuzzyMatchFrom(text, word, start); matched && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+
+	return best, found
+}
+
+// fuzzyMatchFrom matches the letters of the word in order from the start position, each at its first occurrence
+func fuzzyMatchFrom(text []rune, word []rune, start int) (int, bool) {
+	var score, w, previous = 0, 0, start - 1
+
+	for i := start; i < len(text) && w < len(word); i++ {
+		if text[i] != word[w] {
+			continue
+		}
+
+		score++
+		if w > 0 && i == previous+1 {
+			score += 4
+		}
+		if w > 0 {
+			score -= i - previous - 1
+		}
+		if i == 0 || !unicode.IsLetter(text[i-1]) && !unicode.IsDigit(text[i-1]) {
+			score += 2
+		}
+
+		previous = i
+		w++
+	}
+
+	return score, w == len(word)
+}
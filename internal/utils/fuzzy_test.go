@@ -0,0 +1,46 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/utils"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		text    string
+		pattern string
+		matched bool
+	}{
+		{"docker compose up -d", "", true},
+		{"docker compose up -d", "dcu", true},
+		{"docker compose up -d", "DOCK up", true},
+		{"docker compose up -d", "up dock", true},
+		{"docker compose up -d", "dcx", false},
+		{"docker compose up -d", "ud", true},
+		{"git status", "stat git", true},
+		{"git status", "tig", false},
+	}
+
+	for _, test := range tests {
+		if _, matched := utils.FuzzyScore(test.text, test.pattern); matched != test.matched {
+			t.Errorf("FuzzyScore(%q, %q) matched = %v, want %v", test.text, test.pattern, matched, test.matched)
+		}
+	}
+
+	// consecutive letters and letters starting a word rank first
+	ranked := [][2]string{
+		{"git status", "git st"},
+		{"go test ./...", "gt"},
+		{"kubectl get pods", "get"},
+	}
+	others := []string{"grep -rn it", "git log --stat", "kubectl logs target"}
+
+	for i, pair := range ranked {
+		best, _ := utils.FuzzyScore(pair[0], pair[1])
+		other, matched := utils.FuzzyScore(others[i], pair[1])
+		if matched && other >= best {
+			t.Errorf("FuzzyScore(%q, %q) = %d, want more than %d of %q", pair[0], pair[1], best, other, others[i])
+		}
+	}
+}
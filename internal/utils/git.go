@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// how long reading the state of a git repository may take
+const gitContextTimeout = 2 * time.Second
+
+// the length of a short commit hash
+const gitShortCommit = 7
+
+// GitContext returns the state of the git repository holding the directory, nil when it is not in one
+// or git is not available; the untracked files do not make it dirty
+func (u *Utilities) GitContext(dir string) *models.Git {
+	ctx, cancel := context.WithTimeout(context.Background(), gitContextTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain=v2", "--branch", "--untracked-files=no").Output()
+	if err != nil {
+		return nil
+	}
+	return ParseGitStatus(string(output))
+}
+
+// ParseGitStatus reads the state of a repository from the output of git status --porcelain=v2 --branch
+func ParseGitStatus(status string) *models.Git {
+	var git = models.Git{}
+
+	var scanner = bufio.NewScanner(strings.NewReader(status))
+	for scanner.Scan() {
+		var line = scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "# branch.oid "):
+			if oid := strings.TrimPrefix(line, "# branch.oid "); oid != "(initial)" {
+				git.Commit = oid
+				if len(oid) > gitShortCommit {
+					git.Commit = oid[:gitShortCommit]
+				}
+			}
+		case strings.HasPrefix(line, "# branch.head "):
+			git.Branch = strings.TrimPrefix(line, "# branch.head ")
+			if git.Branch == "(detached)" {
+				git.Branch = "HEAD"
+			}
+		case line != "" && !strings.HasPrefix(line, "#"):
+			git.Dirty = true
+		}
+	}
+
+	if git.Branch == "" {
+		return nil
+	}
+	return &git
+}
@@ -0,0 +1,29 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	models "github.com/gi4nks/ambros/internal/models"
+	"github.com/gi4nks/ambros/internal/utils"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	tests := map[string]*models.Git{
+		"# branch.oid 4f2a9c1d0e8b7a6f5e4d3c2b1a0f9e8d7c6b5a49\n# branch.head main\n# branch.upstream origin/main\n# branch.ab +0 -0\n": {
+			Branch: "main", Commit: "4f2a9c1"},
+		"# branch.oid 4f2a9c1d0e8b7a6f5e4d3c2b1a0f9e8d7c6b5a49\n# branch.head feature/login\n1 .M N... 100644 100644 100644 3f1a 3f1a cmd/main.go\n": {
+			Branch: "feature/login", Commit: "4f2a9c1", Dirty: true},
+		"# branch.oid 4f2a9c1d0e8b7a6f5e4d3c2b1a0f9e8d7c6b5a49\n# branch.head (detached)\n": {
+			Branch: "HEAD", Commit: "4f2a9c1"},
+		"# branch.oid (initial)\n# branch.head main\n": {
+			Branch: "main"},
+		"": nil,
+	}
+
+	for status, expected := range tests {
+		if result := utils.ParseGitStatus(status); !reflect.DeepEqual(result, expected) {
+			t.Errorf("ParseGitStatus(%q) = %+v, want %+v", status, result, expected)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// lines of the history files carrying a timestamp
+var (
+	bashTimestamp = regexp.MustCompile(`^#(\d{9,})$`)
+	zshExtended   = regexp.MustCompile(`^: (\d+):(\d+);(.*)$`)
+	fishWhen      = regexp.MustCompile(`^\s+when: (\d+)$`)
+)
+
+// zshMeta marks a metafied byte in the zsh history, the next byte is the original xor 0x20
+const zshMeta = 0x83
+
+// ParseHistory reads a bash, zsh or fish history file; the commands found have a name, arguments and,
+// when the file records them, the time they were started and finished. Their exit status is unknown
+// to the shell history, they are all marked as successful
+func (u *Utilities) ParseHistory(shell string, r io.Reader) ([]models.Command, error) {
+	var lines = []string{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	switch shell {
+	case "bash":
+		return parseBashHistory(lines), nil
+	case "zsh":
+		return parseZshHistory(lines), nil
+	case "fish":
+		return parseFishHistory(lines), nil
+	default:
+		return nil, errors.New("unknown shell '" + shell + "', use bash, zsh or fish")
+	}
+}
+
+// parseBashHistory reads the lines of .bash_history; with HISTTIMEFORMAT set each command follows a
+// #<epoch> line and may span several lines, the lines written before it was set are single commands
+func parseBashHistory(lines []string) []models.Command {
+	var commands = []models.Command{}
+
+	var timestamped = false
+	for _, line := range lines {
+		if bashTimestamp.MatchString(line) {
+			timestamped = true
+			break
+		}
+	}
+
+	var at time.Time
+	var current = []string{}
+
+	var flush = func() {
+		if command, ok := historyCommand(strings.Join(current, "\n"), at, 0); ok {
+			commands = append(commands, command)
+		}
+		current = []string{}
+	}
+
+	for _, line := range lines {
+		if m := bashTimestamp.FindStringSubmatch(line); m != nil {
+			flush()
+			at = epoch(m[1])
+			continue
+		}
+
+		current = append(current, line)
+		if !timestamped || at.IsZero() {
+			flush()
+		}
+	}
+	flush()
+
+	return commands
+}
+
+// parseZshHistory reads the lines of .zsh_history, plain or in the extended format
+// ': <start>:<elapsed seconds>;<command>'; a line ending with a backslash goes on with the next one
+func parseZshHistory(lines []string) []models.Command {
+	var commands = []models.Command{}
+
+	for i := 0; i < len(lines); i++ {
+		var line = unmetafy(lines[i])
+		var at time.Time
+		var elapsed time.Duration
+
+		if m := zshExtended.FindStringSubmatch(line); m != nil {
+			at = epoch(m[1])
+			seconds, _ := strconv.Atoi(m[2])
+			elapsed = time.Duration(seconds) * time.Second
+			line = m[3]
+		}
+
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, "\\") + "\n" + unmetafy(lines[i])
+		}
+
+		if command, ok := historyCommand(line, at, elapsed); ok {
+			commands = append(commands, command)
+		}
+	}
+
+	return commands
+}
+
+// parseFishHistory reads the fish_history file, a list of '- cmd: <command>' entries followed by
+// their 'when: <epoch>'
+func parseFishHistory(lines []string) []models.Command {
+	var commands = []models.Command{}
+
+	var line string
+	var at time.Time
+	var pending = false
+
+	var flush = func() {
+		if !pending {
+			return
+		}
+		if command, ok := historyCommand(line, at, 0); ok {
+			commands = append(commands, command)
+		}
+		pending = false
+	}
+
+	for _, l := range lines {
+		if strings.HasPrefix(l, "- cmd: ") {
+			flush()
+			line = unescapeFish(strings.TrimPrefix(l, "- cmd: "))
+			at = time.Time{}
+			pending = true
+			continue
+		}
+
+		if m := fishWhen.FindStringSubmatch(l); m != nil {
+			at = epoch(m[1])
+		}
+	}
+	flush()
+
+	return commands
+}
+
+// historyCommand builds the command of a history line, false when the line is empty
+func historyCommand(line string, at time.Time, elapsed time.Duration) (models.Command, bool) {
+	var parts = SplitCommandLine(line)
+	if len(parts) == 0 {
+		return models.Command{}, false
+	}
+
+	var command = models.Command{Name: parts[0], Arguments: append([]string{}, parts[1:]...), Status: true}
+	if !at.IsZero() {
+		command.CreatedAt = at
+		command.TerminatedAt = at.Add(elapsed)
+	}
+
+	return command, true
+}
+
+// SplitCommandLine splits a shell command line in words, honouring quotes and backslashes
+func SplitCommandLine(line string) []string {
+	var words = []string{}
+	var word strings.Builder
+	var inWord = false
+	var quote rune
+
+	var runes = []rune(line)
+	for i := 0; i < len(runes); i++ {
+		var r = runes[i]
+
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+
+		case r == '\\' && i+1 < len(runes) && (quote == 0 || strings.ContainsRune("\"\\$`\n", runes[i+1])):
+			i++
+			if runes[i] != '\n' {
+				word.WriteRune(runes[i])
+				inWord = true
+			}
+
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words
+}
+
+func epoch(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// unmetafy restores the bytes zsh escapes in its history
+func unmetafy(line string) string {
+	if strings.IndexByte(line, zshMeta) < 0 {
+		return line
+	}
+
+	var b = []byte{}
+	for i := 0; i < len(line); i++ {
+		if line[i] == zshMeta && i+1 < len(line) {
+			i++
+			b = append(b, line[i]^0x20)
+			continue
+		}
+		b = append(b, line[i])
+	}
+
+	return string(b)
+}
+
+// unescapeFish restores the newlines and backslashes fish escapes in its history
+func unescapeFish(line string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			switch line[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(line[i])
+	}
+
+	return b.String()
+}
+
+// JoinCommandLine joins words in a shell command line, quoting the ones SplitCommandLine would split or change
+func JoinCommandLine(words []string) string {
+	var quoted = make([]string, len(words))
+
+	for i, word := range words {
+		quoted[i] = word
+		if word == "" || strings.ContainsAny(word, " \t\n'\"\\$`") {
+			quoted[i] = "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
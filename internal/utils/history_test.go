@@ -0,0 +1,102 @@
+package utils_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gi4nks/ambros/internal/utils"
+	"github.com/gi4nks/quant"
+)
+
+func TestParseHistory(t *testing.T) {
+	u := utils.NewUtilities(quant.Parrot{})
+
+	tests := []struct {
+		shell   string
+		content string
+		lines   [][]string
+		started []int64
+		ended   []int64
+	}{
+		{"bash", "ls -la\n\ngit commit -m 'first commit'\n",
+			[][]string{{"ls", "-la"}, {"git", "commit", "-m", "first commit"}}, []int64{0, 0}, []int64{0, 0}},
+		{"bash", "make\n#1700000000\necho \"a b\" \\\n  c\n#1700000005\nexit\n",
+			[][]string{{"make"}, {"echo", "a b", "c"}, {"exit"}}, []int64{0, 1700000000, 1700000005}, []int64{0, 1700000000, 1700000005}},
+		{"zsh", ": 1700000000:3;docker ps -a\n: 1700000010:0;for f in *; do \\\necho $f; done\npwd\n",
+			[][]string{{"docker", "ps", "-a"}, {"for", "f", "in", "*;", "do", "echo", "$f;", "done"}, {"pwd"}},
+			[]int64{1700000000, 1700000010, 0}, []int64{1700000003, 1700000010, 0}},
+		{"zsh", ": 1700000000:0;echo caf\xc3\x83\x82\n",
+			[][]string{{"echo", "cafâ"}}, []int64{1700000000}, []int64{1700000000}},
+		{"fish", "- cmd: cd /tmp\n  when: 1700000000\n  paths:\n    - /tmp\n- cmd: echo 'one\\ntwo'\n  when: 1700000020\n",
+			[][]string{{"cd", "/tmp"}, {"echo", "one\ntwo"}}, []int64{1700000000, 1700000020}, []int64{1700000000, 1700000020}},
+	}
+
+	for _, test := range tests {
+		commands, err := u.ParseHistory(test.shell, strings.NewReader(test.content))
+		if err != nil {
+			t.Fatalf("ParseHistory(%s) returned an error: %v", test.shell, err)
+		}
+
+		if len(commands) != len(test.lines) {
+			t.Fatalf("ParseHistory(%s) found %d commands, want %d", test.shell, len(commands), len(test.lines))
+		}
+
+		for i, c := range commands {
+			if got := append([]string{c.Name}, c.Arguments...); !reflect.DeepEqual(got, test.lines[i]) {
+				t.Errorf("ParseHistory(%s) command %d is %q, want %q", test.shell, i, got, test.lines[i])
+			}
+			if unix(c.CreatedAt) != test.started[i] || unix(c.TerminatedAt) != test.ended[i] {
+				t.Errorf("ParseHistory(%s) command %d ran %v - %v, want %d - %d", test.shell, i, c.CreatedAt, c.TerminatedAt, test.started[i], test.ended[i])
+			}
+			if !c.Status {
+				t.Errorf("ParseHistory(%s) command %d is not successful", test.shell, i)
+			}
+		}
+	}
+
+	if _, err := u.ParseHistory("csh", strings.NewReader("ls")); err == nil {
+		t.Errorf("ParseHistory(csh) should fail")
+	}
+}
+
+func unix(at time.Time) int64 {
+	if at.IsZero() {
+		return 0
+	}
+	return at.Unix()
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []string
+	}{
+		{"  ls   -la  ", []string{"ls", "-la"}},
+		{`grep "a \"b\" c" 'd e' f\ g`, []string{"grep", `a "b" c`, "d e", "f g"}},
+		{`echo "" ''`, []string{"echo", "", ""}},
+		{`printf 'it\n'`, []string{"printf", `it\n`}},
+		{"", []string{}},
+	}
+
+	for _, test := range tests {
+		if got := utils.SplitCommandLine(test.line); !reflect.DeepEqual(got, test.words) {
+			t.Errorf("SplitCommandLine(%q) = %q, want %q", test.line, got, test.words)
+		}
+	}
+}
+
+func TestJoinCommandLine(t *testing.T) {
+	tests := [][]string{
+		{"ls", "-la"},
+		{"grep", `a "b" c`, "d e", "it's", ""},
+		{"printf", `it\n`, "$HOME"},
+	}
+
+	for _, words := range tests {
+		if got := utils.SplitCommandLine(utils.JoinCommandLine(words)); !reflect.DeepEqual(got, words) {
+			t.Errorf("SplitCommandLine(JoinCommandLine(%q)) = %q, want the words back", words, got)
+		}
+	}
+}
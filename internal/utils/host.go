@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// HostSnapshot reads load average, available memory and free disk of the given path;
+// values which cannot be read on the current platform are left to zero
+func (u *Utilities) HostSnapshot(path string) models.HostSnapshot {
+	var snapshot = models.HostSnapshot{}
+
+	if b, err := os.ReadFile("/proc/loadavg"); err == nil {
+		fields := strings.Fields(string(b))
+		if len(fields) >= 3 {
+			snapshot.Load1, _ = strconv.ParseFloat(fields[0], 64)
+			snapshot.Load5, _ = strconv.ParseFloat(fields[1], 64)
+			snapshot.Load15, _ = strconv.ParseFloat(fields[2], 64)
+		}
+	}
+
+	if f, err := os.Open("/proc/meminfo"); err == nil {
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+				kb, _ := strconv.ParseInt(fields[1], 10, 64)
+				snapshot.MemAvailable = kb * 1024
+				break
+			}
+		}
+	}
+
+	if free, err := u.FreeDiskSpace(path); err == nil {
+		snapshot.DiskFree = free
+	}
+
+	return snapshot
+}
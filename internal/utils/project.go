@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// the files marking the root of a project: a git repository or an .ambros.yaml declaring its environment
+var projectMarkers = []string{".git", ".ambros.yaml"}
+
+// ProjectRoot returns the closest directory holding the given one, or itself, which is the root of a
+// project, empty when the directory is not in a project
+func (u *Utilities) ProjectRoot(dir string) string {
+	for {
+		for _, marker := range projectMarkers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// WithinDirectory tells if the path is the directory or is below it, both absolute
+func WithinDirectory(path string, dir string) bool {
+	if path == "" {
+		return false
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
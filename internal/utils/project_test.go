@@ -0,0 +1,65 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/utils"
+	"github.com/gi4nks/quant"
+)
+
+func TestProjectRoot(t *testing.T) {
+	var u = utils.NewUtilities(quant.Parrot{})
+	var root = t.TempDir()
+
+	var repository = filepath.Join(root, "repository")
+	var nested = filepath.Join(repository, "cmd", "tool")
+	var declared = filepath.Join(root, "declared")
+	for _, dir := range []string{filepath.Join(repository, ".git"), nested, declared} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("MkdirAll returned unexpected error: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(declared, ".ambros.yaml"), []byte("environment: dev\n"), 0644); err != nil {
+		t.Fatalf("WriteFile returned unexpected error: %v", err)
+	}
+
+	tests := map[string]string{
+		repository: repository,
+		nested:     repository,
+		declared:   declared,
+	}
+
+	for dir, expected := range tests {
+		if result := u.ProjectRoot(dir); result != expected {
+			t.Errorf("ProjectRoot(%q) = %q, want %q", dir, result, expected)
+		}
+	}
+
+	// above the projects, the root is outside of the temporary directory if any
+	if result := u.ProjectRoot(root); utils.WithinDirectory(result, root) {
+		t.Errorf("ProjectRoot(%q) = %q, want no project in it", root, result)
+	}
+}
+
+func TestWithinDirectory(t *testing.T) {
+	tests := []struct {
+		path   string
+		dir    string
+		within bool
+	}{
+		{"/home/me/project", "/home/me/project", true},
+		{"/home/me/project/cmd", "/home/me/project", true},
+		{"/home/me/project-x", "/home/me/project", false},
+		{"/home/me", "/home/me/project", false},
+		{"/home/me/..project", "/home/me", true},
+		{"", "/home/me", false},
+	}
+
+	for _, test := range tests {
+		if result := utils.WithinDirectory(test.path, test.dir); result != test.within {
+			t.Errorf("WithinDirectory(%q, %q) = %v, want %v", test.path, test.dir, result, test.within)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RedactedMarker replaces the secrets removed from the recorded commands
+const RedactedMarker = "[REDACTED]"
+
+// secretDetector recognizes a kind of secret, only the "secret" group is replaced when the pattern has one
+type secretDetector struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+var secretDetectors = []secretDetector{
+	{"authorization header", regexp.MustCompile(`(?i)authorization:\s*(?:bearer\s+|basic\s+|token\s+)?(?P<secret>[^\s"']+)`)},
+	{"bearer token", regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]+=*)`)},
+	{"password assignment", regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[=:]\s*["']?(?P<secret>[^\s"'&,;]+)`)},
+	{"url credentials", regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:(?P<secret>[^/\s@]+)@`)},
+	{"aws access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"private key", regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)},
+}
+
+// flags whose value, given as the next argument, is a secret
+var secretFlags = map[string]bool{
+	"--password": true, "--passwd": true, "--token": true, "--secret": true, "--api-key": true,
+	"--apikey": true, "--access-key": true, "--secret-key": true, "--client-secret": true,
+}
+
+// detectors returns the built in detectors followed by the valid configured patterns
+func detectors(patterns []string) []secretDetector {
+	var all = append([]secretDetector{}, secretDetectors...)
+
+	for _, p := range patterns {
+		if re, err := regexp.Compile(p); err == nil {
+			all = append(all, secretDetector{"pattern " + p, re})
+		}
+	}
+
+	return all
+}
+
+// Redact replaces the secrets found in the text, returning the names of the detectors that matched
+func (u *Utilities) Redact(text string, patterns []string) (string, []string) {
+	var found = []string{}
+
+	for _, d := range detectors(patterns) {
+		var redacted = redactMatches(d.Pattern, text)
+		if redacted != text {
+			found = append(found, d.Name)
+			text = redacted
+		}
+	}
+
+	return text, found
+}
+
+// RedactArguments redacts every argument and the values following secret flags or authorization schemes
+func (u *Utilities) RedactArguments(arguments []string, patterns []string) ([]string, []string) {
+	var redacted = make([]string, len(arguments))
+	var found = []string{}
+
+	for i, argument := range arguments {
+		if i > 0 && secretFollows(arguments[i-1]) && !authorizationScheme(argument) && argument != RedactedMarker {
+			redacted[i] = RedactedMarker
+			found = append(found, "secret argument")
+			continue
+		}
+
+		var names []string
+		redacted[i], names = u.Redact(argument, patterns)
+		found = append(found, names...)
+	}
+
+	return redacted, found
+}
+
+func secretFollows(argument string) bool {
+	var a = strings.ToLower(strings.Trim(argument, `"'`))
+	return secretFlags[a] || authorizationScheme(a) || strings.HasSuffix(a, "authorization:")
+}
+
+func authorizationScheme(argument string) bool {
+	var a = strings.ToLower(strings.Trim(argument, `"'`))
+	return a == "bearer" || a == "basic"
+}
+
+func redactMatches(re *regexp.Regexp, text string) string {
+	var group = re.SubexpIndex("secret")
+	var builder strings.Builder
+	var last = 0
+
+	for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+		var start, end = m[0], m[1]
+		if group > 0 && m[2*group] >= 0 {
+			start, end = m[2*group], m[2*group+1]
+		}
+
+		if text[start:end] == RedactedMarker {
+			continue
+		}
+
+		builder.WriteString(text[last:start])
+		builder.WriteString(RedactedMarker)
+		last = end
+	}
+
+	if last == 0 {
+		return text
+	}
+
+	builder.WriteString(text[last:])
+	return builder.String()
+}
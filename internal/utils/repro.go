@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// variables which usually change how a tool behaves, compared one by one
+var reproVariables = []string{
+	"PATH", "HOME", "SHELL", "LANG", "LC_ALL", "TZ", "GOPATH", "GOROOT", "GOFLAGS", "JAVA_HOME",
+	"PYTHONPATH", "VIRTUAL_ENV", "NODE_ENV", "NODE_PATH", "KUBECONFIG", "DOCKER_HOST", "AWS_PROFILE",
+}
+
+// variables which change at every invocation and would make every fingerprint different
+var volatileVariables = map[string]bool{
+	"PWD": true, "OLDPWD": true, "SHLVL": true, "_": true, "TERM_SESSION_ID": true, "WINDOWID": true,
+	"SSH_CLIENT": true, "SSH_CONNECTION": true, "SSH_TTY": true, "SSH_AUTH_SOCK": true,
+}
+
+// how long a --version probe may run
+const versionProbeTimeout = 2 * time.Second
+
+// Repro fingerprints the environment the named executable runs in, probing its version when asked
+func (u *Utilities) Repro(name string, probe bool) models.Repro {
+	var repro = models.Repro{OS: runtime.GOOS, Arch: runtime.GOARCH, Env: map[string]string{}}
+
+	if path, err := exec.LookPath(name); err == nil {
+		repro.Path = path
+		if probe {
+			repro.Version = u.ProbeVersion(path)
+		}
+	}
+
+	var environment = []string{}
+	for _, variable := range os.Environ() {
+		var name = strings.SplitN(variable, "=", 2)[0]
+		if !volatileVariables[name] && !strings.HasPrefix(name, "AMBROS_") {
+			environment = append(environment, variable)
+		}
+	}
+	sort.Strings(environment)
+	repro.EnvHash = shortHash(strings.Join(environment, "\n"))
+
+	for _, name := range reproVariables {
+		if value, ok := os.LookupEnv(name); ok {
+			repro.Env[name] = shortHash(value)
+		}
+	}
+
+	return repro
+}
+
+// ProbeVersion returns the first line printed by '<path> --version', empty when it fails or hangs
+func (u *Utilities) ProbeVersion(path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if len(line) > 120 {
+				line = line[:120]
+			}
+			return line
+		}
+	}
+
+	return ""
+}
+
+func shortHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:8])
+}
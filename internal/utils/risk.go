@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// riskRule adds its weight to the score of the command lines it matches
+type riskRule struct {
+	reason string
+	weight int
+	match  *regexp.Regexp
+}
+
+// rules applied to the whole command line, without the privilege escalation prefix
+var riskRules = []riskRule{
+	{"recursive forced removal", 50, regexp.MustCompile(`^rm\s+(.*\s)?-[a-zA-Z]*(r[a-zA-Z]*f|f[a-zA-Z]*r)|^rm\s+(.*\s)?(-[a-zA-Z]*r.*\s-[a-zA-Z]*f|-[a-zA-Z]*f.*\s-[a-zA-Z]*r)|^rm\s+(.*\s)?--recursive\b.*--force\b`)},
+	{"file removal", 20, regexp.MustCompile(`^(rm|rmdir|unlink|shred)\s`)},
+	{"raw disk write", 60, regexp.MustCompile(`^(dd\s.*\bof=/dev/|mkfs(\.\w+)?\s|fdisk\s|wipefs\s)`)},
+	{"forced push", 40, regexp.MustCompile(`^git\s+push\s+(.*\s)?(-f\b|--force\b|--force-with-lease\b|\+\S)`)},
+	{"history rewrite", 30, regexp.MustCompile(`^git\s+(reset\s+(.*\s)?--hard|clean\s+(.*\s)?-[a-zA-Z]*f|branch\s+(.*\s)?-D\b|checkout\s+(.*\s)?--\s+\.)`)},
+	{"resource deletion", 40, regexp.MustCompile(`^(kubectl|oc|helm)\s+(.*\s)?(delete|uninstall)\b|^(terraform|tofu)\s+(.*\s)?destroy\b|^docker\s+(.*\s)?(rm|rmi|prune)\b|^aws\s+.*\b(delete|terminate|rb)\b|^gcloud\s+.*\bdelete\b`)},
+	{"database drop", 60, regexp.MustCompile(`(?i)\b(drop\s+(table|database|schema)|truncate\s+table)\b`)},
+	{"permission change", 20, regexp.MustCompile(`^(chmod|chown|chgrp)\s+(.*\s)?-[a-zA-Z]*R`)},
+	{"process kill", 20, regexp.MustCompile(`^(kill\s+(.*\s)?-9\b|killall\s|pkill\s)`)},
+	{"system shutdown", 50, regexp.MustCompile(`^(shutdown|reboot|halt|poweroff)\b|^systemctl\s+(.*\s)?(stop|disable|mask|poweroff|reboot)\b`)},
+}
+
+// commands granting more privileges to the command they run
+var privilegeEscalation = map[string]bool{"sudo": true, "doas": true, "su": true, "pkexec": true}
+
+// variables naming the deployment stage the tools target
+var stageVariables = regexp.MustCompile(`^(\w+_)?(ENV|ENVIRONMENT|STAGE|PROFILE|CONTEXT)$`)
+
+var productionValue = regexp.MustCompile(`(?i)(^|[^a-z])(prod|production|live)([^a-z]|$)`)
+
+// Risk scores how dangerous it is to run the command line again: destructive verbs, privileges,
+// production targets and the command lines matching the guard patterns of the configuration
+func (u *Utilities) Risk(name string, arguments []string, environ []string, environment string, guards []string) models.Risk {
+	var risk = models.Risk{Reasons: []string{}}
+	var add = func(reason string, weight int) {
+		risk.Score += weight
+		risk.Reasons = append(risk.Reasons, reason)
+	}
+
+	var words = append([]string{name}, arguments...)
+	for len(words) > 1 && privilegeEscalation[filepath.Base(words[0])] {
+		if len(risk.Reasons) == 0 {
+			add("runs as "+filepath.Base(words[0]), 30)
+		}
+		words = words[1:]
+		// the options of sudo come before the command
+		for len(words) > 1 && strings.HasPrefix(words[0], "-") {
+			words = words[1:]
+		}
+	}
+
+	words[0] = filepath.Base(words[0])
+	var line = strings.Join(words, " ")
+
+	var removal = false
+	for _, rule := range riskRules {
+		// a recursive forced removal is also a file removal, counted once
+		if !rule.match.MatchString(line) || (removal && rule.reason == "file removal") {
+			continue
+		}
+
+		add(rule.reason, rule.weight)
+		removal = removal || rule.reason == "recursive forced removal"
+	}
+
+	if environment != "" && productionValue.MatchString(environment) {
+		add("production environment "+environment, 30)
+	} else {
+		for _, variable := range environ {
+			var pair = strings.SplitN(variable, "=", 2)
+			if len(pair) == 2 && stageVariables.MatchString(strings.ToUpper(pair[0])) && productionValue.MatchString(pair[1]) {
+				add("production target "+pair[0]+"="+pair[1], 30)
+				break
+			}
+		}
+	}
+
+	for _, guard := range guards {
+		if matched, _ := regexp.MatchString(guard, line); matched {
+			add("matches guard "+guard, 50)
+		}
+	}
+
+	if risk.Score > 100 {
+		risk.Score = 100
+	}
+
+	return risk
+}
@@ -0,0 +1,52 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/utils"
+	"github.com/gi4nks/quant"
+)
+
+func TestRisk(t *testing.T) {
+	u := utils.NewUtilities(quant.Parrot{})
+
+	tests := []struct {
+		line        string
+		environ     []string
+		environment string
+		guards      []string
+		level       string
+		reason      string
+	}{
+		{"ls -la", nil, "", nil, "low", ""},
+		{"rm notes.txt", nil, "", nil, "low", "file removal"},
+		{"rm -rf build", nil, "", nil, "medium", "recursive forced removal"},
+		{"sudo rm -rf /var/lib/app", nil, "", nil, "high", "runs as sudo"},
+		{"sudo -u postgres psql -c DROP TABLE users", nil, "", nil, "high", "database drop"},
+		{"/usr/bin/git push --force origin main", nil, "", nil, "medium", "forced push"},
+		{"git push origin main", nil, "", nil, "low", ""},
+		{"kubectl delete pod web-1", []string{"KUBE_CONTEXT=prod-eu"}, "", nil, "high", "production target KUBE_CONTEXT=prod-eu"},
+		{"kubectl get pods", []string{"NODE_ENV=production"}, "", nil, "medium", "production target"},
+		{"terraform apply", nil, "production", nil, "medium", "production environment production"},
+		{"make deploy", []string{"ENV=product-catalog"}, "", nil, "low", ""},
+		{"deploy.sh --all", nil, "", []string{`^deploy\.sh`}, "medium", "matches guard"},
+	}
+
+	for _, test := range tests {
+		var words = strings.Fields(test.line)
+		risk := u.Risk(words[0], words[1:], test.environ, test.environment, test.guards)
+
+		if risk.Level() != test.level {
+			t.Errorf("Risk(%q) level = %s (%d, %v), want %s", test.line, risk.Level(), risk.Score, risk.Reasons, test.level)
+		}
+
+		if test.reason != "" && !strings.Contains(strings.Join(risk.Reasons, "|"), test.reason) {
+			t.Errorf("Risk(%q) reasons = %v, want %q", test.line, risk.Reasons, test.reason)
+		}
+
+		if test.reason == "" && len(risk.Reasons) > 0 {
+			t.Errorf("Risk(%q) reasons = %v, want none", test.line, risk.Reasons)
+		}
+	}
+}
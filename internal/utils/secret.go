@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// prefix of the values encrypted by EncryptSecret
+const secretPrefix = "enc:v1:"
+
+// NewSecretKey generates a random AES-256 key
+func (u *Utilities) NewSecretKey() ([]byte, error) {
+	var key = make([]byte, 32)
+	_, err := rand.Read(key)
+	return key, err
+}
+
+// rounds of the passphrase key derivation
+const passphraseIterations = 600000
+
+// PassphraseKey derives an AES-256 key from a passphrase with PBKDF2-HMAC-SHA256
+func (u *Utilities) PassphraseKey(passphrase string, salt []byte) []byte {
+	// a single block of PBKDF2 is enough for a 32 bytes key
+	prf := hmac.New(sha256.New, []byte(passphrase))
+	prf.Write(salt)
+	prf.Write([]byte{0, 0, 0, 1})
+
+	var block = prf.Sum(nil)
+	var key = append([]byte{}, block...)
+
+	for i := 1; i < passphraseIterations; i++ {
+		prf.Reset()
+		prf.Write(block)
+		block = prf.Sum(block[:0])
+
+		for j := range key {
+			key[j] ^= block[j]
+		}
+	}
+
+	return key
+}
+
+// EncryptSecret seals a value with AES-GCM, the nonce is stored with the ciphertext
+func (u *Utilities) EncryptSecret(key []byte, value string) (string, error) {
+	gcm, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	var nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret opens a value sealed by EncryptSecret
+func (u *Utilities) DecryptSecret(key []byte, value string) (string, error) {
+	if !strings.HasPrefix(value, secretPrefix) {
+		return "", errors.New("value is not encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("impossible to decrypt the value, was it encrypted with another key?")
+	}
+
+	return string(plain), nil
+}
+
+func secretCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
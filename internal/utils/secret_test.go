@@ -0,0 +1,62 @@
+package utils_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/utils"
+	"github.com/gi4nks/quant"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	u := utils.NewUtilities(quant.Parrot{})
+
+	key, err := u.NewSecretKey()
+	if err != nil {
+		t.Fatalf("NewSecretKey() returned unexpected error: %v", err)
+	}
+
+	encrypted, err := u.EncryptSecret(key, "s3cret")
+	if err != nil {
+		t.Fatalf("EncryptSecret() returned unexpected error: %v", err)
+	}
+	if strings.Contains(encrypted, "s3cret") {
+		t.Errorf("EncryptSecret() leaked the value: %s", encrypted)
+	}
+
+	decrypted, err := u.DecryptSecret(key, encrypted)
+	if err != nil || decrypted != "s3cret" {
+		t.Errorf("DecryptSecret() returned unexpected result: got %q and %v", decrypted, err)
+	}
+
+	// Test case: Another key
+	other, _ := u.NewSecretKey()
+	if _, err := u.DecryptSecret(other, encrypted); err == nil {
+		t.Errorf("DecryptSecret() decrypted a value with the wrong key")
+	}
+
+	// Test case: Plain value
+	if _, err := u.DecryptSecret(key, "s3cret"); err == nil {
+		t.Errorf("DecryptSecret() accepted a plain value")
+	}
+}
+
+func TestPassphraseKey(t *testing.T) {
+	u := utils.NewUtilities(quant.Parrot{})
+
+	// PBKDF2-HMAC-SHA256 with 600000 rounds
+	key := u.PassphraseKey("passphrase", []byte("salt"))
+	if got := hex.EncodeToString(key); got != "a1669ea2cbab0f15f29ded3b7c9683bc913c8d92f598bdc25fea6dc13f197717" {
+		t.Fatalf("PassphraseKey() returned unexpected key: %s", got)
+	}
+
+	if other := u.PassphraseKey("passphrase", []byte("pepper")); string(other) == string(key) {
+		t.Errorf("PassphraseKey() ignored the salt")
+	}
+
+	encrypted, _ := u.EncryptSecret(key, "s3cret")
+	if _, err := u.DecryptSecret(u.PassphraseKey("other", []byte("salt")), encrypted); err == nil {
+		t.Errorf("DecryptSecret() decrypted a value with the wrong passphrase")
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// markers recognized per tool, each line counts at most once
+var warningMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`^\S+:\d+(:\d+)?: warning:`),            // gcc, clang
+	regexp.MustCompile(`^npm WARN`),                            // npm
+	regexp.MustCompile(`^\S+\.py:\d+: \w*Warning:`),            // python warnings
+	regexp.MustCompile(`(?i)^\s*(\[warn(ing)?\]|warn(ing)?:)`), // generic
+}
+
+var errorMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`^\S+:\d+(:\d+)?: (fatal )?error:`),     // gcc, clang
+	regexp.MustCompile(`^\S+\.go:\d+:\d+: `),                   // go build, go vet
+	regexp.MustCompile(`^--- FAIL: `),                          // go test
+	regexp.MustCompile(`^npm ERR!`),                            // npm
+	regexp.MustCompile(`^Traceback \(most recent call last\)`), // python
+	regexp.MustCompile(`(?i)^\s*(\[error\]|error:|fatal:)`),    // generic
+}
+
+// Severities counts the warning and error markers found in a command output
+func (u *Utilities) Severities(text string) (int, int) {
+	var warnings, errors = 0, 0
+
+	for _, line := range strings.Split(text, "\n") {
+		if matchesAny(errorMarkers, line) {
+			errors++
+		} else if matchesAny(warningMarkers, line) {
+			warnings++
+		}
+	}
+
+	return warnings, errors
+}
+
+func matchesAny(markers []*regexp.Regexp, line string) bool {
+	for _, m := range markers {
+		if m.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
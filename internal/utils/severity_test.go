@@ -0,0 +1,34 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/utils"
+	"github.com/gi4nks/quant"
+)
+
+func TestSeverities(t *testing.T) {
+	// Create a new instance of Utilities
+	u := utils.NewUtilities(quant.Parrot{})
+
+	// Test case: Mixed tool output
+	input := `main.c:3:5: warning: unused variable 'x'
+main.c:7:1: error: expected ';' before '}' token
+./main.go:12:2: undefined: handler
+npm WARN deprecated request@2.88.2
+npm ERR! code ERESOLVE
+Traceback (most recent call last):
+  File "app.py", line 1, in <module>
+everything else is fine`
+
+	warnings, errors := u.Severities(input)
+	if warnings != 2 || errors != 4 {
+		t.Errorf("Severities() returned unexpected result: got %d warnings and %d errors, want 2 and 4", warnings, errors)
+	}
+
+	// Test case: Clean output
+	warnings, errors = u.Severities("ok  \tgithub.com/gi4nks/ambros\t0.003s")
+	if warnings != 0 || errors != 0 {
+		t.Errorf("Severities() returned unexpected result: got %d warnings and %d errors, want none", warnings, errors)
+	}
+}
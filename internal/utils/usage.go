@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"os"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// ProcessUsage returns the resources consumed by a terminated process and its children, nil when it
+// did not run; the peak memory is the one of the largest process, the I/O counts the blocks read and
+// written on disk, not the ones served by the page cache
+func (u *Utilities) ProcessUsage(state *os.ProcessState) *models.Usage {
+	if state == nil {
+		return nil
+	}
+
+	var usage = models.Usage{UserTime: state.UserTime(), SystemTime: state.SystemTime()}
+
+	systemUsage(state, &usage)
+
+	return &usage
+}
@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"os"
+	"syscall"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// systemUsage adds the peak memory, in bytes on darwin; the disk I/O is left out, darwin counts the
+// block operations whatever their size
+func systemUsage(state *os.ProcessState, usage *models.Usage) {
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		usage.MaxRSS = rusage.Maxrss
+	}
+}
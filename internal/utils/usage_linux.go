@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"os"
+	"syscall"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// systemUsage adds the peak memory and the disk I/O, in kilobytes and 512 bytes blocks on linux
+func systemUsage(state *os.ProcessState, usage *models.Usage) {
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		usage.MaxRSS = rusage.Maxrss * 1024
+		usage.ReadBytes = rusage.Inblock * 512
+		usage.WrittenBytes = rusage.Oublock * 512
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package utils
+
+import (
+	"os"
+
+	models "github.com/gi4nks/ambros/internal/models"
+)
+
+// systemUsage records the processor times only, the other resources are not known on this system
+func systemUsage(state *os.ProcessState, usage *models.Usage) {}
@@ -0,0 +1,31 @@
+package utils_test
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/gi4nks/ambros/internal/utils"
+	"github.com/gi4nks/quant"
+)
+
+func TestProcessUsage(t *testing.T) {
+	var u = utils.NewUtilities(quant.Parrot{})
+
+	if usage := u.ProcessUsage(nil); usage != nil {
+		t.Errorf("ProcessUsage(nil) = %+v, want nil", usage)
+	}
+
+	cmd := exec.Command("sh", "-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Run returned unexpected error: %v", err)
+	}
+
+	// the peak memory is known on linux and darwin only
+	var memory = runtime.GOOS == "linux" || runtime.GOOS == "darwin"
+
+	usage := u.ProcessUsage(cmd.ProcessState)
+	if usage == nil || usage.CPU() <= 0 || (memory && usage.MaxRSS <= 0) {
+		t.Errorf("ProcessUsage(sh) = %+v, want some processor time and memory", usage)
+	}
+}
@@ -3,6 +3,12 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gi4nks/quant"
 )
@@ -56,3 +62,73 @@ func (u *Utilities) Fatal(e error) {
 		panic(e)
 	}
 }
+
+// ProcessAlive tells if a process with the given pid is still running
+func (u *Utilities) ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// Incognito tells if the session asked ambros not to persist anything
+func Incognito() bool {
+	return os.Getenv(ConstIncognitoEnv) != ""
+}
+
+// ParseDuration parses a duration also accepting days (d) and weeks (w) units
+func (u *Utilities) ParseDuration(s string) (time.Duration, error) {
+	var units = map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+
+	for suffix, unit := range units {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil {
+				return 0, errors.New("invalid duration " + s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	return time.ParseDuration(s)
+}
+
+// ParseSize parses a size like 512KB, 100MB or 2GB into bytes
+func (u *Utilities) ParseSize(s string) (int64, error) {
+	var units = []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+	var value = strings.ToUpper(strings.TrimSpace(s))
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			n, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), 10, 64)
+			if err != nil {
+				return 0, errors.New("invalid size " + s)
+			}
+			return n * unit.size, nil
+		}
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// FormatSize renders a number of bytes in a human readable way
+func (u *Utilities) FormatSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return strconv.FormatFloat(float64(size)/(1<<30), 'f', 1, 64) + "GB"
+	case size >= 1<<20:
+		return strconv.FormatFloat(float64(size)/(1<<20), 'f', 1, 64) + "MB"
+	case size >= 1<<10:
+		return strconv.FormatFloat(float64(size)/(1<<10), 'f', 1, 64) + "KB"
+	}
+	return strconv.FormatInt(size, 10) + "B"
+}
@@ -2,7 +2,9 @@ package utils_test
 
 import (
 	"encoding/json"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/gi4nks/ambros/internal/utils"
 	"github.com/gi4nks/quant"
@@ -74,7 +76,8 @@ func TestCheck(t *testing.T) {
 	u.Check(noError) // Ensure no panic or error
 
 	// Test case: Error
-	testError := json.Unmarshal([]byte("{"), nil)
+	var v interface{}
+	testError := json.Unmarshal([]byte("{"), &v)
 	u.Check(testError) // Ensure no panic or error
 }
 
@@ -87,7 +90,8 @@ func TestFatal(t *testing.T) {
 	u.Fatal(noError) // Ensure no panic or error
 
 	// Test case: Error
-	testError := json.Unmarshal([]byte("{"), nil)
+	var v interface{}
+	testError := json.Unmarshal([]byte("{"), &v)
 	// Fatal should panic, so we need to use a recover function
 	defer func() {
 		if r := recover(); r == nil {
@@ -96,3 +100,78 @@ func TestFatal(t *testing.T) {
 	}()
 	u.Fatal(testError)
 }
+
+func TestProcessAlive(t *testing.T) {
+	// Create a new instance of Utilities
+	u := utils.NewUtilities(quant.Parrot{})
+
+	// Test case: Current process
+	if !u.ProcessAlive(os.Getpid()) {
+		t.Errorf("ProcessAlive() returned false for the current process")
+	}
+
+	// Test case: Invalid pid
+	if u.ProcessAlive(0) {
+		t.Errorf("ProcessAlive() returned true for pid 0")
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	// Create a new instance of Utilities
+	u := utils.NewUtilities(quant.Parrot{})
+
+	cases := map[string]time.Duration{
+		"90d": 90 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+	}
+
+	for input, expected := range cases {
+		result, err := u.ParseDuration(input)
+		if err != nil || result != expected {
+			t.Errorf("ParseDuration(%s) returned unexpected result: got %v (%v), want %v", input, result, err, expected)
+		}
+	}
+
+	// Test case: Invalid duration
+	if _, err := u.ParseDuration("xd"); err == nil {
+		t.Errorf("ParseDuration() did not fail on invalid input")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	// Create a new instance of Utilities
+	u := utils.NewUtilities(quant.Parrot{})
+
+	cases := map[string]int64{
+		"512":   512,
+		"10KB":  10 << 10,
+		"100MB": 100 << 20,
+		"2gb":   2 << 30,
+	}
+
+	for input, expected := range cases {
+		result, err := u.ParseSize(input)
+		if err != nil || result != expected {
+			t.Errorf("ParseSize(%s) returned unexpected result: got %d (%v), want %d", input, result, err, expected)
+		}
+	}
+
+	// Test case: Invalid size
+	if _, err := u.ParseSize("lotsMB"); err == nil {
+		t.Errorf("ParseSize() did not fail on invalid input")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	// Create a new instance of Utilities
+	u := utils.NewUtilities(quant.Parrot{})
+
+	if result := u.FormatSize(100 << 20); result != "100.0MB" {
+		t.Errorf("FormatSize() returned unexpected result: got %s, want %s", result, "100.0MB")
+	}
+
+	if result := u.FormatSize(12); result != "12B" {
+		t.Errorf("FormatSize() returned unexpected result: got %s, want %s", result, "12B")
+	}
+}